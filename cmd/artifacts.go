@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/artifacts"
+)
+
+func newArtifactsCommand(ctx context.Context, input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "Browse the artifacts of the artifact server at --artifact-server-path, the gzipped files of upload-artifact v3 and the zips of v4 are decompressed.",
+		Args:  cobra.NoArgs,
+		RunE:  newArtifactsUIRunE(ctx, input),
+	}
+	cmd.Flags().Bool("serve-ui", false, "serve a web ui to browse the runs and download their artifacts until act is interrupted")
+	cmd.Flags().String("ui-addr", "localhost:0", "address of the web ui, a free port is used if the port is 0")
+
+	ls := &cobra.Command{
+		Use:          "ls [run id [artifact]]",
+		Short:        "List the artifacts with their size and producing job, of all runs or of a run, or the files of an artifact.",
+		Args:         cobra.MaximumNArgs(2),
+		RunE:         newArtifactsListRunE(input),
+		SilenceUsage: true,
+	}
+	ls.Flags().Bool("json", false, "print the artifacts or files as JSON")
+
+	get := &cobra.Command{
+		Use:          "get <run id> <artifact> [file]",
+		Short:        "Write an artifact as zip, or a decompressed file of it.",
+		Args:         cobra.RangeArgs(2, 3),
+		RunE:         newArtifactsGetRunE(input),
+		SilenceUsage: true,
+	}
+	get.Flags().StringP("output", "o", "", "file of the output, - for stdout, <artifact>.zip for an artifact and stdout for a file by default")
+
+	extract := &cobra.Command{
+		Use:          "extract <run id> <artifact> [dir]",
+		Short:        "Write the decompressed files of an artifact to dir, ./<artifact> by default.",
+		Args:         cobra.RangeArgs(2, 3),
+		RunE:         newArtifactsExtractRunE(input),
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(ls, get, extract)
+	return cmd
+}
+
+func openArtifactBrowser(input *Input) (*artifacts.Browser, error) {
+	if input.artifactServerPath == "" {
+		return nil, errors.New("the artifacts are read from --artifact-server-path, which is not set")
+	}
+	return artifacts.OpenBrowser(input.artifactServerPath)
+}
+
+func parseRunID(arg string) (int64, error) {
+	runID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid run id %q", arg)
+	}
+	return runID, nil
+}
+
+// getArtifact returns the browser and the artifact of the run id and the name of the args
+func getArtifact(input *Input, args []string) (*artifacts.Browser, *artifacts.Artifact, error) {
+	runID, err := parseRunID(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	browser, err := openArtifactBrowser(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	artifact, err := browser.Get(runID, args[1])
+	return browser, artifact, err
+}
+
+func newArtifactsUIRunE(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		if serve, _ := cmd.Flags().GetBool("serve-ui"); !serve {
+			return cmd.Help()
+		}
+		browser, err := openArtifactBrowser(input)
+		if err != nil {
+			return err
+		}
+		addr, _ := cmd.Flags().GetString("ui-addr")
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Handler: browser.Handler(), ReadHeaderTimeout: 2 * time.Second}
+		go func() {
+			<-ctx.Done()
+			_ = server.Close()
+		}()
+		fmt.Fprintf(cmd.OutOrStdout(), "artifacts ui listening at http://%s/\n", listener.Addr())
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+func newArtifactsListRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if len(args) == 2 {
+			browser, artifact, err := getArtifact(input, args)
+			if err != nil {
+				return err
+			}
+			files, err := browser.Files(artifact)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				if files == nil {
+					files = []artifacts.ArtifactFile{}
+				}
+				return printJSON(cmd.OutOrStdout(), files)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PATH\tSIZE")
+			for _, file := range files {
+				fmt.Fprintf(w, "%s\t%s\n", file.Path, units.BytesSize(float64(file.Size)))
+			}
+			return w.Flush()
+		}
+
+		browser, err := openArtifactBrowser(input)
+		if err != nil {
+			return err
+		}
+		runIDs := browser.Runs()
+		if len(args) == 1 {
+			runID, err := parseRunID(args[0])
+			if err != nil {
+				return err
+			}
+			runIDs = []int64{runID}
+		}
+		list := []*artifacts.Artifact{}
+		for _, runID := range runIDs {
+			runArtifacts, err := browser.List(runID)
+			if err != nil {
+				return err
+			}
+			list = append(list, runArtifacts...)
+		}
+		if asJSON {
+			return printJSON(cmd.OutOrStdout(), list)
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tNAME\tVERSION\tSIZE\tJOB\tCREATED\tEXPIRES")
+		for _, artifact := range list {
+			job, expires := "-", "-"
+			if artifact.Job != "" {
+				job = artifact.Job
+			}
+			if artifact.ExpiresAt != nil {
+				expires = artifact.ExpiresAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%d\t%s\tv%d\t%s\t%s\t%s\t%s\n", artifact.RunID, artifact.Name, artifact.Version, units.BytesSize(float64(artifact.Size)),
+				job, artifact.CreatedAt.Local().Format(time.DateTime), expires)
+		}
+		return w.Flush()
+	}
+}
+
+func newArtifactsGetRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		browser, artifact, err := getArtifact(input, args)
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = "-"
+			if len(args) == 2 {
+				output = artifact.Name + ".zip"
+			}
+		}
+
+		var out io.Writer = cmd.OutOrStdout()
+		if output != "-" {
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+		if len(args) == 2 {
+			if err := browser.WriteZip(out, artifact); err != nil {
+				return err
+			}
+		} else {
+			if err := browser.WriteFile(out, artifact, args[2]); err != nil {
+				return err
+			}
+		}
+		if output != "-" {
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", output)
+		}
+		return nil
+	}
+}
+
+func newArtifactsExtractRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		browser, artifact, err := getArtifact(input, args)
+		if err != nil {
+			return err
+		}
+		dir := artifact.Name
+		if len(args) == 3 {
+			dir = args[2]
+		}
+		extracted, err := browser.Extract(artifact, dir)
+		for _, file := range extracted {
+			fmt.Fprintln(cmd.OutOrStdout(), file)
+		}
+		return err
+	}
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
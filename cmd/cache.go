@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+)
+
+func newCacheCommand(input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the caches of the cache server at --cache-server-path.",
+		Args:  cobra.NoArgs,
+	}
+
+	ls := &cobra.Command{
+		Use:          "ls [cache ids]",
+		Short:        "List the caches with their key, version, repository, ref, size, creation and last use.",
+		RunE:         newCacheListRunE(input),
+		SilenceUsage: true,
+	}
+	addCacheFilterFlags(ls)
+	ls.Flags().Bool("json", false, "print the caches as JSON")
+
+	inspect := &cobra.Command{
+		Use:          "inspect <cache id>...",
+		Short:        "Print the records of caches as JSON.",
+		Args:         cobra.MinimumNArgs(1),
+		RunE:         newCacheInspectRunE(input),
+		SilenceUsage: true,
+	}
+
+	rm := &cobra.Command{
+		Use:          "rm [cache ids]",
+		Short:        "Remove the caches with the ids or which match the filters.",
+		RunE:         newCacheRemoveRunE(input, false),
+		SilenceUsage: true,
+	}
+	addCacheFilterFlags(rm)
+
+	purge := &cobra.Command{
+		Use:          "purge",
+		Short:        "Remove all caches, including the uploads which are not complete.",
+		Args:         cobra.NoArgs,
+		RunE:         newCacheRemoveRunE(input, true),
+		SilenceUsage: true,
+	}
+
+	export := &cobra.Command{
+		Use:          "export [cache ids]",
+		Short:        "Write the complete caches with the ids or which match the filters to a gzipped tarball, which is read by import on another machine.",
+		RunE:         newCacheExportRunE(input),
+		SilenceUsage: true,
+	}
+	addCacheFilterFlags(export)
+	export.Flags().StringP("output", "o", "-", "file of the tarball, - for stdout")
+
+	imp := &cobra.Command{
+		Use:          "import <file>",
+		Short:        "Add the caches of a tarball which was written by export, - reads stdin. The caches which exist already are skipped.",
+		Args:         cobra.ExactArgs(1),
+		RunE:         newCacheImportRunE(input),
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(ls, inspect, rm, purge, export, imp)
+	return cmd
+}
+
+func addCacheFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("key", "", "select the caches whose key starts with the prefix")
+	cmd.Flags().String("repo", "", "select the caches of the repository, like owner/repo")
+	cmd.Flags().Duration("older-than", 0, "select the caches which were created before the duration, like 168h")
+	cmd.Flags().Duration("unused-for", 0, "select the caches which were not used for the duration, like 72h")
+}
+
+func cacheFilter(cmd *cobra.Command, args []string) (artifactcache.Filter, error) {
+	filter := artifactcache.Filter{}
+	for _, arg := range args {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cache id %q", arg)
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+	filter.KeyPrefix, _ = cmd.Flags().GetString("key")
+	filter.Repo, _ = cmd.Flags().GetString("repo")
+	filter.OlderThan, _ = cmd.Flags().GetDuration("older-than")
+	filter.UnusedFor, _ = cmd.Flags().GetDuration("unused-for")
+	return filter, nil
+}
+
+func newCacheListRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		filter, err := cacheFilter(cmd, args)
+		if err != nil {
+			return err
+		}
+		manager, err := artifactcache.OpenManager(input.cacheServerPath)
+		if err != nil {
+			return err
+		}
+		caches, err := manager.List(filter)
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			return printCachesJSON(cmd.OutOrStdout(), caches)
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tKEY\tVERSION\tREPOSITORY\tREF\tSIZE\tCREATED\tUSED\tCOMPLETE")
+		for _, cache := range caches {
+			version := cache.Version
+			if len(version) > 12 {
+				version = version[:12]
+			}
+			size := "-"
+			if cache.Size >= 0 {
+				size = units.BytesSize(float64(cache.Size))
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", cache.ID, cache.Key, version, cache.Repo, cache.Scope, size,
+				time.Unix(cache.CreatedAt, 0).Format(time.DateTime), time.Unix(cache.UsedAt, 0).Format(time.DateTime), cache.Complete)
+		}
+		return w.Flush()
+	}
+}
+
+func newCacheInspectRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		filter, err := cacheFilter(cmd, args)
+		if err != nil {
+			return err
+		}
+		manager, err := artifactcache.OpenManager(input.cacheServerPath)
+		if err != nil {
+			return err
+		}
+		caches := make([]*artifactcache.Cache, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			cache, err := manager.Get(id)
+			if errors.Is(err, artifactcache.ErrNotFound) {
+				return fmt.Errorf("cache %d not found", id)
+			} else if err != nil {
+				return err
+			}
+			caches = append(caches, cache)
+		}
+		return printCachesJSON(cmd.OutOrStdout(), caches)
+	}
+}
+
+func newCacheRemoveRunE(input *Input, purge bool) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		filter := artifactcache.Filter{}
+		if !purge {
+			var err error
+			if filter, err = cacheFilter(cmd, args); err != nil {
+				return err
+			}
+			// removing all caches needs purge, so a missing filter doesn't remove them by accident
+			if filter.IsZero() {
+				return errors.New("select the caches to remove by id, --key, --repo, --older-than or --unused-for, or remove all of them with purge")
+			}
+		}
+		manager, err := artifactcache.OpenManager(input.cacheServerPath)
+		if err != nil {
+			return err
+		}
+		removed, err := manager.Remove(filter)
+		for _, cache := range removed {
+			fmt.Fprintf(cmd.OutOrStdout(), "removed cache %d %s\n", cache.ID, cache.Key)
+		}
+		return err
+	}
+}
+
+func newCacheExportRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		filter, err := cacheFilter(cmd, args)
+		if err != nil {
+			return err
+		}
+		manager, err := artifactcache.OpenManager(input.cacheServerPath)
+		if err != nil {
+			return err
+		}
+
+		// the summary goes to stderr if the tarball is written to stdout
+		var out io.Writer = cmd.OutOrStdout()
+		summary := cmd.ErrOrStderr()
+		if output, _ := cmd.Flags().GetString("output"); output != "-" {
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+			summary = cmd.OutOrStdout()
+		}
+		exported, err := manager.Export(out, filter)
+		for _, cache := range exported {
+			fmt.Fprintf(summary, "exported cache %d %s\n", cache.ID, cache.Key)
+		}
+		return err
+	}
+}
+
+func newCacheImportRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var in io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+		manager, err := artifactcache.OpenManager(input.cacheServerPath)
+		if err != nil {
+			return err
+		}
+		imported, err := manager.Import(in)
+		for _, cache := range imported {
+			fmt.Fprintf(cmd.OutOrStdout(), "imported cache %d %s\n", cache.ID, cache.Key)
+		}
+		return err
+	}
+}
+
+func printCachesJSON(w io.Writer, caches []*artifactcache.Cache) error {
+	if caches == nil {
+		caches = []*artifactcache.Cache{}
+	}
+	return printJSON(w, caches)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+	"github.com/actions-oss/act-cli/pkg/common"
+)
+
+// runtimeTokenSecretEnv is the secret of the runtime tokens which act cache-server and the act processes of its jobs share
+const runtimeTokenSecretEnv = "ACT_RUNTIME_TOKEN_SECRET"
+
+func newCacheServerCommand(ctx context.Context, input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache-server",
+		Short: "Run the cache server until it is interrupted, so several act processes and machines share it with --env ACTIONS_CACHE_URL=<url>/.",
+		Long: "Run the cache server until it is interrupted, so several act processes and machines share it with --env ACTIONS_CACHE_URL=<url>/.\n" +
+			"It stores the caches at --cache-server-path and listens on --cache-server-port of all interfaces. " +
+			"The requests need the runtime token which act gives the jobs, it is signed with --secret which the act processes get as " + runtimeTokenSecretEnv + ". " +
+			"The caches of a repository are evicted from the least recently used beyond --quota. " +
+			"The counters of hits, misses and bytes are served at <url>/metrics in the Prometheus text format.",
+		Args:         cobra.NoArgs,
+		RunE:         newCacheServerRunE(ctx, input),
+		SilenceUsage: true,
+	}
+	cmd.Flags().String("quota", "", "size of the caches of each repository beyond which the least recently used are evicted, like 10GiB, unlimited if empty")
+	cmd.Flags().String("external-url", "", "url of the cache server which is returned to the clients, like https://cache.example.com when it is behind a proxy")
+	cmd.Flags().String("secret", "", "secret of the runtime tokens, the act processes of the jobs need the same "+runtimeTokenSecretEnv+", defaults to "+runtimeTokenSecretEnv)
+	cmd.Flags().Bool("no-auth", false, "accept the requests of any client, without a runtime token or with one of another secret, the caches of all clients are shared then")
+	return cmd
+}
+
+func newCacheServerRunE(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		var opts []artifactcache.Option
+		if quota, _ := cmd.Flags().GetString("quota"); quota != "" {
+			size, err := units.RAMInBytes(quota)
+			if err != nil {
+				return fmt.Errorf("invalid quota %q: %w", quota, err)
+			}
+			opts = append(opts, artifactcache.WithQuota(size))
+		}
+		if externalURL, _ := cmd.Flags().GetString("external-url"); externalURL != "" {
+			opts = append(opts, artifactcache.WithExternalURL(externalURL))
+		}
+		if noAuth, _ := cmd.Flags().GetBool("no-auth"); !noAuth {
+			secret, _ := cmd.Flags().GetString("secret")
+			if secret == "" {
+				secret = os.Getenv(runtimeTokenSecretEnv)
+			}
+			if secret == "" {
+				return errors.New("the runtime tokens need a secret, give --secret or " + runtimeTokenSecretEnv + " or --no-auth")
+			}
+			common.SetAuthorizationSecret([]byte(secret))
+			opts = append(opts, artifactcache.WithAuth())
+		}
+
+		handler, err := artifactcache.StartHandler(input.cacheServerPath, input.cacheServerAddr, input.cacheServerPort, common.Logger(ctx), opts...)
+		if err != nil {
+			return err
+		}
+		defer handler.Close()
+		fmt.Fprintf(cmd.OutOrStdout(), "cache server listening at %s, metrics at %s/metrics\n", handler.ExternalURL(), handler.ExternalURL())
+
+		<-ctx.Done()
+		return nil
+	}
+}
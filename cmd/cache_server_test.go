@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+)
+
+func TestCacheServerNoAuth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	input := &Input{cacheServerPath: t.TempDir(), cacheServerAddr: "127.0.0.1", cacheServerPort: uint16(port)}
+	cmd := newCacheServerCommand(ctx, input)
+	cmd.SetArgs([]string{"--no-auth"})
+	cmd.SetOut(io.Discard)
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Execute()
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	require.Eventually(t, func() bool {
+		res, err := http.Get(base + "/metrics")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the token of a job of another act process is signed with the secret of that process
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"scp": "Actions.Results:1:1", "repository": "owner/repo", "ac": `[{"Scope":"refs/heads/main","Permission":3}]`,
+	}).SignedString([]byte("secret of the client"))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(&artifactcache.CreateCacheEntryRequest{
+		Key:     "key",
+		Version: "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20",
+	})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, base+artifactcache.CacheV2RouteBase+"/CreateCacheEntry", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "a server without auth accepts any caller")
+}
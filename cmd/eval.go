@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	eval "github.com/actions-oss/act-cli/internal/eval/v2"
+	"github.com/actions-oss/act-cli/pkg/exprparser"
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+func newEvalCommand(ctx context.Context, input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "eval [expression]",
+		Short:        "Evaluate an expression in the context of a job. Without an expression the expressions are read interactively.",
+		Args:         cobra.MaximumNArgs(1),
+		RunE:         newEvalRunE(ctx, input),
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringP("job", "j", "", "job ID which provides the context of the expression")
+	cmd.Flags().String("event-name", "push", "name of the event which triggers the job")
+	cmd.Flags().String("needs", "", "JSON file with the outputs and results of the needed jobs, e.g. {\"setup\": {\"outputs\": {\"matrix\": \"[1]\"}, \"result\": \"success\"}}")
+	cmd.Flags().Bool("trace", false, "print the result of every evaluated sub-expression")
+	_ = cmd.MarkFlagRequired("job")
+	return cmd
+}
+
+func newEvalRunE(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		jobID, _ := cmd.Flags().GetString("job")
+		eventName, _ := cmd.Flags().GetString("event-name")
+		needsFile, _ := cmd.Flags().GetString("needs")
+		trace, _ := cmd.Flags().GetBool("trace")
+
+		forge, err := input.Forge()
+		if err != nil {
+			return err
+		}
+		planner, err := model.NewForgeWorkflowPlanner(input.WorkflowsPath(), !input.workflowRecurse, input.strict, forge)
+		if err != nil {
+			return err
+		}
+		plan, err := planner.PlanJob(jobID)
+		if plan == nil {
+			return err
+		}
+
+		needs, err := readNeeds(needsFile)
+		if err != nil {
+			return err
+		}
+
+		config, err := newRunnerConfig(ctx, cmd, input, eventName)
+		if err != nil {
+			return err
+		}
+		evaluator, err := runner.NewJobEvaluator(ctx, config, plan, jobID, needs)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var tracer eval.TraceWriter
+		if trace {
+			tracer = &evalTrace{w: out}
+		}
+		if len(args) > 0 {
+			return evalExpression(out, evaluator, args[0], tracer)
+		}
+		return evalREPL(cmd.InOrStdin(), out, evaluator, tracer)
+	}
+}
+
+// readNeeds reads the outputs and results of the needed jobs, e.g. recorded from a previous run
+func readNeeds(file string) (map[string]exprparser.Needs, error) {
+	if file == "" {
+		return nil, nil
+	}
+	log.Debugf("Loading needs from %s", file)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	needs := map[string]exprparser.Needs{}
+	if err := json.Unmarshal(data, &needs); err != nil {
+		return nil, fmt.Errorf("failed to parse needs file '%s': %w", file, err)
+	}
+	return needs, nil
+}
+
+func evalExpression(w io.Writer, evaluator *runner.JobEvaluator, expression string, trace eval.TraceWriter) error {
+	value, kind, err := evaluator.Evaluate(expression, trace)
+	if err != nil {
+		return err
+	}
+	formatted, err := formatEvalResult(value, kind)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s: %s\n", kind, formatted)
+	return nil
+}
+
+// evalREPL evaluates one expression per line until the input ends or exit is entered
+func evalREPL(r io.Reader, w io.Writer, evaluator *runner.JobEvaluator, trace eval.TraceWriter) error {
+	fmt.Fprintf(w, "Evaluating expressions with matrix %v, enter exit to quit\n", evaluator.Matrix)
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+		if err := evalExpression(w, evaluator, line, trace); err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+		}
+	}
+}
+
+func formatEvalResult(value interface{}, kind eval.ValueKind) (string, error) {
+	switch kind {
+	case eval.ValueKindObject, eval.ValueKindArray:
+		data, err := json.MarshalIndent(value, "", "  ")
+		return string(data), err
+	case eval.ValueKindString:
+		return fmt.Sprintf("%q", value), nil
+	case eval.ValueKindNull:
+		return "null", nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+type evalTrace struct {
+	w io.Writer
+}
+
+func (t *evalTrace) Trace(level int, message string) {
+	fmt.Fprintf(t.w, "%s%s\n", strings.Repeat("  ", level), message)
+}
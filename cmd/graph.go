@@ -19,7 +19,7 @@ func drawGraph(plan *model.Plan) error {
 
 		ids := make([]string, 0)
 		for _, r := range stage.Runs {
-			ids = append(ids, r.String())
+			ids = append(ids, r.PlanName())
 		}
 		drawings = append(drawings, jobPen.DrawBoxes(ids...))
 	}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"path/filepath"
+	"time"
 
+	"github.com/actions-oss/act-cli/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -38,6 +40,7 @@ type Input struct {
 	workflowRecurse                    bool
 	useGitIgnore                       bool
 	githubInstance                     string
+	forge                              string
 	gitHubServerURL                    string
 	gitHubAPIServerURL                 string
 	gitHubGraphQlAPIServerURL          string
@@ -47,6 +50,7 @@ type Input struct {
 	artifactServerPath                 string
 	artifactServerAddr                 string
 	artifactServerPort                 string
+	keepArtifacts                      bool
 	noCacheServer                      bool
 	cacheServerPath                    string
 	cacheServerAddr                    string
@@ -57,6 +61,8 @@ type Input struct {
 	replaceGheActionWithGithubCom      []string
 	replaceGheActionTokenWithGithubCom string
 	matrix                             []string
+	traceMatrix                        bool
+	statePath                          string
 	actionCachePath                    string
 	actionOfflineMode                  bool
 	logPrefixJobID                     bool
@@ -66,6 +72,17 @@ type Input struct {
 	validate                           bool
 	strict                             bool
 	parallel                           int
+	serviceLogDir                      string
+	serviceLogLines                    int
+	composeFile                        string
+	composeServices                    []string
+	actionBuildCachePath               string
+	actionBuildCacheSize               string
+	actionBuildArgs                    []string
+	actionBuildSecrets                 []string
+	pullRetries                        int
+	pullRetryBackoff                   time.Duration
+	registryMirrors                    []string
 }
 
 func (i *Input) resolve(path string) string {
@@ -111,7 +128,17 @@ func (i *Input) EventPath() string {
 	return i.resolve(i.eventPath)
 }
 
+// ComposeFile returns the path to the docker compose file
+func (i *Input) ComposeFile() string {
+	return i.resolve(i.composeFile)
+}
+
 // Inputfile returns the path to the input file
 func (i *Input) Inputfile() string {
 	return i.resolve(i.inputfile)
 }
+
+// Forge returns the profile of the forge selected by --forge
+func (i *Input) Forge() (*model.Forge, error) {
+	return model.GetForge(i.forge)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/lint"
+)
+
+func newLintCommand(_ context.Context, input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "lint [workflow files]",
+		Short:        "Check the workflows for problems beyond the schema validation. Without files the workflows of --workflows are checked.",
+		RunE:         newLintRunE(input),
+		SilenceUsage: true,
+	}
+	cmd.Flags().Bool("fix", false, "rewrite the workflows to resolve the problems which can be fixed safely")
+	cmd.Flags().String("config", ".actlint.yml", "config file with the severity of the rules and the suppressed problems")
+	cmd.Flags().Bool("list-rules", false, "list the rules with their default severity")
+	return cmd
+}
+
+func newLintRunE(input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		if ok, _ := cmd.Flags().GetBool("list-rules"); ok {
+			for _, rule := range lint.Rules() {
+				fmt.Fprintf(out, "%-20s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+			}
+			return nil
+		}
+		fix, _ := cmd.Flags().GetBool("fix")
+		configPath, _ := cmd.Flags().GetString("config")
+		config, err := lint.LoadConfig(input.resolve(configPath))
+		if err != nil {
+			return err
+		}
+		linter, err := lint.New(config)
+		if err != nil {
+			return err
+		}
+
+		files := args
+		if len(files) == 0 {
+			if files, err = workflowFiles(input.WorkflowsPath(), input.workflowRecurse); err != nil {
+				return err
+			}
+		}
+		var errorCount int
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			findings, err := linter.Lint(file, content)
+			if err != nil {
+				// files which can't be read as workflows don't stop checking the others
+				fmt.Fprintln(out, err.Error())
+				errorCount++
+				continue
+			}
+			if fix {
+				fixed, n := lint.ApplyFixes(content, findings)
+				if n > 0 {
+					// the workflow keeps its mode, the permissions of os.WriteFile only apply to new files
+					info, err := os.Stat(file)
+					if err != nil {
+						return err
+					}
+					if err := os.WriteFile(file, fixed, info.Mode().Perm()); err != nil {
+						return err
+					}
+					if err := os.Chmod(file, info.Mode().Perm()); err != nil {
+						return err
+					}
+					fmt.Fprintf(out, "%s: fixed %d problems\n", file, n)
+					// report the problems which are left
+					if findings, err = linter.Lint(file, fixed); err != nil {
+						return err
+					}
+				}
+			}
+			for _, f := range findings {
+				fmt.Fprintln(out, f.String())
+				if f.Severity == lint.SeverityError {
+					errorCount++
+				}
+			}
+		}
+		if errorCount > 0 {
+			return fmt.Errorf("found %d errors", errorCount)
+		}
+		return nil
+	}
+}
+
+// workflowFiles returns the yaml files of a workflow directory or the path itself if it is a file
+func workflowFiles(path string, recurse bool) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && !recurse {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); strings.EqualFold(ext, ".yml") || strings.EqualFold(ext, ".yaml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintFixKeepsMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ci.yml")
+	require.NoError(t, os.WriteFile(file, []byte(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "::set-output name=version::1.0"
+`), 0o600))
+	require.NoError(t, os.Chmod(file, 0o600))
+
+	cmd := newLintCommand(context.Background(), &Input{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--fix", "--config", filepath.Join(t.TempDir(), "missing.yml"), file})
+	_ = cmd.Execute()
+
+	assert.Contains(t, out.String(), "fixed")
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `echo "version=1.0" >> "$GITHUB_OUTPUT"`)
+}
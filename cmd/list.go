@@ -43,7 +43,7 @@ func printList(plan *model.Plan) error {
 			jobID := r.JobID
 			line := lineInfoDef{
 				jobID:   jobID,
-				jobName: r.String(),
+				jobName: r.PlanName(),
 				stage:   strconv.Itoa(i),
 				wfName:  r.Workflow.Name,
 				wfFile:  r.Workflow.File,
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/lsp"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+func newLspCommand(ctx context.Context, input *Input) *cobra.Command {
+	return &cobra.Command{
+		Use:          "lsp",
+		Short:        "Run a language server for workflow and action files, which communicates over stdin and stdout.",
+		Args:         cobra.NoArgs,
+		RunE:         newLspRunE(ctx, input),
+		SilenceUsage: true,
+	}
+}
+
+func newLspRunE(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
+	return func(_ *cobra.Command, _ []string) error {
+		// the inputs of remote actions are read from the actions fetched by previous runs
+		server := lsp.NewServer(&runner.GoGitActionCache{Path: input.actionCachePath})
+		return server.Run(ctx, os.Stdin, os.Stdout)
+	}
+}
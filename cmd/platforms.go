@@ -12,10 +12,19 @@ func (i *Input) newPlatforms() map[string]string {
 		"ubuntu-18.04":  "node:16-buster-slim",
 	}
 
+	// the labels of Gitea and Forgejo are case sensitive
+	caseSensitive := false
+	if forge, err := i.Forge(); err == nil {
+		caseSensitive = forge.CaseSensitiveLabels
+	}
 	for _, p := range i.platforms {
 		pParts := strings.SplitN(p, "=", 2)
 		if len(pParts) == 2 {
-			platforms[strings.ToLower(pParts[0])] = pParts[1]
+			label := pParts[0]
+			if !caseSensitive {
+				label = strings.ToLower(label)
+			}
+			platforms[label] = pParts[1]
 		}
 	}
 	return platforms
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+func newPrepareCommand(ctx context.Context, input *Input) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "prepare [event name to plan]",
+		Short:        "Pull the images and fetch the actions used by the workflows, e.g. before working offline. Without an event all jobs are prepared.",
+		Args:         cobra.MaximumNArgs(1),
+		RunE:         newPrepareRunE(ctx, input),
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringP("job", "j", "", "prepare a specific job ID")
+	return cmd
+}
+
+func newPrepareRunE(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if input.jsonLogger {
+			log.SetFormatter(&log.JSONFormatter{})
+		}
+		setupDockerHost(input)
+
+		forge, err := input.Forge()
+		if err != nil {
+			return err
+		}
+		planner, err := model.NewForgeWorkflowPlanner(input.WorkflowsPath(), !input.workflowRecurse, input.strict, forge)
+		if err != nil {
+			return err
+		}
+
+		jobID, err := cmd.Flags().GetString("job")
+		if err != nil {
+			return err
+		}
+
+		eventName := "push"
+		var plan *model.Plan
+		var plannerErr error
+		switch {
+		case jobID != "":
+			log.Debugf("Preparing job: %s", jobID)
+			plan, plannerErr = planner.PlanJob(jobID)
+		case len(args) > 0:
+			eventName = args[0]
+			log.Debugf("Preparing jobs for event: %s", eventName)
+			plan, plannerErr = planner.PlanEvent(eventName)
+		default:
+			log.Debugf("Preparing all jobs")
+			plan, plannerErr = planner.PlanAll()
+		}
+		if plan == nil && plannerErr != nil {
+			return plannerErr
+		}
+
+		config, err := newRunnerConfig(ctx, cmd, input, eventName)
+		if err != nil {
+			return err
+		}
+
+		summary := &runner.PrepareSummary{}
+		executor, err := runner.NewPrepareExecutor(config, plan, summary)
+		if err != nil {
+			return err
+		}
+		err = executor(common.WithDryrun(ctx, input.dryrun))
+		printPrepareSummary(cmd.OutOrStdout(), summary)
+		if err != nil {
+			return err
+		}
+		return plannerErr
+	}
+}
+
+func printPrepareSummary(w io.Writer, summary *runner.PrepareSummary) {
+	section := func(title string, items []string) {
+		fmt.Fprintf(w, "%s (%d)\n", title, len(items))
+		for _, item := range items {
+			fmt.Fprintf(w, "  %s\n", item)
+		}
+	}
+	section("Pulled images", summary.Images)
+	section("Built action images", summary.Built)
+	section("Fetched actions", summary.Actions)
+	if len(summary.Failed) > 0 {
+		section("Failed", summary.Failed)
+	}
+}
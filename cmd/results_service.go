@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+	"github.com/actions-oss/act-cli/pkg/artifacts"
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/results"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+// runResultsService is the results service of a run, it serves the artifacts, the cache, the summaries and the logs
+// of the jobs on one port. It stores the artifacts in a temporary directory unless --artifact-server-path is given.
+type runResultsService struct {
+	service *results.Service
+	// cache serves the cache on its own port if --cache-server-addr or --cache-server-port is given
+	cache     *artifactcache.Handler
+	path      string
+	temporary bool
+	keep      bool
+}
+
+// startResultsService starts the results service of the run and points the jobs to it, the artifacts are served
+// if --artifact-server-path is given or the plan uses artifacts and the cache unless --no-cache-server is given.
+// An ACTIONS_RESULTS_URL or ACTIONS_RUNTIME_URL of --env is kept, then it only serves the cache and returns nil
+// if it isn't needed either. If separateCache is true, the cache is served at --cache-server-addr and
+// --cache-server-port instead, like the cache server before the results service.
+func startResultsService(ctx context.Context, input *Input, plan *model.Plan, config *runner.Config, separateCache bool) (*runResultsService, error) {
+	external := config.Env["ACTIONS_RESULTS_URL"] != "" || config.Env["ACTIONS_RUNTIME_URL"] != ""
+	cfg := results.Config{
+		Addr: input.artifactServerAddr,
+		Port: input.artifactServerPort,
+		// a token of the environment may not be one of act
+		RequireAuth: !external && os.Getenv("ACTIONS_RUNTIME_TOKEN") == "",
+	}
+	s := &runResultsService{path: input.artifactServerPath, keep: input.keepArtifacts}
+	if !input.noCacheServer && config.Env["ACTIONS_CACHE_URL"] == "" {
+		if separateCache {
+			var opts []artifactcache.Option
+			if cfg.RequireAuth {
+				opts = append(opts, artifactcache.WithAuth())
+			}
+			cache, err := artifactcache.StartHandler(input.cacheServerPath, input.cacheServerAddr, input.cacheServerPort, common.Logger(ctx), opts...)
+			if err != nil {
+				return nil, err
+			}
+			s.cache = cache
+			config.Env["ACTIONS_CACHE_URL"] = cache.ExternalURL() + "/"
+		} else {
+			cfg.CachePath = input.cacheServerPath
+		}
+	}
+
+	if s.path == "" && !external && planUsesArtifacts(plan) {
+		dir, err := os.MkdirTemp("", "act-artifacts-")
+		if err != nil {
+			s.Close("")
+			return nil, err
+		}
+		s.path = dir
+		s.temporary = true
+	}
+	if !external {
+		cfg.ArtifactPath = s.path
+	} else if cfg.CachePath == "" {
+		if s.cache == nil {
+			return nil, nil
+		}
+		return s, nil
+	}
+
+	service, err := results.Start(ctx, cfg)
+	if err != nil {
+		s.Close("")
+		return nil, err
+	}
+	s.service = service
+	if cfg.CachePath != "" {
+		config.Env["ACTIONS_CACHE_URL"] = service.URL()
+	}
+	if external {
+		return s, nil
+	}
+	if cfg.CachePath != "" {
+		config.Env["ACTIONS_CACHE_SERVICE_V2"] = "true"
+	}
+	config.ResultsURL = service.URL()
+	config.ArtifactServerPath = cfg.ArtifactPath
+	config.ArtifactServerPort = service.Port()
+	return s, nil
+}
+
+// planUsesArtifacts reports whether a job of the plan may need the artifacts, the steps of the called
+// workflows and the composite actions are not known before they run, so a called workflow counts as a use
+func planUsesArtifacts(plan *model.Plan) bool {
+	if plan == nil {
+		return false
+	}
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil {
+				continue
+			}
+			if job.Uses != "" {
+				return true
+			}
+			for _, step := range job.Steps {
+				if step != nil && stepUsesArtifacts(step) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func stepUsesArtifacts(step *model.Step) bool {
+	action, _, _ := strings.Cut(strings.ToLower(step.Uses), "@")
+	if strings.Contains(action, "artifact") {
+		return true
+	}
+	usesRuntime := func(s string) bool {
+		return strings.Contains(s, "ACTIONS_RUNTIME_") || strings.Contains(s, "ACTIONS_RESULTS_URL")
+	}
+	if usesRuntime(step.Run) {
+		return true
+	}
+	for _, v := range step.Environment() {
+		if usesRuntime(v) {
+			return true
+		}
+	}
+	for _, v := range step.With {
+		if usesRuntime(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops the service, prints where the artifacts of the run went and removes the temporary directory
+// unless --keep-artifacts is given
+func (s *runResultsService) Close(runID string) {
+	if s == nil {
+		return
+	}
+	id, err := strconv.ParseInt(runID, 10, 64)
+	if err != nil {
+		id = 1
+	}
+	if s.cache != nil {
+		_ = s.cache.Close()
+	}
+	var list []*artifacts.Artifact
+	if s.service != nil {
+		if browser := s.service.Artifacts(); browser != nil {
+			list, _ = browser.List(id)
+		}
+		s.service.Close()
+	}
+	if s.path == "" {
+		return
+	}
+
+	names := make([]string, 0, len(list))
+	for _, artifact := range list {
+		names = append(names, artifact.Name)
+	}
+	switch {
+	case s.temporary && !s.keep:
+		if len(names) > 0 {
+			log.Infof("The artifacts %s of run %d were removed with the temporary directory of the artifact server, keep them with --keep-artifacts", strings.Join(names, ", "), id)
+		}
+		if err := os.RemoveAll(s.path); err != nil {
+			log.Warnf("failed to remove the artifacts at %s: %v", s.path, err)
+		}
+	case strings.HasPrefix(s.path, "memory://"):
+	case len(names) > 0:
+		location := s.path
+		if !strings.HasPrefix(location, "s3://") {
+			location = filepath.Join(location, strconv.FormatInt(id, 10))
+		}
+		log.Infof("The artifacts %s of run %d are stored at %s, browse them with act artifacts --artifact-server-path %s", strings.Join(names, ", "), id, location, s.path)
+	}
+}
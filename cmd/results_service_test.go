@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+func TestPlanUsesArtifacts(t *testing.T) {
+	plan := func(job *model.Job) *model.Plan {
+		workflow := &model.Workflow{Jobs: map[string]*model.Job{"job": job}}
+		return &model.Plan{Stages: []*model.Stage{{Runs: []*model.Run{{Workflow: workflow, JobID: "job"}}}}}
+	}
+
+	assert.False(t, planUsesArtifacts(nil))
+	assert.False(t, planUsesArtifacts(plan(&model.Job{Steps: []*model.Step{{Uses: "actions/checkout@v4"}, {Run: "make"}}})))
+	assert.True(t, planUsesArtifacts(plan(&model.Job{Steps: []*model.Step{{Uses: "actions/upload-artifact@v4"}}})))
+	assert.True(t, planUsesArtifacts(plan(&model.Job{Steps: []*model.Step{{Uses: "actions/download-artifact@v4"}}})))
+	assert.True(t, planUsesArtifacts(plan(&model.Job{Steps: []*model.Step{{Run: `curl "$ACTIONS_RUNTIME_URL"`}}})))
+	assert.True(t, planUsesArtifacts(plan(&model.Job{Uses: "./.github/workflows/called.yml"})), "the steps of a called workflow are not known")
+}
+
+func TestStartResultsServiceSeparateCache(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	input := &Input{
+		artifactServerAddr: "127.0.0.1",
+		artifactServerPort: "0",
+		cacheServerPath:    t.TempDir(),
+		cacheServerAddr:    "127.0.0.1",
+		cacheServerPort:    uint16(port),
+	}
+	config := &runner.Config{Env: map[string]string{}}
+	s, err := startResultsService(context.Background(), input, nil, config, true)
+	require.NoError(t, err)
+	defer s.Close("1")
+
+	assert.Equal(t, fmt.Sprintf("http://127.0.0.1:%d/", port), config.Env["ACTIONS_CACHE_URL"], "the cache is served at the given port")
+	assert.Empty(t, config.Env["ACTIONS_CACHE_SERVICE_V2"], "the cache service v2 is served at the results url")
+	assert.NotEmpty(t, config.ResultsURL)
+	assert.NotEqual(t, config.Env["ACTIONS_CACHE_URL"], config.ResultsURL)
+}
@@ -12,11 +12,13 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/adrg/xdg"
 	"github.com/andreaskoch/go-fswatch"
 	docker_container "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
 	"github.com/joho/godotenv"
 	gitignore "github.com/sabhiram/go-gitignore"
 	log "github.com/sirupsen/logrus"
@@ -25,8 +27,6 @@ import (
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v3"
 
-	"github.com/actions-oss/act-cli/pkg/artifactcache"
-	"github.com/actions-oss/act-cli/pkg/artifacts"
 	"github.com/actions-oss/act-cli/pkg/common"
 	"github.com/actions-oss/act-cli/pkg/container"
 	"github.com/actions-oss/act-cli/pkg/gh"
@@ -64,6 +64,14 @@ func createRootCommand(ctx context.Context, input *Input, version string) *cobra
 		SilenceUsage:     true,
 	}
 
+	rootCmd.AddCommand(newPrepareCommand(ctx, input))
+	rootCmd.AddCommand(newEvalCommand(ctx, input))
+	rootCmd.AddCommand(newLspCommand(ctx, input))
+	rootCmd.AddCommand(newLintCommand(ctx, input))
+	rootCmd.AddCommand(newCacheServerCommand(ctx, input))
+	rootCmd.AddCommand(newCacheCommand(input))
+	rootCmd.AddCommand(newArtifactsCommand(ctx, input))
+
 	rootCmd.Flags().BoolP("watch", "w", false, "watch the contents of the local repo and run when files change")
 	rootCmd.Flags().BoolVar(&input.validate, "validate", false, "validate workflows")
 	rootCmd.Flags().BoolVar(&input.strict, "strict", false, "use strict workflow schema")
@@ -73,28 +81,29 @@ func createRootCommand(ctx context.Context, input *Input, version string) *cobra
 	rootCmd.Flags().BoolP("bug-report", "", false, "Display system information for bug report")
 	rootCmd.Flags().BoolP("man-page", "", false, "Print a generated manual page to stdout")
 
-	rootCmd.Flags().StringVar(&input.remoteName, "remote-name", "origin", "git remote name that will be used to retrieve url of git repo")
-	rootCmd.Flags().StringArrayVarP(&input.secrets, "secret", "s", []string{}, "secret to make available to actions with optional value (e.g. -s mysecret=foo or -s mysecret)")
-	rootCmd.Flags().StringArrayVar(&input.vars, "var", []string{}, "variable to make available to actions with optional value (e.g. --var myvar=foo or --var myvar)")
-	rootCmd.Flags().StringArrayVarP(&input.envs, "env", "", []string{}, "env to make available to actions with optional value (e.g. --env myenv=foo or --env myenv)")
-	rootCmd.Flags().StringArrayVarP(&input.inputs, "input", "", []string{}, "action input to make available to actions (e.g. --input myinput=foo)")
-	rootCmd.Flags().StringArrayVarP(&input.platforms, "platform", "P", []string{}, "custom image to use per platform (e.g. -P ubuntu-18.04=nektos/act-environments-ubuntu:18.04)")
+	rootCmd.PersistentFlags().StringVar(&input.remoteName, "remote-name", "origin", "git remote name that will be used to retrieve url of git repo")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.secrets, "secret", "s", []string{}, "secret to make available to actions with optional value (e.g. -s mysecret=foo or -s mysecret)")
+	rootCmd.PersistentFlags().StringArrayVar(&input.vars, "var", []string{}, "variable to make available to actions with optional value (e.g. --var myvar=foo or --var myvar)")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.envs, "env", "", []string{}, "env to make available to actions with optional value (e.g. --env myenv=foo or --env myenv)")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.inputs, "input", "", []string{}, "action input to make available to actions (e.g. --input myinput=foo)")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.platforms, "platform", "P", []string{}, "custom image to use per platform (e.g. -P ubuntu-18.04=nektos/act-environments-ubuntu:18.04)")
 	rootCmd.Flags().BoolVarP(&input.reuseContainers, "reuse", "r", false, "don't remove container(s) on successfully completed workflow(s) to maintain state between runs")
 	rootCmd.Flags().BoolVarP(&input.bindWorkdir, "bind", "b", false, "bind working directory to container, rather than copy")
-	rootCmd.Flags().BoolVarP(&input.pullIfNeeded, "pull-if-needed", "", false, "only pull docker image(s) if not present")
-	rootCmd.Flags().BoolVarP(&input.noRebuild, "no-rebuild", "", false, "don't rebuild local action docker action image(s) if already present for correct platform")
-	rootCmd.Flags().BoolVarP(&input.autodetectEvent, "detect-event", "", false, "Use first event type from workflow as event that triggered the workflow")
-	rootCmd.Flags().StringVarP(&input.eventPath, "eventpath", "e", "", "path to event JSON file")
-	rootCmd.Flags().StringVar(&input.defaultBranch, "defaultbranch", "", "the name of the main branch")
+	rootCmd.PersistentFlags().BoolVarP(&input.pullIfNeeded, "pull-if-needed", "", false, "only pull docker image(s) if not present")
+	rootCmd.PersistentFlags().BoolVarP(&input.noRebuild, "no-rebuild", "", false, "don't rebuild local action docker action image(s) if already present for correct platform")
+	rootCmd.PersistentFlags().BoolVarP(&input.autodetectEvent, "detect-event", "", false, "Use first event type from workflow as event that triggered the workflow")
+	rootCmd.PersistentFlags().StringVarP(&input.eventPath, "eventpath", "e", "", "path to event JSON file")
+	rootCmd.PersistentFlags().StringVar(&input.defaultBranch, "defaultbranch", "", "the name of the main branch")
 	rootCmd.Flags().BoolVar(&input.privileged, "privileged", false, "use privileged mode")
 	rootCmd.Flags().StringVar(&input.usernsMode, "userns", "", "user namespace to use")
 	rootCmd.Flags().BoolVar(&input.useGitIgnore, "use-gitignore", true, "Controls whether paths specified in .gitignore should be copied into container")
 	rootCmd.Flags().StringArrayVarP(&input.containerCapAdd, "container-cap-add", "", []string{}, "kernel capabilities to add to the workflow containers (e.g. --container-cap-add SYS_PTRACE)")
 	rootCmd.Flags().StringArrayVarP(&input.containerCapDrop, "container-cap-drop", "", []string{}, "kernel capabilities to remove from the workflow containers (e.g. --container-cap-drop SYS_PTRACE)")
 	rootCmd.Flags().BoolVar(&input.autoRemove, "rm", false, "automatically remove container(s)/volume(s) after a workflow(s) failure")
-	rootCmd.Flags().StringArrayVarP(&input.replaceGheActionWithGithubCom, "replace-ghe-action-with-github-com", "", []string{}, "If you are using GitHub Enterprise Server and allow specified actions from GitHub (github.com), you can set actions on this. (e.g. --replace-ghe-action-with-github-com =github/super-linter)")
-	rootCmd.Flags().StringVar(&input.replaceGheActionTokenWithGithubCom, "replace-ghe-action-token-with-github-com", "", "If you are using replace-ghe-action-with-github-com  and you want to use private actions on GitHub, you have to set personal access token")
-	rootCmd.Flags().StringArrayVarP(&input.matrix, "matrix", "", []string{}, "specify which matrix configuration to include (e.g. --matrix java:13")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.replaceGheActionWithGithubCom, "replace-ghe-action-with-github-com", "", []string{}, "If you are using GitHub Enterprise Server and allow specified actions from GitHub (github.com), you can set actions on this. (e.g. --replace-ghe-action-with-github-com =github/super-linter)")
+	rootCmd.PersistentFlags().StringVar(&input.replaceGheActionTokenWithGithubCom, "replace-ghe-action-token-with-github-com", "", "If you are using replace-ghe-action-with-github-com  and you want to use private actions on GitHub, you have to set personal access token")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.matrix, "matrix", "", []string{}, "specify which matrix configuration to include (e.g. --matrix java:13")
+	rootCmd.PersistentFlags().BoolVar(&input.traceMatrix, "trace-matrix", false, "explain how every include and exclude entry changed the combinations of the matrix")
 	rootCmd.Flags().IntVarP(&input.parallel, "parallel", "", 0, "number of jobs to run in parallel")
 	rootCmd.Flags().IntVarP(&input.parallel, "concurrent-jobs", "", 0, "number of jobs to run in parallel")
 	rootCmd.PersistentFlags().StringVarP(&input.actor, "actor", "a", "nektos/act", "user that triggered the event")
@@ -114,23 +123,37 @@ func createRootCommand(ctx context.Context, input *Input, version string) *cobra
 	rootCmd.PersistentFlags().StringVarP(&input.containerArchitecture, "container-architecture", "", "", "Architecture which should be used to run containers, e.g.: linux/amd64. If not specified, will use host default architecture. Requires Docker server API Version 1.41+. Ignored on earlier Docker server platforms.")
 	rootCmd.PersistentFlags().StringVarP(&input.containerDaemonSocket, "container-daemon-socket", "", "", "URI to Docker Engine socket (e.g.: unix://~/.docker/run/docker.sock or - to disable bind mounting the socket)")
 	rootCmd.PersistentFlags().StringVarP(&input.containerOptions, "container-options", "", "", "Custom docker container options for the job container without an options property in the job definition")
-	rootCmd.PersistentFlags().StringVarP(&input.githubInstance, "github-instance", "", "github.com", "GitHub instance to use. Only use this when using GitHub Enterprise Server.")
+	rootCmd.PersistentFlags().StringVarP(&input.forge, "forge", "", model.ForgeGitHub, "Forge whose workflows are run: "+strings.Join(model.ForgeNames(), ", ")+". It selects the schema, the contexts, the events, the default instance and how runs-on labels are matched.")
+	rootCmd.PersistentFlags().StringVarP(&input.githubInstance, "github-instance", "", "", "GitHub instance to use, defaults to the instance of the forge like github.com. Only use this when using GitHub Enterprise Server.")
 	rootCmd.PersistentFlags().StringVarP(&input.gitHubServerURL, "github-server-url", "", "", "Fully qualified URL to the GitHub instance to use with http/https protocol. Only use this when using GitHub Enterprise Server or Gitea.")
 	rootCmd.PersistentFlags().StringVarP(&input.gitHubAPIServerURL, "github-api-server-url", "", "", "Fully qualified URL to the GitHub instance api url to use with http/https protocol. Only use this when using GitHub Enterprise Server or Gitea.")
 	rootCmd.PersistentFlags().StringVarP(&input.gitHubGraphQlAPIServerURL, "github-graph-ql-api-server-url", "", "", "Fully qualified URL to the GitHub instance graphql api to use with http/https protocol. Only use this when using GitHub Enterprise Server or Gitea.")
-	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPath, "artifact-server-path", "", "", "Defines the path where the artifact server stores uploads and retrieves downloads from. If not specified the artifact server will not start.")
-	rootCmd.PersistentFlags().StringVarP(&input.artifactServerAddr, "artifact-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the artifact server binds.")
-	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPort, "artifact-server-port", "", "34567", "Defines the port where the artifact server listens.")
+	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPath, "artifact-server-path", "", "", "Defines the path where the artifact server stores uploads and retrieves downloads from, s3://bucket/prefix?endpoint=url for an S3-compatible object store with the credentials of AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or memory:// to keep the artifacts in memory until act exits. If not specified the artifact server stores them in a temporary directory when the workflows use artifacts, which is removed after the run unless --keep-artifacts is given.")
+	rootCmd.PersistentFlags().StringVarP(&input.artifactServerAddr, "artifact-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the results service binds, it serves the artifacts, the cache, the step summaries and the logs of the jobs at ACTIONS_RESULTS_URL.")
+	rootCmd.PersistentFlags().StringVarP(&input.artifactServerPort, "artifact-server-port", "", "0", "Defines the port where the results service listens. 0 means a randomly available port.")
+	rootCmd.PersistentFlags().BoolVarP(&input.keepArtifacts, "keep-artifacts", "", false, "Keep the temporary directory of the artifact server after the run, when --artifact-server-path is not specified.")
 	rootCmd.PersistentFlags().BoolVarP(&input.noSkipCheckout, "no-skip-checkout", "", false, "Do not skip actions/checkout")
 	rootCmd.PersistentFlags().BoolVarP(&input.noCacheServer, "no-cache-server", "", false, "Disable cache server")
-	rootCmd.PersistentFlags().StringVarP(&input.cacheServerPath, "cache-server-path", "", filepath.Join(CacheHomeDir, "actcache"), "Defines the path where the cache server stores caches.")
-	rootCmd.PersistentFlags().StringVarP(&input.cacheServerAddr, "cache-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the cache server binds.")
-	rootCmd.PersistentFlags().Uint16VarP(&input.cacheServerPort, "cache-server-port", "", 0, "Defines the port where the artifact server listens. 0 means a randomly available port.")
+	rootCmd.PersistentFlags().StringVarP(&input.cacheServerPath, "cache-server-path", "", filepath.Join(CacheHomeDir, "actcache"), "Defines the path where the cache server stores caches, shared:///path for a directory shared by several machines like a NFS mount, or s3://bucket/prefix?endpoint=url for an S3-compatible object store with the credentials of AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.")
+	rootCmd.PersistentFlags().StringVarP(&input.cacheServerAddr, "cache-server-addr", "", common.GetOutboundIP().String(), "Defines the address to which the cache server binds. The cache of a run is served by the results service unless this or --cache-server-port is given.")
+	rootCmd.PersistentFlags().Uint16VarP(&input.cacheServerPort, "cache-server-port", "", 0, "Defines the port where the cache server listens. 0 means a randomly available port. The cache of a run is served by the results service unless this or --cache-server-addr is given.")
 	rootCmd.PersistentFlags().StringVarP(&input.actionCachePath, "action-cache-path", "", filepath.Join(CacheHomeDir, "act"), "Defines the path where the actions get cached and host workspaces created.")
+	rootCmd.PersistentFlags().StringVarP(&input.actionBuildCachePath, "action-build-cache-path", "", filepath.Join(CacheHomeDir, "actbuild"), "Defines the path where the images of Dockerfile actions are cached as docker save archives keyed by the digest of the build context. Set to an empty string to disable the cache.")
+	rootCmd.PersistentFlags().StringVarP(&input.actionBuildCacheSize, "action-build-cache-size", "", "5GiB", "Defines the size of the images in --action-build-cache-path beyond which the least recently used are removed, like 10GiB. 0 means unlimited.")
+	rootCmd.PersistentFlags().StringVarP(&input.statePath, "state-path", "", filepath.Join(CacheHomeDir, "actstate"), "Defines the path where the state of the jobs of a workflow run is stored while it runs. Set to an empty string to disable it.")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.actionBuildArgs, "action-build-arg", "", []string{}, "build arg for Dockerfile actions (e.g. --action-build-arg VERSION=1.0)")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.actionBuildSecrets, "action-build-secret", "", []string{}, "exposes a secret to RUN --mount=type=secret in Dockerfile actions, requires BuildKit (e.g. --action-build-secret npmrc=NPM_TOKEN or --action-build-secret NPM_TOKEN)")
+	rootCmd.PersistentFlags().IntVarP(&input.pullRetries, "pull-retries", "", 3, "Number of retries when pulling an image fails with a registry server error or a timeout")
+	rootCmd.PersistentFlags().DurationVarP(&input.pullRetryBackoff, "pull-retry-backoff", "", time.Second, "Delay before the first retry of an image pull, it doubles with every retry")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.registryMirrors, "registry-mirror", "", []string{}, "Pulls images of a registry from a mirror (e.g. --registry-mirror docker.io=registry.local:5000)")
 	rootCmd.PersistentFlags().BoolVarP(&input.actionOfflineMode, "action-offline-mode", "", false, "If action contents exists, it will not be fetch and pull again. If turn on this, will turn off force pull")
 	rootCmd.PersistentFlags().StringVarP(&input.networkName, "network", "", "host", "Sets a docker network name. Defaults to host.")
 	rootCmd.PersistentFlags().StringArrayVarP(&input.localRepository, "local-repository", "", []string{}, "Replaces the specified repository and ref with a local folder (e.g. https://github.com/test/test@v0=/home/act/test or test/test@v0=/home/act/test, the latter matches any hosts or protocols)")
 	rootCmd.PersistentFlags().BoolVar(&input.listOptions, "list-options", false, "Print a json structure of compatible options")
+	rootCmd.PersistentFlags().StringVarP(&input.serviceLogDir, "service-log-dir", "", "", "Defines the path where the output of service containers is written, one file per service. If not specified the output is only forwarded to the job log.")
+	rootCmd.PersistentFlags().StringVarP(&input.composeFile, "compose-file", "", "", "docker compose file used as a source of job services, its services are only added to the jobs given by --compose-service")
+	rootCmd.PersistentFlags().StringArrayVarP(&input.composeServices, "compose-service", "", []string{}, "add services of the --compose-file to a job, all services are added if none are listed (e.g. --compose-service build=db,redis or --compose-service build)")
+	rootCmd.PersistentFlags().IntVarP(&input.serviceLogLines, "service-log-lines", "", 50, "Number of service container log lines to print when a service fails to start or a step fails")
 	rootCmd.SetArgs(args())
 	return rootCmd
 }
@@ -381,6 +404,93 @@ func parseMatrix(matrix []string) map[string]map[string]bool {
 	return matrixes
 }
 
+// parseBuildSecrets maps build secret ids to act secrets, an id without a
+// secret name refers to the secret with the same name
+func parseBuildSecrets(mappings []string, secrets map[string]string) (map[string]string, error) {
+	buildSecrets := map[string]string{}
+	for _, mapping := range mappings {
+		id, name, ok := strings.Cut(mapping, "=")
+		if !ok {
+			name = id
+		}
+		value, ok := secrets[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("build secret %s refers to the unknown secret %s", id, name)
+		}
+		buildSecrets[id] = value
+	}
+	return buildSecrets, nil
+}
+
+// parseRegistryMirrors parses host=mirror mappings, the aliases of docker hub are normalized to docker.io
+func parseRegistryMirrors(mappings []string) (map[string]string, error) {
+	mirrors := map[string]string{}
+	for _, mapping := range mappings {
+		host, mirror, ok := strings.Cut(mapping, "=")
+		host, mirror = strings.TrimSpace(host), strings.TrimSpace(mirror)
+		if !ok || host == "" || mirror == "" {
+			return nil, fmt.Errorf("invalid registry mirror '%s', expected host=mirror", mapping)
+		}
+		switch host {
+		case "index.docker.io", "registry-1.docker.io":
+			host = "docker.io"
+		}
+		mirrors[host] = mirror
+	}
+	return mirrors, nil
+}
+
+// parseActionBuildCacheSize parses --action-build-cache-size like 10GiB, empty and 0 mean unlimited
+func parseActionBuildCacheSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --action-build-cache-size %q: %w", size, err)
+	}
+	return bytes, nil
+}
+
+// parseComposeServices maps the services of a docker compose file to jobs,
+// each mapping is of the form - job=service,service or job
+func parseComposeServices(composeFile string, mappings []string) (map[string]map[string]*model.ContainerSpec, error) {
+	if len(mappings) == 0 {
+		if composeFile != "" {
+			// a compose file on its own doesn't add its services to any job
+			return nil, fmt.Errorf("--compose-file requires --compose-service")
+		}
+		return nil, nil
+	}
+	if composeFile == "" {
+		return nil, fmt.Errorf("--compose-service requires --compose-file")
+	}
+	compose, err := model.ReadComposeFile(composeFile)
+	if err != nil {
+		return nil, err
+	}
+	services := map[string]map[string]*model.ContainerSpec{}
+	for _, m := range mappings {
+		jobID, names, _ := strings.Cut(m, "=")
+		var serviceNames []string
+		if names != "" {
+			serviceNames = strings.Split(names, ",")
+		}
+		specs, err := compose.ContainerSpecs(serviceNames...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --compose-service %s: %w", m, err)
+		}
+		if services[jobID] == nil {
+			services[jobID] = map[string]*model.ContainerSpec{}
+		}
+		for id, spec := range specs {
+			services[jobID][id] = spec
+		}
+	}
+	log.Debugf("Loaded compose services: %v", services)
+	return services, nil
+}
+
 //nolint:gocyclo
 func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
@@ -400,13 +510,7 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			return listOptions(cmd)
 		}
 
-		if ret, err := container.GetSocketAndHost(input.containerDaemonSocket); err != nil {
-			log.Warnf("Couldn't get a valid docker connection: %+v", err)
-		} else {
-			os.Setenv("DOCKER_HOST", ret.Host)
-			input.containerDaemonSocket = ret.Socket
-			log.Infof("Using docker host '%s', and daemon socket '%s'", ret.Host, ret.Socket)
-		}
+		setupDockerHost(input)
 
 		if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" && input.containerArchitecture == "" {
 			l := log.New()
@@ -417,42 +521,11 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			l.Warnf(" \U000026A0 You are using Apple M-series chip and you have not specified container architecture, you might encounter issues while running act. If so, try running it with '--container-architecture linux/amd64'. \U000026A0 \n")
 		}
 
-		log.Debugf("Loading environment from %s", input.Envfile())
-		envs := parseEnvs(input.envs)
-		_ = readEnvs(input.Envfile(), envs)
-
-		log.Debugf("Loading action inputs from %s", input.Inputfile())
-		inputs := parseEnvs(input.inputs)
-		_ = readEnvs(input.Inputfile(), inputs)
-
-		log.Debugf("Loading secrets from %s", input.Secretfile())
-		secrets := newSecrets(input.secrets)
-		_ = readEnvsEx(input.Secretfile(), secrets, true)
-
-		if _, hasGitHubToken := secrets["GITHUB_TOKEN"]; !hasGitHubToken {
-			ctx, cancel := common.EarlyCancelContext(ctx)
-			defer cancel()
-			secrets["GITHUB_TOKEN"], _ = gh.GetToken(ctx, "")
-		}
-
-		log.Debugf("Loading vars from %s", input.Varfile())
-		vars := newSecrets(input.vars)
-		_ = readEnvs(input.Varfile(), vars)
-
-		log.Debugf("Cleaning up %s old action cache format", input.actionCachePath)
-		entries, _ := os.ReadDir(input.actionCachePath)
-		for _, entry := range entries {
-			if strings.Contains(entry.Name(), "@") {
-				fullPath := filepath.Join(input.actionCachePath, entry.Name())
-				log.Debugf("Removing %s", fullPath)
-				_ = os.RemoveAll(fullPath)
-			}
+		forge, err := input.Forge()
+		if err != nil {
+			return err
 		}
-
-		matrixes := parseMatrix(input.matrix)
-		log.Debugf("Evaluated matrix inclusions: %v", matrixes)
-
-		planner, err := model.NewWorkflowPlanner(input.WorkflowsPath(), !input.workflowRecurse, input.strict)
+		planner, err := model.NewForgeWorkflowPlanner(input.WorkflowsPath(), !input.workflowRecurse, input.strict, forge)
 		if err != nil {
 			return err
 		}
@@ -550,6 +623,9 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			log.Debugf("Using default workflow event: push")
 			eventName = "push"
 		}
+		if !forge.SupportsEvent(eventName) {
+			return fmt.Errorf("the event %s does not trigger workflows on %s", eventName, forge.Name)
+		}
 
 		// build the plan for this run
 		if jobID != "" {
@@ -568,116 +644,28 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			return plannerErr
 		}
 
-		// check to see if the main branch was defined
-		defaultbranch, err := cmd.Flags().GetString("defaultbranch")
+		config, err := newRunnerConfig(ctx, cmd, input, eventName)
 		if err != nil {
 			return err
 		}
 
-		// Check if platforms flag is set, if not, run default image survey
-		if len(input.platforms) == 0 {
-			cfgFound := false
-			cfgLocations := configLocations()
-			for _, v := range cfgLocations {
-				_, err := os.Stat(v)
-				if os.IsExist(err) {
-					cfgFound = true
-				}
-			}
-			if !cfgFound && len(cfgLocations) > 0 {
-				// The first config location refers to the global config folder one
-				if err := defaultImageSurvey(cfgLocations[0]); err != nil {
-					log.Fatal(err)
-				}
-				input.platforms = readArgsFile(cfgLocations[0], true)
-			}
+		// the tokens of the jobs are accepted by an act cache-server with the same secret
+		if secret := os.Getenv(runtimeTokenSecretEnv); secret != "" {
+			common.SetAuthorizationSecret([]byte(secret))
 		}
-		deprecationWarning := "--%s is deprecated and will be removed soon, please switch to cli: `--container-options \"%[2]s\"` or `.actrc`: `--container-options %[2]s`."
-		if input.privileged {
-			log.Warnf(deprecationWarning, "privileged", "--privileged")
-		}
-		if len(input.usernsMode) > 0 {
-			log.Warnf(deprecationWarning, "userns", fmt.Sprintf("--userns=%s", input.usernsMode))
-		}
-		if len(input.containerCapAdd) > 0 {
-			log.Warnf(deprecationWarning, "container-cap-add", fmt.Sprintf("--cap-add=%s", input.containerCapAdd))
-		}
-		if len(input.containerCapDrop) > 0 {
-			log.Warnf(deprecationWarning, "container-cap-drop", fmt.Sprintf("--cap-drop=%s", input.containerCapDrop))
-		}
-
-		// run the plan
-		config := &runner.Config{
-			Actor:                              input.actor,
-			EventName:                          eventName,
-			EventPath:                          input.EventPath(),
-			DefaultBranch:                      defaultbranch,
-			ForcePull:                          !input.actionOfflineMode && !input.pullIfNeeded,
-			ForceRebuild:                       !input.noRebuild,
-			ReuseContainers:                    input.reuseContainers,
-			Workdir:                            input.Workdir(),
-			ActionCacheDir:                     input.actionCachePath,
-			ActionOfflineMode:                  input.actionOfflineMode,
-			BindWorkdir:                        input.bindWorkdir,
-			LogOutput:                          !input.noOutput,
-			JSONLogger:                         input.jsonLogger,
-			LogPrefixJobID:                     input.logPrefixJobID,
-			Env:                                envs,
-			Secrets:                            secrets,
-			Vars:                               vars,
-			Inputs:                             inputs,
-			Token:                              secrets["GITHUB_TOKEN"],
-			InsecureSecrets:                    input.insecureSecrets,
-			Platforms:                          input.newPlatforms(),
-			Privileged:                         input.privileged,
-			UsernsMode:                         input.usernsMode,
-			ContainerArchitecture:              input.containerArchitecture,
-			ContainerDaemonSocket:              input.containerDaemonSocket,
-			ContainerOptions:                   input.containerOptions,
-			UseGitIgnore:                       input.useGitIgnore,
-			GitHubInstance:                     input.githubInstance,
-			GitHubServerURL:                    input.gitHubServerURL,
-			GitHubAPIServerURL:                 input.gitHubAPIServerURL,
-			GitHubGraphQlAPIServerURL:          input.gitHubGraphQlAPIServerURL,
-			ContainerCapAdd:                    input.containerCapAdd,
-			ContainerCapDrop:                   input.containerCapDrop,
-			AutoRemove:                         input.autoRemove,
-			ArtifactServerPath:                 input.artifactServerPath,
-			ArtifactServerAddr:                 input.artifactServerAddr,
-			ArtifactServerPort:                 input.artifactServerPort,
-			NoSkipCheckout:                     input.noSkipCheckout,
-			RemoteName:                         input.remoteName,
-			ReplaceGheActionWithGithubCom:      input.replaceGheActionWithGithubCom,
-			ReplaceGheActionTokenWithGithubCom: input.replaceGheActionTokenWithGithubCom,
-			Matrix:                             matrixes,
-			ContainerNetworkMode:               docker_container.NetworkMode(input.networkName),
-			Parallel:                           input.parallel,
-		}
-		if input.actionOfflineMode {
-			config.ActionCache = &runner.GoGitActionCacheOfflineMode{
-				Parent: runner.GoGitActionCache{
-					Path: config.ActionCacheDir,
-				},
-			}
-		}
-		if len(input.localRepository) > 0 {
-			localRepositories := map[string]string{}
-			for _, l := range input.localRepository {
-				k, v, _ := strings.Cut(l, "=")
-				localRepositories[k] = v
-			}
-			config.ActionCache = &runner.LocalRepositoryCache{
-				Parent:            config.ActionCache,
-				LocalRepositories: localRepositories,
-				CacheDirCache:     map[string]string{},
-			}
+		// a cache server address or port which is given explicitly is kept for the cache of the run, like for firewalls
+		separateCache := cmd.Flags().Changed("cache-server-addr") || cmd.Flags().Changed("cache-server-port")
+		resultsService, err := startResultsService(ctx, input, plan, config, separateCache)
+		if err != nil {
+			return err
 		}
+		defer resultsService.Close(config.Env["GITHUB_RUN_ID"])
 
 		var r runner.Runner
 		if eventName == "workflow_call" {
 			// Do not use the totally broken code and instead craft a fake caller
 			convertedInputs := make(map[string]interface{})
-			for k, v := range inputs {
+			for k, v := range config.Inputs {
 				var raw interface{}
 				if err := yaml.Unmarshal([]byte(v), &raw); err != nil {
 					return fmt.Errorf("failed to unmarshal input %s: %w", k, err)
@@ -707,19 +695,6 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			return err
 		}
 
-		cancel := artifacts.Serve(ctx, input.artifactServerPath, input.artifactServerAddr, input.artifactServerPort)
-
-		const cacheURLKey = "ACTIONS_CACHE_URL"
-		var cacheHandler *artifactcache.Handler
-		if !input.noCacheServer && envs[cacheURLKey] == "" {
-			var err error
-			cacheHandler, err = artifactcache.StartHandler(input.cacheServerPath, input.cacheServerAddr, input.cacheServerPort, common.Logger(ctx))
-			if err != nil {
-				return err
-			}
-			envs[cacheURLKey] = cacheHandler.ExternalURL() + "/"
-		}
-
 		ctx = common.WithDryrun(ctx, input.dryrun)
 		if watch, err := cmd.Flags().GetBool("watch"); err != nil {
 			return err
@@ -731,12 +706,7 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 			return plannerErr
 		}
 
-		executor := r.NewPlanExecutor(plan).Finally(func(_ context.Context) error {
-			cancel()
-			_ = cacheHandler.Close()
-			return nil
-		})
-		err = executor(ctx)
+		err = r.NewPlanExecutor(plan)(ctx)
 		if err != nil {
 			return err
 		}
@@ -744,6 +714,193 @@ func newRunCommand(ctx context.Context, input *Input) func(*cobra.Command, []str
 	}
 }
 
+// setupDockerHost resolves the docker daemon socket and exports DOCKER_HOST
+func setupDockerHost(input *Input) {
+	if ret, err := container.GetSocketAndHost(input.containerDaemonSocket); err != nil {
+		log.Warnf("Couldn't get a valid docker connection: %+v", err)
+	} else {
+		os.Setenv("DOCKER_HOST", ret.Host)
+		input.containerDaemonSocket = ret.Socket
+		log.Infof("Using docker host '%s', and daemon socket '%s'", ret.Host, ret.Socket)
+	}
+}
+
+// newRunnerConfig loads the env, inputs, secrets and vars and creates the runner config for an event
+func newRunnerConfig(ctx context.Context, cmd *cobra.Command, input *Input, eventName string) (*runner.Config, error) {
+	log.Debugf("Loading environment from %s", input.Envfile())
+	envs := parseEnvs(input.envs)
+	_ = readEnvs(input.Envfile(), envs)
+
+	log.Debugf("Loading action inputs from %s", input.Inputfile())
+	inputs := parseEnvs(input.inputs)
+	_ = readEnvs(input.Inputfile(), inputs)
+
+	log.Debugf("Loading secrets from %s", input.Secretfile())
+	secrets := newSecrets(input.secrets)
+	_ = readEnvsEx(input.Secretfile(), secrets, true)
+
+	if _, hasGitHubToken := secrets["GITHUB_TOKEN"]; !hasGitHubToken {
+		ctx, cancel := common.EarlyCancelContext(ctx)
+		defer cancel()
+		secrets["GITHUB_TOKEN"], _ = gh.GetToken(ctx, "")
+	}
+
+	log.Debugf("Loading vars from %s", input.Varfile())
+	vars := newSecrets(input.vars)
+	_ = readEnvs(input.Varfile(), vars)
+
+	log.Debugf("Cleaning up %s old action cache format", input.actionCachePath)
+	entries, _ := os.ReadDir(input.actionCachePath)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "@") {
+			fullPath := filepath.Join(input.actionCachePath, entry.Name())
+			log.Debugf("Removing %s", fullPath)
+			_ = os.RemoveAll(fullPath)
+		}
+	}
+
+	buildSecrets, err := parseBuildSecrets(input.actionBuildSecrets, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	registryMirrors, err := parseRegistryMirrors(input.registryMirrors)
+	if err != nil {
+		return nil, err
+	}
+
+	matrixes := parseMatrix(input.matrix)
+	log.Debugf("Evaluated matrix inclusions: %v", matrixes)
+
+	composeServices, err := parseComposeServices(input.ComposeFile(), input.composeServices)
+	if err != nil {
+		return nil, err
+	}
+
+	actionBuildCacheSize, err := parseActionBuildCacheSize(input.actionBuildCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// check to see if the main branch was defined, it is a persistent flag of the root command which
+	// cmd.Flags() only has once the command line is parsed
+	defaultbranch := input.defaultBranch
+
+	// Check if platforms flag is set, if not, run default image survey
+	if len(input.platforms) == 0 {
+		cfgFound := false
+		cfgLocations := configLocations()
+		for _, v := range cfgLocations {
+			_, err := os.Stat(v)
+			if os.IsExist(err) {
+				cfgFound = true
+			}
+		}
+		if !cfgFound && len(cfgLocations) > 0 {
+			// The first config location refers to the global config folder one
+			if err := defaultImageSurvey(cfgLocations[0]); err != nil {
+				log.Fatal(err)
+			}
+			input.platforms = readArgsFile(cfgLocations[0], true)
+		}
+	}
+	deprecationWarning := "--%s is deprecated and will be removed soon, please switch to cli: `--container-options \"%[2]s\"` or `.actrc`: `--container-options %[2]s`."
+	if input.privileged {
+		log.Warnf(deprecationWarning, "privileged", "--privileged")
+	}
+	if len(input.usernsMode) > 0 {
+		log.Warnf(deprecationWarning, "userns", fmt.Sprintf("--userns=%s", input.usernsMode))
+	}
+	if len(input.containerCapAdd) > 0 {
+		log.Warnf(deprecationWarning, "container-cap-add", fmt.Sprintf("--cap-add=%s", input.containerCapAdd))
+	}
+	if len(input.containerCapDrop) > 0 {
+		log.Warnf(deprecationWarning, "container-cap-drop", fmt.Sprintf("--cap-drop=%s", input.containerCapDrop))
+	}
+
+	config := &runner.Config{
+		Actor:                              input.actor,
+		EventName:                          eventName,
+		EventPath:                          input.EventPath(),
+		DefaultBranch:                      defaultbranch,
+		ForcePull:                          !input.actionOfflineMode && !input.pullIfNeeded,
+		ForceRebuild:                       !input.noRebuild,
+		ReuseContainers:                    input.reuseContainers,
+		Workdir:                            input.Workdir(),
+		ActionCacheDir:                     input.actionCachePath,
+		ActionOfflineMode:                  input.actionOfflineMode,
+		BindWorkdir:                        input.bindWorkdir,
+		LogOutput:                          !input.noOutput,
+		JSONLogger:                         input.jsonLogger,
+		LogPrefixJobID:                     input.logPrefixJobID,
+		Env:                                envs,
+		Secrets:                            secrets,
+		Vars:                               vars,
+		Inputs:                             inputs,
+		Token:                              secrets["GITHUB_TOKEN"],
+		InsecureSecrets:                    input.insecureSecrets,
+		Platforms:                          input.newPlatforms(),
+		Privileged:                         input.privileged,
+		UsernsMode:                         input.usernsMode,
+		ContainerArchitecture:              input.containerArchitecture,
+		ContainerDaemonSocket:              input.containerDaemonSocket,
+		ContainerOptions:                   input.containerOptions,
+		UseGitIgnore:                       input.useGitIgnore,
+		Forge:                              input.forge,
+		GitHubInstance:                     input.githubInstance,
+		GitHubServerURL:                    input.gitHubServerURL,
+		GitHubAPIServerURL:                 input.gitHubAPIServerURL,
+		GitHubGraphQlAPIServerURL:          input.gitHubGraphQlAPIServerURL,
+		ContainerCapAdd:                    input.containerCapAdd,
+		ContainerCapDrop:                   input.containerCapDrop,
+		AutoRemove:                         input.autoRemove,
+		ArtifactServerPath:                 input.artifactServerPath,
+		ArtifactServerAddr:                 input.artifactServerAddr,
+		ArtifactServerPort:                 input.artifactServerPort,
+		NoSkipCheckout:                     input.noSkipCheckout,
+		RemoteName:                         input.remoteName,
+		ReplaceGheActionWithGithubCom:      input.replaceGheActionWithGithubCom,
+		ReplaceGheActionTokenWithGithubCom: input.replaceGheActionTokenWithGithubCom,
+		Matrix:                             matrixes,
+		TraceMatrix:                        input.traceMatrix,
+		StatePath:                          input.statePath,
+		ContainerNetworkMode:               docker_container.NetworkMode(input.networkName),
+		Parallel:                           input.parallel,
+		ServiceLogDir:                      input.resolve(input.serviceLogDir),
+		ServiceLogLines:                    input.serviceLogLines,
+		ComposeServices:                    composeServices,
+		ActionBuildCacheDir:                input.actionBuildCachePath,
+		ActionBuildCacheSize:               actionBuildCacheSize,
+		ActionBuildArgs:                    parseEnvs(input.actionBuildArgs),
+		ActionBuildSecrets:                 buildSecrets,
+		ImagePull: container.ImagePullConfig{
+			Retries:      input.pullRetries,
+			RetryBackoff: input.pullRetryBackoff,
+			Mirrors:      registryMirrors,
+		},
+	}
+	if input.actionOfflineMode {
+		config.ActionCache = &runner.GoGitActionCacheOfflineMode{
+			Parent: runner.GoGitActionCache{
+				Path: config.ActionCacheDir,
+			},
+		}
+	}
+	if len(input.localRepository) > 0 {
+		localRepositories := map[string]string{}
+		for _, l := range input.localRepository {
+			k, v, _ := strings.Cut(l, "=")
+			localRepositories[k] = v
+		}
+		config.ActionCache = &runner.LocalRepositoryCache{
+			Parent:            config.ActionCache,
+			LocalRepositories: localRepositories,
+			CacheDirCache:     map[string]string{},
+		}
+	}
+	return config, nil
+}
+
 func defaultImageSurvey(actrc string) error {
 	var answer string
 	confirmation := &survey.Select{
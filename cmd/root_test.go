@@ -93,3 +93,37 @@ func TestWorkflowCall(t *testing.T) {
 	})(rootCmd, []string{"workflow_call"})
 	assert.NoError(t, err)
 }
+
+func TestParseRegistryMirrors(t *testing.T) {
+	mirrors, err := parseRegistryMirrors([]string{"docker.io=registry.local:5000", "index.docker.io = hub.local", "ghcr.io=ghcr.local"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"docker.io": "hub.local", "ghcr.io": "ghcr.local"}, mirrors)
+
+	_, err = parseRegistryMirrors([]string{"docker.io"})
+	assert.Error(t, err)
+}
+
+func TestParseComposeServicesRequiresBothFlags(t *testing.T) {
+	services, err := parseComposeServices("", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, services)
+
+	_, err = parseComposeServices("docker-compose.yml", nil)
+	assert.ErrorContains(t, err, "--compose-file requires --compose-service")
+
+	_, err = parseComposeServices("", []string{"build"})
+	assert.ErrorContains(t, err, "--compose-service requires --compose-file")
+}
+
+func TestParseActionBuildCacheSize(t *testing.T) {
+	size, err := parseActionBuildCacheSize("")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), size, "empty means unlimited")
+
+	size, err = parseActionBuildCacheSize("5GiB")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5*1024*1024*1024), size)
+
+	_, err = parseActionBuildCacheSize("five")
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+// Package conformance provides the expression cases which every evaluator of act must
+// evaluate like the runner of GitHub
+package conformance
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed corpus.yml
+var corpus []byte
+
+// Case is an expression with its expected result or error
+type Case struct {
+	Expr string `yaml:"expr"`
+	// Result is compared after converting both values to json
+	Result any `yaml:"result"`
+	// Error is a part of the expected error message, the result is ignored if it is set
+	Error string `yaml:"error"`
+}
+
+// Corpus holds the contexts, which are available to every case, and the cases
+type Corpus struct {
+	Contexts map[string]any `yaml:"contexts"`
+	Cases    []Case         `yaml:"cases"`
+}
+
+// Load reads the embedded corpus
+func Load() (*Corpus, error) {
+	c := &Corpus{}
+	if err := yaml.Unmarshal(corpus, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Normalize converts a result to the types of encoding/json, so results of different
+// evaluators and the expected results can be compared with reflect.DeepEqual
+func Normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
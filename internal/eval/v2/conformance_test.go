@@ -0,0 +1,38 @@
+package v2
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actions-oss/act-cli/internal/eval/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	corpus, err := conformance.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval := NewEvaluator(&EvaluationContext{
+		Variables: CaseInsensitiveObject[any](corpus.Contexts),
+		Functions: GetFunctions(),
+	})
+	for _, c := range corpus.Cases {
+		got, err := eval.EvaluateRaw(c.Expr)
+		if c.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), c.Error) {
+				t.Errorf("evaluate %s expected error %q got %v", c.Expr, c.Error, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("evaluate %s error: %v", c.Expr, err)
+			continue
+		}
+		got, _ = conformance.Normalize(got)
+		want, _ := conformance.Normalize(c.Result)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("evaluate %s expected %#v got %#v", c.Expr, want, got)
+		}
+	}
+}
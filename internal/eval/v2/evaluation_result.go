@@ -27,6 +27,24 @@ const (
 	ValueKindArray
 )
 
+func (k ValueKind) String() string {
+	switch k {
+	case ValueKindNull:
+		return "Null"
+	case ValueKindBoolean:
+		return "Boolean"
+	case ValueKindNumber:
+		return "Number"
+	case ValueKindString:
+		return "String"
+	case ValueKindObject:
+		return "Object"
+	case ValueKindArray:
+		return "Array"
+	}
+	return fmt.Sprintf("ValueKind(%d)", int(k))
+}
+
 type ReadOnlyArray[T any] interface {
 	GetAt(i int64) T
 	GetEnumerator() []T
@@ -179,6 +197,9 @@ func (er *EvaluationResult) ConvertToString() string {
 		return fmt.Sprintf(ExpressionConstants.NumberFormat, er.value.(float64))
 	case ValueKindString:
 		return er.value.(string)
+	case ValueKindArray, ValueKindObject:
+		// like GitHub, collections are converted to the name of their kind
+		return er.kind.String()
 	default:
 		return fmt.Sprintf("%v", er.value)
 	}
@@ -371,13 +392,18 @@ func abstractGreaterThan(left, right interface{}) bool {
 		}
 		return l > r
 	case ValueKindString:
-		return strings.Compare(left.(string), right.(string)) > 0
+		return compareIgnoreCase(left.(string), right.(string)) > 0
 	case ValueKindBoolean:
 		return left.(bool) && !right.(bool)
 	}
 	return false
 }
 
+// compareIgnoreCase compares strings ordinal ignoring the case like GitHub
+func compareIgnoreCase(left, right string) int {
+	return strings.Compare(strings.ToUpper(left), strings.ToUpper(right))
+}
+
 // abstractLessThan uses coerceTypes before comparing.
 func abstractLessThan(left, right interface{}) bool {
 	left, right, leftKind, rightKind := coerceTypes(left, right)
@@ -393,7 +419,7 @@ func abstractLessThan(left, right interface{}) bool {
 		}
 		return l < r
 	case ValueKindString:
-		return strings.Compare(left.(string), right.(string)) < 0
+		return compareIgnoreCase(left.(string), right.(string)) < 0
 	case ValueKindBoolean:
 		return !left.(bool) && right.(bool)
 	}
@@ -456,9 +482,23 @@ func getKind(v interface{}) ValueKind {
 	}
 }
 
-// traceValue is a placeholder for tracing logic.
+// traceValue writes the result to the trace writer of the context.
 func (er *EvaluationResult) traceValue() {
-	// No-op in this simplified implementation.
+	if er.context == nil || er.context.Trace == nil {
+		return
+	}
+	var value string
+	switch er.kind {
+	case ValueKindNull:
+		value = "null"
+	case ValueKindString:
+		value = "'" + strings.ReplaceAll(er.value.(string), "'", "''") + "'"
+	case ValueKindObject, ValueKindArray:
+		value = er.kind.String()
+	default:
+		value = er.ConvertToString()
+	}
+	er.context.Trace.Trace(er.level, "=> "+value)
 }
 
 // --- End of file ---------------------------------------
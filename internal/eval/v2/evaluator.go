@@ -3,6 +3,7 @@ package v2
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	exprparser "github.com/actions-oss/act-cli/internal/expr"
 )
@@ -11,6 +12,13 @@ import (
 type EvaluationContext struct {
 	Variables ReadOnlyObject[any]
 	Functions ReadOnlyObject[Function]
+	// Trace receives every evaluated node and its result if set
+	Trace TraceWriter
+}
+
+// TraceWriter writes the trace of an evaluation, level is the depth of the node.
+type TraceWriter interface {
+	Trace(level int, message string)
 }
 
 func NewEvaluationContext() *EvaluationContext {
@@ -23,7 +31,8 @@ type Function interface {
 
 // Evaluator evaluates workflow expressions using the lexer and parser from workflow.
 type Evaluator struct {
-	ctx *EvaluationContext
+	ctx   *EvaluationContext
+	level int
 }
 
 // NewEvaluator creates an Evaluator with the supplied context.
@@ -100,7 +109,7 @@ func (e *Evaluator) EvaluateRaw(expr string) (interface{}, error) {
 type FilteredArray []interface{}
 
 func (a FilteredArray) GetAt(i int64) interface{} {
-	if int(i) > len(a) {
+	if i < 0 || int(i) >= len(a) {
 		return nil
 	}
 	return a[i]
@@ -112,6 +121,22 @@ func (a FilteredArray) GetEnumerator() []interface{} {
 
 // evalNode recursively evaluates a parser node and returns an EvaluationResult.
 func (e *Evaluator) evalNode(n exprparser.Node) (*EvaluationResult, error) {
+	if e.ctx == nil || e.ctx.Trace == nil {
+		return e.evalNodeUntraced(n)
+	}
+	level := e.level
+	e.ctx.Trace.Trace(level, fmt.Sprintf("Evaluating %s", n))
+	e.level++
+	result, err := e.evalNodeUntraced(n)
+	e.level--
+	if err != nil {
+		e.ctx.Trace.Trace(level, fmt.Sprintf("=> error: %v", err))
+		return nil, err
+	}
+	return NewEvaluationResult(e.ctx, level, result.value, result.kind, result.raw, false), nil
+}
+
+func (e *Evaluator) evalNodeUntraced(n exprparser.Node) (*EvaluationResult, error) {
 	switch node := n.(type) {
 	case *exprparser.ValueNode:
 		return e.evalValueNode(node)
@@ -172,9 +197,10 @@ func (e *Evaluator) evalBinaryNodeLeft(node *exprparser.BinaryNode, left *Evalua
 		if left.IsTruthy() {
 			return left, nil
 		}
-	case ".":
+	case ".", "[":
+		// a.* and a[*] filter the items of arrays and the values of objects
 		if v, ok := node.Right.(*exprparser.ValueNode); ok && v.Kind == exprparser.TokenKindWildcard {
-			var ret FilteredArray
+			ret := FilteredArray{}
 			if col, ok := left.TryGetCollectionInterface(); ok {
 				if farray, ok := col.(FilteredArray); ok {
 					for _, subcol := range farray.GetEnumerator() {
@@ -211,16 +237,14 @@ func (e *Evaluator) evalBinaryNodeRight(node *exprparser.BinaryNode, left *Evalu
 		return CreateIntermediateResult(e.Context(), left.AbstractLessThanOrEqual(right)), nil
 	case ".", "[":
 		if farray, ok := left.Value().(FilteredArray); ok {
-			var ret FilteredArray
+			// a filter without matches results in an empty array like on GitHub
+			ret := FilteredArray{}
 			for _, subcol := range farray.GetEnumerator() {
 				res := processIndex(CreateIntermediateResult(e.Context(), subcol).Value(), right)
 				if res != nil {
 					ret = append(ret, res)
 				}
 			}
-			if ret == nil {
-				return CreateIntermediateResult(e.Context(), nil), nil
-			}
 			return CreateIntermediateResult(e.Context(), ret), nil
 		}
 		col, _ := left.TryGetCollectionInterface()
@@ -268,8 +292,15 @@ func processStar(subcol interface{}, ret FilteredArray) FilteredArray {
 	if array, ok := subcol.(ReadOnlyArray[any]); ok {
 		ret = append(ret, array.GetEnumerator()...)
 	} else if obj, ok := subcol.(ReadOnlyObject[any]); ok {
-		for _, v := range obj.GetEnumerator() {
-			ret = append(ret, v)
+		// sort the keys, the order of the values must not change between evaluations
+		values := obj.GetEnumerator()
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ret = append(ret, values[k])
 		}
 	}
 	return ret
@@ -1,6 +1,8 @@
 package v2
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -48,7 +50,7 @@ func TestEvaluator_Raw(t *testing.T) {
 	}{
 		{"a.b['x']", nil},
 		{"(a.b).c['x']", nil},
-		{"(a.b).*['x']", nil},
+		{"(a.b).*['x']", []interface{}{}},
 		{"(a['x'])", nil},
 		{"true || false", true},
 		{"false || false", false},
@@ -110,8 +112,61 @@ func TestEvaluator_Raw(t *testing.T) {
 		if err != nil {
 			t.Fatalf("evaluate %s error: %v", tt.expr, err)
 		}
-		if got != tt.want {
+		if !reflect.DeepEqual(got, tt.want) {
 			t.Fatalf("evaluate %s expected %v got %v", tt.expr, tt.want, got)
 		}
 	}
 }
+
+type traceLines []string
+
+func (t *traceLines) Trace(level int, message string) {
+	*t = append(*t, strings.Repeat(" ", level)+message)
+}
+
+func TestEvaluator_Trace(t *testing.T) {
+	var trace traceLines
+	ctx := &EvaluationContext{
+		Variables: CaseInsensitiveObject[any](map[string]any{"a": map[string]any{"b": "x"}}),
+		Functions: GetFunctions(),
+		Trace:     &trace,
+	}
+	eval := NewEvaluator(ctx)
+
+	got, err := eval.EvaluateRaw("a.b == 'X' && !contains(a.b, 'y')")
+	if err != nil {
+		t.Fatalf("evaluate error: %v", err)
+	}
+	if got != true {
+		t.Fatalf("expected true got %v", got)
+	}
+	want := []string{
+		"Evaluating (((a . b) == X) && (!contains((a . b), y)))",
+		" Evaluating ((a . b) == X)",
+		"  Evaluating (a . b)",
+		"   Evaluating a",
+		"   => Object",
+		"   Evaluating b",
+		"   => 'b'",
+		"  => 'x'",
+		"  Evaluating X",
+		"  => 'X'",
+		" => true",
+		" Evaluating (!contains((a . b), y))",
+		"  Evaluating contains((a . b), y)",
+		"   Evaluating (a . b)",
+		"    Evaluating a",
+		"    => Object",
+		"    Evaluating b",
+		"    => 'b'",
+		"   => 'x'",
+		"   Evaluating y",
+		"   => 'y'",
+		"  => false",
+		" => true",
+		"=> true",
+	}
+	if strings.Join(trace, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected trace:\n%s", strings.Join(trace, "\n"))
+	}
+}
@@ -1,6 +1,7 @@
 package v2
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -37,11 +38,15 @@ func (ToJSON) Evaluate(eval *Evaluator, args []exprparser.Node) (*EvaluationResu
 	if err != nil {
 		return nil, err
 	}
-	data, err := json.MarshalIndent(raw, "", "  ")
-	if err != nil {
+	// pretty print like GitHub, which doesn't escape html characters
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(raw); err != nil {
 		return nil, err
 	}
-	return CreateIntermediateResult(eval.Context(), string(data)), nil
+	return CreateIntermediateResult(eval.Context(), strings.TrimSuffix(buf.String(), "\n")), nil
 }
 
 type Contains struct {
@@ -188,15 +193,7 @@ func (Case) Evaluate(eval *Evaluator, args []exprparser.Node) (*EvaluationResult
 	return eval.Evaluate(args[len(args)-1])
 }
 
+// GetFunctions returns the builtin functions of GitHub
 func GetFunctions() CaseInsensitiveObject[Function] {
-	return CaseInsensitiveObject[Function](map[string]Function{
-		"fromjson":   &FromJSON{},
-		"tojson":     &ToJSON{},
-		"contains":   &Contains{},
-		"startswith": &StartsWith{},
-		"endswith":   &EndsWith{},
-		"format":     &Format{},
-		"join":       &Join{},
-		"case":       &Case{},
-	})
+	return HostRegistry(HostGitHub).Functions()
 }
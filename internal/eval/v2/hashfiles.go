@@ -0,0 +1,180 @@
+package v2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+	"github.com/actions-oss/act-cli/pkg/workflowpattern"
+)
+
+// HashFiles hashes the files below Root which match the patterns of its arguments, it is
+// used by hosts which evaluate hashFiles without a job container
+type HashFiles struct {
+	Root string
+}
+
+func (h HashFiles) Evaluate(eval *Evaluator, args []exprparser.Node) (*EvaluationResult, error) {
+	sargs := make([]string, 0, len(args))
+	for _, arg := range args {
+		r, err := eval.Evaluate(arg)
+		if err != nil {
+			return nil, err
+		}
+		sargs = append(sargs, r.ConvertToString())
+	}
+	hash, err := HashFilesIn(h.Root, sargs...)
+	if err != nil {
+		return nil, err
+	}
+	return CreateIntermediateResult(eval.Context(), hash), nil
+}
+
+// HashFilesIn computes hashFiles like the runner of GitHub. The first argument may be
+// --follow-symbolic-links, patterns prefixed with ! exclude the files matched by the
+// patterns before them and a pattern which matches a directory matches all its files.
+// The sha256 of every matched file in lexical order is hashed again, the result is empty
+// if no file matches.
+func HashFilesIn(root string, args ...string) (string, error) {
+	followSymlinks := false
+	if len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		if !strings.EqualFold(args[0], "--follow-symbolic-links") {
+			return "", fmt.Errorf("invalid glob option %s, available option: '--follow-symbolic-links'", args[0])
+		}
+		followSymlinks = true
+		args = args[1:]
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	var patterns []*workflowpattern.WorkflowPattern
+	for _, arg := range args {
+		for _, line := range strings.Split(arg, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			p, err := compileHashFilesPattern(root, line)
+			if err != nil {
+				return "", err
+			}
+			if p != nil {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return "", nil
+	}
+
+	all := sha256.New()
+	count := 0
+	err = walkFiles(root, "", followSymlinks, map[string]bool{}, func(rel, file string) error {
+		if !matchHashFilesPatterns(patterns, rel) {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		all.Write(h.Sum(nil))
+		count++
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return "", nil
+	}
+	return hex.EncodeToString(all.Sum(nil)), nil
+}
+
+// compileHashFilesPattern makes the pattern relative to root, patterns outside of root are ignored
+func compileHashFilesPattern(root, pattern string) (*workflowpattern.WorkflowPattern, error) {
+	negative := strings.HasPrefix(pattern, "!")
+	pattern = strings.TrimPrefix(pattern, "!")
+	if filepath.IsAbs(pattern) {
+		rel, err := filepath.Rel(root, pattern)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, nil
+		}
+		pattern = rel
+	}
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if negative {
+		pattern = "!" + pattern
+	}
+	return workflowpattern.CompilePattern(pattern)
+}
+
+// matchHashFilesPatterns reports whether the last pattern which matches the file or one of its directories is not negated
+func matchHashFilesPatterns(patterns []*workflowpattern.WorkflowPattern, rel string) bool {
+	matched := false
+	for _, p := range patterns {
+		if p.Negative != matched {
+			continue
+		}
+		for path := rel; path != "."; path = filepath.ToSlash(filepath.Dir(path)) {
+			if p.Regex.MatchString(path) {
+				matched = !p.Negative
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// walkFiles calls fn for the files below dir in lexical order, visited protects against symlink cycles
+func walkFiles(dir, rel string, followSymlinks bool, visited map[string]bool, fn func(rel, file string) error) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if visited[resolved] {
+		return nil
+	}
+	visited[resolved] = true
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		file := filepath.Join(dir, entry.Name())
+		entryRel := entry.Name()
+		if rel != "" {
+			entryRel = rel + "/" + entry.Name()
+		}
+		mode := entry.Type()
+		if mode&fs.ModeSymlink != 0 {
+			// the target of a link is hashed, links to directories are only followed with --follow-symbolic-links
+			fi, err := os.Stat(file)
+			if err != nil || fi.IsDir() && !followSymlinks {
+				continue
+			}
+			mode = fi.Mode().Type()
+		}
+		if mode.IsDir() {
+			if err := walkFiles(file, entryRel, followSymlinks, visited, fn); err != nil {
+				return err
+			}
+		} else if mode.IsRegular() {
+			if err := fn(entryRel, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package v2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func hashOf(contents ...string) string {
+	all := sha256.New()
+	for _, c := range contents {
+		h := sha256.Sum256([]byte(c))
+		all.Write(h[:])
+	}
+	return hex.EncodeToString(all.Sum(nil))
+}
+
+func TestHashFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.sum":               "root",
+		"a/go.sum":             "a",
+		"a/b/go.sum":           "b",
+		"vendor/x/go.sum":      "vendor",
+		"docs/readme.md":       "docs",
+		"docs/nested/guide.md": "guide",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "go.sum"), []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"**/go.sum"}, hashOf("b", "a", "root", "vendor")},
+		{[]string{"**/go.sum", "!vendor/**"}, hashOf("b", "a", "root")},
+		{[]string{"**/go.sum\n!vendor/**\n# comment"}, hashOf("b", "a", "root")},
+		{[]string{"!vendor/**", "**/go.sum"}, hashOf("b", "a", "root", "vendor")},
+		{[]string{"go.sum", "./a/go.sum"}, hashOf("a", "root")},
+		{[]string{"docs"}, hashOf("guide", "docs")},
+		{[]string{filepath.Join(root, "a", "*")}, hashOf("b", "a")},
+		{[]string{filepath.Join(outside, "*")}, ""},
+		{[]string{"**/missing"}, ""},
+		{[]string{"--follow-symbolic-links", "link/go.sum"}, hashOf("outside")},
+		{[]string{"link/go.sum"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := HashFilesIn(root, tt.args...)
+		if err != nil {
+			t.Fatalf("hashFiles %v error: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("hashFiles %v expected %s got %s", tt.args, tt.want, got)
+		}
+	}
+	if _, err := HashFilesIn(root, "--unknown", "*"); err == nil {
+		t.Fatal("expected an error for an unknown option")
+	}
+
+	eval := NewEvaluator(&EvaluationContext{Functions: CaseInsensitiveObject[Function]{"hashfiles": HashFiles{Root: root}}})
+	got, err := eval.EvaluateRaw("hashFiles('a/**', 'go.sum')")
+	if err != nil || got != hashOf("b", "a", "root") {
+		t.Fatalf("unexpected result %v %v", got, err)
+	}
+}
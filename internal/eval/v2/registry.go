@@ -0,0 +1,133 @@
+package v2
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+)
+
+// Hosts which run workflows, each host has its own registry of functions
+const (
+	HostGitHub  = "github"
+	HostGitea   = "gitea"
+	HostForgejo = "forgejo"
+)
+
+// MaxArgs is the maximum number of arguments of functions with variadic arguments
+const MaxArgs = math.MaxInt32
+
+// FunctionInfo describes a function which can be called by expressions
+type FunctionInfo struct {
+	Name     string
+	Min      int
+	Max      int
+	Function Function
+}
+
+// Evaluate checks the number of arguments before calling the function
+func (info *FunctionInfo) Evaluate(eval *Evaluator, args []exprparser.Node) (*EvaluationResult, error) {
+	if len(args) < info.Min {
+		return nil, fmt.Errorf("too few arguments for function %s, expected at least %d", info.Name, info.Min)
+	}
+	if len(args) > info.Max {
+		return nil, fmt.Errorf("too many arguments for function %s, expected at most %d", info.Name, info.Max)
+	}
+	return info.Function.Evaluate(eval, args)
+}
+
+// Registry holds the functions of a host, hosts like Gitea or Forgejo and act itself
+// add their own functions to the builtin ones without changes to the evaluator
+type Registry struct {
+	functions map[string]*FunctionInfo
+}
+
+// NewRegistry creates a registry with the builtin functions of GitHub
+func NewRegistry() *Registry {
+	r := &Registry{functions: map[string]*FunctionInfo{}}
+	r.Register("fromJSON", 1, 1, &FromJSON{})
+	r.Register("toJSON", 1, 1, &ToJSON{})
+	r.Register("contains", 2, 2, &Contains{})
+	r.Register("startsWith", 2, 2, &StartsWith{})
+	r.Register("endsWith", 2, 2, &EndsWith{})
+	r.Register("format", 1, MaxArgs, &Format{})
+	r.Register("join", 1, 2, &Join{})
+	r.Register("case", 3, MaxArgs, &Case{})
+	return r
+}
+
+// Register adds a function or replaces the function with the same name, names are case insensitive
+func (r *Registry) Register(name string, minArgs, maxArgs int, fn Function) {
+	r.functions[strings.ToLower(name)] = &FunctionInfo{Name: name, Min: minArgs, Max: maxArgs, Function: fn}
+}
+
+// Unregister removes a function which is not supported by a host
+func (r *Registry) Unregister(name string) {
+	delete(r.functions, strings.ToLower(name))
+}
+
+// Get returns the function with the name or nil
+func (r *Registry) Get(name string) *FunctionInfo {
+	return r.functions[strings.ToLower(name)]
+}
+
+// Infos returns the registered functions sorted by name
+func (r *Registry) Infos() []*FunctionInfo {
+	infos := make([]*FunctionInfo, 0, len(r.functions))
+	for _, info := range r.functions {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return strings.ToLower(infos[i].Name) < strings.ToLower(infos[j].Name)
+	})
+	return infos
+}
+
+// Functions returns the functions for an EvaluationContext, which check their number of arguments
+func (r *Registry) Functions() CaseInsensitiveObject[Function] {
+	functions := CaseInsensitiveObject[Function]{}
+	for key, info := range r.functions {
+		functions[key] = info
+	}
+	return functions
+}
+
+// Clone returns a copy of the registry, which can be changed without affecting the original
+func (r *Registry) Clone() *Registry {
+	c := &Registry{functions: make(map[string]*FunctionInfo, len(r.functions))}
+	for key, info := range r.functions {
+		c.functions[key] = info
+	}
+	return c
+}
+
+var (
+	hostsMu sync.RWMutex
+	hosts   = map[string][]func(*Registry){}
+)
+
+// RegisterHost adds a setup function for the registry of a host, it is called in the
+// order of registration for each new registry of the host
+func RegisterHost(host string, setup func(r *Registry)) {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+	host = strings.ToLower(host)
+	hosts[host] = append(hosts[host], setup)
+}
+
+// HostRegistry creates the registry of a host, an empty host is GitHub
+func HostRegistry(host string) *Registry {
+	r := NewRegistry()
+	if host == "" {
+		host = HostGitHub
+	}
+	hostsMu.RLock()
+	defer hostsMu.RUnlock()
+	for _, setup := range hosts[strings.ToLower(host)] {
+		setup(r)
+	}
+	return r
+}
@@ -0,0 +1,50 @@
+package v2
+
+import (
+	"testing"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+)
+
+type constFunction string
+
+func (c constFunction) Evaluate(eval *Evaluator, _ []exprparser.Node) (*EvaluationResult, error) {
+	return CreateIntermediateResult(eval.Context(), string(c)), nil
+}
+
+func TestHostRegistry(t *testing.T) {
+	RegisterHost("test-host", func(r *Registry) {
+		r.Register("hostName", 0, 0, constFunction("test-host"))
+		r.Unregister("case")
+	})
+	evaluate := func(host, expr string) (interface{}, error) {
+		return NewEvaluator(&EvaluationContext{Functions: HostRegistry(host).Functions()}).EvaluateRaw(expr)
+	}
+
+	got, err := evaluate("TEST-HOST", "HOSTNAME()")
+	if err != nil || got != "test-host" {
+		t.Fatalf("expected test-host got %v %v", got, err)
+	}
+	if _, err := evaluate("test-host", "case(true, 1, 2)"); err == nil {
+		t.Fatal("expected case to be unregistered")
+	}
+	if _, err := evaluate(HostGitHub, "hostName()"); err == nil {
+		t.Fatal("expected hostName to be unknown on github")
+	}
+	if _, err := evaluate("", "hostName(1)"); err == nil {
+		t.Fatal("expected hostName to be unknown on the default host")
+	}
+	if _, err := evaluate("test-host", "hostName(1)"); err == nil || err.Error() != "too many arguments for function hostName, expected at most 0" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	r := NewRegistry()
+	c := r.Clone()
+	c.Register("extra", 0, 0, constFunction("x"))
+	if r.Get("extra") != nil || c.Get("EXTRA") == nil {
+		t.Fatal("expected the clone to be independent")
+	}
+	if infos := r.Infos(); len(infos) != 8 || infos[0].Name != "case" || infos[7].Name != "toJSON" {
+		t.Fatalf("unexpected functions %v", infos)
+	}
+}
@@ -1,8 +1,9 @@
 package model
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -14,116 +15,238 @@ type TraceWriter interface {
 	Info(format string, args ...interface{})
 }
 
+// maxMatrixEntries is the job matrix limit of github
+const maxMatrixEntries = 256
+
 // StrategyResult holds the result of expanding a strategy.
 // FlatMatrix contains the expanded matrix entries.
 // IncludeMatrix contains entries that were added via include.
 // FailFast indicates whether the job should fail fast.
-// MaxParallel is the maximum parallelism allowed.
+// MaxParallel is the maximum parallelism allowed, nil if it is unlimited.
 // MatrixKeys is the set of keys present in the matrix.
+// MatrixKeyOrder are the keys of the matrix in the order of the workflow.
 type StrategyResult struct {
-	FlatMatrix    []map[string]yaml.Node
-	IncludeMatrix []map[string]yaml.Node
-	FailFast      bool
-	MaxParallel   *float64
-	MatrixKeys    map[string]struct{}
+	FlatMatrix     []map[string]yaml.Node
+	IncludeMatrix  []map[string]yaml.Node
+	FailFast       bool
+	MaxParallel    *float64
+	MatrixKeys     map[string]struct{}
+	MatrixKeyOrder []string
+
+	// includeKeys are the keys of the entries of IncludeMatrix in the order of the workflow
+	includeKeys [][]string
+}
+
+// MatrixEntry is a combination of the matrix which runs as its own job
+type MatrixEntry struct {
+	Matrix map[string]yaml.Node
+	// DisplaySuffix is appended to the name of the job, like "(ubuntu-latest, 18)"
+	DisplaySuffix string
+}
+
+// Entries returns the combinations of FlatMatrix followed by the ones of IncludeMatrix
+func (r *StrategyResult) Entries() []MatrixEntry {
+	entries := make([]MatrixEntry, 0, len(r.FlatMatrix)+len(r.IncludeMatrix))
+	for _, row := range r.FlatMatrix {
+		entries = append(entries, MatrixEntry{
+			Matrix:        row,
+			DisplaySuffix: GetDefaultDisplaySuffix(GetDisplayStrings(r.MatrixKeyOrder, nodePointers(row))),
+		})
+	}
+	for i, row := range r.IncludeMatrix {
+		var keys []string
+		if i < len(r.includeKeys) {
+			keys = r.includeKeys[i]
+		}
+		entries = append(entries, MatrixEntry{
+			Matrix:        row,
+			DisplaySuffix: GetDefaultDisplaySuffix(GetDisplayStrings(keys, nodePointers(row))),
+		})
+	}
+	return entries
+}
+
+func nodePointers(row map[string]yaml.Node) map[string]*yaml.Node {
+	res := make(map[string]*yaml.Node, len(row))
+	for k, v := range row {
+		v := v
+		res[k] = &v
+	}
+	return res
+}
+
+// Strategy is the strategy of a job with an evaluated matrix.
+// MatrixOrder keeps the order of the keys of the matrix, which defines
+// the order of the combinations.
+type Strategy struct {
+	Matrix      map[string][]yaml.Node `yaml:"matrix"`
+	MatrixOrder []string               `yaml:"-"`
+	MaxParallel float64                `yaml:"max-parallel"`
+	FailFast    bool                   `yaml:"fail-fast"`
+}
+
+func (s *Strategy) UnmarshalYAML(node *yaml.Node) error {
+	type StrategyObj Strategy
+	if err := node.Decode((*StrategyObj)(s)); err != nil {
+		return err
+	}
+	s.MatrixOrder = nil
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "matrix" {
+			s.MatrixOrder = mappingKeys(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// SetMatrix decodes an evaluated matrix mapping into the strategy
+func (s *Strategy) SetMatrix(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("matrix is not a mapping")
+	}
+	var matrix map[string][]yaml.Node
+	if err := node.Decode(&matrix); err != nil {
+		return err
+	}
+	s.Matrix = matrix
+	s.MatrixOrder = mappingKeys(node)
+	return nil
+}
+
+func mappingKeys(node *yaml.Node) []string {
+	var keys []string
+	if node.Kind != yaml.MappingNode {
+		return keys
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
 }
 
 type strategyContext struct {
 	jobTraceWriter TraceWriter
-	failFast       bool
-	maxParallel    float64
-	matrix         map[string][]yaml.Node
+	matrixKeys     []string
 
 	flatMatrix    []map[string]yaml.Node
 	includeMatrix []map[string]yaml.Node
+	includeKeys   [][]string
 
 	include []yaml.Node
 	exclude []yaml.Node
 }
 
+func (strategyContext *strategyContext) trace(format string, args ...interface{}) {
+	if strategyContext.jobTraceWriter != nil {
+		strategyContext.jobTraceWriter.Info(format, args...)
+	}
+}
+
+// orderedEntry converts a mapping node of include or exclude into a map and the order of its keys
+func orderedEntry(kind string, node yaml.Node) (map[string]yaml.Node, []string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s entry is not a mapping node", kind)
+	}
+	entry := make(map[string]yaml.Node)
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, nil, fmt.Errorf("%s key is not scalar", kind)
+		}
+		entry[keyNode.Value] = *node.Content[i+1]
+		keys = append(keys, keyNode.Value)
+	}
+	return entry, keys, nil
+}
+
+func (strategyContext *strategyContext) isMatrixKey(key string) bool {
+	for _, k := range strategyContext.matrixKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// handleInclude adds the values of an include entry to every combination whose original
+// matrix values it doesn't change. Values added by an earlier include may be overwritten.
+// An include entry which can't be added to any combination becomes a combination on its own.
 func (strategyContext *strategyContext) handleInclude() error {
-	// Handle include logic
-	if len(strategyContext.include) > 0 {
-		for _, incNode := range strategyContext.include {
-			if incNode.Kind != yaml.MappingNode {
-				return fmt.Errorf("include entry is not a mapping node")
-			}
-			incMap := make(map[string]yaml.Node)
-			for i := 0; i < len(incNode.Content); i += 2 {
-				keyNode := incNode.Content[i]
-				valNode := incNode.Content[i+1]
-				if keyNode.Kind != yaml.ScalarNode {
-					return fmt.Errorf("include key is not scalar")
-				}
-				incMap[keyNode.Value] = *valNode
-			}
-			matched := false
+	for _, incNode := range strategyContext.include {
+		incMap, incKeys, err := orderedEntry("include", incNode)
+		if err != nil {
+			return err
+		}
+		matched := false
+		// a matrix which only consists of include entries has no combinations to extend
+		if len(strategyContext.matrixKeys) > 0 {
 			for _, row := range strategyContext.flatMatrix {
-				match := true
-				for k, v := range incMap {
-					if rv, ok := row[k]; ok && !nodesEqual(rv, v) {
-						match = false
-						break
-					}
-				}
-				if match {
-					matched = true
-					// Add missing keys
-					strategyContext.jobTraceWriter.Info("Add missing keys %v", incMap)
-					for k, v := range incMap {
-						if _, ok := row[k]; !ok {
-							row[k] = v
-						}
-					}
+				if !strategyContext.includeMatches(row, incMap) {
+					continue
 				}
-			}
-			if !matched {
-				if strategyContext.jobTraceWriter != nil {
-					strategyContext.jobTraceWriter.Info("Append include entry %v", incMap)
+				matched = true
+				strategyContext.trace("include %s extends %s", formatEntry(incKeys, incMap), formatEntry(strategyContext.matrixKeys, row))
+				for k, v := range incMap {
+					row[k] = v
 				}
-				strategyContext.includeMatrix = append(strategyContext.includeMatrix, incMap)
 			}
 		}
+		if !matched {
+			strategyContext.trace("include %s matches no combination, it is added as a new combination", formatEntry(incKeys, incMap))
+			strategyContext.includeMatrix = append(strategyContext.includeMatrix, incMap)
+			strategyContext.includeKeys = append(strategyContext.includeKeys, incKeys)
+		}
 	}
 	return nil
 }
 
+func (strategyContext *strategyContext) includeMatches(row map[string]yaml.Node, incMap map[string]yaml.Node) bool {
+	for k, v := range incMap {
+		if !strategyContext.isMatrixKey(k) {
+			continue
+		}
+		if rv, ok := row[k]; ok && !nodesEqual(rv, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleExclude removes every combination which matches all values of an exclude entry
 func (strategyContext *strategyContext) handleExclude() error {
-	// Handle exclude logic
-	if len(strategyContext.exclude) > 0 {
-		for _, exNode := range strategyContext.exclude {
-			// exNode is expected to be a mapping node
-			if exNode.Kind != yaml.MappingNode {
-				return fmt.Errorf("exclude entry is not a mapping node")
+	for _, exNode := range strategyContext.exclude {
+		exMap, exKeys, err := orderedEntry("exclude", exNode)
+		if err != nil {
+			return err
+		}
+		for _, k := range exKeys {
+			if !strategyContext.isMatrixKey(k) {
+				// GitHub fails for unknown keys of exclude, but silently accepts them in include
+				return fmt.Errorf("the workflow is not valid. Matrix exclude key %q does not match any key within the matrix", k)
 			}
-			// Convert mapping to map[string]yaml.Node
-			exMap := make(map[string]yaml.Node)
-			for i := 0; i < len(exNode.Content); i += 2 {
-				keyNode := exNode.Content[i]
-				valNode := exNode.Content[i+1]
-				if keyNode.Kind != yaml.ScalarNode {
-					return fmt.Errorf("exclude key is not scalar")
+		}
+		filtered := []map[string]yaml.Node{}
+		removed := 0
+		for _, row := range strategyContext.flatMatrix {
+			match := true
+			for k, v := range exMap {
+				if rv, ok := row[k]; !ok || !nodesEqual(rv, v) {
+					match = false
+					break
 				}
-				exMap[keyNode.Value] = *valNode
 			}
-			// Remove matching rows
-			filtered := []map[string]yaml.Node{}
-			for _, row := range strategyContext.flatMatrix {
-				match := true
-				for k, v := range exMap {
-					if rv, ok := row[k]; !ok || !nodesEqual(rv, v) {
-						match = false
-						break
-					}
-				}
-				if !match {
-					filtered = append(filtered, row)
-				} else if strategyContext.jobTraceWriter != nil {
-					strategyContext.jobTraceWriter.Info("Removing %v from matrix due to exclude entry %v", row, exMap)
-				}
+			if !match {
+				filtered = append(filtered, row)
+				continue
 			}
-			strategyContext.flatMatrix = filtered
+			removed++
+			strategyContext.trace("exclude %s removes %s", formatEntry(exKeys, exMap), formatEntry(strategyContext.matrixKeys, row))
+		}
+		if removed == 0 {
+			strategyContext.trace("exclude %s matches no combination", formatEntry(exKeys, exMap))
 		}
+		strategyContext.flatMatrix = filtered
 	}
 	return nil
 }
@@ -133,27 +256,33 @@ func (strategyContext *strategyContext) handleExclude() error {
 // to be populated from a YAML mapping that follows the GitHub Actions strategy schema.
 func ExpandStrategy(strategy *Strategy, jobTraceWriter TraceWriter) (*StrategyResult, error) {
 	if strategy == nil {
-		return &StrategyResult{FlatMatrix: []map[string]yaml.Node{{}}, IncludeMatrix: []map[string]yaml.Node{}, FailFast: true}, nil
+		return &StrategyResult{FlatMatrix: []map[string]yaml.Node{{}}, IncludeMatrix: []map[string]yaml.Node{}, FailFast: true, MatrixKeys: map[string]struct{}{}}, nil
 	}
 
-	// Initialize defaults
 	strategyContext := &strategyContext{
 		jobTraceWriter: jobTraceWriter,
-		failFast:       strategy.FailFast,
-		maxParallel:    strategy.MaxParallel,
-		matrix:         strategy.Matrix,
 		flatMatrix:     []map[string]yaml.Node{{}},
 	}
-	// Process matrix entries
-	for key, values := range strategyContext.matrix {
+
+	keys := strategy.MatrixOrder
+	if len(keys) != len(strategy.Matrix) {
+		// without the order of the workflow the combinations are at least stable
+		keys = make([]string, 0, len(strategy.Matrix))
+		for key := range strategy.Matrix {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+	for _, key := range keys {
+		values := strategy.Matrix[key]
 		switch key {
 		case "include":
-			strategyContext.include = values
+			strategyContext.include = flattenEntries(values)
 		case "exclude":
-			strategyContext.exclude = values
+			strategyContext.exclude = flattenEntries(values)
 		default:
-			// Other keys are treated as matrix dimensions
-			// Expand each existing row with the new key/value pairs
+			// Other keys are treated as matrix dimensions, the first key varies the slowest
+			strategyContext.matrixKeys = append(strategyContext.matrixKeys, key)
 			next := []map[string]yaml.Node{}
 			for _, row := range strategyContext.flatMatrix {
 				for _, val := range values {
@@ -168,47 +297,125 @@ func ExpandStrategy(strategy *Strategy, jobTraceWriter TraceWriter) (*StrategyRe
 			strategyContext.flatMatrix = next
 		}
 	}
+	if len(strategyContext.matrixKeys) > 0 {
+		strategyContext.trace("matrix %s has %d combinations", strings.Join(strategyContext.matrixKeys, ", "), len(strategyContext.flatMatrix))
+	}
 
 	if err := strategyContext.handleExclude(); err != nil {
 		return nil, err
 	}
 
-	if len(strategyContext.flatMatrix) == 0 {
-		if jobTraceWriter != nil {
-			jobTraceWriter.Info("Matrix is empty, adding an empty entry")
-		}
-		strategyContext.flatMatrix = []map[string]yaml.Node{{}}
+	if len(strategyContext.flatMatrix) > maxMatrixEntries {
+		strategyContext.trace("Failure: Matrix contains more than %d entries after exclude", maxMatrixEntries)
+		return nil, fmt.Errorf("matrix contains more than %d entries", maxMatrixEntries)
 	}
 
-	// Enforce job matrix limit of github
-	if len(strategyContext.flatMatrix) > 256 {
-		if jobTraceWriter != nil {
-			jobTraceWriter.Info("Failure: Matrix contains more than 256 entries after exclude")
-		}
-		return nil, errors.New("matrix contains more than 256 entries")
-	}
-
-	// Build matrix keys set
 	matrixKeys := make(map[string]struct{})
-	if len(strategyContext.flatMatrix) > 0 {
-		for k := range strategyContext.flatMatrix[0] {
-			matrixKeys[k] = struct{}{}
-		}
+	for _, k := range strategyContext.matrixKeys {
+		matrixKeys[k] = struct{}{}
 	}
 
 	if err := strategyContext.handleInclude(); err != nil {
 		return nil, err
 	}
 
+	if len(strategyContext.matrixKeys) == 0 && len(strategyContext.includeMatrix) > 0 {
+		// the empty combination only exists to be extended by include
+		strategyContext.flatMatrix = []map[string]yaml.Node{}
+	}
+	if len(strategyContext.flatMatrix)+len(strategyContext.includeMatrix) == 0 {
+		strategyContext.trace("Matrix is empty, adding an empty entry")
+		strategyContext.flatMatrix = []map[string]yaml.Node{{}}
+	}
+	if len(strategyContext.flatMatrix)+len(strategyContext.includeMatrix) > maxMatrixEntries {
+		strategyContext.trace("Failure: Matrix contains more than %d entries after include", maxMatrixEntries)
+		return nil, fmt.Errorf("matrix contains more than %d entries", maxMatrixEntries)
+	}
+	strategyContext.trace("matrix has %d combinations after exclude and include", len(strategyContext.flatMatrix)+len(strategyContext.includeMatrix))
+
+	var maxParallel *float64
+	if strategy.MaxParallel > 0 {
+		maxParallel = &strategy.MaxParallel
+	}
 	return &StrategyResult{
-		FlatMatrix:    strategyContext.flatMatrix,
-		IncludeMatrix: strategyContext.includeMatrix,
-		FailFast:      strategyContext.failFast,
-		MaxParallel:   &strategyContext.maxParallel,
-		MatrixKeys:    matrixKeys,
+		FlatMatrix:     strategyContext.flatMatrix,
+		IncludeMatrix:  strategyContext.includeMatrix,
+		FailFast:       strategy.FailFast,
+		MaxParallel:    maxParallel,
+		MatrixKeys:     matrixKeys,
+		MatrixKeyOrder: strategyContext.matrixKeys,
+		includeKeys:    strategyContext.includeKeys,
 	}, nil
 }
 
+// flattenEntries inlines the sequences of include and exclude, which
+// an expression like ${{ fromJSON(...) }} inside of the list evaluates to
+func flattenEntries(values []yaml.Node) []yaml.Node {
+	var res []yaml.Node
+	for _, v := range values {
+		if v.Kind == yaml.SequenceNode {
+			for _, c := range v.Content {
+				res = append(res, flattenEntries([]yaml.Node{*c})...)
+			}
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// formatEntry formats a combination like {os: ubuntu-latest, node: 18}, the keys
+// which are not in keys follow in alphabetical order
+func formatEntry(keys []string, entry map[string]yaml.Node) string {
+	ordered := append([]string{}, keys...)
+	var rest []string
+	for k := range entry {
+		found := false
+		for _, o := range keys {
+			found = found || o == k
+		}
+		if !found {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	ordered = append(ordered, rest...)
+
+	var b strings.Builder
+	b.WriteString("{")
+	first := true
+	for _, k := range ordered {
+		v, ok := entry[k]
+		if !ok {
+			continue
+		}
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(formatNode(v))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func formatNode(node yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return node.Value
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
 // nodesEqual compares two yaml.Node values for equality.
 func nodesEqual(a, b yaml.Node) bool {
 	return DeepEquals(a, b, true)
@@ -1,8 +1,10 @@
 package model
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
@@ -66,3 +68,122 @@ matrix:
 		require.Len(t, res.IncludeMatrix, tc.includematrix)
 	}
 }
+
+type recordingTraceWriter struct {
+	lines []string
+}
+
+func (r *recordingTraceWriter) Info(format string, args ...interface{}) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func TestStrategyIncludeExclude(t *testing.T) {
+	// the example of the GitHub documentation, extended by an exclude
+	var strategy Strategy
+	err := yaml.Unmarshal([]byte(`
+fail-fast: false
+max-parallel: 2
+matrix:
+  fruit: [apple, pear]
+  animal: [cat, dog]
+  include:
+  - color: green
+  - color: pink
+    animal: cat
+  - fruit: apple
+    shape: circle
+  - fruit: banana
+  - fruit: banana
+    animal: cat
+  exclude:
+  - fruit: pear
+    animal: dog
+`), &strategy)
+	require.NoError(t, err)
+	trace := &recordingTraceWriter{}
+	res, err := ExpandStrategy(&strategy, trace)
+	require.NoError(t, err)
+
+	var combinations []map[string]string
+	var suffixes []string
+	for _, e := range res.Entries() {
+		m := map[string]string{}
+		for k, v := range e.Matrix {
+			m[k] = v.Value
+		}
+		combinations = append(combinations, m)
+		suffixes = append(suffixes, e.DisplaySuffix)
+	}
+	assert.Equal(t, []map[string]string{
+		{"fruit": "apple", "animal": "cat", "color": "pink", "shape": "circle"},
+		{"fruit": "apple", "animal": "dog", "color": "green", "shape": "circle"},
+		{"fruit": "pear", "animal": "cat", "color": "pink"},
+		{"fruit": "banana"},
+		{"fruit": "banana", "animal": "cat"},
+	}, combinations)
+	assert.Equal(t, []string{"(apple, cat)", "(apple, dog)", "(pear, cat)", "(banana)", "(banana, cat)"}, suffixes)
+	assert.False(t, res.FailFast)
+	require.NotNil(t, res.MaxParallel)
+	assert.Equal(t, 2.0, *res.MaxParallel)
+	assert.Equal(t, []string{"fruit", "animal"}, res.MatrixKeyOrder)
+
+	assert.Contains(t, trace.lines, "exclude {fruit: pear, animal: dog} removes {fruit: pear, animal: dog}")
+	assert.Contains(t, trace.lines, "include {color: pink, animal: cat} extends {fruit: apple, animal: cat, color: green}")
+	assert.Contains(t, trace.lines, "include {fruit: banana, animal: cat} matches no combination, it is added as a new combination")
+}
+
+func TestStrategyEdgeCases(t *testing.T) {
+	expand := func(t *testing.T, content string) (*StrategyResult, error) {
+		var strategy Strategy
+		require.NoError(t, yaml.Unmarshal([]byte(content), &strategy))
+		return ExpandStrategy(&strategy, nil)
+	}
+
+	res, err := expand(t, `
+matrix:
+  include:
+  - a: 1
+  - a: 2
+`)
+	require.NoError(t, err)
+	assert.Empty(t, res.FlatMatrix, "the empty combination is replaced by the include entries")
+	assert.Len(t, res.IncludeMatrix, 2)
+
+	res, err = expand(t, `
+matrix:
+  a: [1]
+  exclude:
+  - a: 1
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]yaml.Node{{}}, res.FlatMatrix)
+	assert.Nil(t, res.MaxParallel)
+
+	_, err = expand(t, `
+matrix:
+  a: [1, 2]
+  exclude:
+  - b: 1
+`)
+	assert.EqualError(t, err, `the workflow is not valid. Matrix exclude key "b" does not match any key within the matrix`)
+
+	_, err = expand(t, `
+matrix:
+  a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17]
+  b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16]
+`)
+	assert.EqualError(t, err, "matrix contains more than 256 entries")
+
+	res, err = expand(t, `
+matrix:
+  a: [1]
+  include:
+  - - b: 1
+    - b: 2
+      a: 2
+`)
+	require.NoError(t, err)
+	assert.Len(t, res.FlatMatrix, 1)
+	assert.Equal(t, "1", res.FlatMatrix[0]["b"].Value, "nested include lists are flattened")
+	assert.Len(t, res.IncludeMatrix, 1)
+}
@@ -1,30 +1,63 @@
 package model
 
-import "gopkg.in/yaml.v3"
+import (
+	"fmt"
 
+	"gopkg.in/yaml.v3"
+)
+
+// JobStatus is the scheduling state of a job or of a combination of its matrix
 type JobStatus int
 
 const (
+	// JobStatusPending waits for the jobs it needs
 	JobStatusPending JobStatus = iota
+	// JobStatusDependenciesReady can start, the jobs it needs are completed
 	JobStatusDependenciesReady
+	// JobStatusBlocked can start, but waits for a free slot of --parallel or max-parallel
 	JobStatusBlocked
+	JobStatusRunning
 	JobStatusCompleted
 )
 
+var jobStatusNames = []string{"pending", "dependencies-ready", "blocked", "running", "completed"}
+
+func (s JobStatus) String() string {
+	if s < 0 || int(s) >= len(jobStatusNames) {
+		return fmt.Sprintf("JobStatus(%d)", int(s))
+	}
+	return jobStatusNames[s]
+}
+
+func (s JobStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *JobStatus) UnmarshalText(text []byte) error {
+	for i, name := range jobStatusNames {
+		if name == string(text) {
+			*s = JobStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown job status %s", text)
+}
+
 type JobState struct {
-	JobID    string            // Workflow path to job, incl matrix and parent jobids
-	Result   string            // Actions Job Result
-	Outputs  map[string]string // Returned Outputs
-	State    JobStatus
-	Strategy []MatrixJobState
+	JobID    string            `json:"job_id"`  // Workflow path to job, incl matrix and parent jobids
+	Result   string            `json:"result"`  // Actions Job Result
+	Outputs  map[string]string `json:"outputs"` // Returned Outputs
+	State    JobStatus         `json:"state"`
+	Needs    []string          `json:"needs,omitempty"`
+	Strategy []MatrixJobState  `json:"strategy,omitempty"`
 }
 
 type MatrixJobState struct {
-	Matrix  map[string]any
-	Name    string
-	Result  string
-	Outputs map[string]string // Returned Outputs
-	State   JobStatus
+	Matrix  map[string]any    `json:"matrix,omitempty"`
+	Name    string            `json:"name"`
+	Result  string            `json:"result"`
+	Outputs map[string]string `json:"outputs"` // Returned Outputs
+	State   JobStatus         `json:"state"`
 }
 
 type WorkflowStatus int
@@ -33,14 +66,40 @@ const (
 	WorkflowStatusPending WorkflowStatus = iota
 	WorkflowStatusDependenciesReady
 	WorkflowStatusBlocked
+	WorkflowStatusRunning
 	WorkflowStatusCompleted
 )
 
+var workflowStatusNames = []string{"pending", "dependencies-ready", "blocked", "running", "completed"}
+
+func (s WorkflowStatus) String() string {
+	if s < 0 || int(s) >= len(workflowStatusNames) {
+		return fmt.Sprintf("WorkflowStatus(%d)", int(s))
+	}
+	return workflowStatusNames[s]
+}
+
+func (s WorkflowStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *WorkflowStatus) UnmarshalText(text []byte) error {
+	for i, name := range workflowStatusNames {
+		if name == string(text) {
+			*s = WorkflowStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown workflow status %s", text)
+}
+
+// WorkflowState is the state of the jobs of a workflow run, keyed by their JobID
 type WorkflowState struct {
-	Name                string
-	RunName             string
-	Jobs                JobState
-	StateWorkflowStatus WorkflowStatus
+	Name                string               `json:"name"`
+	RunName             string               `json:"run_name,omitempty"`
+	File                string               `json:"file,omitempty"`
+	Jobs                map[string]*JobState `json:"jobs"`
+	StateWorkflowStatus WorkflowStatus       `json:"state"`
 }
 
 type Workflow struct {
@@ -208,12 +267,6 @@ func (p *Permissions) UnmarshalYAML(node *yaml.Node) error {
 	return node.Decode((*map[string]string)(p))
 }
 
-type Strategy struct {
-	Matrix      map[string][]yaml.Node `yaml:"matrix"`
-	MaxParallel float64                `yaml:"max-parallel"`
-	FailFast    bool                   `yaml:"fail-fast"`
-}
-
 type Concurrency struct {
 	Group            string `yaml:"group"`
 	CancelInProgress bool   `yaml:"cancel-in-progress"`
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Save writes the state as json. The file is replaced by a rename, so a
+// concurrent reader sees either the previous or the new state.
+func (s *WorkflowState) Save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadWorkflowState reads a state written by Save
+func LoadWorkflowState(path string) (*WorkflowState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &WorkflowState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to read workflow state %s: %w", path, err)
+	}
+	return state, nil
+}
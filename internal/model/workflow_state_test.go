@@ -2,6 +2,8 @@ package model
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	v2 "github.com/actions-oss/act-cli/internal/eval/v2"
@@ -139,3 +141,33 @@ jobs:
 	assert.NoError(t, err)
 	assert.NotEmpty(t, out)
 }
+
+func TestWorkflowStateSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ci.yml.json")
+	ws := &WorkflowState{
+		Name: "ci",
+		Jobs: map[string]*JobState{
+			"build": {
+				JobID:  "build",
+				Result: "success",
+				State:  JobStatusCompleted,
+				Strategy: []MatrixJobState{
+					{Name: "build (1)", Matrix: map[string]any{"n": 1.0}, State: JobStatusBlocked},
+				},
+			},
+		},
+		StateWorkflowStatus: WorkflowStatusRunning,
+	}
+	require.NoError(t, ws.Save(path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"state": "blocked"`)
+
+	loaded, err := LoadWorkflowState(path)
+	require.NoError(t, err)
+	assert.Equal(t, ws, loaded)
+
+	var status JobStatus
+	assert.Error(t, status.UnmarshalText([]byte("unknown")))
+}
@@ -0,0 +1,33 @@
+package templateeval
+
+import (
+	"fmt"
+	"strconv"
+
+	v2 "github.com/actions-oss/act-cli/internal/eval/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeResult converts the raw result of an expression to a yaml node, numbers keep the
+// formatting of the expression engine like GitHub, e.g. 10000000 instead of 1e+07
+func EncodeResult(res interface{}) (*yaml.Node, error) {
+	ret := &yaml.Node{}
+	if err := ret.Encode(res); err != nil {
+		return nil, err
+	}
+	formatNumbers(ret)
+	return ret, nil
+}
+
+func formatNumbers(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!float" {
+		// .nan and .inf are not parsed and kept as they are
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			node.Value = fmt.Sprintf(v2.ExpressionConstants.NumberFormat, f)
+		}
+		return
+	}
+	for _, c := range node.Content {
+		formatNumbers(c)
+	}
+}
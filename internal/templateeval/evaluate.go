@@ -30,7 +30,8 @@ func (ee ExpressionEvaluator) evaluateScalarYamlNode(_ context.Context, node *ya
 	if err != nil {
 		return nil, err
 	}
-	if snode == nil || !isExpr && !isImplExpr(snode) || snode.Schema.GetDefinition(snode.Definition).String.IsExpression || ee.RestrictEval && node.Tag != "!!expr" {
+	// expressions of keys like if are evaluated later by their users
+	if snode == nil || !isExpr || isImplExpr(snode) || ee.RestrictEval && node.Tag != "!!expr" {
 		return node, nil
 	}
 	parsed, err := exprparser.Parse(expr)
@@ -48,14 +49,36 @@ func (ee ExpressionEvaluator) evaluateScalarYamlNode(_ context.Context, node *ya
 	if err != nil {
 		return nil, err
 	}
-	ret := &yaml.Node{}
-	if err := ret.Encode(res); err != nil {
+	ret, err := EncodeResult(res)
+	if err != nil {
 		return nil, err
 	}
 	ret.Line = node.Line
 	ret.Column = node.Column
 	// Finally check if we found a schema validation error
-	return ret, snode.UnmarshalYAML(ret)
+	err = snode.UnmarshalYAML(ret)
+	if err != nil && ret.Kind == yaml.ScalarNode && ret.Tag == "!!str" {
+		// a string like the output of a job is decoded again if the schema expects a sequence or a mapping
+		if decoded := decodeStringResult(ret); decoded != nil && snode.UnmarshalYAML(decoded) == nil {
+			return decoded, nil
+		}
+	}
+	return ret, err
+}
+
+// decodeStringResult returns the sequence or mapping of a JSON or YAML string, it is nil for any other string
+func decodeStringResult(node *yaml.Node) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(node.Value), &doc); err != nil || len(doc.Content) != 1 {
+		return nil
+	}
+	ret := doc.Content[0]
+	if ret.Kind != yaml.SequenceNode && ret.Kind != yaml.MappingNode {
+		return nil
+	}
+	ret.Line = node.Line
+	ret.Column = node.Column
+	return ret
 }
 
 func (ee ExpressionEvaluator) canEvaluate(parsed exprparser.Node, snode *schema.Node) bool {
@@ -98,7 +121,7 @@ func (ee ExpressionEvaluator) evaluateMappingYamlNode(ctx context.Context, node
 		var ek *yaml.Node
 		if !shouldInsert {
 			var err error
-			ek, err = ee.evaluateYamlNodeInternal(ctx, k, snode)
+			ek, err = ee.evaluateYamlNodeInternal(ctx, k, snode.GetKeyNode())
 			if err != nil {
 				return nil, err
 			}
@@ -111,7 +134,12 @@ func (ee ExpressionEvaluator) evaluateMappingYamlNode(ctx context.Context, node
 			}
 		}
 		v := node.Content[i*2+1]
-		ev, err := ee.evaluateYamlNodeInternal(ctx, v, snode.GetNestedNode(ek.Value))
+		// the mapping of the insert directive is merged, so its keys belong to the schema of the parent
+		vnode := snode
+		if !shouldInsert {
+			vnode = snode.GetNestedNode(ek.Value)
+		}
+		ev, err := ee.evaluateYamlNodeInternal(ctx, v, vnode)
 		if err != nil {
 			return nil, err
 		}
@@ -171,6 +199,10 @@ func (ee ExpressionEvaluator) evaluateSequenceYamlNode(ctx context.Context, node
 }
 
 func (ee ExpressionEvaluator) evaluateYamlNodeInternal(ctx context.Context, node *yaml.Node, snode *schema.Node) (*yaml.Node, error) {
+	if snode == nil {
+		// unknown keys are reported by the schema validation
+		return nil, nil
+	}
 	switch node.Kind {
 	case yaml.ScalarNode:
 		return ee.evaluateScalarYamlNode(ctx, node, snode)
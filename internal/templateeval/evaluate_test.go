@@ -92,3 +92,19 @@ jobs:
 		})
 	}
 }
+
+func TestEncodeResultKeepsNumberFormat(t *testing.T) {
+	ret, err := EncodeResult(map[string]any{
+		"big":   float64(10000000),
+		"float": 1.5,
+		"list":  []any{float64(100000000), true, nil},
+	})
+	require.NoError(t, err)
+	out, err := yaml.Marshal(ret)
+	require.NoError(t, err)
+	require.Equal(t, "big: !!float 10000000\nfloat: 1.5\nlist:\n    - !!float 100000000\n    - true\n    - null\n", string(out))
+
+	var decoded map[string]any
+	require.NoError(t, ret.Decode(&decoded))
+	require.Equal(t, float64(10000000), decoded["big"])
+}
@@ -7,18 +7,15 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
-	"github.com/timshannon/bolthold"
-	"go.etcd.io/bbolt"
 
 	"github.com/actions-oss/act-cli/pkg/common"
 )
@@ -28,8 +25,8 @@ const (
 )
 
 type Handler struct {
-	dir      string
-	storage  *Storage
+	storage  Storage
+	index    Index
 	router   *httprouter.Router
 	listener net.Listener
 	server   *http.Server
@@ -40,10 +37,49 @@ type Handler struct {
 
 	outboundIP      string
 	externalAddress string
+
+	// secret signs the blob urls of the cache service v2
+	secret []byte
+
+	// requireAuth rejects the requests of the api without a valid runtime token
+	requireAuth bool
+	// quota is the size of the caches of a repository beyond which the least recently used are evicted, 0 means unlimited
+	quota    int64
+	evicting sync.Mutex
+
+	metrics metrics
+}
+
+// Option configures a Handler
+type Option func(h *Handler)
+
+// WithAuth requires a valid runtime token for the requests of the api, like a cache server which is shared by many runners.
+// The content of the caches is downloaded from signed urls, so the clients don't need to send the token.
+func WithAuth() Option {
+	return func(h *Handler) {
+		h.requireAuth = true
+	}
+}
+
+// WithQuota limits the size of the caches of each repository, the least recently used caches are evicted beyond it
+func WithQuota(size int64) Option {
+	return func(h *Handler) {
+		h.quota = size
+	}
 }
 
-func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger) (*Handler, error) {
+// WithExternalURL sets the url which is returned to the clients, when the handler is behind a proxy for example
+func WithExternalURL(externalURL string) Option {
+	return func(h *Handler) {
+		h.externalAddress = strings.TrimSuffix(externalURL, "/")
+	}
+}
+
+func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger, opts ...Option) (*Handler, error) {
 	h := &Handler{}
+	for _, opt := range opts {
+		opt(h)
+	}
 
 	if logger == nil {
 		discard := logrus.New()
@@ -53,24 +89,16 @@ func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger
 	logger = logger.WithField("module", "artifactcache")
 	h.logger = logger
 
-	if dir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, err
-		}
-		dir = filepath.Join(home, ".cache", "actcache")
-	}
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	storage, index, err := OpenStorage(dir)
+	if err != nil {
 		return nil, err
 	}
+	h.storage = storage
+	h.index = index
 
-	h.dir = dir
-
-	storage, err := NewStorage(filepath.Join(dir, "cache"))
-	if err != nil {
+	if h.secret, err = newSecret(); err != nil {
 		return nil, err
 	}
-	h.storage = storage
 
 	if outboundIP != "" {
 		h.outboundIP = outboundIP
@@ -80,15 +108,7 @@ func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger
 		h.outboundIP = ip.String()
 	}
 
-	router := httprouter.New()
-	router.GET(urlBase+"/cache", h.middleware(h.find))
-	router.POST(urlBase+"/caches", h.middleware(h.reserve))
-	router.PATCH(urlBase+"/caches/:id", h.middleware(h.upload))
-	router.POST(urlBase+"/caches/:id", h.middleware(h.commit))
-	router.GET(urlBase+"/artifacts/:id", h.middleware(h.get))
-	router.POST(urlBase+"/clean", h.middleware(h.clean))
-
-	h.router = router
+	router := h.routes()
 
 	h.gcCache()
 
@@ -111,8 +131,11 @@ func StartHandler(dir, outboundIP string, port uint16, logger logrus.FieldLogger
 	return h, nil
 }
 
-func CreateHandler(dir, externalAddress string, logger logrus.FieldLogger) (*Handler, http.Handler, error) {
+func CreateHandler(dir, externalAddress string, logger logrus.FieldLogger, opts ...Option) (*Handler, http.Handler, error) {
 	h := &Handler{}
+	for _, opt := range opts {
+		opt(h)
+	}
 
 	if logger == nil {
 		discard := logrus.New()
@@ -122,24 +145,16 @@ func CreateHandler(dir, externalAddress string, logger logrus.FieldLogger) (*Han
 	logger = logger.WithField("module", "artifactcache")
 	h.logger = logger
 
-	if dir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, nil, err
-		}
-		dir = filepath.Join(home, ".cache", "actcache")
-	}
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	storage, index, err := OpenStorage(dir)
+	if err != nil {
 		return nil, nil, err
 	}
+	h.storage = storage
+	h.index = index
 
-	h.dir = dir
-
-	storage, err := NewStorage(filepath.Join(dir, "cache"))
-	if err != nil {
+	if h.secret, err = newSecret(); err != nil {
 		return nil, nil, err
 	}
-	h.storage = storage
 
 	if externalAddress != "" {
 		h.externalAddress = externalAddress
@@ -149,21 +164,28 @@ func CreateHandler(dir, externalAddress string, logger logrus.FieldLogger) (*Han
 		h.outboundIP = ip.String()
 	}
 
-	router := httprouter.New()
-	router.GET(urlBase+"/cache", h.middleware(h.find))
-	router.POST(urlBase+"/caches", h.middleware(h.reserve))
-	router.PATCH(urlBase+"/caches/:id", h.middleware(h.upload))
-	router.POST(urlBase+"/caches/:id", h.middleware(h.commit))
-	router.GET(urlBase+"/artifacts/:id", h.middleware(h.get))
-	router.POST(urlBase+"/clean", h.middleware(h.clean))
-
-	h.router = router
+	router := h.routes()
 
 	h.gcCache()
 
 	return h, router, nil
 }
 
+func (h *Handler) routes() *httprouter.Router {
+	router := httprouter.New()
+	router.GET(urlBase+"/cache", h.middleware(h.authenticate(h.find)))
+	router.POST(urlBase+"/caches", h.middleware(h.authenticate(h.reserve)))
+	router.PATCH(urlBase+"/caches/:id", h.middleware(h.authenticate(h.upload)))
+	router.POST(urlBase+"/caches/:id", h.middleware(h.authenticate(h.commit)))
+	router.GET(urlBase+"/artifacts/:id", h.middleware(h.get))
+	router.POST(urlBase+"/clean", h.middleware(h.authenticate(h.clean)))
+	h.routesV2(router)
+	router.GET("/metrics", h.serveMetrics)
+
+	h.router = router
+	return router
+}
+
 func (h *Handler) ExternalURL() string {
 	if h.externalAddress != "" {
 		return h.externalAddress
@@ -199,18 +221,6 @@ func (h *Handler) Close() error {
 	return retErr
 }
 
-func (h *Handler) openDB() (*bolthold.Store, error) {
-	return bolthold.Open(filepath.Join(h.dir, "bolt.db"), 0o644, &bolthold.Options{
-		Encoder: json.Marshal,
-		Decoder: json.Unmarshal,
-		Options: &bbolt.Options{
-			Timeout:      5 * time.Second,
-			NoGrowSync:   bbolt.DefaultOptions.NoGrowSync,
-			FreelistType: bbolt.DefaultOptions.FreelistType,
-		},
-	})
-}
-
 // GET /_apis/artifactcache/cache
 func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	keys := strings.Split(r.URL.Query().Get("keys"), ",")
@@ -220,19 +230,19 @@ func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	}
 	version := r.URL.Query().Get("version")
 
-	db, err := h.openDB()
+	scope, err := h.cacheScope(r)
 	if err != nil {
-		h.responseJSON(w, r, 500, err)
+		h.responseJSON(w, r, 401, err)
 		return
 	}
-	defer db.Close()
 
-	cache, err := findCache(db, keys, version)
+	cache, err := findCache(h.index, scope, keys, version)
 	if err != nil {
 		h.responseJSON(w, r, 500, err)
 		return
 	}
 	if cache == nil {
+		h.metrics.misses.Add(1)
 		h.responseJSON(w, r, 204)
 		return
 	}
@@ -241,13 +251,20 @@ func (h *Handler) find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 		h.responseJSON(w, r, 500, err)
 		return
 	} else if !ok {
-		_ = db.Delete(cache.ID, cache)
+		_ = h.index.Delete(cache.ID)
+		h.metrics.misses.Add(1)
 		h.responseJSON(w, r, 204)
 		return
 	}
+	h.metrics.hits.Add(1)
+	archiveLocation := fmt.Sprintf("%s%s/artifacts/%d", h.ExternalURL(), urlBase, cache.ID)
+	if h.requireAuth {
+		// the archive is downloaded without the token
+		archiveLocation = h.signedURL(urlBase+"/artifacts", http.MethodGet, cache.ID)
+	}
 	h.responseJSON(w, r, 200, map[string]any{
 		"result":          "hit",
-		"archiveLocation": fmt.Sprintf("%s%s/artifacts/%d", h.ExternalURL(), urlBase, cache.ID),
+		"archiveLocation": archiveLocation,
 		"cacheKey":        cache.Key,
 	})
 }
@@ -262,18 +279,29 @@ func (h *Handler) reserve(w http.ResponseWriter, r *http.Request, _ httprouter.P
 	// cache keys are case insensitive
 	api.Key = strings.ToLower(api.Key)
 
-	cache := api.ToCache()
-	db, err := h.openDB()
+	scope, err := h.cacheScope(r)
 	if err != nil {
-		h.responseJSON(w, r, 500, err)
+		h.responseJSON(w, r, 401, err)
+		return
+	}
+	if !scope.CanWrite {
+		h.responseJSON(w, r, 403, fmt.Errorf("cache %q: the token may not write caches", api.Key))
 		return
 	}
-	defer db.Close()
+
+	if h.quota > 0 && api.Size > h.quota {
+		h.responseJSON(w, r, 400, fmt.Errorf("cache %q: its size %d exceeds the quota %d", api.Key, api.Size, h.quota))
+		return
+	}
+
+	cache := api.ToCache()
+	cache.Repo = scope.Repo
+	cache.Scope = scope.WriteRef
 
 	now := time.Now().Unix()
 	cache.CreatedAt = now
 	cache.UsedAt = now
-	if err := insertCache(db, cache); err != nil {
+	if err := h.index.Insert(cache); err != nil {
 		h.responseJSON(w, r, 500, err)
 		return
 	}
@@ -290,15 +318,9 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
-	cache := &Cache{}
-	db, err := h.openDB()
+	cache, err := h.index.Get(id)
 	if err != nil {
-		h.responseJSON(w, r, 500, err)
-		return
-	}
-	defer db.Close()
-	if err := db.Get(id, cache); err != nil {
-		if errors.Is(err, bolthold.ErrNotFound) {
+		if errors.Is(err, ErrNotFound) {
 			h.responseJSON(w, r, 400, fmt.Errorf("cache %d: not reserved", id))
 			return
 		}
@@ -310,13 +332,12 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request, params httprout
 		h.responseJSON(w, r, 400, fmt.Errorf("cache %v %q: already complete", cache.ID, cache.Key))
 		return
 	}
-	db.Close()
 	start, _, err := parseContentRange(r.Header.Get("Content-Range"))
 	if err != nil {
 		h.responseJSON(w, r, 400, err)
 		return
 	}
-	if err := h.storage.Write(cache.ID, start, r.Body); err != nil {
+	if err := h.storage.Write(cache.ID, start, h.metrics.countUpload(r.Body)); err != nil {
 		h.responseJSON(w, r, 500, err)
 	}
 	h.useCache(id)
@@ -331,15 +352,9 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
-	cache := &Cache{}
-	db, err := h.openDB()
+	cache, err := h.index.Get(uint64(id))
 	if err != nil {
-		h.responseJSON(w, r, 500, err)
-		return
-	}
-	defer db.Close()
-	if err := db.Get(id, cache); err != nil {
-		if errors.Is(err, bolthold.ErrNotFound) {
+		if errors.Is(err, ErrNotFound) {
 			h.responseJSON(w, r, 400, fmt.Errorf("cache %d: not reserved", id))
 			return
 		}
@@ -352,8 +367,6 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 		return
 	}
 
-	db.Close()
-
 	size, err := h.storage.Commit(cache.ID, cache.Size)
 	if err != nil {
 		h.responseJSON(w, r, 500, err)
@@ -362,31 +375,33 @@ func (h *Handler) commit(w http.ResponseWriter, r *http.Request, params httprout
 	// write real size back to cache, it may be different from the current value when the request doesn't specify it.
 	cache.Size = size
 
-	db, err = h.openDB()
-	if err != nil {
-		h.responseJSON(w, r, 500, err)
-		return
-	}
-	defer db.Close()
-
 	cache.Complete = true
-	if err := db.Update(cache.ID, cache); err != nil {
+	if err := h.index.Update(cache); err != nil {
 		h.responseJSON(w, r, 500, err)
 		return
 	}
+	h.evictCaches(cache.Repo)
 
 	h.responseJSON(w, r, 200)
 }
 
 // GET /_apis/artifactcache/artifacts/:id
 func (h *Handler) get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	id, err := strconv.ParseUint(params.ByName("id"), 10, 64)
-	if err != nil {
-		h.responseJSON(w, r, 400, err)
-		return
+	var id uint64
+	if h.requireAuth {
+		var ok bool
+		if id, ok = h.verifyBlobURL(w, r, params); !ok {
+			return
+		}
+	} else {
+		var err error
+		if id, err = strconv.ParseUint(params.ByName("id"), 10, 64); err != nil {
+			h.responseJSON(w, r, 400, err)
+			return
+		}
 	}
 	h.useCache(id)
-	h.storage.Serve(w, r, id)
+	h.storage.Serve(h.metrics.countDownload(w), r, id)
 }
 
 // POST /_apis/artifactcache/clean
@@ -405,64 +420,83 @@ func (h *Handler) middleware(handler httprouter.Handle) httprouter.Handle {
 	}
 }
 
-// if not found, return (nil, nil) instead of an error.
-func findCache(db *bolthold.Store, keys []string, version string) (*Cache, error) {
-	cache := &Cache{}
-	for _, prefix := range keys {
-		// if a key in the list matches exactly, don't return partial matches
-		if err := db.FindOne(cache,
-			bolthold.Where("Key").Eq(prefix).
-				And("Version").Eq(version).
-				And("Complete").Eq(true).
-				SortBy("CreatedAt").Reverse()); err == nil || !errors.Is(err, bolthold.ErrNotFound) {
-			if err != nil {
-				return nil, fmt.Errorf("find cache: %w", err)
+// authenticate rejects the requests without a valid runtime token if the handler requires it
+func (h *Handler) authenticate(handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if h.requireAuth {
+			if r.Header.Get("Authorization") == "" {
+				h.responseJSON(w, r, 401, errors.New("missing token"))
+				return
+			}
+			if _, err := common.ParseAuthorizationToken(r); err != nil {
+				h.responseJSON(w, r, 401, fmt.Errorf("invalid token: %w", err))
+				return
 			}
-			return cache, nil
-		}
-		prefixPattern := fmt.Sprintf("^%s", regexp.QuoteMeta(prefix))
-		re, err := regexp.Compile(prefixPattern)
-		if err != nil {
-			continue
 		}
-		if err := db.FindOne(cache,
-			bolthold.Where("Key").RegExp(re).
-				And("Version").Eq(version).
-				And("Complete").Eq(true).
-				SortBy("CreatedAt").Reverse()); err != nil {
-			if errors.Is(err, bolthold.ErrNotFound) {
-				continue
+		handler(w, r, params)
+	}
+}
+
+// if not found, return (nil, nil) instead of an error.
+func findCache(index Index, scope *cacheScope, keys []string, version string) (*Cache, error) {
+	caches, err := index.Find(func(cache *Cache) bool {
+		return cache.Complete && cache.Version == version && cache.Repo == scope.Repo
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find cache: %w", err)
+	}
+	// the latest cache is preferred
+	sort.SliceStable(caches, func(i, j int) bool {
+		return caches[i].CreatedAt > caches[j].CreatedAt
+	})
+	// like GitHub, the caches of the ref of the job are preferred to those of the base ref and the default branch
+	for _, ref := range scope.Refs {
+		for _, prefix := range keys {
+			// if a key in the list matches exactly, don't return partial matches
+			for _, cache := range caches {
+				if cache.Scope == ref && cache.Key == prefix {
+					return cache, nil
+				}
+			}
+			for _, cache := range caches {
+				if cache.Scope == ref && strings.HasPrefix(cache.Key, prefix) {
+					return cache, nil
+				}
 			}
-			return nil, fmt.Errorf("find cache: %w", err)
 		}
-		return cache, nil
 	}
 	return nil, nil
 }
 
-func insertCache(db *bolthold.Store, cache *Cache) error {
-	if err := db.Insert(bolthold.NextSequence(), cache); err != nil {
-		return fmt.Errorf("insert cache: %w", err)
+// cacheScope returns the caches which the token of a request may access
+func (h *Handler) cacheScope(r *http.Request) (*cacheScope, error) {
+	claims, err := common.ParseAuthorizationClaims(r)
+	if err != nil && h.requireAuth {
+		return nil, fmt.Errorf("invalid token: %w", err)
 	}
-	// write back id to db
-	if err := db.Update(cache.ID, cache); err != nil {
-		return fmt.Errorf("write back id to db: %w", err)
+	if claims == nil {
+		if h.requireAuth {
+			return nil, errors.New("missing token")
+		}
+		// without auth the requests without a token of act, like the token of another runner or of another act process,
+		// share the caches which have no repository and ref
+		return &cacheScope{Refs: []string{""}, CanWrite: true}, nil
 	}
-	return nil
+	scope := &cacheScope{
+		Repo: claims.Repository,
+		Refs: claims.CacheScopes(),
+	}
+	scope.WriteRef, scope.CanWrite = claims.CacheWriteScope()
+	return scope, nil
 }
 
 func (h *Handler) useCache(id uint64) {
-	db, err := h.openDB()
+	cache, err := h.index.Get(id)
 	if err != nil {
 		return
 	}
-	defer db.Close()
-	cache := &Cache{}
-	if err := db.Get(id, cache); err != nil {
-		return
-	}
 	cache.UsedAt = time.Now().Unix()
-	_ = db.Update(cache.ID, cache)
+	_ = h.index.Update(cache)
 }
 
 const (
@@ -488,95 +522,108 @@ func (h *Handler) gcCache() {
 	h.gcAt = time.Now()
 	h.logger.Debugf("gc: %v", h.gcAt.String())
 
-	db, err := h.openDB()
+	// Remove the caches which are not completed for a while, they are most likely to be broken.
+	h.removeCaches(func(cache *Cache) bool {
+		return cache.UsedAt < time.Now().Add(-keepTemp).Unix() && !cache.Complete
+	})
+
+	// Remove the old caches which have not been used recently.
+	h.removeCaches(func(cache *Cache) bool {
+		return cache.UsedAt < time.Now().Add(-keepUnused).Unix()
+	})
+
+	// Remove the old caches which are too old.
+	h.removeCaches(func(cache *Cache) bool {
+		return cache.CreatedAt < time.Now().Add(-keepUsed).Unix()
+	})
+
+	// Remove the old caches with the same key and version, keep the latest one.
+	// Also keep the olds which have been used recently for a while in case of the cache is still in use.
+	caches, err := h.index.Find(func(cache *Cache) bool {
+		return cache.Complete
+	})
 	if err != nil {
+		h.logger.Warnf("find caches: %v", err)
 		return
 	}
-	defer db.Close()
-
-	// Remove the caches which are not completed for a while, they are most likely to be broken.
-	var caches []*Cache
-	if err := db.Find(&caches, bolthold.
-		Where("UsedAt").Lt(time.Now().Add(-keepTemp).Unix()).
-		And("Complete").Eq(false),
-	); err != nil {
-		h.logger.Warnf("find caches: %v", err)
-	} else {
-		for _, cache := range caches {
-			h.storage.Remove(cache.ID)
-			if err := db.Delete(cache.ID, cache); err != nil {
-				h.logger.Warnf("delete cache: %v", err)
-				continue
-			}
-			h.logger.Infof("deleted cache: %+v", cache)
+	type editions struct {
+		Repo, Scope, Key, Version string
+	}
+	latest := map[editions]*Cache{}
+	for _, cache := range caches {
+		k := editions{cache.Repo, cache.Scope, cache.Key, cache.Version}
+		if v, ok := latest[k]; !ok || cache.CreatedAt > v.CreatedAt {
+			latest[k] = cache
 		}
 	}
+	h.removeCaches(func(cache *Cache) bool {
+		v, ok := latest[editions{cache.Repo, cache.Scope, cache.Key, cache.Version}]
+		// Keep it if it has been used recently, even if it's old.
+		// Or it could break downloading in process.
+		return cache.Complete && ok && v.ID != cache.ID && time.Since(time.Unix(cache.UsedAt, 0)) >= keepOld
+	})
+}
 
-	// Remove the old caches which have not been used recently.
-	caches = caches[:0]
-	if err := db.Find(&caches, bolthold.
-		Where("UsedAt").Lt(time.Now().Add(-keepUnused).Unix()),
-	); err != nil {
-		h.logger.Warnf("find caches: %v", err)
-	} else {
-		for _, cache := range caches {
-			h.storage.Remove(cache.ID)
-			if err := db.Delete(cache.ID, cache); err != nil {
-				h.logger.Warnf("delete cache: %v", err)
-				continue
-			}
-			h.logger.Infof("deleted cache: %+v", cache)
-		}
+// evictCaches removes the least recently used caches of the repository until they fit in the quota
+func (h *Handler) evictCaches(repo string) {
+	if h.quota <= 0 {
+		return
 	}
+	h.evicting.Lock()
+	defer h.evicting.Unlock()
 
-	// Remove the old caches which are too old.
-	caches = caches[:0]
-	if err := db.Find(&caches, bolthold.
-		Where("CreatedAt").Lt(time.Now().Add(-keepUsed).Unix()),
-	); err != nil {
+	caches, err := h.index.Find(func(cache *Cache) bool {
+		return cache.Complete && cache.Repo == repo
+	})
+	if err != nil {
 		h.logger.Warnf("find caches: %v", err)
-	} else {
-		for _, cache := range caches {
-			h.storage.Remove(cache.ID)
-			if err := db.Delete(cache.ID, cache); err != nil {
-				h.logger.Warnf("delete cache: %v", err)
-				continue
-			}
-			h.logger.Infof("deleted cache: %+v", cache)
+		return
+	}
+	var size int64
+	for _, cache := range caches {
+		size += cache.Size
+	}
+	sort.SliceStable(caches, func(i, j int) bool {
+		if caches[i].UsedAt != caches[j].UsedAt {
+			return caches[i].UsedAt < caches[j].UsedAt
+		}
+		return caches[i].CreatedAt < caches[j].CreatedAt
+	})
+	evict := map[uint64]bool{}
+	for _, cache := range caches {
+		if size <= h.quota {
+			break
 		}
+		evict[cache.ID] = true
+		size -= cache.Size
 	}
+	if len(evict) == 0 {
+		return
+	}
+	h.logger.Infof("evict %d caches of repository %q beyond the quota %d", len(evict), repo, h.quota)
+	h.metrics.evictions.Add(int64(h.removeCaches(func(cache *Cache) bool {
+		return evict[cache.ID]
+	})))
+}
 
-	// Remove the old caches with the same key and version, keep the latest one.
-	// Also keep the olds which have been used recently for a while in case of the cache is still in use.
-	if results, err := db.FindAggregate(
-		&Cache{},
-		bolthold.Where("Complete").Eq(true),
-		"Key", "Version",
-	); err != nil {
-		h.logger.Warnf("find aggregate caches: %v", err)
-	} else {
-		for _, result := range results {
-			if result.Count() <= 1 {
-				continue
-			}
-			result.Sort("CreatedAt")
-			caches = caches[:0]
-			result.Reduction(&caches)
-			for _, cache := range caches[:len(caches)-1] {
-				if time.Since(time.Unix(cache.UsedAt, 0)) < keepOld {
-					// Keep it since it has been used recently, even if it's old.
-					// Or it could break downloading in process.
-					continue
-				}
-				h.storage.Remove(cache.ID)
-				if err := db.Delete(cache.ID, cache); err != nil {
-					h.logger.Warnf("delete cache: %v", err)
-					continue
-				}
-				h.logger.Infof("deleted cache: %+v", cache)
-			}
+// removeCaches removes the caches which match and returns how many were removed
+func (h *Handler) removeCaches(match func(*Cache) bool) int {
+	caches, err := h.index.Find(match)
+	if err != nil {
+		h.logger.Warnf("find caches: %v", err)
+		return 0
+	}
+	removed := 0
+	for _, cache := range caches {
+		h.storage.Remove(cache.ID)
+		if err := h.index.Delete(cache.ID); err != nil {
+			h.logger.Warnf("delete cache: %v", err)
+			continue
 		}
+		h.logger.Infof("deleted cache: %+v", cache)
+		removed++
 	}
+	return removed
 }
 
 func (h *Handler) responseJSON(w http.ResponseWriter, r *http.Request, code int, v ...any) {
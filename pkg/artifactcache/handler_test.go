@@ -12,10 +12,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/timshannon/bolthold"
-	"go.etcd.io/bbolt"
+
+	"github.com/actions-oss/act-cli/pkg/common"
 )
 
 func TestHandler(t *testing.T) {
@@ -27,15 +28,11 @@ func TestHandler(t *testing.T) {
 
 	defer func() {
 		t.Run("inpect db", func(t *testing.T) {
-			db, err := handler.openDB()
-			require.NoError(t, err)
-			defer db.Close()
-			require.NoError(t, db.Bolt().View(func(tx *bbolt.Tx) error {
-				return tx.Bucket([]byte("Cache")).ForEach(func(k, v []byte) error {
-					t.Logf("%s: %s", k, v)
-					return nil
-				})
-			}))
+			caches, err := handler.index.Find(nil)
+			require.NoError(t, err)
+			for _, cache := range caches {
+				t.Logf("%d: %+v", cache.ID, cache)
+			}
 		})
 		t.Run("close", func(t *testing.T) {
 			require.NoError(t, handler.Close())
@@ -670,30 +667,23 @@ func TestHandler_gcCache(t *testing.T) {
 		},
 	}
 
-	db, err := handler.openDB()
-	require.NoError(t, err)
 	for _, c := range cases {
-		require.NoError(t, insertCache(db, c.Cache))
+		require.NoError(t, handler.index.Insert(c.Cache))
 	}
-	require.NoError(t, db.Close())
 
 	handler.gcAt = time.Time{} // ensure gcCache will not skip
 	handler.gcCache()
 
-	db, err = handler.openDB()
-	require.NoError(t, err)
 	for i, v := range cases {
 		t.Run(fmt.Sprintf("%d_%s", i, v.Cache.Key), func(t *testing.T) {
-			cache := &Cache{}
-			err = db.Get(v.Cache.ID, cache)
+			_, err := handler.index.Get(v.Cache.ID)
 			if v.Kept {
 				assert.NoError(t, err)
 			} else {
-				assert.ErrorIs(t, err, bolthold.ErrNotFound)
+				assert.ErrorIs(t, err, ErrNotFound)
 			}
 		})
 	}
-	require.NoError(t, db.Close())
 }
 
 func TestCreateHandler(t *testing.T) {
@@ -705,3 +695,268 @@ func TestCreateHandler(t *testing.T) {
 
 	require.Equal(t, "http://localhost:8080", handler.ExternalURL())
 }
+
+func TestHandler_cacheScope(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifactcache")
+	handler, err := StartHandler(dir, "", 0, nil)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, handler.Close())
+	}()
+
+	base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+	key := "scoped-key"
+	version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+	token := func(repo string, refs ...string) string {
+		token, err := common.CreateScopedAuthorizationToken(1, 1, 1, repo, refs...)
+		require.NoError(t, err)
+		return token
+	}
+	do := func(method, url, token string, body []byte) *http.Response {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/*", len(body)-1))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	save := func(token string, content []byte) {
+		body, err := json.Marshal(&Request{Key: key, Version: version, Size: int64(len(content))})
+		require.NoError(t, err)
+		resp := do(http.MethodPost, base+"/caches", token, body)
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			CacheID uint64 `json:"cacheId"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		require.Equal(t, 200, do(http.MethodPatch, fmt.Sprintf("%s/caches/%d", base, got.CacheID), token, content).StatusCode)
+		require.Equal(t, 200, do(http.MethodPost, fmt.Sprintf("%s/caches/%d", base, got.CacheID), token, nil).StatusCode)
+	}
+	restore := func(token string) []byte {
+		resp := do(http.MethodGet, fmt.Sprintf("%s/cache?keys=%s&version=%s", base, key, version), token, nil)
+		if resp.StatusCode == 204 {
+			return nil
+		}
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			ArchiveLocation string `json:"archiveLocation"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		resp = do(http.MethodGet, got.ArchiveLocation, "", nil)
+		require.Equal(t, 200, resp.StatusCode)
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return content
+	}
+
+	save(token("owner/repo", "refs/heads/main"), []byte("main"))
+	save(token("owner/repo", "refs/heads/feature", "refs/heads/main"), []byte("feature"))
+
+	t.Run("ref of the job first", func(t *testing.T) {
+		assert.Equal(t, []byte("feature"), restore(token("owner/repo", "refs/heads/feature", "refs/heads/main")))
+	})
+	t.Run("base ref", func(t *testing.T) {
+		assert.Equal(t, []byte("feature"), restore(token("owner/repo", "refs/pull/1/merge", "refs/heads/feature", "refs/heads/main")))
+	})
+	t.Run("default branch", func(t *testing.T) {
+		assert.Equal(t, []byte("main"), restore(token("owner/repo", "refs/heads/other", "refs/heads/main")))
+	})
+	t.Run("other branch", func(t *testing.T) {
+		assert.Equal(t, []byte("main"), restore(token("owner/repo", "refs/heads/main")))
+		assert.Nil(t, restore(token("owner/repo", "refs/heads/other")))
+	})
+	t.Run("other repository", func(t *testing.T) {
+		assert.Nil(t, restore(token("owner/other", "refs/heads/feature", "refs/heads/main")))
+	})
+	t.Run("no token", func(t *testing.T) {
+		assert.Nil(t, restore(""))
+	})
+}
+
+func TestHandler_auth(t *testing.T) {
+	handler, err := StartHandler(filepath.Join(t.TempDir(), "artifactcache"), "", 0, nil, WithAuth())
+	require.NoError(t, err)
+	defer handler.Close()
+	base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+	version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+	t.Run("without token", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/cache?keys=key&version=%s", base, version))
+		require.NoError(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		resp := doWithToken(t, http.MethodGet, fmt.Sprintf("%s/cache?keys=key&version=%s", base, version), "invalid", nil)
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+
+	t.Run("with token", func(t *testing.T) {
+		token, err := common.CreateScopedAuthorizationToken(1, 1, 1, "owner/repo", "refs/heads/main")
+		require.NoError(t, err)
+		id := uploadCacheWithToken(t, base, token, "key", version, []byte("content"))
+
+		resp := doWithToken(t, http.MethodGet, fmt.Sprintf("%s/cache?keys=key&version=%s", base, version), token, nil)
+		require.Equal(t, 200, resp.StatusCode)
+		got := struct {
+			ArchiveLocation string `json:"archiveLocation"`
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+		// the archive is downloaded from the signed url without the token
+		resp, err = http.Get(got.ArchiveLocation)
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+
+		resp, err = http.Get(fmt.Sprintf("%s/artifacts/%d", base, id))
+		require.NoError(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+}
+
+func TestHandler_quota(t *testing.T) {
+	handler, err := StartHandler(filepath.Join(t.TempDir(), "artifactcache"), "", 0, nil, WithQuota(250))
+	require.NoError(t, err)
+	defer handler.Close()
+	base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+	version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+	token, err := common.CreateScopedAuthorizationToken(1, 1, 1, "owner/repo", "refs/heads/main")
+	require.NoError(t, err)
+	other, err := common.CreateScopedAuthorizationToken(2, 2, 2, "owner/other", "refs/heads/main")
+	require.NoError(t, err)
+
+	first := uploadCacheWithToken(t, base, token, "first", version, make([]byte, 100))
+	second := uploadCacheWithToken(t, base, token, "second", version, make([]byte, 100))
+	otherID := uploadCacheWithToken(t, base, other, "other", version, make([]byte, 100))
+	// the first is used later than the second, so the second is the least recently used
+	cache, err := handler.index.Get(second)
+	require.NoError(t, err)
+	cache.UsedAt = time.Now().Add(-time.Hour).Unix()
+	require.NoError(t, handler.index.Update(cache))
+
+	third := uploadCacheWithToken(t, base, token, "third", version, make([]byte, 100))
+
+	for id, exist := range map[uint64]bool{first: true, second: false, third: true, otherID: true} {
+		_, err := handler.index.Get(id)
+		if exist {
+			assert.NoError(t, err, id)
+		} else {
+			assert.ErrorIs(t, err, ErrNotFound, id)
+		}
+	}
+
+	t.Run("too large", func(t *testing.T) {
+		body, err := json.Marshal(&Request{Key: "large", Version: version, Size: 300})
+		require.NoError(t, err)
+		resp := doWithToken(t, http.MethodPost, base+"/caches", token, body)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		resp := doWithToken(t, http.MethodGet, fmt.Sprintf("%s/cache?keys=missing&version=%s", base, version), token, nil)
+		require.Equal(t, 204, resp.StatusCode)
+
+		resp, err := http.Get(handler.ExternalURL() + "/metrics")
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		for _, line := range []string{
+			"act_cache_hits_total 4",
+			"act_cache_misses_total 1",
+			"act_cache_uploaded_bytes_total 400",
+			"act_cache_downloaded_bytes_total 400",
+			"act_cache_evictions_total 1",
+			"act_cache_quota_bytes 250",
+			`act_cache_entries{repository="owner/repo"} 2`,
+			`act_cache_size_bytes{repository="owner/other"} 100`,
+		} {
+			assert.Contains(t, string(body), line+"\n")
+		}
+	})
+}
+
+func doWithToken(t *testing.T, method, url, token string, body []byte) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+	return resp
+}
+
+// uploadCacheWithToken saves a cache like uploadCacheNormally and returns its id, it is found and downloaded once
+func uploadCacheWithToken(t *testing.T, base, token, key, version string, content []byte) uint64 {
+	body, err := json.Marshal(&Request{Key: key, Version: version, Size: int64(len(content))})
+	require.NoError(t, err)
+	resp := doWithToken(t, http.MethodPost, base+"/caches", token, body)
+	require.Equal(t, 200, resp.StatusCode)
+	got := struct {
+		CacheID uint64 `json:"cacheId"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/caches/%d", base, got.CacheID), bytes.NewReader(content))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/*", len(content)-1))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+
+	resp = doWithToken(t, http.MethodPost, fmt.Sprintf("%s/caches/%d", base, got.CacheID), token, nil)
+	require.Equal(t, 200, resp.StatusCode)
+
+	resp = doWithToken(t, http.MethodGet, fmt.Sprintf("%s/cache?keys=%s&version=%s", base, key, version), token, nil)
+	require.Equal(t, 200, resp.StatusCode)
+	found := struct {
+		ArchiveLocation string `json:"archiveLocation"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&found))
+	resp, err = http.Get(found.ArchiveLocation)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+	return got.CacheID
+}
+
+func TestHandler_cacheScopeRequiresToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	scope, err := (&Handler{}).cacheScope(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, scope.Refs)
+
+	_, err = (&Handler{requireAuth: true}).cacheScope(req)
+	assert.Error(t, err, "the requests without token have no scope if auth is required")
+
+	// a token which is not signed by this process, like a token of another runner
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"repository": "owner/repo"}).SignedString([]byte("other"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+forged)
+
+	scope, err = (&Handler{}).cacheScope(req)
+	require.NoError(t, err, "a token which can't be verified is anonymous without auth")
+	assert.Equal(t, &cacheScope{Refs: []string{""}, CanWrite: true}, scope)
+
+	_, err = (&Handler{requireAuth: true}).cacheScope(req)
+	assert.Error(t, err)
+}
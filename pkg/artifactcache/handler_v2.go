@@ -0,0 +1,327 @@
+package artifactcache
+
+// The cache service v2 is used by actions/cache and @actions/cache when ACTIONS_CACHE_SERVICE_V2 is set,
+// its Twirp API is found at ACTIONS_RESULTS_URL.
+//
+// 1. Save a cache
+// 1.1. CreateCacheEntry reserves the cache and returns a signed url to upload its content
+// POST /twirp/github.actions.results.api.v1.CacheService/CreateCacheEntry
+// {"key": "linux-npm-abc", "version": "8a4a..."}
+// {"ok": true, "signedUploadUrl": "http://host:port/_apis/artifactcache/blobs/1?sig=...&expires=..."}
+// 1.2. Upload the content like to an Azure block blob, in one request or in blocks which are joined by a block list
+// PUT <signedUploadUrl>
+// PUT <signedUploadUrl>&comp=block&blockid=<id>
+// PUT <signedUploadUrl>&comp=blocklist <BlockList><Latest>id</Latest>...</BlockList>
+// 1.3. FinalizeCacheEntryUpload commits the uploaded content
+// POST /twirp/github.actions.results.api.v1.CacheService/FinalizeCacheEntryUpload
+// {"key": "linux-npm-abc", "version": "8a4a...", "sizeBytes": "1024"}
+// {"ok": true, "entryId": "1"}
+// 2. Restore a cache
+// 2.1. GetCacheEntryDownloadURL finds the cache by its key or the prefixes of restoreKeys
+// POST /twirp/github.actions.results.api.v1.CacheService/GetCacheEntryDownloadURL
+// {"key": "linux-npm-abc", "restoreKeys": ["linux-npm-"], "version": "8a4a..."}
+// {"ok": true, "signedDownloadUrl": "http://host:port/_apis/artifactcache/blobs/1?sig=...&expires=...", "matchedKey": "linux-npm-abc"}
+// 2.2. Download the content
+// GET <signedDownloadUrl>
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	CacheV2RouteBase = "/twirp/github.actions.results.api.v1.CacheService"
+	blobURLBase      = urlBase + "/blobs"
+	blobURLExpiry    = time.Hour
+)
+
+func (h *Handler) routesV2(router *httprouter.Router) {
+	router.POST(CacheV2RouteBase+"/CreateCacheEntry", h.middleware(h.authenticate(h.createCacheEntry)))
+	router.POST(CacheV2RouteBase+"/FinalizeCacheEntryUpload", h.middleware(h.authenticate(h.finalizeCacheEntryUpload)))
+	router.POST(CacheV2RouteBase+"/GetCacheEntryDownloadURL", h.middleware(h.authenticate(h.getCacheEntryDownloadURL)))
+	router.PUT(blobURLBase+"/:id", h.middleware(h.uploadBlob))
+	router.GET(blobURLBase+"/:id", h.middleware(h.downloadBlob))
+}
+
+// POST /twirp/github.actions.results.api.v1.CacheService/CreateCacheEntry
+func (h *Handler) createCacheEntry(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := &CreateCacheEntryRequest{}
+	if err := decodeTwirpJSON(r.Body, req); err != nil {
+		h.responseTwirpError(w, r, http.StatusBadRequest, "malformed", err)
+		return
+	}
+	if req.Key == "" || req.Version == "" {
+		h.responseTwirpError(w, r, http.StatusBadRequest, "invalid_argument", errors.New("key and version are required"))
+		return
+	}
+	scope, err := h.cacheScope(r)
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusUnauthorized, "unauthenticated", err)
+		return
+	}
+	if !scope.CanWrite {
+		h.responseTwirpError(w, r, http.StatusForbidden, "permission_denied", fmt.Errorf("cache %q: the token may not write caches", req.Key))
+		return
+	}
+	// cache keys are case insensitive
+	cache := (&Request{Key: strings.ToLower(req.Key), Version: req.Version}).ToCache()
+	cache.Repo = scope.Repo
+	cache.Scope = scope.WriteRef
+
+	now := time.Now().Unix()
+	cache.CreatedAt = now
+	cache.UsedAt = now
+	if err := h.index.Insert(cache); err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	h.responseJSON(w, r, 200, &CreateCacheEntryResponse{
+		Ok:              true,
+		SignedUploadURL: h.signedBlobURL(http.MethodPut, cache.ID),
+	})
+}
+
+// POST /twirp/github.actions.results.api.v1.CacheService/FinalizeCacheEntryUpload
+func (h *Handler) finalizeCacheEntryUpload(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := &FinalizeCacheEntryUploadRequest{}
+	if err := decodeTwirpJSON(r.Body, req); err != nil {
+		h.responseTwirpError(w, r, http.StatusBadRequest, "malformed", err)
+		return
+	}
+	scope, err := h.cacheScope(r)
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusUnauthorized, "unauthenticated", err)
+		return
+	}
+
+	// the latest reserved cache of the key and version is the one which was uploaded
+	key := strings.ToLower(req.Key)
+	caches, err := h.index.Find(func(cache *Cache) bool {
+		return !cache.Complete && cache.Key == key && cache.Version == req.Version &&
+			cache.Repo == scope.Repo && cache.Scope == scope.WriteRef
+	})
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	if len(caches) == 0 {
+		h.responseTwirpError(w, r, http.StatusNotFound, "not_found", fmt.Errorf("cache %q: not reserved", req.Key))
+		return
+	}
+	cache := caches[0]
+	for _, v := range caches[1:] {
+		if v.CreatedAt >= cache.CreatedAt {
+			cache = v
+		}
+	}
+
+	size := int64(req.SizeBytes)
+	if size <= 0 {
+		size = -1
+	}
+	if h.quota > 0 && size > h.quota {
+		h.removeCaches(func(v *Cache) bool {
+			return v.ID == cache.ID
+		})
+		h.responseTwirpError(w, r, http.StatusBadRequest, "resource_exhausted", fmt.Errorf("cache %q: its size %d exceeds the quota %d", req.Key, size, h.quota))
+		return
+	}
+	size, err = h.storage.Commit(cache.ID, size)
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	cache.Size = size
+	cache.Complete = true
+	if err := h.index.Update(cache); err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	h.evictCaches(cache.Repo)
+	h.responseJSON(w, r, 200, &FinalizeCacheEntryUploadResponse{
+		Ok:      true,
+		EntryID: twirpInt64(cache.ID),
+	})
+}
+
+// POST /twirp/github.actions.results.api.v1.CacheService/GetCacheEntryDownloadURL
+func (h *Handler) getCacheEntryDownloadURL(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := &GetCacheEntryDownloadURLRequest{}
+	if err := decodeTwirpJSON(r.Body, req); err != nil {
+		h.responseTwirpError(w, r, http.StatusBadRequest, "malformed", err)
+		return
+	}
+	keys := append([]string{req.Key}, req.RestoreKeys...)
+	// cache keys are case insensitive
+	for i, key := range keys {
+		keys[i] = strings.ToLower(key)
+	}
+	scope, err := h.cacheScope(r)
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusUnauthorized, "unauthenticated", err)
+		return
+	}
+
+	cache, err := findCache(h.index, scope, keys, req.Version)
+	if err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	}
+	// a miss is not an error, the response is not ok
+	if cache == nil {
+		h.metrics.misses.Add(1)
+		h.responseJSON(w, r, 200, &GetCacheEntryDownloadURLResponse{})
+		return
+	}
+	if ok, err := h.storage.Exist(cache.ID); err != nil {
+		h.responseTwirpError(w, r, http.StatusInternalServerError, "internal", err)
+		return
+	} else if !ok {
+		_ = h.index.Delete(cache.ID)
+		h.metrics.misses.Add(1)
+		h.responseJSON(w, r, 200, &GetCacheEntryDownloadURLResponse{})
+		return
+	}
+	h.metrics.hits.Add(1)
+	h.responseJSON(w, r, 200, &GetCacheEntryDownloadURLResponse{
+		Ok:                true,
+		SignedDownloadURL: h.signedBlobURL(http.MethodGet, cache.ID),
+		MatchedKey:        cache.Key,
+	})
+}
+
+// PUT /_apis/artifactcache/blobs/:id
+func (h *Handler) uploadBlob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id, ok := h.verifyBlobURL(w, r, params)
+	if !ok {
+		return
+	}
+
+	cache, err := h.index.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			h.responseJSON(w, r, 404, fmt.Errorf("cache %d: not reserved", id))
+			return
+		}
+		h.responseJSON(w, r, 500, err)
+		return
+	}
+	if cache.Complete {
+		h.responseJSON(w, r, 400, fmt.Errorf("cache %v %q: already complete", cache.ID, cache.Key))
+		return
+	}
+
+	switch comp := r.URL.Query().Get("comp"); comp {
+	case "":
+		err = h.storage.Write(id, 0, h.metrics.countUpload(r.Body))
+	case "block":
+		err = h.storage.WriteBlock(id, r.URL.Query().Get("blockid"), h.metrics.countUpload(r.Body))
+	case "blocklist":
+		var blockIDs []string
+		if blockIDs, err = parseBlockList(r.Body); err == nil {
+			err = h.storage.CommitBlocks(id, blockIDs)
+		}
+	default:
+		h.responseJSON(w, r, 400, fmt.Errorf("unsupported comp %q", comp))
+		return
+	}
+	if err != nil {
+		h.responseJSON(w, r, 500, err)
+		return
+	}
+	h.useCache(id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GET /_apis/artifactcache/blobs/:id
+func (h *Handler) downloadBlob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id, ok := h.verifyBlobURL(w, r, params)
+	if !ok {
+		return
+	}
+	h.useCache(id)
+	h.storage.Serve(h.metrics.countDownload(w), r, id)
+}
+
+func newSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (h *Handler) blobSignature(method string, id uint64, expires string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	fmt.Fprintf(mac, "%s\n%d\n%s", method, id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedBlobURL returns the url to upload or download the content of a cache until it expires
+func (h *Handler) signedBlobURL(method string, id uint64) string {
+	return h.signedURL(blobURLBase, method, id)
+}
+
+// signedURL returns the url of a cache below base, which is verified by verifyBlobURL
+func (h *Handler) signedURL(base, method string, id uint64) string {
+	expires := strconv.FormatInt(time.Now().Add(blobURLExpiry).Unix(), 10)
+	return fmt.Sprintf("%s%s/%d?sig=%s&expires=%s", h.ExternalURL(), base, id, h.blobSignature(method, id, expires), expires)
+}
+
+func (h *Handler) verifyBlobURL(w http.ResponseWriter, r *http.Request, params httprouter.Params) (uint64, bool) {
+	id, err := strconv.ParseUint(params.ByName("id"), 10, 64)
+	if err != nil {
+		h.responseJSON(w, r, 400, err)
+		return 0, false
+	}
+	expires := r.URL.Query().Get("expires")
+	sig := r.URL.Query().Get("sig")
+	if !hmac.Equal([]byte(sig), []byte(h.blobSignature(r.Method, id, expires))) {
+		h.responseJSON(w, r, 401, errors.New("invalid signature"))
+		return 0, false
+	}
+	if t, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > t {
+		h.responseJSON(w, r, 401, errors.New("url expired"))
+		return 0, false
+	}
+	return id, true
+}
+
+// parseBlockList returns the ids of a block list in their order, it is like
+// <BlockList><Latest>id</Latest><Uncommitted>id</Uncommitted></BlockList>
+func parseBlockList(r io.Reader) ([]string, error) {
+	var blockList struct {
+		Blocks []struct {
+			ID string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	if err := xml.NewDecoder(r).Decode(&blockList); err != nil {
+		return nil, fmt.Errorf("parse block list: %w", err)
+	}
+	ids := make([]string, 0, len(blockList.Blocks))
+	for _, b := range blockList.Blocks {
+		ids = append(ids, strings.TrimSpace(b.ID))
+	}
+	return ids, nil
+}
+
+// responseTwirpError writes an error like Twirp, its code is one of the Twirp error codes
+func (h *Handler) responseTwirpError(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	h.logger.Errorf("%v %v: %v", r.Method, r.RequestURI, err)
+	h.responseJSON(w, r, status, map[string]any{
+		"code": code,
+		"msg":  err.Error(),
+	})
+}
@@ -0,0 +1,164 @@
+package artifactcache
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerV2(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifactcache")
+	handler, err := StartHandler(dir, "", 0, nil)
+	require.NoError(t, err)
+	defer handler.Close()
+
+	base := handler.ExternalURL() + CacheV2RouteBase
+	version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+
+	call := func(t *testing.T, method string, req, res any) int {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		resp, err := http.Post(fmt.Sprintf("%s/%s", base, method), "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if res != nil && resp.StatusCode == 200 {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+		}
+		return resp.StatusCode
+	}
+	put := func(t *testing.T, url string, body []byte) int {
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	download := func(t *testing.T, url string) []byte {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, 200, resp.StatusCode)
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return got
+	}
+
+	t.Run("get not exist", func(t *testing.T) {
+		res := &GetCacheEntryDownloadURLResponse{}
+		require.Equal(t, 200, call(t, "GetCacheEntryDownloadURL", &GetCacheEntryDownloadURLRequest{
+			Key:     "not-exist",
+			Version: version,
+		}, res))
+		assert.False(t, res.Ok)
+		assert.Empty(t, res.SignedDownloadURL)
+	})
+
+	t.Run("upload in one request", func(t *testing.T) {
+		content := make([]byte, 100)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+
+		created := &CreateCacheEntryResponse{}
+		require.Equal(t, 200, call(t, "CreateCacheEntry", &CreateCacheEntryRequest{Key: "Single-Key", Version: version}, created))
+		require.True(t, created.Ok)
+		require.Equal(t, 201, put(t, created.SignedUploadURL, content))
+
+		finalized := &FinalizeCacheEntryUploadResponse{}
+		require.Equal(t, 200, call(t, "FinalizeCacheEntryUpload", &FinalizeCacheEntryUploadRequest{
+			Key:       "Single-Key",
+			Version:   version,
+			SizeBytes: twirpInt64(len(content)),
+		}, finalized))
+		require.True(t, finalized.Ok)
+		assert.NotZero(t, finalized.EntryID)
+
+		found := &GetCacheEntryDownloadURLResponse{}
+		require.Equal(t, 200, call(t, "GetCacheEntryDownloadURL", &GetCacheEntryDownloadURLRequest{
+			Key:     "single-key",
+			Version: version,
+		}, found))
+		require.True(t, found.Ok)
+		assert.Equal(t, "single-key", found.MatchedKey)
+		assert.Equal(t, content, download(t, found.SignedDownloadURL))
+	})
+
+	t.Run("upload in blocks", func(t *testing.T) {
+		blocks := [][]byte{[]byte("first block,"), []byte("second block,"), []byte("third block")}
+
+		created := &CreateCacheEntryResponse{}
+		require.Equal(t, 200, call(t, "CreateCacheEntry", &CreateCacheEntryRequest{Key: "blocks-key", Version: version}, created))
+		require.True(t, created.Ok)
+
+		// the blocks are uploaded in reverse order, the block list decides the order of the content
+		blockList := &strings.Builder{}
+		for i := len(blocks) - 1; i >= 0; i-- {
+			id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%03d", i)))
+			require.Equal(t, 201, put(t, created.SignedUploadURL+"&comp=block&blockid="+id, blocks[i]))
+		}
+		blockList.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+		for i := range blocks {
+			id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%03d", i)))
+			blockList.WriteString("<Latest>" + id + "</Latest>")
+		}
+		blockList.WriteString("</BlockList>")
+		require.Equal(t, 201, put(t, created.SignedUploadURL+"&comp=blocklist", []byte(blockList.String())))
+
+		// the proto names of the fields are accepted as well
+		resp, err := http.Post(base+"/FinalizeCacheEntryUpload", "application/json", strings.NewReader(
+			fmt.Sprintf(`{"key": "blocks-key", "version": %q, "size_bytes": "%d"}`, version, len(bytes.Join(blocks, nil)))))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		resp.Body.Close()
+
+		found := &GetCacheEntryDownloadURLResponse{}
+		require.Equal(t, 200, call(t, "GetCacheEntryDownloadURL", &GetCacheEntryDownloadURLRequest{
+			Key:         "blocks-key-miss",
+			RestoreKeys: []string{"blocks-"},
+			Version:     version,
+		}, found))
+		require.True(t, found.Ok)
+		assert.Equal(t, "blocks-key", found.MatchedKey)
+		assert.Equal(t, bytes.Join(blocks, nil), download(t, found.SignedDownloadURL))
+	})
+
+	t.Run("finalize not reserved", func(t *testing.T) {
+		assert.Equal(t, 404, call(t, "FinalizeCacheEntryUpload", &FinalizeCacheEntryUploadRequest{
+			Key:     "not-reserved",
+			Version: version,
+		}, nil))
+	})
+
+	t.Run("create with bad request", func(t *testing.T) {
+		resp, err := http.Post(base+"/CreateCacheEntry", "application/json", strings.NewReader("invalid json"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, 400, resp.StatusCode)
+		twirpErr := map[string]string{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&twirpErr))
+		assert.Equal(t, "malformed", twirpErr["code"])
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		created := &CreateCacheEntryResponse{}
+		require.Equal(t, 200, call(t, "CreateCacheEntry", &CreateCacheEntryRequest{Key: "signed-key", Version: version}, created))
+		require.True(t, created.Ok)
+		assert.Equal(t, 401, put(t, strings.Replace(created.SignedUploadURL, "sig=", "sig=x", 1), []byte("content")))
+
+		// an upload url can't be used to download
+		resp, err := http.Get(created.SignedUploadURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+}
@@ -0,0 +1,289 @@
+package artifactcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timshannon/bolthold"
+	"go.etcd.io/bbolt"
+)
+
+var ErrNotFound = errors.New("cache not found")
+
+// Index stores the metadata of the caches
+type Index interface {
+	// Get returns ErrNotFound if there is no cache with the id
+	Get(id uint64) (*Cache, error)
+	// Find returns the caches which match
+	Find(match func(*Cache) bool) ([]*Cache, error)
+	// Insert assigns a new id to the cache and stores it
+	Insert(cache *Cache) error
+	Update(cache *Cache) error
+	Delete(id uint64) error
+}
+
+// BoltIndex stores the metadata in a bolthold database, which may only be used by one machine
+type BoltIndex struct {
+	path string
+}
+
+func NewBoltIndex(path string) *BoltIndex {
+	return &BoltIndex{path: path}
+}
+
+func (i *BoltIndex) open() (*bolthold.Store, error) {
+	return bolthold.Open(i.path, 0o644, &bolthold.Options{
+		Encoder: json.Marshal,
+		Decoder: json.Unmarshal,
+		Options: &bbolt.Options{
+			Timeout:      5 * time.Second,
+			NoGrowSync:   bbolt.DefaultOptions.NoGrowSync,
+			FreelistType: bbolt.DefaultOptions.FreelistType,
+		},
+	})
+}
+
+func (i *BoltIndex) Get(id uint64) (*Cache, error) {
+	db, err := i.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	cache := &Cache{}
+	if err := db.Get(id, cache); err != nil {
+		if errors.Is(err, bolthold.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (i *BoltIndex) Find(match func(*Cache) bool) ([]*Cache, error) {
+	db, err := i.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var caches []*Cache
+	if err := db.Find(&caches, nil); err != nil {
+		return nil, fmt.Errorf("find caches: %w", err)
+	}
+	return filterCaches(caches, match), nil
+}
+
+func (i *BoltIndex) Insert(cache *Cache) error {
+	db, err := i.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return insertCache(db, cache)
+}
+
+func (i *BoltIndex) Update(cache *Cache) error {
+	db, err := i.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(cache.ID, cache)
+}
+
+func (i *BoltIndex) Delete(id uint64) error {
+	db, err := i.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Delete(id, &Cache{})
+}
+
+func insertCache(db *bolthold.Store, cache *Cache) error {
+	if err := db.Insert(bolthold.NextSequence(), cache); err != nil {
+		return fmt.Errorf("insert cache: %w", err)
+	}
+	// write back id to db
+	if err := db.Update(cache.ID, cache); err != nil {
+		return fmt.Errorf("write back id to db: %w", err)
+	}
+	return nil
+}
+
+func filterCaches(caches []*Cache, match func(*Cache) bool) []*Cache {
+	ret := caches[:0]
+	for _, cache := range caches {
+		if match == nil || match(cache) {
+			ret = append(ret, cache)
+		}
+	}
+	return ret
+}
+
+var errConflict = errors.New("the index was changed concurrently")
+
+// documentStore loads and stores the document of a DocumentIndex in the backend of the storage
+type documentStore interface {
+	// load returns the document and its version, they are empty if there is no document
+	load() ([]byte, string, error)
+	// store replaces the document if it still has the version, otherwise it returns errConflict
+	store(data []byte, version string) error
+}
+
+type indexDocument struct {
+	LastID uint64   `json:"lastId"`
+	Caches []*Cache `json:"caches"`
+}
+
+// DocumentIndex stores the metadata as one JSON document in the backend of the storage,
+// the machines which share the backend update it optimistically and retry on conflicts
+type DocumentIndex struct {
+	store documentStore
+}
+
+func (i *DocumentIndex) read() (*indexDocument, string, error) {
+	data, version, err := i.store.load()
+	if err != nil {
+		return nil, "", fmt.Errorf("load index: %w", err)
+	}
+	doc := &indexDocument{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, "", fmt.Errorf("load index: %w", err)
+		}
+	}
+	return doc, version, nil
+}
+
+func (i *DocumentIndex) update(f func(doc *indexDocument) error) error {
+	for attempt := 0; attempt < 20; attempt++ {
+		doc, version, err := i.read()
+		if err != nil {
+			return err
+		}
+		if err := f(doc); err != nil {
+			return err
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := i.store.store(data, version); !errors.Is(err, errConflict) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+	}
+	return fmt.Errorf("update index: %w", errConflict)
+}
+
+func (i *DocumentIndex) Get(id uint64) (*Cache, error) {
+	doc, _, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, cache := range doc.Caches {
+		if cache.ID == id {
+			return cache, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (i *DocumentIndex) Find(match func(*Cache) bool) ([]*Cache, error) {
+	doc, _, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	return filterCaches(doc.Caches, match), nil
+}
+
+func (i *DocumentIndex) Insert(cache *Cache) error {
+	return i.update(func(doc *indexDocument) error {
+		doc.LastID++
+		cache.ID = doc.LastID
+		doc.Caches = append(doc.Caches, cache)
+		return nil
+	})
+}
+
+func (i *DocumentIndex) Update(cache *Cache) error {
+	return i.update(func(doc *indexDocument) error {
+		for k, v := range doc.Caches {
+			if v.ID == cache.ID {
+				doc.Caches[k] = cache
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+func (i *DocumentIndex) Delete(id uint64) error {
+	return i.update(func(doc *indexDocument) error {
+		doc.Caches = filterCaches(doc.Caches, func(cache *Cache) bool {
+			return cache.ID != id
+		})
+		return nil
+	})
+}
+
+// fileDocumentStore stores the document in a shared directory, it is replaced while holding its lock
+type fileDocumentStore struct {
+	name string
+}
+
+// NewFileIndex returns an index which is stored in the file name, which may be shared by several machines
+func NewFileIndex(name string) *DocumentIndex {
+	return &DocumentIndex{store: &fileDocumentStore{name: name}}
+}
+
+func (s *fileDocumentStore) load() ([]byte, string, error) {
+	data, err := os.ReadFile(s.name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+func (s *fileDocumentStore) store(data []byte, version string) error {
+	unlock, err := lockFile(s.name + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, current, err := s.load(); err != nil {
+		return err
+	} else if current != version {
+		return errConflict
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.name), filepath.Base(s.name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.name)
+}
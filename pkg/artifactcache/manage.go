@@ -0,0 +1,229 @@
+package artifactcache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter selects caches, the zero value selects all of them
+type Filter struct {
+	IDs []uint64
+	// KeyPrefix is case insensitive like the keys of the caches
+	KeyPrefix string
+	Repo      string
+	// OlderThan selects the caches which were created before it
+	OlderThan time.Duration
+	// UnusedFor selects the caches which were not used since it
+	UnusedFor time.Duration
+}
+
+// IsZero reports whether the filter selects all caches
+func (f *Filter) IsZero() bool {
+	return len(f.IDs) == 0 && f.KeyPrefix == "" && f.Repo == "" && f.OlderThan == 0 && f.UnusedFor == 0
+}
+
+func (f *Filter) match(cache *Cache, now time.Time) bool {
+	return (len(f.IDs) == 0 || slices.Contains(f.IDs, cache.ID)) &&
+		strings.HasPrefix(cache.Key, strings.ToLower(f.KeyPrefix)) &&
+		(f.Repo == "" || cache.Repo == f.Repo) &&
+		(f.OlderThan == 0 || cache.CreatedAt < now.Add(-f.OlderThan).Unix()) &&
+		(f.UnusedFor == 0 || cache.UsedAt < now.Add(-f.UnusedFor).Unix())
+}
+
+// Manager lists, removes, exports and imports the caches of a storage, like the handler does for the jobs
+type Manager struct {
+	storage Storage
+	index   Index
+}
+
+// OpenManager returns a manager of the caches at location, see OpenStorage
+func OpenManager(location string) (*Manager, error) {
+	storage, index, err := OpenStorage(location)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{storage: storage, index: index}, nil
+}
+
+// List returns the caches which match the filter in the order of their ids
+func (m *Manager) List(filter Filter) ([]*Cache, error) {
+	now := time.Now()
+	caches, err := m.index.Find(func(cache *Cache) bool {
+		return filter.match(cache, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].ID < caches[j].ID
+	})
+	return caches, nil
+}
+
+// Get returns ErrNotFound if there is no cache with the id
+func (m *Manager) Get(id uint64) (*Cache, error) {
+	return m.index.Get(id)
+}
+
+// Remove removes the caches which match the filter and returns them
+func (m *Manager) Remove(filter Filter) ([]*Cache, error) {
+	caches, err := m.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	removed := caches[:0]
+	for _, cache := range caches {
+		m.storage.Remove(cache.ID)
+		if err := m.index.Delete(cache.ID); err != nil {
+			return removed, fmt.Errorf("delete cache %d: %w", cache.ID, err)
+		}
+		removed = append(removed, cache)
+	}
+	return removed, nil
+}
+
+// exportPrefix is the directory of the caches in an export, each cache is <id>/cache.json followed by <id>/content
+const exportPrefix = "caches/"
+
+// Export writes the complete caches which match the filter to a gzipped tarball and returns them
+func (m *Manager) Export(w io.Writer, filter Filter) ([]*Cache, error) {
+	caches, err := m.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	exported := caches[:0]
+	for _, cache := range caches {
+		if !cache.Complete {
+			continue
+		}
+		if err := m.exportCache(tw, cache); err != nil {
+			return exported, fmt.Errorf("export cache %d: %w", cache.ID, err)
+		}
+		exported = append(exported, cache)
+	}
+	if err := tw.Close(); err != nil {
+		return exported, err
+	}
+	return exported, gw.Close()
+}
+
+func (m *Manager) exportCache(tw *tar.Writer, cache *Cache) error {
+	reader, err := m.storage.Open(cache.ID)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	metadata, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	dir := fmt.Sprintf("%s%d/", exportPrefix, cache.ID)
+	modTime := time.Unix(cache.CreatedAt, 0)
+	if err := tw.WriteHeader(&tar.Header{Name: dir + "cache.json", Mode: 0o644, Size: int64(len(metadata)), ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(metadata); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: dir + "content", Mode: 0o644, Size: cache.Size, ModTime: modTime}); err != nil {
+		return err
+	}
+	// the size is checked by the tar writer, so a cache which changed in the meantime is not exported partially
+	_, err = io.Copy(tw, reader)
+	return err
+}
+
+// Import adds the caches of a tarball which was written by Export and returns them with their new ids,
+// the caches which already exist with the same key, version, repository, ref and creation time are skipped
+func (m *Manager) Import(r io.Reader) ([]*Cache, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read export: %w", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	var imported []*Cache
+	var pending *Cache
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("read export: %w", err)
+		}
+		switch path.Base(hdr.Name) {
+		case "cache.json":
+			pending = &Cache{}
+			if err := json.NewDecoder(tr).Decode(pending); err != nil {
+				return imported, fmt.Errorf("read %s: %w", hdr.Name, err)
+			}
+		case "content":
+			if pending == nil || path.Dir(hdr.Name) != path.Join(exportPrefix, fmt.Sprint(pending.ID)) {
+				return imported, fmt.Errorf("read %s: missing cache.json", hdr.Name)
+			}
+			cache, err := m.importCache(pending, tr)
+			if err != nil {
+				return imported, fmt.Errorf("import cache %q: %w", pending.Key, err)
+			}
+			if cache != nil {
+				imported = append(imported, cache)
+			}
+			pending = nil
+		}
+	}
+	return imported, nil
+}
+
+func (m *Manager) importCache(metadata *Cache, content io.Reader) (*Cache, error) {
+	existing, err := m.index.Find(func(cache *Cache) bool {
+		return cache.Complete && cache.Key == metadata.Key && cache.Version == metadata.Version &&
+			cache.Repo == metadata.Repo && cache.Scope == metadata.Scope && cache.CreatedAt == metadata.CreatedAt
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, nil
+	}
+
+	cache := &Cache{
+		Key:       metadata.Key,
+		Version:   metadata.Version,
+		Repo:      metadata.Repo,
+		Scope:     metadata.Scope,
+		Size:      metadata.Size,
+		CreatedAt: metadata.CreatedAt,
+		UsedAt:    time.Now().Unix(),
+	}
+	if err := m.index.Insert(cache); err != nil {
+		return nil, err
+	}
+	if err := m.storage.Write(cache.ID, 0, content); err != nil {
+		m.storage.Remove(cache.ID)
+		_ = m.index.Delete(cache.ID)
+		return nil, err
+	}
+	if cache.Size, err = m.storage.Commit(cache.ID, cache.Size); err != nil {
+		m.storage.Remove(cache.ID)
+		_ = m.index.Delete(cache.ID)
+		return nil, err
+	}
+	cache.Complete = true
+	if err := m.index.Update(cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
@@ -0,0 +1,114 @@
+package artifactcache
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addCache(t *testing.T, m *Manager, cache *Cache, content string) *Cache {
+	require.NoError(t, m.index.Insert(cache))
+	require.NoError(t, m.storage.Write(cache.ID, 0, strings.NewReader(content)))
+	size, err := m.storage.Commit(cache.ID, int64(len(content)))
+	require.NoError(t, err)
+	cache.Size = size
+	cache.Complete = true
+	require.NoError(t, m.index.Update(cache))
+	return cache
+}
+
+func TestManager(t *testing.T) {
+	m, err := OpenManager(filepath.Join(t.TempDir(), "actcache"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	old := addCache(t, m, &Cache{Key: "linux-npm-old", Version: "v1", Repo: "owner/repo", CreatedAt: now.Add(-48 * time.Hour).Unix(), UsedAt: now.Add(-48 * time.Hour).Unix()}, "old")
+	npm := addCache(t, m, &Cache{Key: "linux-npm-new", Version: "v1", Repo: "owner/repo", Scope: "refs/heads/main", CreatedAt: now.Unix(), UsedAt: now.Unix()}, "new")
+	goCache := addCache(t, m, &Cache{Key: "linux-go", Version: "v1", Repo: "owner/other", CreatedAt: now.Add(-time.Hour).Unix(), UsedAt: now.Unix()}, "go")
+
+	ids := func(caches []*Cache) []uint64 {
+		var ret []uint64
+		for _, cache := range caches {
+			ret = append(ret, cache.ID)
+		}
+		return ret
+	}
+
+	t.Run("list", func(t *testing.T) {
+		for name, c := range map[string]struct {
+			filter Filter
+			want   []uint64
+		}{
+			"all":        {Filter{}, []uint64{old.ID, npm.ID, goCache.ID}},
+			"ids":        {Filter{IDs: []uint64{goCache.ID}}, []uint64{goCache.ID}},
+			"key prefix": {Filter{KeyPrefix: "Linux-NPM-"}, []uint64{old.ID, npm.ID}},
+			"repo":       {Filter{Repo: "owner/other"}, []uint64{goCache.ID}},
+			"older than": {Filter{OlderThan: 30 * time.Minute}, []uint64{old.ID, goCache.ID}},
+			"unused for": {Filter{UnusedFor: 24 * time.Hour}, []uint64{old.ID}},
+			"combined":   {Filter{KeyPrefix: "linux-", OlderThan: 24 * time.Hour}, []uint64{old.ID}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				caches, err := m.List(c.filter)
+				require.NoError(t, err)
+				assert.Equal(t, c.want, ids(caches))
+			})
+		}
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		exported, err := m.Export(buf, Filter{KeyPrefix: "linux-npm-"})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{old.ID, npm.ID}, ids(exported))
+
+		other, err := OpenManager(filepath.Join(t.TempDir(), "actcache"))
+		require.NoError(t, err)
+		addCache(t, other, &Cache{Key: "unrelated", Version: "v1"}, "unrelated")
+		imported, err := other.Import(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, imported, 2)
+		for i, want := range []*Cache{old, npm} {
+			got := imported[i]
+			assert.NotEqual(t, want.ID, got.ID)
+			assert.Equal(t, want.Key, got.Key)
+			assert.Equal(t, want.Repo, got.Repo)
+			assert.Equal(t, want.Scope, got.Scope)
+			assert.Equal(t, want.CreatedAt, got.CreatedAt)
+			assert.True(t, got.Complete)
+
+			reader, err := other.storage.Open(got.ID)
+			require.NoError(t, err)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			reader.Close()
+			assert.Equal(t, strings.TrimPrefix(want.Key, "linux-npm-"), string(content))
+		}
+
+		// importing again skips the caches which exist
+		imported, err = other.Import(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		assert.Empty(t, imported)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		removed, err := m.Remove(Filter{UnusedFor: 24 * time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{old.ID}, ids(removed))
+		ok, err := m.storage.Exist(old.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		removed, err = m.Remove(Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{npm.ID, goCache.ID}, ids(removed))
+		caches, err := m.List(Filter{})
+		require.NoError(t, err)
+		assert.Empty(t, caches)
+	})
+}
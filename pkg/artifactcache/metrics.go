@@ -0,0 +1,92 @@
+package artifactcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// metrics counts the requests of the handler, they are served at /metrics in the Prometheus text format
+type metrics struct {
+	hits            atomic.Int64
+	misses          atomic.Int64
+	uploadedBytes   atomic.Int64
+	downloadedBytes atomic.Int64
+	evictions       atomic.Int64
+}
+
+func (m *metrics) countUpload(r io.Reader) io.Reader {
+	return &countingReader{Reader: r, n: &m.uploadedBytes}
+}
+
+func (m *metrics) countDownload(w http.ResponseWriter) http.ResponseWriter {
+	return &countingResponseWriter{ResponseWriter: w, n: &m.downloadedBytes}
+}
+
+type countingReader struct {
+	io.Reader
+	n *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n.Add(int64(n))
+	return n, err
+}
+
+// GET /metrics
+func (h *Handler) serveMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	caches, err := h.index.Find(func(cache *Cache) bool {
+		return cache.Complete
+	})
+	if err != nil {
+		h.responseJSON(w, r, 500, err)
+		return
+	}
+	entries := map[string]int64{}
+	sizes := map[string]int64{}
+	for _, cache := range caches {
+		entries[cache.Repo]++
+		sizes[cache.Repo] += cache.Size
+	}
+	repos := make([]string, 0, len(entries))
+	for repo := range entries {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(w, "act_cache_hits_total", "counter", "Number of cache lookups which found a cache.", h.metrics.hits.Load())
+	writeMetric(w, "act_cache_misses_total", "counter", "Number of cache lookups which found no cache.", h.metrics.misses.Load())
+	writeMetric(w, "act_cache_uploaded_bytes_total", "counter", "Number of bytes uploaded to caches.", h.metrics.uploadedBytes.Load())
+	writeMetric(w, "act_cache_downloaded_bytes_total", "counter", "Number of bytes downloaded from caches.", h.metrics.downloadedBytes.Load())
+	writeMetric(w, "act_cache_evictions_total", "counter", "Number of caches evicted beyond the quota of their repository.", h.metrics.evictions.Load())
+	writeMetric(w, "act_cache_quota_bytes", "gauge", "Size of the caches of a repository beyond which they are evicted, 0 means unlimited.", h.quota)
+	fmt.Fprintf(w, "# HELP act_cache_entries Number of complete caches of a repository.\n# TYPE act_cache_entries gauge\n")
+	for _, repo := range repos {
+		fmt.Fprintf(w, "act_cache_entries{repository=%q} %d\n", repo, entries[repo])
+	}
+	fmt.Fprintf(w, "# HELP act_cache_size_bytes Size of the complete caches of a repository.\n# TYPE act_cache_size_bytes gauge\n")
+	for _, repo := range repos {
+		fmt.Fprintf(w, "act_cache_size_bytes{repository=%q} %d\n", repo, sizes[repo])
+	}
+}
+
+func writeMetric(w io.Writer, name, typ, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
@@ -27,8 +27,21 @@ type Cache struct {
 	ID        uint64 `json:"id" boltholdKey:"ID"`
 	Key       string `json:"key" boltholdIndex:"Key"`
 	Version   string `json:"version" boltholdIndex:"Version"`
+	Repo      string `json:"repo" boltholdIndex:"Repo"`
+	Scope     string `json:"scope" boltholdIndex:"Scope"`
 	Size      int64  `json:"cacheSize"`
 	Complete  bool   `json:"complete" boltholdIndex:"Complete"`
 	UsedAt    int64  `json:"usedAt" boltholdIndex:"UsedAt"`
 	CreatedAt int64  `json:"createdAt" boltholdIndex:"CreatedAt"`
 }
+
+// cacheScope is the repository and the refs of the caches which a request may access,
+// caches without repository and ref belong to requests without token
+type cacheScope struct {
+	Repo string
+	// Refs may be read in the order of the lookup, the ref of the job comes first
+	Refs []string
+	// WriteRef may be written if CanWrite is true
+	WriteRef string
+	CanWrite bool
+}
@@ -0,0 +1,121 @@
+package artifactcache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The messages of the Twirp CacheService of github.actions.results.api.v1 in their JSON form.
+// Like protojson the fields are written in lowerCamelCase, the original proto names are accepted as well,
+// and int64 values are strings.
+
+type CacheScope struct {
+	Scope      string     `json:"scope,omitempty"`
+	Permission twirpInt64 `json:"permission,omitempty"`
+}
+
+type CacheMetadata struct {
+	RepositoryID twirpInt64    `json:"repositoryId,omitempty"`
+	Scope        []*CacheScope `json:"scope,omitempty"`
+}
+
+type CreateCacheEntryRequest struct {
+	Metadata *CacheMetadata `json:"metadata,omitempty"`
+	Key      string         `json:"key,omitempty"`
+	Version  string         `json:"version,omitempty"`
+}
+
+type CreateCacheEntryResponse struct {
+	Ok              bool   `json:"ok,omitempty"`
+	SignedUploadURL string `json:"signedUploadUrl,omitempty"`
+	Message         string `json:"message,omitempty"`
+}
+
+type FinalizeCacheEntryUploadRequest struct {
+	Metadata  *CacheMetadata `json:"metadata,omitempty"`
+	Key       string         `json:"key,omitempty"`
+	SizeBytes twirpInt64     `json:"sizeBytes,omitempty"`
+	Version   string         `json:"version,omitempty"`
+}
+
+type FinalizeCacheEntryUploadResponse struct {
+	Ok      bool       `json:"ok,omitempty"`
+	EntryID twirpInt64 `json:"entryId,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type GetCacheEntryDownloadURLRequest struct {
+	Metadata    *CacheMetadata `json:"metadata,omitempty"`
+	Key         string         `json:"key,omitempty"`
+	RestoreKeys []string       `json:"restoreKeys,omitempty"`
+	Version     string         `json:"version,omitempty"`
+}
+
+type GetCacheEntryDownloadURLResponse struct {
+	Ok                bool   `json:"ok,omitempty"`
+	SignedDownloadURL string `json:"signedDownloadUrl,omitempty"`
+	MatchedKey        string `json:"matchedKey,omitempty"`
+}
+
+// twirpInt64 is an int64 which is written as string like protojson, numbers are accepted as well
+type twirpInt64 int64
+
+func (i twirpInt64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *twirpInt64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid int64 %s: %w", data, err)
+	}
+	*i = twirpInt64(v)
+	return nil
+}
+
+// decodeTwirpJSON decodes a request, the keys may use the proto names like size_bytes
+func decodeTwirpJSON(r io.Reader, v any) error {
+	var raw any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(camelCaseKeys(raw))
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func camelCaseKeys(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			parts := strings.Split(k, "_")
+			for i := 1; i < len(parts); i++ {
+				if parts[i] != "" {
+					parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+				}
+			}
+			m[strings.Join(parts, "")] = camelCaseKeys(val)
+		}
+		return m
+	case []any:
+		for i, val := range v {
+			v[i] = camelCaseKeys(val)
+		}
+		return v
+	}
+	return v
+}
@@ -1,27 +1,44 @@
 package artifactcache
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-type Storage struct {
+// Storage stores the content of the caches. The content is uploaded in chunks by Write or in blocks by
+// WriteBlock and CommitBlocks, Commit joins the chunks to the content which is served by Serve or read by Open.
+type Storage interface {
+	Exist(id uint64) (bool, error)
+	Write(id uint64, offset int64, reader io.Reader) error
+	WriteBlock(id uint64, blockID string, reader io.Reader) error
+	CommitBlocks(id uint64, blockIDs []string) error
+	Commit(id uint64, size int64) (int64, error)
+	Serve(w http.ResponseWriter, r *http.Request, id uint64)
+	// Open returns the content of a committed cache, the error wraps os.ErrNotExist if there is none
+	Open(id uint64) (io.ReadCloser, error)
+	Remove(id uint64)
+}
+
+// LocalStorage stores the caches in a local directory
+type LocalStorage struct {
 	rootDir string
 }
 
-func NewStorage(rootDir string) (*Storage, error) {
+func NewLocalStorage(rootDir string) (*LocalStorage, error) {
 	if err := os.MkdirAll(rootDir, 0o755); err != nil {
 		return nil, err
 	}
-	return &Storage{
+	return &LocalStorage{
 		rootDir: rootDir,
 	}, nil
 }
 
-func (s *Storage) Exist(id uint64) (bool, error) {
+func (s *LocalStorage) Exist(id uint64) (bool, error) {
 	name := s.filename(id)
 	if _, err := os.Stat(name); os.IsNotExist(err) {
 		return false, nil
@@ -31,7 +48,7 @@ func (s *Storage) Exist(id uint64) (bool, error) {
 	return true, nil
 }
 
-func (s *Storage) Write(id uint64, offset int64, reader io.Reader) error {
+func (s *LocalStorage) Write(id uint64, offset int64, reader io.Reader) error {
 	name := s.tempName(id, offset)
 	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
 		return err
@@ -46,7 +63,53 @@ func (s *Storage) Write(id uint64, offset int64, reader io.Reader) error {
 	return err
 }
 
-func (s *Storage) Commit(id uint64, size int64) (int64, error) {
+// WriteBlock stores a block of a blob upload, the blocks are joined by CommitBlocks in the order of their ids
+func (s *LocalStorage) WriteBlock(id uint64, blockID string, reader io.Reader) error {
+	name := s.blockName(id, blockID)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, reader)
+	return err
+}
+
+// CommitBlocks joins the blocks of blockIDs to the content of the cache, which is committed by Commit
+func (s *LocalStorage) CommitBlocks(id uint64, blockIDs []string) error {
+	defer func() {
+		_ = os.RemoveAll(s.blockDir(id))
+	}()
+
+	name := s.tempName(id, 0)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, blockID := range blockIDs {
+		f, err := os.Open(s.blockName(id, blockID))
+		if err != nil {
+			return fmt.Errorf("block %q: %w", blockID, err)
+		}
+		_, err = io.Copy(file, f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalStorage) Commit(id uint64, size int64) (int64, error) {
 	defer func() {
 		_ = os.RemoveAll(s.tempDir(id))
 	}()
@@ -60,11 +123,15 @@ func (s *Storage) Commit(id uint64, size int64) (int64, error) {
 	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
 		return 0, err
 	}
-	file, err := os.Create(name)
+	// the content is renamed when it is complete, so it is never served partially
+	file, err := os.CreateTemp(filepath.Dir(name), fmt.Sprintf("%d.*.tmp", id))
 	if err != nil {
 		return 0, err
 	}
-	defer file.Close()
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
 
 	var written int64
 	for _, v := range tempNames {
@@ -84,37 +151,56 @@ func (s *Storage) Commit(id uint64, size int64) (int64, error) {
 	// We can't check the size of the file, just skip the check.
 	// It happens when the request comes from old versions of actions, like `actions/cache@v2`.
 	if size >= 0 && written != size {
-		_ = file.Close()
-		_ = os.Remove(name)
 		return 0, fmt.Errorf("broken file: %v != %v", written, size)
 	}
+	if err := file.Chmod(0o644); err != nil {
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(file.Name(), name); err != nil {
+		return 0, err
+	}
 
 	return written, nil
 }
 
-func (s *Storage) Serve(w http.ResponseWriter, r *http.Request, id uint64) {
+func (s *LocalStorage) Serve(w http.ResponseWriter, r *http.Request, id uint64) {
 	name := s.filename(id)
 	http.ServeFile(w, r, name)
 }
 
-func (s *Storage) Remove(id uint64) {
+func (s *LocalStorage) Open(id uint64) (io.ReadCloser, error) {
+	return os.Open(s.filename(id))
+}
+
+func (s *LocalStorage) Remove(id uint64) {
 	_ = os.Remove(s.filename(id))
 	_ = os.RemoveAll(s.tempDir(id))
 }
 
-func (s *Storage) filename(id uint64) string {
+func (s *LocalStorage) filename(id uint64) string {
 	return filepath.Join(s.rootDir, fmt.Sprintf("%02x", id%0xff), fmt.Sprint(id))
 }
 
-func (s *Storage) tempDir(id uint64) string {
+func (s *LocalStorage) tempDir(id uint64) string {
 	return filepath.Join(s.rootDir, "tmp", fmt.Sprint(id))
 }
 
-func (s *Storage) tempName(id uint64, offset int64) string {
+func (s *LocalStorage) blockDir(id uint64) string {
+	return filepath.Join(s.tempDir(id), "blocks")
+}
+
+func (s *LocalStorage) blockName(id uint64, blockID string) string {
+	return filepath.Join(s.blockDir(id), hex.EncodeToString([]byte(blockID)))
+}
+
+func (s *LocalStorage) tempName(id uint64, offset int64) string {
 	return filepath.Join(s.tempDir(id), fmt.Sprintf("%016x", offset))
 }
 
-func (s *Storage) tempNames(id uint64) ([]string, error) {
+func (s *LocalStorage) tempNames(id uint64) ([]string, error) {
 	dir := s.tempDir(id)
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -128,3 +214,53 @@ func (s *Storage) tempNames(id uint64) ([]string, error) {
 	}
 	return names, nil
 }
+
+// OpenStorage returns the storage and the index of the caches at location, which is
+//   - a local directory, the default is ~/.cache/actcache
+//   - shared:///path for a directory which is shared by several machines like a NFS mount
+//   - s3://bucket/prefix for an S3-compatible object store, see ParseS3URL
+func OpenStorage(location string) (Storage, Index, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		cfg, err := ParseS3URL(location)
+		if err != nil {
+			return nil, nil, err
+		}
+		storage, err := NewS3Storage(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		index, err := NewS3Index(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, index, nil
+	case strings.HasPrefix(location, "shared://"):
+		dir := strings.TrimPrefix(location, "shared://")
+		if !filepath.IsAbs(dir) {
+			return nil, nil, fmt.Errorf("invalid shared directory %q, expected shared:///path", location)
+		}
+		storage, err := NewSharedStorage(filepath.Join(dir, "cache"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, NewFileIndex(filepath.Join(dir, "index.json")), nil
+	}
+
+	dir := location
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		dir = filepath.Join(home, ".cache", "actcache")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	storage, err := NewLocalStorage(filepath.Join(dir, "cache"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return storage, NewBoltIndex(filepath.Join(dir, "bolt.db")), nil
+}
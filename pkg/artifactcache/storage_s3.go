@@ -0,0 +1,255 @@
+package artifactcache
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3"
+)
+
+// S3Config configures the object store of S3Storage and NewS3Index
+type S3Config = s3.Config
+
+// ParseS3URL returns the config of s3://bucket/prefix, see s3.ParseURL
+func ParseS3URL(s string) (*S3Config, error) {
+	return s3.ParseURL(s)
+}
+
+// S3Storage stores the caches in an S3-compatible object store, so they may be shared by several machines.
+// The chunks of an upload are stored as temporary objects, which are joined to the object of the cache by Commit.
+type S3Storage struct {
+	client *s3.Client
+	prefix string
+}
+
+func NewS3Storage(cfg *S3Config) (*S3Storage, error) {
+	client, err := s3.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{
+		client: client,
+		prefix: cfg.Prefix,
+	}, nil
+}
+
+// NewS3Index returns an index which is stored as an object beside the caches,
+// it is replaced by conditional requests
+func NewS3Index(cfg *S3Config) (*DocumentIndex, error) {
+	client, err := s3.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentIndex{store: &s3DocumentStore{client: client, key: cfg.Prefix + "index.json"}}, nil
+}
+
+func (s *S3Storage) Exist(id uint64) (bool, error) {
+	return s.client.Head(s.objectKey(id))
+}
+
+func (s *S3Storage) Write(id uint64, offset int64, reader io.Reader) error {
+	return s.client.PutFile(s.tempKey(id, offset), reader)
+}
+
+func (s *S3Storage) WriteBlock(id uint64, blockID string, reader io.Reader) error {
+	return s.client.PutFile(s.blockKey(id, blockID), reader)
+}
+
+// CommitBlocks copies the blocks to chunks at their offsets in the order of blockIDs
+func (s *S3Storage) CommitBlocks(id uint64, blockIDs []string) error {
+	blocks, err := s.client.List(s.blockKey(id, ""))
+	if err != nil {
+		return err
+	}
+	sizes := make(map[string]int64, len(blocks))
+	for _, block := range blocks {
+		sizes[block.Key] = block.Size
+	}
+
+	var offset int64
+	for _, blockID := range blockIDs {
+		key := s.blockKey(id, blockID)
+		size, ok := sizes[key]
+		if !ok {
+			return fmt.Errorf("block %q: %w", blockID, os.ErrNotExist)
+		}
+		if err := s.client.Copy(key, s.tempKey(id, offset)); err != nil {
+			return err
+		}
+		offset += size
+	}
+	for _, block := range blocks {
+		_ = s.client.Delete(block.Key)
+	}
+	return nil
+}
+
+func (s *S3Storage) Commit(id uint64, size int64) (int64, error) {
+	defer s.removeTemp(id)
+
+	chunks, err := s.tempChunks(id)
+	if err != nil {
+		return 0, err
+	}
+	var written int64
+	readers := make([]io.Reader, 0, len(chunks))
+	for _, chunk := range chunks {
+		written += chunk.Size
+		readers = append(readers, &s3ObjectReader{client: s.client, key: chunk.Key})
+	}
+
+	// If size is less than 0, it means the size is unknown.
+	// It happens when the request comes from old versions of actions, like `actions/cache@v2`.
+	if size >= 0 && written != size {
+		return 0, fmt.Errorf("broken file: %v != %v", written, size)
+	}
+	if _, err := s.client.Put(s.objectKey(id), io.MultiReader(readers...), written, nil); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// Serve proxies the object of the cache, so the jobs don't need access to the object store
+func (s *S3Storage) Serve(w http.ResponseWriter, r *http.Request, id uint64) {
+	header := http.Header{}
+	if v := r.Header.Get("Range"); v != "" {
+		header.Set("Range", v)
+	}
+	resp, err := s.client.Get(s.objectKey(id), header)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, k := range []string{"Content-Length", "Content-Range", "Content-Type", "Accept-Ranges", "ETag", "Last-Modified"} {
+		if v := resp.Header.Get(k); v != "" {
+			w.Header().Set(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, resp.Body)
+	}
+}
+
+func (s *S3Storage) Open(id uint64) (io.ReadCloser, error) {
+	resp, err := s.client.Get(s.objectKey(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Remove(id uint64) {
+	_ = s.client.Delete(s.objectKey(id))
+	s.removeTemp(id)
+}
+
+func (s *S3Storage) removeTemp(id uint64) {
+	objects, err := s.client.List(s.tempKey(id, -1))
+	if err != nil {
+		return
+	}
+	for _, object := range objects {
+		_ = s.client.Delete(object.Key)
+	}
+}
+
+// tempChunks returns the chunks of an upload in the order of their offsets
+func (s *S3Storage) tempChunks(id uint64) ([]s3.Object, error) {
+	objects, err := s.client.List(s.tempKey(id, -1))
+	if err != nil {
+		return nil, err
+	}
+	chunks := objects[:0]
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Key, s.blockKey(id, "")) {
+			chunks = append(chunks, object)
+		}
+	}
+	return chunks, nil
+}
+
+func (s *S3Storage) objectKey(id uint64) string {
+	return fmt.Sprintf("%scache/%02x/%d", s.prefix, id%0xff, id)
+}
+
+// tempKey returns the key of the chunk at offset, or the prefix of the chunks if offset is negative
+func (s *S3Storage) tempKey(id uint64, offset int64) string {
+	if offset < 0 {
+		return fmt.Sprintf("%stmp/%d/", s.prefix, id)
+	}
+	return fmt.Sprintf("%stmp/%d/%016x", s.prefix, id, offset)
+}
+
+// blockKey returns the key of a block, or the prefix of the blocks if blockID is empty
+func (s *S3Storage) blockKey(id uint64, blockID string) string {
+	return fmt.Sprintf("%stmp/%d/blocks/%s", s.prefix, id, hex.EncodeToString([]byte(blockID)))
+}
+
+// s3ObjectReader downloads an object when it is read first, so the objects of a multi reader are downloaded one by one
+type s3ObjectReader struct {
+	client *s3.Client
+	key    string
+	body   io.ReadCloser
+}
+
+func (r *s3ObjectReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		resp, err := r.client.Get(r.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		r.body = resp.Body
+	}
+	n, err := r.body.Read(p)
+	if errors.Is(err, io.EOF) {
+		_ = r.body.Close()
+	}
+	return n, err
+}
+
+// s3DocumentStore stores the document of an index as an object, it is replaced only if its ETag is unchanged
+type s3DocumentStore struct {
+	client *s3.Client
+	key    string
+}
+
+func (s *s3DocumentStore) load() ([]byte, string, error) {
+	resp, err := s.client.Get(s.key, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+func (s *s3DocumentStore) store(data []byte, version string) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if version == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", version)
+	}
+	_, err := s.client.Put(s.key, bytes.NewReader(data), int64(len(data)), header)
+	if errors.Is(err, s3.ErrConflict) {
+		return fmt.Errorf("%w: %w", err, errConflict)
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package artifactcache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const lockTimeout = 30 * time.Second
+
+// SharedStorage stores the caches in a directory which is shared by several machines, like a NFS mount.
+// A cache is committed and removed while holding its lock, and its content is renamed when it is complete,
+// so the other machines never see it partially.
+type SharedStorage struct {
+	*LocalStorage
+}
+
+func NewSharedStorage(rootDir string) (*SharedStorage, error) {
+	local, err := NewLocalStorage(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(rootDir, "locks"), 0o755); err != nil {
+		return nil, err
+	}
+	return &SharedStorage{LocalStorage: local}, nil
+}
+
+func (s *SharedStorage) WriteBlock(id uint64, blockID string, reader io.Reader) error {
+	unlock, err := lockFile(s.lockName(id))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.LocalStorage.WriteBlock(id, blockID, reader)
+}
+
+func (s *SharedStorage) CommitBlocks(id uint64, blockIDs []string) error {
+	unlock, err := lockFile(s.lockName(id))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.LocalStorage.CommitBlocks(id, blockIDs)
+}
+
+func (s *SharedStorage) Commit(id uint64, size int64) (int64, error) {
+	unlock, err := lockFile(s.lockName(id))
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	return s.LocalStorage.Commit(id, size)
+}
+
+func (s *SharedStorage) Remove(id uint64) {
+	unlock, err := lockFile(s.lockName(id))
+	if err != nil {
+		return
+	}
+	defer unlock()
+	s.LocalStorage.Remove(id)
+	_ = os.Remove(s.lockName(id))
+}
+
+func (s *SharedStorage) lockName(id uint64) string {
+	return filepath.Join(s.rootDir, "locks", fmt.Sprintf("%d.lock", id))
+}
+
+// lockFile locks name exclusively across processes and machines, the lock is released by the returned func
+func lockFile(name string) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	lock := flock.New(name)
+	if ok, err := lock.TryLockContext(ctx, 50*time.Millisecond); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	} else if !ok {
+		return nil, fmt.Errorf("lock %s: timeout", name)
+	}
+	return func() {
+		_ = lock.Unlock()
+	}, nil
+}
@@ -0,0 +1,210 @@
+package artifactcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3/s3test"
+)
+
+func testStorage(t *testing.T, storage Storage) {
+	t.Run("chunks", func(t *testing.T) {
+		require.NoError(t, storage.Write(1, 6, strings.NewReader("world")))
+		require.NoError(t, storage.Write(1, 0, strings.NewReader("hello ")))
+		ok, err := storage.Exist(1)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		size, err := storage.Commit(1, 11)
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), size)
+		ok, err = storage.Exist(1)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		w := httptest.NewRecorder()
+		storage.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), 1)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "hello world", w.Body.String())
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Range", "bytes=6-10")
+		w = httptest.NewRecorder()
+		storage.Serve(w, r, 1)
+		assert.Equal(t, 206, w.Code)
+		assert.Equal(t, "world", w.Body.String())
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		require.NoError(t, storage.WriteBlock(2, "b", strings.NewReader("second")))
+		require.NoError(t, storage.WriteBlock(2, "a", strings.NewReader("first,")))
+		require.NoError(t, storage.CommitBlocks(2, []string{"a", "b"}))
+		size, err := storage.Commit(2, -1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), size)
+
+		w := httptest.NewRecorder()
+		storage.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), 2)
+		assert.Equal(t, "first,second", w.Body.String())
+
+		reader, err := storage.Open(2)
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		assert.Equal(t, "first,second", string(content))
+
+		_, err = storage.Open(100)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		require.NoError(t, storage.Write(3, 0, strings.NewReader("short")))
+		_, err := storage.Commit(3, 100)
+		assert.Error(t, err)
+		ok, err := storage.Exist(3)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		storage.Remove(1)
+		ok, err := storage.Exist(1)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func testIndex(t *testing.T, index Index) {
+	first := &Cache{Key: "key", Version: "v1", Size: -1}
+	require.NoError(t, index.Insert(first))
+	second := &Cache{Key: "key", Version: "v2", Size: -1}
+	require.NoError(t, index.Insert(second))
+	assert.NotEqual(t, first.ID, second.ID)
+
+	first.Complete = true
+	require.NoError(t, index.Update(first))
+	got, err := index.Get(first.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Complete)
+
+	caches, err := index.Find(func(cache *Cache) bool {
+		return cache.Complete
+	})
+	require.NoError(t, err)
+	require.Len(t, caches, 1)
+	assert.Equal(t, first.ID, caches[0].ID)
+
+	require.NoError(t, index.Delete(first.ID))
+	_, err = index.Get(first.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the machines which share the index never get the same id
+	var wg sync.WaitGroup
+	ids := make([]uint64, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cache := &Cache{Key: "concurrent-" + strconv.Itoa(i)}
+			assert.NoError(t, index.Insert(cache))
+			ids[i] = cache.ID
+		}(i)
+	}
+	wg.Wait()
+	caches, err = index.Find(func(cache *Cache) bool {
+		return strings.HasPrefix(cache.Key, "concurrent-")
+	})
+	require.NoError(t, err)
+	assert.Len(t, caches, len(ids))
+	unique := map[uint64]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+	assert.Len(t, unique, len(ids))
+}
+
+func TestLocalStorage(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	testStorage(t, storage)
+}
+
+func TestSharedStorage(t *testing.T) {
+	storage, err := NewSharedStorage(t.TempDir())
+	require.NoError(t, err)
+	testStorage(t, storage)
+}
+
+func TestS3Storage(t *testing.T) {
+	fake, cfg := s3test.NewServer(t, "bucket")
+	storage, err := NewS3Storage(cfg)
+	require.NoError(t, err)
+	testStorage(t, storage)
+
+	// the temporary objects are removed by Commit and Remove
+	assert.Equal(t, []string{"actcache/cache/02/2"}, fake.Keys())
+}
+
+func TestBoltIndex(t *testing.T) {
+	testIndex(t, NewBoltIndex(filepath.Join(t.TempDir(), "bolt.db")))
+}
+
+func TestFileIndex(t *testing.T) {
+	testIndex(t, NewFileIndex(filepath.Join(t.TempDir(), "index.json")))
+}
+
+func TestS3Index(t *testing.T) {
+	_, cfg := s3test.NewServer(t, "bucket")
+	index, err := NewS3Index(cfg)
+	require.NoError(t, err)
+	testIndex(t, index)
+}
+
+func TestOpenStorage(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, index, err := OpenStorage(dir)
+	require.NoError(t, err)
+	assert.IsType(t, &LocalStorage{}, storage)
+	assert.IsType(t, &BoltIndex{}, index)
+
+	storage, index, err = OpenStorage("shared://" + dir)
+	require.NoError(t, err)
+	assert.IsType(t, &SharedStorage{}, storage)
+	assert.IsType(t, &DocumentIndex{}, index)
+
+	_, _, err = OpenStorage("shared://relative/dir")
+	assert.Error(t, err)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	storage, index, err = OpenStorage("s3://bucket/some/prefix?endpoint=http://localhost:9000&region=eu-west-1")
+	require.NoError(t, err)
+	assert.IsType(t, &S3Storage{}, storage)
+	assert.IsType(t, &DocumentIndex{}, index)
+	assert.Equal(t, "some/prefix/", storage.(*S3Storage).prefix)
+}
+
+func TestHandlerS3(t *testing.T) {
+	_, cfg := s3test.NewServer(t, "bucket")
+	t.Setenv("AWS_ACCESS_KEY_ID", cfg.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", cfg.SecretAccessKey)
+	handler, err := StartHandler(fmt.Sprintf("s3://%s/actcache?endpoint=%s", cfg.Bucket, cfg.Endpoint), "", 0, nil)
+	require.NoError(t, err)
+	defer handler.Close()
+
+	base := fmt.Sprintf("%s%s", handler.ExternalURL(), urlBase)
+	uploadCacheNormally(t, base, "s3-key", "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20", make([]byte, 100))
+}
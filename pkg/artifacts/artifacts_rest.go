@@ -0,0 +1,146 @@
+package artifacts
+
+// A subset of the REST api of GitHub for the artifacts, which @actions/artifact uses for the artifacts of
+// other runs, like download-artifact with run-id. It is used when GITHUB_API_URL is the url of the artifact server,
+// the owner and the repository of the requests are ignored since the artifacts are stored by run.
+//
+// GET /repos/{owner}/{repo}/actions/runs/{run_id}/artifacts?name=test&per_page=100&page=1
+// GET /repos/{owner}/{repo}/actions/artifacts/{artifact_id}
+// GET /repos/{owner}/{repo}/actions/artifacts/{artifact_id}/zip redirects to the signed url of DownloadArtifact
+// DELETE /repos/{owner}/{repo}/actions/artifacts/{artifact_id}
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type restArtifact struct {
+	ID                 int64              `json:"id"`
+	Name               string             `json:"name"`
+	SizeInBytes        int64              `json:"size_in_bytes"`
+	URL                string             `json:"url"`
+	ArchiveDownloadURL string             `json:"archive_download_url"`
+	Expired            bool               `json:"expired"`
+	Digest             string             `json:"digest,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	ExpiresAt          *time.Time         `json:"expires_at"`
+	WorkflowRun        restArtifactRunRef `json:"workflow_run"`
+}
+
+type restArtifactRunRef struct {
+	ID int64 `json:"id"`
+}
+
+type restArtifactList struct {
+	TotalCount int             `json:"total_count"`
+	Artifacts  []*restArtifact `json:"artifacts"`
+}
+
+func (r *artifactV4Routes) restRoutes(router *httprouter.Router) {
+	router.GET("/repos/:owner/:repo/actions/runs/:runId/artifacts", r.restListArtifacts)
+	router.GET("/repos/:owner/:repo/actions/artifacts/:artifactId", r.restGetArtifact)
+	router.GET("/repos/:owner/:repo/actions/artifacts/:artifactId/:format", r.restDownloadArtifact)
+	router.DELETE("/repos/:owner/:repo/actions/artifacts/:artifactId", r.restDeleteArtifact)
+}
+
+func (r *artifactV4Routes) toRESTArtifact(req *http.Request, params httprouter.Params, meta *artifactV4Metadata) *restArtifact {
+	url := fmt.Sprintf("http://%s/repos/%s/%s/actions/artifacts/%d", req.Host, params.ByName("owner"), params.ByName("repo"), meta.ID)
+	return &restArtifact{
+		ID:                 meta.ID,
+		Name:               meta.Name,
+		SizeInBytes:        meta.Size,
+		URL:                url,
+		ArchiveDownloadURL: url + "/zip",
+		Digest:             meta.Hash,
+		CreatedAt:          meta.CreatedAt,
+		UpdatedAt:          meta.CreatedAt,
+		ExpiresAt:          meta.ExpiresAt,
+		WorkflowRun:        restArtifactRunRef{ID: meta.RunID},
+	}
+}
+
+func (r *artifactV4Routes) restListArtifacts(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	runID, err := strconv.ParseInt(params.ByName("runId"), 10, 64)
+	if err != nil {
+		restError(w, http.StatusNotFound)
+		return
+	}
+	query := req.URL.Query()
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	perPage = min(perPage, 100)
+	page, _ := strconv.Atoi(query.Get("page"))
+	page = max(page, 1)
+
+	list := &restArtifactList{Artifacts: []*restArtifact{}}
+	for _, meta := range r.artifacts(runID, time.Now()) {
+		if name := query.Get("name"); name != "" && name != meta.Name {
+			continue
+		}
+		list.TotalCount++
+		if list.TotalCount > (page-1)*perPage && list.TotalCount <= page*perPage {
+			list.Artifacts = append(list.Artifacts, r.toRESTArtifact(req, params, meta))
+		}
+	}
+	restJSON(w, http.StatusOK, list)
+}
+
+func (r *artifactV4Routes) restFindArtifact(w http.ResponseWriter, params httprouter.Params) *artifactV4Metadata {
+	id, err := strconv.ParseInt(params.ByName("artifactId"), 10, 64)
+	if err != nil {
+		restError(w, http.StatusNotFound)
+		return nil
+	}
+	meta := r.findArtifact(id, time.Now())
+	if meta == nil {
+		restError(w, http.StatusNotFound)
+	}
+	return meta
+}
+
+func (r *artifactV4Routes) restGetArtifact(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	if meta := r.restFindArtifact(w, params); meta != nil {
+		restJSON(w, http.StatusOK, r.toRESTArtifact(req, params, meta))
+	}
+}
+
+func (r *artifactV4Routes) restDownloadArtifact(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	if params.ByName("format") != "zip" {
+		restError(w, http.StatusNotFound)
+		return
+	}
+	meta := r.restFindArtifact(w, params)
+	if meta == nil {
+		return
+	}
+	r.AppURL = req.Host
+	w.Header().Set("Location", r.buildArtifactURL("DownloadArtifact", meta.Name, meta.RunID))
+	w.WriteHeader(http.StatusFound)
+}
+
+func (r *artifactV4Routes) restDeleteArtifact(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	meta := r.restFindArtifact(w, params)
+	if meta == nil {
+		return
+	}
+	_ = r.fs.RemoveAll(r.artifactPath(meta.RunID, meta.Name))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func restJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func restError(w http.ResponseWriter, status int) {
+	restJSON(w, status, map[string]string{"message": http.StatusText(status)})
+}
@@ -13,8 +13,10 @@ package artifacts
 //     "workflow_run_backend_id": "21",
 //     "workflow_job_run_backend_id": "49",
 //     "name": "test",
+//     "expires_at": "2024-04-22T21:48:37Z",
 //     "version": 4
 // }
+// expires_at is set by the retention days, the artifact is removed in the background when it expires
 // Response:
 // {
 //     "ok": true,
@@ -24,7 +26,7 @@ package artifacts
 // PUT: http://localhost:3000/twirp/github.actions.results.api.v1.ArtifactService/UploadArtifact?sig=mO7y35r4GyjN7fwg0DTv3-Fv1NDXD84KLEgLpoPOtDI=&expires=2024-01-23+21%3A48%3A37.20833956+%2B0100+CET&artifactName=test&taskID=75&comp=block
 // 1.3. Continue Upload Zip Content to Blobstorage (unauthenticated request), repeat until everything is uploaded
 // PUT: http://localhost:3000/twirp/github.actions.results.api.v1.ArtifactService/UploadArtifact?sig=mO7y35r4GyjN7fwg0DTv3-Fv1NDXD84KLEgLpoPOtDI=&expires=2024-01-23+21%3A48%3A37.20833956+%2B0100+CET&artifactName=test&taskID=75&comp=appendBlock
-// 1.4. Unknown xml payload to Blobstorage (unauthenticated request), ignored for now
+// 1.4. Block list of the blocks which were uploaded with a blockid, they are committed to the zip in its order
 // PUT: http://localhost:3000/twirp/github.actions.results.api.v1.ArtifactService/UploadArtifact?sig=mO7y35r4GyjN7fwg0DTv3-Fv1NDXD84KLEgLpoPOtDI=&expires=2024-01-23+21%3A48%3A37.20833956+%2B0100+CET&artifactName=test&taskID=75&comp=blockList
 // 1.5. FinalizeArtifact
 // Post: /twirp/github.actions.results.api.v1.ArtifactService/FinalizeArtifact
@@ -42,7 +44,8 @@ package artifacts
 //     "artifactId": "4"
 // }
 // 2. Download artifact
-// 2.1. ListArtifacts and optionally filter by artifact exact name or id
+// 2.1. ListArtifacts and optionally filter by artifact exact name or id, the ids are unique across the runs
+// so an id finds the artifact of a previous run
 // Post: /twirp/github.actions.results.api.v1.ArtifactService/ListArtifacts
 // Request
 // {
@@ -79,9 +82,13 @@ package artifacts
 // GET: http://localhost:3000/twirp/github.actions.results.api.v1.ArtifactService/DownloadArtifact?sig=wHzFOwpF-6220-5CA0CIRmAX9VbiTC2Mji89UOqo1E8=&expires=2024-01-23+21%3A51%3A56.872846295+%2B0100+CET&artifactName=test&taskID=76
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"hash/fnv"
@@ -89,7 +96,6 @@ import (
 	"io/fs"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
 	"strconv"
 	"strings"
@@ -100,6 +106,8 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/actions-oss/act-cli/pkg/common"
 )
 
 const (
@@ -120,12 +128,33 @@ type ArtifactContext struct {
 	Resp http.ResponseWriter
 }
 
-func artifactNameToID(s string) int64 {
+// artifactIDV4 returns the id of an artifact, it is unique across the runs so artifacts may be found by id alone
+func artifactIDV4(runID int64, name string) int64 {
 	h := fnv.New32a()
-	h.Write([]byte(s))
+	fmt.Fprintf(h, "%d/%s", runID, name)
 	return int64(h.Sum32())
 }
 
+// artifactV4Metadata is stored beside the zip of an artifact, artifacts which were uploaded by older versions don't have it
+type artifactV4Metadata struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	RunID    int64  `json:"runId"`
+	JobRunID string `json:"jobRunId"`
+	// Job is the id of the job in the workflow which uploaded the artifact, from the runtime token
+	Job  string `json:"job,omitempty"`
+	Size int64  `json:"size"`
+	// Hash is like sha256:<hex> if the client sent it
+	Hash      string     `json:"hash,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Finalized bool       `json:"finalized"`
+}
+
+func (m *artifactV4Metadata) expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
+}
+
 func (c ArtifactContext) Error(status int, _ ...interface{}) {
 	c.Resp.WriteHeader(status)
 }
@@ -196,6 +225,138 @@ func RoutesV4(router *httprouter.Router, baseDir string, fsys WriteFS, rfs fs.FS
 			Resp: w,
 		})
 	})
+	route.restRoutes(router)
+}
+
+const artifactV4ExpiryInterval = time.Hour
+
+// expireArtifactsV4 removes the expired artifacts now and then periodically until ctx is done
+func expireArtifactsV4(ctx context.Context, baseDir string, fsys WriteFS, rfs fs.FS) {
+	route := &artifactV4Routes{
+		fs:      fsys,
+		rfs:     rfs,
+		baseDir: baseDir,
+		prefix:  ArtifactV4RouteBase,
+	}
+	ticker := time.NewTicker(artifactV4ExpiryInterval)
+	defer ticker.Stop()
+	for {
+		route.expireArtifacts(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *artifactV4Routes) artifactPath(runID int64, artifactName string) string {
+	safeRunPath := safeResolve(r.baseDir, fmt.Sprint(runID))
+	return safeResolve(safeRunPath, artifactName)
+}
+
+func (r *artifactV4Routes) zipPath(runID int64, artifactName string) string {
+	return safeResolve(r.artifactPath(runID, artifactName), artifactName+".zip")
+}
+
+func (r *artifactV4Routes) metadataPath(runID int64, artifactName string) string {
+	return safeResolve(r.artifactPath(runID, artifactName), artifactName+".json")
+}
+
+// readMetadata returns the metadata of an artifact, it is derived from the zip if the artifact has none.
+// It returns nil if there is no zip of the artifact.
+func (r *artifactV4Routes) readMetadata(runID int64, artifactName string) *artifactV4Metadata {
+	info, err := fs.Stat(r.rfs, r.zipPath(runID, artifactName))
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	meta := &artifactV4Metadata{}
+	if data, err := fs.ReadFile(r.rfs, r.metadataPath(runID, artifactName)); err == nil && json.Unmarshal(data, meta) == nil {
+		return meta
+	}
+	return &artifactV4Metadata{
+		ID:        artifactIDV4(runID, artifactName),
+		Name:      artifactName,
+		RunID:     runID,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+		Finalized: true,
+	}
+}
+
+func (r *artifactV4Routes) writeMetadata(meta *artifactV4Metadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	file, err := r.fs.OpenWritable(r.metadataPath(meta.RunID, meta.Name))
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runIDs returns the runs which have artifacts
+func (r *artifactV4Routes) runIDs() []int64 {
+	entries, err := fs.ReadDir(r.rfs, safeResolve(r.baseDir, ""))
+	if err != nil {
+		return nil
+	}
+	var ids []int64
+	for _, entry := range entries {
+		if id, err := strconv.ParseInt(entry.Name(), 10, 64); err == nil && entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// artifacts returns the finalized artifacts of a run which have not expired
+func (r *artifactV4Routes) artifacts(runID int64, now time.Time) []*artifactV4Metadata {
+	entries, err := fs.ReadDir(r.rfs, safeResolve(r.baseDir, fmt.Sprint(runID)))
+	if err != nil {
+		return nil
+	}
+	var list []*artifactV4Metadata
+	for _, entry := range entries {
+		meta := r.readMetadata(runID, entry.Name())
+		if meta != nil && meta.Finalized && !meta.expired(now) {
+			list = append(list, meta)
+		}
+	}
+	return list
+}
+
+// findArtifact returns the artifact with the id in any run, or nil
+func (r *artifactV4Routes) findArtifact(id int64, now time.Time) *artifactV4Metadata {
+	for _, runID := range r.runIDs() {
+		for _, meta := range r.artifacts(runID, now) {
+			if meta.ID == id {
+				return meta
+			}
+		}
+	}
+	return nil
+}
+
+// expireArtifacts removes the artifacts whose retention ended
+func (r *artifactV4Routes) expireArtifacts(now time.Time) {
+	for _, runID := range r.runIDs() {
+		entries, err := fs.ReadDir(r.rfs, safeResolve(r.baseDir, fmt.Sprint(runID)))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if meta := r.readMetadata(runID, entry.Name()); meta != nil && meta.expired(now) {
+				log.Infof("artifact %s of run %d expired at %s", meta.Name, runID, meta.ExpiresAt)
+				_ = r.fs.RemoveAll(r.artifactPath(runID, meta.Name))
+			}
+		}
+	}
 }
 
 func (r artifactV4Routes) buildSignature(endp, expires, artifactName string, taskID int64) []byte {
@@ -278,16 +439,32 @@ func (r *artifactV4Routes) createArtifact(ctx *ArtifactContext) {
 
 	artifactName := req.Name
 
-	safeRunPath := safeResolve(r.baseDir, fmt.Sprint(runID))
-	safePath := safeResolve(safeRunPath, artifactName)
-	safePath = safeResolve(safePath, artifactName+".zip")
-	file, err := r.fs.OpenWritable(safePath)
+	file, err := r.fs.OpenWritable(r.zipPath(runID, artifactName))
 
 	if err != nil {
 		panic(err)
 	}
 	file.Close()
 
+	meta := &artifactV4Metadata{
+		ID:        artifactIDV4(runID, artifactName),
+		Name:      artifactName,
+		RunID:     runID,
+		JobRunID:  req.WorkflowJobRunBackendId,
+		CreatedAt: time.Now(),
+	}
+	if claims, err := common.ParseAuthorizationClaims(ctx.Req); err == nil && claims != nil {
+		meta.Job = claims.Job
+	}
+	// the retention days of upload-artifact are sent as the time of the expiry
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.AsTime()
+		meta.ExpiresAt = &expiresAt
+	}
+	if err := r.writeMetadata(meta); err != nil {
+		panic(err)
+	}
+
 	respData := CreateArtifactResponse{
 		Ok:              true,
 		SignedUploadUrl: r.buildArtifactURL("UploadArtifact", artifactName, runID),
@@ -301,16 +478,23 @@ func (r *artifactV4Routes) uploadArtifact(ctx *ArtifactContext) {
 		return
 	}
 
-	comp := ctx.Req.URL.Query().Get("comp")
-	switch comp {
-	case "block", "appendBlock":
-
-		safeRunPath := safeResolve(r.baseDir, fmt.Sprint(task))
-		safePath := safeResolve(safeRunPath, artifactName)
-		safePath = safeResolve(safePath, artifactName+".zip")
-
-		file, err := r.fs.OpenAppendable(safePath)
+	if ctx.Req.Body == nil {
+		panic(errors.New("no body given"))
+	}
 
+	query := ctx.Req.URL.Query()
+	switch query.Get("comp") {
+	case "block", "appendBlock":
+		name := r.zipPath(task, artifactName)
+		var file WritableFile
+		var err error
+		// a block with an id is stored on its own until the block list commits the blocks in its order,
+		// so the large uploads are streamed in blocks which don't need to arrive in order
+		if blockID := query.Get("blockid"); blockID != "" && query.Get("comp") == "block" {
+			file, err = r.fs.OpenWritable(r.blockPath(task, artifactName, blockID))
+		} else {
+			file, err = r.fs.OpenAppendable(name)
+		}
 		if err != nil {
 			panic(err)
 		}
@@ -321,39 +505,151 @@ func (r *artifactV4Routes) uploadArtifact(ctx *ArtifactContext) {
 			panic(errors.New("file is not writable"))
 		}
 
-		if ctx.Req.Body == nil {
-			panic(errors.New("no body given"))
-		}
-
 		_, err = io.Copy(writer, ctx.Req.Body)
 		if err != nil {
 			panic(err)
 		}
-		file.Close()
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
 		ctx.JSON(http.StatusCreated, "appended")
-	case "blocklist":
+	case "blocklist", "blockList":
+		blockIDs, err := parseBlockList(ctx.Req.Body)
+		if err != nil {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(blockIDs) > 0 {
+			if err := r.commitBlocks(task, artifactName, blockIDs); err != nil {
+				log.Errorf("Error commit blocks of artifact %s: %v", artifactName, err)
+				ctx.Error(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
 		ctx.JSON(http.StatusCreated, "created")
 	}
 }
 
+// blockPath returns the path of an uploaded block, the id is hex encoded since it is base64 which may contain a slash
+func (r *artifactV4Routes) blockPath(runID int64, artifactName, blockID string) string {
+	return safeResolve(r.blocksPath(runID, artifactName), hex.EncodeToString([]byte(blockID)))
+}
+
+func (r *artifactV4Routes) blocksPath(runID int64, artifactName string) string {
+	return safeResolve(r.artifactPath(runID, artifactName), artifactName+".blocks")
+}
+
+// commitBlocks writes the blocks to the zip of the artifact in the order of the block list and removes them
+func (r *artifactV4Routes) commitBlocks(runID int64, artifactName string, blockIDs []string) error {
+	file, err := r.fs.OpenWritable(r.zipPath(runID, artifactName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer, ok := file.(io.Writer)
+	if !ok {
+		return errors.New("file is not writable")
+	}
+	for _, blockID := range blockIDs {
+		if err := r.copyBlock(writer, runID, artifactName, blockID); err != nil {
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return r.fs.RemoveAll(r.blocksPath(runID, artifactName))
+}
+
+func (r *artifactV4Routes) copyBlock(w io.Writer, runID int64, artifactName, blockID string) error {
+	block, err := r.rfs.Open(r.blockPath(runID, artifactName, blockID))
+	if err != nil {
+		return fmt.Errorf("block %s: %w", blockID, err)
+	}
+	defer block.Close()
+	_, err = io.Copy(w, block)
+	return err
+}
+
+// parseBlockList returns the ids of the block list of Azure's Put Block List, which the blob client of
+// @actions/artifact sends after the blocks
+func parseBlockList(r io.Reader) ([]string, error) {
+	var list struct {
+		Blocks []struct {
+			XMLName xml.Name
+			ID      string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	if err := xml.NewDecoder(r).Decode(&list); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid block list: %w", err)
+	}
+	ids := make([]string, 0, len(list.Blocks))
+	for _, block := range list.Blocks {
+		ids = append(ids, block.ID)
+	}
+	return ids, nil
+}
+
 func (r *artifactV4Routes) finalizeArtifact(ctx *ArtifactContext) {
 	var req FinalizeArtifactRequest
 
 	if ok := r.parseProtbufBody(ctx, &req); !ok {
 		return
 	}
-	_, _, ok := validateRunIDV4(ctx, req.WorkflowRunBackendId)
+	_, runID, ok := validateRunIDV4(ctx, req.WorkflowRunBackendId)
 	if !ok {
 		return
 	}
 
+	meta := r.readMetadata(runID, req.Name)
+	if meta == nil {
+		log.Errorf("Error artifact %s not found", req.Name)
+		ctx.Error(http.StatusNotFound, "artifact not found")
+		return
+	}
+	size, hash, err := r.checksum(r.zipPath(runID, req.Name))
+	if err != nil {
+		panic(err)
+	}
+	if req.Size != 0 && req.Size != size {
+		log.Errorf("Error artifact %s has %d bytes instead of %d", req.Name, size, req.Size)
+		ctx.Error(http.StatusBadRequest, fmt.Sprintf("artifact has %d bytes instead of %d", size, req.Size))
+		return
+	}
+	if req.Hash != nil && req.Hash.Value != "" && req.Hash.Value != hash {
+		log.Errorf("Error artifact %s has the hash %s instead of %s", req.Name, hash, req.Hash.Value)
+		ctx.Error(http.StatusBadRequest, fmt.Sprintf("artifact has the hash %s instead of %s", hash, req.Hash.Value))
+		return
+	}
+	meta.Size = size
+	meta.Hash = hash
+	meta.Finalized = true
+	if err := r.writeMetadata(meta); err != nil {
+		panic(err)
+	}
+
 	respData := FinalizeArtifactResponse{
 		Ok:         true,
-		ArtifactId: artifactNameToID(req.Name),
+		ArtifactId: meta.ID,
 	}
 	r.sendProtbufBody(ctx, &respData)
 }
 
+// checksum returns the size and the sha256 of a file, like the hash which upload-artifact sends to FinalizeArtifact
+func (r *artifactV4Routes) checksum(name string) (int64, string, error) {
+	file, err := r.rfs.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func (r *artifactV4Routes) listArtifacts(ctx *ArtifactContext) {
 	var req ListArtifactsRequest
 
@@ -365,32 +661,30 @@ func (r *artifactV4Routes) listArtifacts(ctx *ArtifactContext) {
 		return
 	}
 
-	safePath := safeResolve(r.baseDir, fmt.Sprint(runID))
-
-	entries, err := fs.ReadDir(r.rfs, safePath)
-	if err != nil {
-		panic(err)
+	now := time.Now()
+	var artifacts []*artifactV4Metadata
+	if req.IdFilter != nil {
+		// the ids are unique across the runs, so download-artifact finds the artifacts of previous runs by id
+		if meta := r.findArtifact(req.IdFilter.Value, now); meta != nil {
+			artifacts = append(artifacts, meta)
+		}
+	} else {
+		artifacts = r.artifacts(runID, now)
 	}
 
 	list := []*ListArtifactsResponse_MonolithArtifact{}
-
-	for _, entry := range entries {
-		id := artifactNameToID(entry.Name())
-		if (req.NameFilter == nil || req.NameFilter.Value == entry.Name()) && (req.IdFilter == nil || req.IdFilter.Value == id) {
-			data := &ListArtifactsResponse_MonolithArtifact{
-				Name:                    entry.Name(),
-				CreatedAt:               timestamppb.Now(),
-				DatabaseId:              id,
-				WorkflowRunBackendId:    req.WorkflowRunBackendId,
-				WorkflowJobRunBackendId: req.WorkflowJobRunBackendId,
-				Size:                    0,
-			}
-			if info, err := entry.Info(); err == nil {
-				data.Size = info.Size()
-				data.CreatedAt = timestamppb.New(info.ModTime())
-			}
-			list = append(list, data)
+	for _, meta := range artifacts {
+		if req.NameFilter != nil && req.NameFilter.Value != meta.Name {
+			continue
 		}
+		list = append(list, &ListArtifactsResponse_MonolithArtifact{
+			Name:                    meta.Name,
+			CreatedAt:               timestamppb.New(meta.CreatedAt),
+			DatabaseId:              meta.ID,
+			WorkflowRunBackendId:    fmt.Sprint(meta.RunID),
+			WorkflowJobRunBackendId: meta.JobRunID,
+			Size:                    meta.Size,
+		})
 	}
 
 	respData := ListArtifactsResponse{
@@ -411,6 +705,11 @@ func (r *artifactV4Routes) getSignedArtifactURL(ctx *ArtifactContext) {
 	}
 
 	artifactName := req.Name
+	if meta := r.readMetadata(runID, artifactName); meta == nil || !meta.Finalized || meta.expired(time.Now()) {
+		log.Errorf("Error artifact %s not found", artifactName)
+		ctx.Error(http.StatusNotFound, "artifact not found")
+		return
+	}
 
 	respData := GetSignedArtifactURLResponse{}
 
@@ -424,12 +723,15 @@ func (r *artifactV4Routes) downloadArtifact(ctx *ArtifactContext) {
 		return
 	}
 
-	safeRunPath := safeResolve(r.baseDir, fmt.Sprint(task))
-	safePath := safeResolve(safeRunPath, artifactName)
-	safePath = safeResolve(safePath, artifactName+".zip")
-
-	file, _ := r.rfs.Open(safePath)
+	file, err := r.rfs.Open(r.zipPath(task, artifactName))
+	if err != nil {
+		log.Errorf("Error artifact %s not found: %v", artifactName, err)
+		ctx.Error(http.StatusNotFound, "artifact not found")
+		return
+	}
+	defer file.Close()
 
+	ctx.Resp.Header().Set("Content-Type", ArtifactV4ContentEncoding)
 	_, _ = io.Copy(ctx.Resp, file)
 }
 
@@ -443,14 +745,17 @@ func (r *artifactV4Routes) deleteArtifact(ctx *ArtifactContext) {
 	if !ok {
 		return
 	}
-	safeRunPath := safeResolve(r.baseDir, fmt.Sprint(runID))
-	safePath := safeResolve(safeRunPath, req.Name)
-
-	_ = os.RemoveAll(safePath)
+	meta := r.readMetadata(runID, req.Name)
+	if meta == nil {
+		log.Errorf("Error artifact %s not found", req.Name)
+		ctx.Error(http.StatusNotFound, "artifact not found")
+		return
+	}
+	_ = r.fs.RemoveAll(r.artifactPath(runID, req.Name))
 
 	respData := DeleteArtifactResponse{
 		Ok:         true,
-		ArtifactId: artifactNameToID(req.Name),
+		ArtifactId: meta.ID,
 	}
 	r.sendProtbufBody(ctx, &respData)
 }
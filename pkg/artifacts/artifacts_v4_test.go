@@ -0,0 +1,201 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3/s3test"
+)
+
+func postTwirpV4(t *testing.T, server *httptest.Server, method, body string, resp proto.Message) int {
+	t.Helper()
+	res, err := http.Post(server.URL+path.Join(ArtifactV4RouteBase, method), "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	if res.StatusCode == http.StatusOK && resp != nil {
+		require.NoError(t, protojson.Unmarshal(data, resp))
+	}
+	return res.StatusCode
+}
+
+func uploadArtifactV4(t *testing.T, server *httptest.Server, runID, name, content, expiresAt string) int64 {
+	t.Helper()
+	create := &CreateArtifactResponse{}
+	body := fmt.Sprintf(`{"workflow_run_backend_id":%q,"workflow_job_run_backend_id":"7","name":%q,"version":4`, runID, name)
+	if expiresAt != "" {
+		body += fmt.Sprintf(`,"expires_at":%q`, expiresAt)
+	}
+	require.Equal(t, http.StatusOK, postTwirpV4(t, server, "CreateArtifact", body+"}", create))
+
+	req, err := http.NewRequest(http.MethodPut, create.SignedUploadUrl+"&comp=block", strings.NewReader(content))
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	finalize := &FinalizeArtifactResponse{}
+	require.Equal(t, http.StatusOK, postTwirpV4(t, server, "FinalizeArtifact",
+		fmt.Sprintf(`{"workflow_run_backend_id":%q,"name":%q,"size":"%d","hash":%q}`, runID, name, len(content), sha256Hash(content)), finalize))
+	return finalize.ArtifactId
+}
+
+func sha256Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func putBlock(t *testing.T, url, query, content string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPut, url+query, strings.NewReader(content))
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestArtifactsV4(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		testArtifactsV4(t, readWriteFSImpl{}, t.TempDir())
+	})
+	t.Run("memory", func(t *testing.T) {
+		testArtifactsV4(t, NewMemoryFS(), "/")
+	})
+	t.Run("s3", func(t *testing.T) {
+		_, cfg := s3test.NewServer(t, "artifacts")
+		cfg.Prefix = "act/"
+		fsys, err := NewS3FS(cfg)
+		require.NoError(t, err)
+		testArtifactsV4(t, fsys, "/")
+	})
+}
+
+func testArtifactsV4(t *testing.T, fsys ReadWriteFS, dir string) {
+	router := httprouter.New()
+	RoutesV4(router, dir, fsys, fsys)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	first := uploadArtifactV4(t, server, "1", "first", "first content", "")
+	second := uploadArtifactV4(t, server, "1", "second", "second content", "")
+	other := uploadArtifactV4(t, server, "2", "first", "other content", time.Now().Add(24*time.Hour).Format(time.RFC3339))
+	assert.NotEqual(t, first, other, "the ids are unique across the runs")
+
+	t.Run("list", func(t *testing.T) {
+		list := &ListArtifactsResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "ListArtifacts", `{"workflow_run_backend_id":"1"}`, list))
+		require.Len(t, list.Artifacts, 2)
+		assert.Equal(t, int64(len("first content")), list.Artifacts[0].Size)
+		assert.Equal(t, "7", list.Artifacts[0].WorkflowJobRunBackendId)
+
+		list = &ListArtifactsResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "ListArtifacts", `{"workflow_run_backend_id":"1","name_filter":"second"}`, list))
+		require.Len(t, list.Artifacts, 1)
+		assert.Equal(t, second, list.Artifacts[0].DatabaseId)
+
+		// an artifact of another run is found by its id
+		list = &ListArtifactsResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "ListArtifacts", fmt.Sprintf(`{"workflow_run_backend_id":"1","id_filter":"%d"}`, other), list))
+		require.Len(t, list.Artifacts, 1)
+		assert.Equal(t, "2", list.Artifacts[0].WorkflowRunBackendId)
+	})
+
+	t.Run("download", func(t *testing.T) {
+		signed := &GetSignedArtifactURLResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "GetSignedArtifactURL", `{"workflow_run_backend_id":"2","name":"first"}`, signed))
+		res, err := http.Get(signed.SignedUrl)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		content, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "other content", string(content))
+
+		assert.Equal(t, http.StatusNotFound, postTwirpV4(t, server, "GetSignedArtifactURL", `{"workflow_run_backend_id":"2","name":"missing"}`, nil))
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/repos/owner/repo/actions/runs/2/artifacts?name=first")
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"total_count":1`)
+		assert.Contains(t, string(body), fmt.Sprintf(`"id":%d`, other))
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err = client.Get(fmt.Sprintf("%s/repos/owner/repo/actions/artifacts/%d/zip", server.URL, other))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		res, err = http.Get(res.Header.Get("Location"))
+		require.NoError(t, err)
+		content, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "other content", string(content))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := &DeleteArtifactResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "DeleteArtifact", `{"workflow_run_backend_id":"1","name":"second"}`, resp))
+		assert.Equal(t, second, resp.ArtifactId)
+		list := &ListArtifactsResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "ListArtifacts", `{"workflow_run_backend_id":"1"}`, list))
+		require.Len(t, list.Artifacts, 1)
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		create := &CreateArtifactResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "CreateArtifact", `{"workflow_run_backend_id":"3","name":"blocks","version":4}`, create))
+		// the blocks are committed in the order of the block list instead of the order of their upload
+		require.Equal(t, http.StatusCreated, putBlock(t, create.SignedUploadUrl, "&comp=block&blockid=Yg%3D%3D", "second "))
+		require.Equal(t, http.StatusCreated, putBlock(t, create.SignedUploadUrl, "&comp=block&blockid=YS8%3D", "first "))
+		require.Equal(t, http.StatusCreated, putBlock(t, create.SignedUploadUrl, "&comp=blocklist",
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><BlockList><Latest>YS8=</Latest><Latest>Yg==</Latest></BlockList>`))
+
+		body := `{"workflow_run_backend_id":"3","name":"blocks","size":"%d","hash":%q}`
+		assert.Equal(t, http.StatusBadRequest, postTwirpV4(t, server, "FinalizeArtifact", fmt.Sprintf(body, 12, sha256Hash("first second ")), nil))
+		assert.Equal(t, http.StatusBadRequest, postTwirpV4(t, server, "FinalizeArtifact", fmt.Sprintf(body, 13, sha256Hash("second first ")), nil))
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "FinalizeArtifact", fmt.Sprintf(body, 13, sha256Hash("first second ")), nil))
+
+		signed := &GetSignedArtifactURLResponse{}
+		require.Equal(t, http.StatusOK, postTwirpV4(t, server, "GetSignedArtifactURL", `{"workflow_run_backend_id":"3","name":"blocks"}`, signed))
+		res, err := http.Get(signed.SignedUrl)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		content, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "first second ", string(content))
+
+		entries, err := fs.ReadDir(fsys, path.Join(dir, "3", "blocks"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "the blocks are removed after the commit")
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		route := &artifactV4Routes{fs: fsys, rfs: fsys, baseDir: dir, prefix: ArtifactV4RouteBase}
+		route.expireArtifacts(time.Now().Add(48 * time.Hour))
+		assert.Nil(t, route.readMetadata(2, "first"))
+		assert.NotNil(t, route.readMetadata(1, "first"), "artifacts without retention don't expire")
+	})
+}
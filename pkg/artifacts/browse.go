@@ -0,0 +1,356 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Browser if there is no artifact with the name in the run
+var ErrNotFound = errors.New("artifact not found")
+
+// Artifact is an artifact of a run, upload-artifact v3 uploads it as files which may be gzipped and v4 as a zip
+type Artifact struct {
+	RunID int64 `json:"runId"`
+	// ID is the id of the REST api and download-artifact, v3 artifacts don't have one
+	ID      int64  `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	// Size is the size of the zip of v4 or the sum of the files of v3 as they are stored
+	Size int64 `json:"size"`
+	// Job is the id of the job in the workflow which uploaded the artifact, it is only known for v4
+	Job       string     `json:"job,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// ArtifactFile is a file of an artifact, Size is its size after the decompression
+type ArtifactFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Browser reads the artifacts of the runs in a storage of the artifact server
+type Browser struct {
+	fsys ReadWriteFS
+	v4   *artifactV4Routes
+}
+
+// OpenBrowser returns a browser of the artifacts at location, see OpenStorage
+func OpenBrowser(location string) (*Browser, error) {
+	fsys, baseDir, err := OpenStorage(location)
+	if err != nil {
+		return nil, err
+	}
+	return NewBrowser(fsys, baseDir), nil
+}
+
+func NewBrowser(fsys ReadWriteFS, baseDir string) *Browser {
+	return &Browser{
+		fsys: fsys,
+		v4:   &artifactV4Routes{fs: fsys, rfs: fsys, baseDir: baseDir, prefix: ArtifactV4RouteBase},
+	}
+}
+
+// Runs returns the ids of the runs which have artifacts, the latest first
+func (b *Browser) Runs() []int64 {
+	runIDs := b.v4.runIDs()
+	slices.Sort(runIDs)
+	slices.Reverse(runIDs)
+	return runIDs
+}
+
+// List returns the artifacts of a run in the order of their names
+func (b *Browser) List(runID int64) ([]*Artifact, error) {
+	entries, err := fs.ReadDir(b.fsys, b.runPath(runID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []*Artifact
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		artifact, err := b.artifact(runID, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if artifact != nil {
+			list = append(list, artifact)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Get returns ErrNotFound if the run has no artifact with the name
+func (b *Browser) Get(runID int64, name string) (*Artifact, error) {
+	artifact, err := b.artifact(runID, name)
+	if err != nil {
+		return nil, err
+	} else if artifact == nil {
+		return nil, fmt.Errorf("%w: %s of run %d", ErrNotFound, name, runID)
+	}
+	return artifact, nil
+}
+
+func (b *Browser) runPath(runID int64) string {
+	return safeResolve(b.v4.baseDir, fmt.Sprint(runID))
+}
+
+// artifact returns nil if there is no artifact, the v4 artifacts which are not finalized or expired are skipped
+func (b *Browser) artifact(runID int64, name string) (*Artifact, error) {
+	if meta := b.v4.readMetadata(runID, name); meta != nil {
+		if !meta.Finalized || meta.expired(time.Now()) {
+			return nil, nil
+		}
+		return &Artifact{
+			RunID:     runID,
+			ID:        meta.ID,
+			Name:      meta.Name,
+			Version:   4,
+			Size:      meta.Size,
+			Job:       meta.Job,
+			CreatedAt: meta.CreatedAt,
+			ExpiresAt: meta.ExpiresAt,
+		}, nil
+	}
+
+	artifact := &Artifact{RunID: runID, Name: name, Version: 3}
+	found := false
+	err := fs.WalkDir(b.fsys, b.v4.artifactPath(runID, name), func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		found = true
+		artifact.Size += info.Size()
+		if info.ModTime().After(artifact.CreatedAt) {
+			artifact.CreatedAt = info.ModTime()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) || !found {
+		return nil, nil
+	}
+	return artifact, err
+}
+
+// Files returns the files of an artifact in the order of their paths
+func (b *Browser) Files(artifact *Artifact) ([]ArtifactFile, error) {
+	var files []ArtifactFile
+	err := b.walk(artifact, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		if size < 0 {
+			// the size of a gzipped file is only known after the decompression
+			reader, err := open()
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+			if size, err = io.Copy(io.Discard, reader); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		files = append(files, ArtifactFile{Path: name, Size: size})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, err
+}
+
+// WriteFile writes the decompressed content of a file of an artifact, ErrNotFound if it has no such file
+func (b *Browser) WriteFile(w io.Writer, artifact *Artifact, file string) error {
+	file = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(file)), "/")
+	found := false
+	err := b.walk(artifact, func(name string, _ int64, open func() (io.ReadCloser, error)) error {
+		if found || name != file {
+			return nil
+		}
+		found = true
+		reader, err := open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = io.Copy(w, reader)
+		return err
+	})
+	if err == nil && !found {
+		return fmt.Errorf("%w: %s has no file %s", ErrNotFound, artifact.Name, file)
+	}
+	return err
+}
+
+// WriteZip writes an artifact as a zip like the download of GitHub, the zip of v4 is written as it is
+func (b *Browser) WriteZip(w io.Writer, artifact *Artifact) error {
+	if artifact.Version == 4 {
+		file, err := b.fsys.Open(b.v4.zipPath(artifact.RunID, artifact.Name))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	err := b.walk(artifact, func(name string, _ int64, open func() (io.ReadCloser, error)) error {
+		reader, err := open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		writer, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: artifact.CreatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, reader)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Extract writes the decompressed files of an artifact to dir and returns their paths
+func (b *Browser) Extract(artifact *Artifact, dir string) ([]string, error) {
+	var extracted []string
+	err := b.walk(artifact, func(name string, _ int64, open func() (io.ReadCloser, error)) error {
+		// the names of a zip are not trusted to stay in dir
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("invalid file name %q", name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		reader, err := open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		file, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, reader); err != nil {
+			file.Close()
+			return err
+		}
+		extracted = append(extracted, target)
+		return file.Close()
+	})
+	return extracted, err
+}
+
+// walk calls fn with the files of an artifact, size is -1 if it is only known after the decompression
+func (b *Browser) walk(artifact *Artifact, fn func(name string, size int64, open func() (io.ReadCloser, error)) error) error {
+	if artifact.Version == 4 {
+		return b.walkZip(artifact, fn)
+	}
+
+	dir := b.v4.artifactPath(artifact.RunID, artifact.Name)
+	return fs.WalkDir(b.fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(name, dir)), "/")
+		size := info.Size()
+		gzipped := strings.HasSuffix(rel, gzipExtension)
+		if gzipped {
+			rel = strings.TrimSuffix(rel, gzipExtension)
+			size = -1
+		}
+		return fn(rel, size, func() (io.ReadCloser, error) {
+			file, err := b.fsys.Open(name)
+			if err != nil || !gzipped {
+				return file, err
+			}
+			reader, err := gzip.NewReader(file)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s: %w", rel, err)
+			}
+			return &gzipFile{Reader: reader, file: file}, nil
+		})
+	})
+}
+
+func (b *Browser) walkZip(artifact *Artifact, fn func(name string, size int64, open func() (io.ReadCloser, error)) error) error {
+	file, err := b.fsys.Open(b.v4.zipPath(artifact.RunID, artifact.Name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the zip is read at random offsets, so the storages which only stream it are spooled to a temporary file
+	readerAt, ok := file.(io.ReaderAt)
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if !ok {
+		tmp, err := os.CreateTemp("", "act-artifact-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if size, err = io.Copy(tmp, file); err != nil {
+			return err
+		}
+		readerAt = tmp
+	}
+
+	zr, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return fmt.Errorf("%s: %w", artifact.Name, err)
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := fn(f.Name, int64(f.UncompressedSize64), f.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gzipFile closes the file of a gzip reader with it
+type gzipFile struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (f *gzipFile) Close() error {
+	return errors.Join(f.Reader.Close(), f.file.Close())
+}
@@ -0,0 +1,168 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+)
+
+func writeFile(t *testing.T, fsys WriteFS, name string, content []byte) {
+	t.Helper()
+	file, err := fsys.OpenWritable(name)
+	require.NoError(t, err)
+	_, err = file.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func TestBrowser(t *testing.T) {
+	fsys := NewMemoryFS()
+	router := httprouter.New()
+	RoutesV4(router, "/", fsys, fsys)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// v3 uploads the files of an artifact, gzipped or not
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte("compressed content"))
+	require.NoError(t, gw.Close())
+	writeFile(t, fsys, "/1/logs/dir/plain.txt", []byte("plain content"))
+	writeFile(t, fsys, "/1/logs/compressed.txt"+gzipExtension, gz.Bytes())
+
+	// v4 uploads a zip with the runtime token of the job
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	w, err := zw.Create("out/result.txt")
+	require.NoError(t, err)
+	_, _ = w.Write([]byte("result"))
+	require.NoError(t, zw.Close())
+
+	token, err := common.CreateJobAuthorizationToken(1, 1, 1, "build", "owner/repo", "refs/heads/main")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, server.URL+ArtifactV4RouteBase+"/CreateArtifact", strings.NewReader(`{"workflow_run_backend_id":"1","name":"result","version":4}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	writeFile(t, fsys, "/1/result/result.zip", zipped.Bytes())
+	require.Equal(t, http.StatusOK, postTwirpV4(t, server, "FinalizeArtifact",
+		fmt.Sprintf(`{"workflow_run_backend_id":"1","name":"result","size":"%d"}`, zipped.Len()), nil))
+
+	browser := NewBrowser(fsys, "/")
+	assert.Equal(t, []int64{1}, browser.Runs())
+
+	list, err := browser.List(1)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "logs", list[0].Name)
+	assert.Equal(t, 3, list[0].Version)
+	assert.Equal(t, int64(len("plain content")+gz.Len()), list[0].Size)
+	assert.Equal(t, "result", list[1].Name)
+	assert.Equal(t, 4, list[1].Version)
+	assert.Equal(t, "build", list[1].Job)
+
+	_, err = browser.Get(1, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	t.Run("files", func(t *testing.T) {
+		files, err := browser.Files(list[0])
+		require.NoError(t, err)
+		assert.Equal(t, []ArtifactFile{
+			{Path: "compressed.txt", Size: int64(len("compressed content"))},
+			{Path: "dir/plain.txt", Size: int64(len("plain content"))},
+		}, files)
+
+		files, err = browser.Files(list[1])
+		require.NoError(t, err)
+		assert.Equal(t, []ArtifactFile{{Path: "out/result.txt", Size: int64(len("result"))}}, files)
+
+		var content bytes.Buffer
+		require.NoError(t, browser.WriteFile(&content, list[0], "compressed.txt"))
+		assert.Equal(t, "compressed content", content.String())
+		content.Reset()
+		require.NoError(t, browser.WriteFile(&content, list[1], "out/result.txt"))
+		assert.Equal(t, "result", content.String())
+
+		assert.ErrorIs(t, browser.WriteFile(io.Discard, list[1], "missing.txt"), ErrNotFound)
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, browser.WriteZip(&buf, list[0]))
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		names := []string{}
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"compressed.txt", "dir/plain.txt"}, names)
+
+		buf.Reset()
+		require.NoError(t, browser.WriteZip(&buf, list[1]))
+		assert.Equal(t, zipped.Bytes(), buf.Bytes(), "the zip of v4 is written as it is")
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := browser.Extract(list[0], dir)
+		require.NoError(t, err)
+		_, err = browser.Extract(list[1], dir)
+		require.NoError(t, err)
+		for name, content := range map[string]string{
+			"compressed.txt": "compressed content",
+			"dir/plain.txt":  "plain content",
+			"out/result.txt": "result",
+		} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		}
+	})
+
+	t.Run("ui", func(t *testing.T) {
+		ui := httptest.NewServer(browser.Handler())
+		defer ui.Close()
+		get := func(url string) (int, string) {
+			res, err := http.Get(ui.URL + url)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			return res.StatusCode, string(body)
+		}
+
+		status, body := get("/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, `href="/runs/1"`)
+		status, body = get("/runs/1")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, `href="/runs/1/artifacts/result/zip"`)
+		assert.Contains(t, body, "build")
+		status, body = get("/runs/1/artifacts/logs")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "dir/plain.txt")
+		status, body = get("/runs/1/artifacts/logs/files/compressed.txt")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "compressed content", body)
+		status, _ = get("/runs/1/artifacts/missing")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,6 +55,8 @@ type WritableFile interface {
 type WriteFS interface {
 	OpenWritable(name string) (WritableFile, error)
 	OpenAppendable(name string) (WritableFile, error)
+	// RemoveAll removes name and its children, like os.RemoveAll
+	RemoveAll(name string) error
 }
 
 type readWriteFSImpl struct {
@@ -86,6 +90,10 @@ func (fwfs readWriteFSImpl) OpenAppendable(name string) (WritableFile, error) {
 	return file, nil
 }
 
+func (fwfs readWriteFSImpl) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
 var gzipExtension = ".gz__"
 
 func safeResolve(baseDir string, relPath string) string {
@@ -275,45 +283,113 @@ func downloads(router *httprouter.Router, baseDir string, fsys fs.FS) {
 	})
 }
 
-func Serve(ctx context.Context, artifactPath string, addr string, port string) context.CancelFunc {
+// Routes registers the routes of the v3 and v4 artifact apis for the artifacts in fsys at baseDir,
+// the expired v4 artifacts are removed until ctx is done
+func Routes(ctx context.Context, router *httprouter.Router, baseDir string, fsys ReadWriteFS) {
+	uploads(router, baseDir, fsys)
+	downloads(router, baseDir, fsys)
+	RoutesV4(router, baseDir, fsys, fsys)
+	go expireArtifactsV4(ctx, baseDir, fsys, fsys)
+}
+
+// Server is an artifact server which was started by StartServer
+type Server struct {
+	server  *http.Server
+	cancel  context.CancelFunc
+	done    chan struct{}
+	browser *Browser
+	addr    string
+	port    string
+}
+
+// StartServer starts an artifact server for the artifacts at artifactPath, see OpenStorage.
+// It listens on addr and port, on a free port if port is empty or 0, until ctx is done or it is closed.
+func StartServer(ctx context.Context, artifactPath, addr, port string) (*Server, error) {
 	serverContext, cancel := context.WithCancel(ctx)
 	logger := common.Logger(serverContext)
 
-	if artifactPath == "" {
-		return cancel
+	logger.Debugf("Artifacts base path '%s'", artifactPath)
+	fsys, baseDir, err := OpenStorage(artifactPath)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("artifact storage %s: %w", artifactPath, err)
+	}
+	if port == "" {
+		port = "0"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(addr, port))
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
 	router := httprouter.New()
-
-	logger.Debugf("Artifacts base path '%s'", artifactPath)
-	fsys := readWriteFSImpl{}
-	uploads(router, artifactPath, fsys)
-	downloads(router, artifactPath, fsys)
-	RoutesV4(router, artifactPath, fsys, fsys)
-
-	server := &http.Server{
-		Addr:              fmt.Sprintf("%s:%s", addr, port),
-		ReadHeaderTimeout: 2 * time.Second,
-		Handler:           router,
+	Routes(serverContext, router, baseDir, fsys)
+
+	s := &Server{
+		server: &http.Server{
+			ReadHeaderTimeout: 2 * time.Second,
+			Handler:           router,
+		},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		browser: NewBrowser(fsys, baseDir),
+		addr:    addr,
+		port:    strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
 	}
 
 	// run server
 	go func() {
-		logger.Infof("Start server on http://%s:%s", addr, port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal(err)
+		logger.Infof("Start server on %s", s.URL())
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("artifact server: %v", err)
 		}
 	}()
 
 	// wait for cancel to gracefully shutdown server
 	go func() {
+		defer close(s.done)
 		<-serverContext.Done()
 
-		if err := server.Shutdown(ctx); err != nil {
+		if err := s.server.Shutdown(ctx); err != nil {
 			logger.Errorf("failed shutdown gracefully - force shutdown: %v", err)
-			server.Close()
+			s.server.Close()
 		}
 	}()
 
+	return s, nil
+}
+
+// Port returns the port which the server listens on, it was chosen by the system if the port of StartServer was 0
+func (s *Server) Port() string {
+	return s.port
+}
+
+func (s *Server) URL() string {
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(s.addr, s.port))
+}
+
+// Browser returns a browser of the artifacts in the storage of the server
+func (s *Server) Browser() *Browser {
+	return s.browser
+}
+
+// Close shuts the server down and waits for it
+func (s *Server) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Serve starts an artifact server like StartServer unless artifactPath is empty, it runs until the returned function is called
+func Serve(ctx context.Context, artifactPath string, addr string, port string) context.CancelFunc {
+	serverContext, cancel := context.WithCancel(ctx)
+
+	if artifactPath == "" {
+		return cancel
+	}
+
+	if _, err := StartServer(serverContext, artifactPath, addr, port); err != nil {
+		common.Logger(ctx).Fatal(err)
+	}
 	return cancel
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/actions-oss/act-cli/pkg/model"
 	"github.com/actions-oss/act-cli/pkg/runner"
@@ -49,6 +50,15 @@ func (fsys writeMapFS) OpenWritable(name string) (WritableFile, error) {
 	return file, nil
 }
 
+func (fsys writeMapFS) RemoveAll(name string) error {
+	for k := range fsys.MapFS {
+		if k == name || strings.HasPrefix(k, name+"/") {
+			delete(fsys.MapFS, k)
+		}
+	}
+	return nil
+}
+
 func (fsys writeMapFS) OpenAppendable(name string) (WritableFile, error) {
 	var file = &writableMapFile{
 		MapFile: fstest.MapFile{
@@ -396,3 +406,18 @@ func TestArtifactUploadBlobUnsafePath(t *testing.T) {
 	assert.Equal("success", response.Message)
 	assert.Equal("content", string(memfs["artifact/server/path/1/some/file"].Data))
 }
+
+func TestStartServer(t *testing.T) {
+	server, err := StartServer(context.Background(), t.TempDir(), "127.0.0.1", "0")
+	require.NoError(t, err)
+	assert.NotEqual(t, "0", server.Port(), "a free port is chosen")
+
+	res, err := http.Post(server.URL()+"_apis/pipelines/workflows/1/artifacts", "application/json", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	server.Close()
+	_, err = http.Post(server.URL()+"_apis/pipelines/workflows/1/artifacts", "application/json", nil)
+	assert.Error(t, err)
+}
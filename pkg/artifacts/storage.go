@@ -0,0 +1,365 @@
+package artifacts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3"
+)
+
+// ReadWriteFS stores the artifacts, the names are the paths below the base dir of the artifact server
+type ReadWriteFS interface {
+	fs.FS
+	WriteFS
+}
+
+// OpenStorage returns the storage of the artifacts at location and the base dir of the artifacts in it.
+// The location is
+//   - a local directory
+//   - s3://bucket/prefix for an S3-compatible object store, see s3.ParseURL
+//   - memory:// for a storage in memory, which is lost when the server stops
+func OpenStorage(location string) (ReadWriteFS, string, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		cfg, err := s3.ParseURL(location)
+		if err != nil {
+			return nil, "", err
+		}
+		fsys, err := NewS3FS(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return fsys, "/", nil
+	case location == "memory://":
+		return NewMemoryFS(), "/", nil
+	}
+	return readWriteFSImpl{}, location, nil
+}
+
+// fileInfo describes the files and directories of the storages which are not on disk
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+// readerFile is a file which is read from r
+type readerFile struct {
+	io.Reader
+	info *fileInfo
+}
+
+func (f *readerFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *readerFile) Close() error {
+	if c, ok := f.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// dirFile is a directory with its entries
+type dirFile struct {
+	info    *fileInfo
+	entries []fs.DirEntry
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+func (d *dirFile) Close() error { return nil }
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// newDirFile returns the directory dir of the files, whose names are relative to the root and use slashes,
+// ok is false if there are no files in it
+func newDirFile(dir string, files map[string]*fileInfo) (*dirFile, bool) {
+	prefix := strings.TrimPrefix(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	children := map[string]*fileInfo{}
+	for name, info := range files {
+		rel, ok := strings.CutPrefix(name, prefix)
+		if !ok || rel == "" {
+			continue
+		}
+		if child, _, isDir := strings.Cut(rel, "/"); isDir {
+			if _, ok := children[child]; !ok {
+				children[child] = &fileInfo{name: child, modTime: info.modTime, dir: true}
+			}
+		} else {
+			children[child] = &fileInfo{name: child, size: info.size, modTime: info.modTime}
+		}
+	}
+	if len(children) == 0 && dir != "/" {
+		return nil, false
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, child)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return &dirFile{info: &fileInfo{name: path.Base(dir), dir: true}, entries: entries}, true
+}
+
+// cleanName returns the name as an absolute path with slashes
+func cleanName(name string) string {
+	return path.Clean("/" + filepath.ToSlash(name))
+}
+
+// MemoryFS stores the artifacts in memory, like for tests
+type MemoryFS struct {
+	mu    sync.Mutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{files: map[string]*memoryFile{}}
+}
+
+func (m *MemoryFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = cleanName(name)
+	if f, ok := m.files[name]; ok {
+		return &readerFile{
+			Reader: bytes.NewReader(bytes.Clone(f.data)),
+			info:   &fileInfo{name: path.Base(name), size: int64(len(f.data)), modTime: f.modTime},
+		}, nil
+	}
+	files := make(map[string]*fileInfo, len(m.files))
+	for k, f := range m.files {
+		files[strings.TrimPrefix(k, "/")] = &fileInfo{size: int64(len(f.data)), modTime: f.modTime}
+	}
+	if dir, ok := newDirFile(name, files); ok {
+		return dir, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemoryFS) OpenWritable(name string) (WritableFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanName(name)
+	m.files[name] = &memoryFile{modTime: time.Now()}
+	return &memoryWriter{fs: m, name: name}, nil
+}
+
+func (m *MemoryFS) OpenAppendable(name string) (WritableFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanName(name)
+	if _, ok := m.files[name]; !ok {
+		m.files[name] = &memoryFile{modTime: time.Now()}
+	}
+	return &memoryWriter{fs: m, name: name}, nil
+}
+
+func (m *MemoryFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = cleanName(name)
+	for k := range m.files {
+		if k == name || strings.HasPrefix(k, strings.TrimSuffix(name, "/")+"/") {
+			delete(m.files, k)
+		}
+	}
+	return nil
+}
+
+type memoryWriter struct {
+	fs   *MemoryFS
+	name string
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	f, ok := w.fs.files[w.name]
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: w.name, Err: fs.ErrNotExist}
+	}
+	f.data = append(f.data, p...)
+	f.modTime = time.Now()
+	return len(p), nil
+}
+
+func (w *memoryWriter) Close() error {
+	return nil
+}
+
+// S3FS stores the artifacts in an S3-compatible object store, a file is uploaded when it is closed.
+// S3 can't append to objects, so an appended file is uploaded again with the content of its object.
+type S3FS struct {
+	client *s3.Client
+	prefix string
+}
+
+func NewS3FS(cfg *s3.Config) (*S3FS, error) {
+	client, err := s3.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &S3FS{client: client, prefix: cfg.Prefix}, nil
+}
+
+func (s *S3FS) key(name string) string {
+	return s.prefix + strings.TrimPrefix(cleanName(name), "/")
+}
+
+func (s *S3FS) Open(name string) (fs.File, error) {
+	name = cleanName(name)
+	if name != "/" {
+		resp, err := s.client.Get(s.key(name), nil)
+		if err == nil {
+			modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+			return &readerFile{
+				Reader: resp.Body,
+				info:   &fileInfo{name: path.Base(name), size: resp.ContentLength, modTime: modTime},
+			}, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+
+	dirPrefix := strings.TrimSuffix(s.key(name), "/") + "/"
+	if name == "/" {
+		dirPrefix = s.prefix
+	}
+	objects, err := s.client.List(dirPrefix)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	files := make(map[string]*fileInfo, len(objects))
+	for _, object := range objects {
+		files[strings.TrimPrefix(object.Key, s.prefix)] = &fileInfo{size: object.Size}
+	}
+	if dir, ok := newDirFile(name, files); ok {
+		return dir, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (s *S3FS) OpenWritable(name string) (WritableFile, error) {
+	return s.newWriter(name, false)
+}
+
+func (s *S3FS) OpenAppendable(name string) (WritableFile, error) {
+	return s.newWriter(name, true)
+}
+
+func (s *S3FS) RemoveAll(name string) error {
+	key := s.key(name)
+	if err := s.client.Delete(key); err != nil {
+		return err
+	}
+	objects, err := s.client.List(strings.TrimSuffix(key, "/") + "/")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := s.client.Delete(object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3FS) newWriter(name string, appendable bool) (*s3Writer, error) {
+	file, err := os.CreateTemp("", "act-artifact-*")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Writer{File: file, fs: s, key: s.key(name), appendable: appendable}, nil
+}
+
+// s3Writer writes a file to a temporary file, which is uploaded when it is closed
+type s3Writer struct {
+	*os.File
+	fs         *S3FS
+	key        string
+	appendable bool
+}
+
+func (w *s3Writer) Close() error {
+	defer os.Remove(w.Name())
+	defer w.File.Close()
+
+	size, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if !w.appendable {
+		_, err = w.fs.client.Put(w.key, w.File, size, nil)
+		return err
+	}
+
+	// the content of the object is streamed before the appended content
+	resp, err := w.fs.client.Get(w.key, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		_, err = w.fs.client.Put(w.key, w.File, size, nil)
+		return err
+	} else if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return fmt.Errorf("append to %s: unknown size", w.key)
+	}
+	_, err = w.fs.client.Put(w.key, io.MultiReader(resp.Body, w.File), resp.ContentLength+size, nil)
+	return err
+}
@@ -0,0 +1,177 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+// The web ui of the browser, the pages are
+//
+// GET /                                         runs which have artifacts
+// GET /runs/{run_id}                            artifacts of a run
+// GET /runs/{run_id}/artifacts/{name}           files of an artifact
+// GET /runs/{run_id}/artifacts/{name}/zip       download of an artifact as zip
+// GET /runs/{run_id}/artifacts/{name}/files/... download of a decompressed file
+
+var uiTemplate = template.Must(template.New("ui").Funcs(template.FuncMap{
+	"size": func(size int64) string {
+		return units.BytesSize(float64(size))
+	},
+	"time": func(t time.Time) string {
+		return t.Local().Format(time.DateTime)
+	},
+	"escape": url.PathEscape,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>act artifacts</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.3em 1em 0.3em 0; }
+tr:nth-child(even) { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<p><a href="/">runs</a>{{with .RunID}} / <a href="/runs/{{.}}">run {{.}}</a>{{end}}{{with .Artifact}} / {{.Name}}{{end}}</p>
+{{if .Artifact}}
+{{$a := .Artifact}}
+<p>v{{$a.Version}}{{with $a.Job}}, uploaded by {{.}}{{end}}, <a href="/runs/{{$a.RunID}}/artifacts/{{escape $a.Name}}/zip">download zip</a></p>
+<table>
+<tr><th>File</th><th>Size</th></tr>
+{{range .Files}}<tr><td><a href="/runs/{{$a.RunID}}/artifacts/{{escape $a.Name}}/files/{{.Path}}">{{.Path}}</a></td><td>{{size .Size}}</td></tr>
+{{end}}</table>
+{{else if .RunID}}
+<table>
+<tr><th>Artifact</th><th>Version</th><th>Size</th><th>Job</th><th>Created</th><th>Expires</th><th></th></tr>
+{{range .Artifacts}}<tr><td><a href="/runs/{{.RunID}}/artifacts/{{escape .Name}}">{{.Name}}</a></td><td>v{{.Version}}</td><td>{{size .Size}}</td><td>{{.Job}}</td><td>{{time .CreatedAt}}</td><td>{{with .ExpiresAt}}{{time .}}{{end}}</td><td><a href="/runs/{{.RunID}}/artifacts/{{escape .Name}}/zip">zip</a></td></tr>
+{{else}}<tr><td colspan="7">The run has no artifacts.</td></tr>
+{{end}}</table>
+{{else}}
+<table>
+<tr><th>Run</th></tr>
+{{range .Runs}}<tr><td><a href="/runs/{{.}}">run {{.}}</a></td></tr>
+{{else}}<tr><td>There are no artifacts.</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+type uiPage struct {
+	Runs      []int64
+	RunID     int64
+	Artifacts []*Artifact
+	Artifact  *Artifact
+	Files     []ArtifactFile
+}
+
+// Handler returns the web ui to browse the runs and download their artifacts
+func (b *Browser) Handler() http.Handler {
+	router := httprouter.New()
+	router.GET("/", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		b.render(w, &uiPage{Runs: b.Runs()})
+	})
+	router.GET("/runs/:runId", func(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+		runID, err := strconv.ParseInt(params.ByName("runId"), 10, 64)
+		if err != nil {
+			http.NotFound(w, nil)
+			return
+		}
+		artifacts, err := b.List(runID)
+		if err != nil {
+			uiError(w, err)
+			return
+		}
+		b.render(w, &uiPage{RunID: runID, Artifacts: artifacts})
+	})
+	router.GET("/runs/:runId/artifacts/:name", func(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+		artifact, ok := b.uiArtifact(w, params)
+		if !ok {
+			return
+		}
+		files, err := b.Files(artifact)
+		if err != nil {
+			uiError(w, err)
+			return
+		}
+		b.render(w, &uiPage{RunID: artifact.RunID, Artifact: artifact, Files: files})
+	})
+	router.GET("/runs/:runId/artifacts/:name/zip", func(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+		artifact, ok := b.uiArtifact(w, params)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name+".zip"))
+		if err := b.WriteZip(w, artifact); err != nil {
+			log.Errorf("write zip of artifact %s: %v", artifact.Name, err)
+		}
+	})
+	router.GET("/runs/:runId/artifacts/:name/files/*path", func(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+		artifact, ok := b.uiArtifact(w, params)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		writer := &uiFileWriter{ResponseWriter: w}
+		if err := b.WriteFile(writer, artifact, params.ByName("path")); err != nil {
+			if !writer.written {
+				uiError(w, err)
+				return
+			}
+			log.Errorf("write file of artifact %s: %v", artifact.Name, err)
+		}
+	})
+	return router
+}
+
+func (b *Browser) uiArtifact(w http.ResponseWriter, params httprouter.Params) (*Artifact, bool) {
+	runID, err := strconv.ParseInt(params.ByName("runId"), 10, 64)
+	if err != nil {
+		http.NotFound(w, nil)
+		return nil, false
+	}
+	artifact, err := b.Get(runID, params.ByName("name"))
+	if err != nil {
+		uiError(w, err)
+		return nil, false
+	}
+	return artifact, true
+}
+
+func (b *Browser) render(w http.ResponseWriter, page *uiPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplate.Execute(w, page); err != nil {
+		log.Errorf("render artifacts ui: %v", err)
+	}
+}
+
+// uiFileWriter records whether the response was started, an error can't be answered after it
+type uiFileWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *uiFileWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+func uiError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
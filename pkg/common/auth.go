@@ -4,10 +4,12 @@
 package common
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,6 +23,38 @@ type actionsClaims struct {
 	RunID  int64
 	JobID  int64
 	Ac     string `json:"ac"`
+	// Repository isolates the caches of the repositories which share a cache server
+	Repository string `json:"repository,omitempty"`
+	// Job is the id of the job in the workflow, like GITHUB_JOB, the artifact server records it as the producer
+	Job string `json:"job,omitempty"`
+}
+
+var (
+	secretMu sync.RWMutex
+	// secret signs and verifies the tokens, it is random for each process unless SetAuthorizationSecret shares one
+	secret = newAuthorizationSecret()
+)
+
+func newAuthorizationSecret() []byte {
+	s := make([]byte, 32)
+	if _, err := rand.Read(s); err != nil {
+		panic(fmt.Errorf("generate secret: %w", err))
+	}
+	return s
+}
+
+// SetAuthorizationSecret sets the key which signs and verifies the tokens, like the secret of a cache server
+// which is shared by the act processes whose jobs use it
+func SetAuthorizationSecret(s []byte) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secret = append([]byte{}, s...)
+}
+
+func authorizationSecret() []byte {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	return secret
 }
 
 type actionsCacheScope struct {
@@ -36,14 +70,32 @@ const (
 )
 
 func CreateAuthorizationToken(taskID, runID, jobID int64) (string, error) {
+	return CreateScopedAuthorizationToken(taskID, runID, jobID, "", "")
+}
+
+// CreateScopedAuthorizationToken creates a token of a job in repository which may read and write the caches
+// of the first ref and read the caches of the other refs, in the order of the lookup like
+// refs/heads/feature, refs/heads/main
+func CreateScopedAuthorizationToken(taskID, runID, jobID int64, repository string, refs ...string) (string, error) {
+	return CreateJobAuthorizationToken(taskID, runID, jobID, "", repository, refs...)
+}
+
+// CreateJobAuthorizationToken creates a token like CreateScopedAuthorizationToken of the job with the id job in the workflow
+func CreateJobAuthorizationToken(taskID, runID, jobID int64, job, repository string, refs ...string) (string, error) {
 	now := time.Now()
 
-	ac, err := json.Marshal(&[]actionsCacheScope{
-		{
-			Scope:      "",
-			Permission: actionsCachePermissionWrite,
-		},
-	})
+	scopes := make([]actionsCacheScope, 0, len(refs))
+	for i, ref := range refs {
+		permission := actionsCachePermission(actionsCachePermissionRead)
+		if i == 0 {
+			permission |= actionsCachePermissionWrite
+		}
+		scopes = append(scopes, actionsCacheScope{
+			Scope:      ref,
+			Permission: permission,
+		})
+	}
+	ac, err := json.Marshal(&scopes)
 	if err != nil {
 		return "", err
 	}
@@ -53,15 +105,17 @@ func CreateAuthorizationToken(taskID, runID, jobID int64) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
 			NotBefore: jwt.NewNumericDate(now),
 		},
-		Scp:    fmt.Sprintf("Actions.Results:%d:%d", runID, jobID),
-		TaskID: taskID,
-		RunID:  runID,
-		JobID:  jobID,
-		Ac:     string(ac),
+		Scp:        fmt.Sprintf("Actions.Results:%d:%d", runID, jobID),
+		TaskID:     taskID,
+		RunID:      runID,
+		JobID:      jobID,
+		Ac:         string(ac),
+		Repository: repository,
+		Job:        job,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenString, err := token.SignedString([]byte{})
+	tokenString, err := token.SignedString(authorizationSecret())
 	if err != nil {
 		return "", err
 	}
@@ -70,31 +124,81 @@ func CreateAuthorizationToken(taskID, runID, jobID int64) (string, error) {
 }
 
 func ParseAuthorizationToken(req *http.Request) (int64, error) {
+	claims, err := ParseAuthorizationClaims(req)
+	if err != nil || claims == nil {
+		return 0, err
+	}
+	return claims.TaskID, nil
+}
+
+// AuthorizationClaims are the claims of the token of a job
+type AuthorizationClaims struct {
+	TaskID     int64
+	RunID      int64
+	JobID      int64
+	Repository string
+	Job        string
+	scopes     []actionsCacheScope
+}
+
+// CacheScopes returns the refs whose caches may be read, in the order of the lookup
+func (c *AuthorizationClaims) CacheScopes() []string {
+	refs := make([]string, 0, len(c.scopes))
+	for _, s := range c.scopes {
+		refs = append(refs, s.Scope)
+	}
+	return refs
+}
+
+// CacheWriteScope returns the ref whose caches may be written, false if the token may not write caches
+func (c *AuthorizationClaims) CacheWriteScope() (string, bool) {
+	for _, s := range c.scopes {
+		if s.Permission&actionsCachePermissionWrite != 0 {
+			return s.Scope, true
+		}
+	}
+	return "", false
+}
+
+// ParseAuthorizationClaims returns the claims of the token of a request, they are nil if it has no token
+func ParseAuthorizationClaims(req *http.Request) (*AuthorizationClaims, error) {
 	h := req.Header.Get("Authorization")
 	if h == "" {
-		return 0, nil
+		return nil, nil
 	}
 
 	parts := strings.SplitN(h, " ", 2)
 	if len(parts) != 2 {
 		log.Errorf("split token failed: %s", h)
-		return 0, fmt.Errorf("split token failed")
+		return nil, fmt.Errorf("split token failed")
 	}
 
 	token, err := jwt.ParseWithClaims(parts[1], &actionsClaims{}, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return []byte{}, nil
+		return authorizationSecret(), nil
 	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	c, ok := token.Claims.(*actionsClaims)
 	if !token.Valid || !ok {
-		return 0, fmt.Errorf("invalid token claim")
+		return nil, fmt.Errorf("invalid token claim")
 	}
 
-	return c.TaskID, nil
+	claims := &AuthorizationClaims{
+		TaskID:     c.TaskID,
+		RunID:      c.RunID,
+		JobID:      c.JobID,
+		Repository: c.Repository,
+		Job:        c.Job,
+	}
+	if c.Ac != "" {
+		if err := json.Unmarshal([]byte(c.Ac), &claims.scopes); err != nil {
+			return nil, fmt.Errorf("invalid ac claim: %w", err)
+		}
+	}
+	return claims, nil
 }
@@ -19,7 +19,7 @@ func TestCreateAuthorizationToken(t *testing.T) {
 	assert.NotEqual(t, "", token)
 	claims := jwt.MapClaims{}
 	_, err = jwt.ParseWithClaims(token, claims, func(_ *jwt.Token) (interface{}, error) {
-		return []byte{}, nil
+		return authorizationSecret(), nil
 	})
 	assert.Nil(t, err)
 	scp, ok := claims["scp"]
@@ -60,3 +60,47 @@ func TestParseAuthorizationTokenNoAuthHeader(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), rTaskID)
 }
+
+func TestParseAuthorizationClaims(t *testing.T) {
+	token, err := CreateScopedAuthorizationToken(23, 1, 2, "owner/repo", "refs/heads/feature", "refs/heads/main")
+	assert.Nil(t, err)
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+token)
+	claims, err := ParseAuthorizationClaims(&http.Request{
+		Header: headers,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(23), claims.TaskID)
+	assert.Equal(t, "owner/repo", claims.Repository)
+	assert.Equal(t, []string{"refs/heads/feature", "refs/heads/main"}, claims.CacheScopes())
+	ref, ok := claims.CacheWriteScope()
+	assert.True(t, ok)
+	assert.Equal(t, "refs/heads/feature", ref)
+}
+
+func TestParseAuthorizationClaimsJob(t *testing.T) {
+	token, err := CreateJobAuthorizationToken(23, 1, 2, "build", "owner/repo", "refs/heads/main")
+	assert.Nil(t, err)
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+token)
+	claims, err := ParseAuthorizationClaims(&http.Request{
+		Header: headers,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "build", claims.Job)
+	assert.Equal(t, "owner/repo", claims.Repository)
+}
+
+func TestParseAuthorizationClaimsSecret(t *testing.T) {
+	defer SetAuthorizationSecret(authorizationSecret())
+	token, err := CreateAuthorizationToken(23, 1, 2)
+	assert.Nil(t, err)
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+token)
+
+	SetAuthorizationSecret([]byte("other"))
+	_, err = ParseAuthorizationClaims(&http.Request{
+		Header: headers,
+	})
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid, "a token signed with another secret is rejected")
+}
@@ -0,0 +1,334 @@
+// Package s3 implements the few requests of the S3 api which act uses to store caches and artifacts
+// in an S3-compatible object store like AWS S3 or MinIO.
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config configures an S3-compatible object store like AWS S3 or MinIO
+type Config struct {
+	// Endpoint is like https://s3.us-east-1.amazonaws.com or http://localhost:9000, the bucket is in the path
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// ErrConflict is returned if a conditional request failed, because the object was changed concurrently
+var ErrConflict = errors.New("the object was changed concurrently")
+
+// ParseURL returns the config of s3://bucket/prefix?endpoint=http://localhost:9000&region=us-east-1,
+// the credentials and defaults are read from the AWS_* environment variables
+func ParseURL(s string) (*Config, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 url %q, expected s3://bucket/prefix", s)
+	}
+	cfg := &Config{
+		Endpoint:        u.Query().Get("endpoint"),
+		Region:          u.Query().Get("region"),
+		Bucket:          u.Host,
+		Prefix:          strings.Trim(u.Path, "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = os.Getenv("AWS_ENDPOINT_URL_S3")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.Prefix != "" {
+		cfg.Prefix += "/"
+	}
+	return cfg, nil
+}
+
+// Client implements the few requests of the S3 api which are used by the storages, they are signed
+// with AWS Signature Version 4 and address the bucket in the path
+type Client struct {
+	cfg      *Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// Object is an object of a list
+type Object struct {
+	Key  string `xml:"Key"`
+	Size int64  `xml:"Size"`
+}
+
+func NewClient(cfg *Config) (*Client, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint %q: %w", cfg.Endpoint, err)
+	}
+	return &Client{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{},
+	}, nil
+}
+
+func (c *Client) do(method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.cfg.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.sign(req, time.Now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("s3 %s %s: %s %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %w", err, os.ErrNotExist)
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return nil, fmt.Errorf("%w: %w", err, ErrConflict)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Put uploads size bytes of body, header may contain conditions like If-Match
+func (c *Client) Put(key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	resp, err := c.do(http.MethodPut, key, nil, header, body, size)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// PutFile uploads the content of reader, it is written to a temporary file first since S3 requires its size
+func (c *Client) PutFile(key string, reader io.Reader) error {
+	file, err := os.CreateTemp("", "act-s3-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = c.Put(key, file, size, nil)
+	return err
+}
+
+// Get returns the response of the object, its error wraps os.ErrNotExist if there is no object
+func (c *Client) Get(key string, header http.Header) (*http.Response, error) {
+	return c.do(http.MethodGet, key, nil, header, nil, 0)
+}
+
+// Head reports whether the object exists
+func (c *Client) Head(key string) (bool, error) {
+	resp, err := c.do(http.MethodHead, key, nil, nil, nil, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// Copy copies the object src to dst in the same bucket
+func (c *Client) Copy(src, dst string) error {
+	header := http.Header{}
+	header.Set("x-amz-copy-source", escapePath("/"+c.cfg.Bucket+"/"+src))
+	_, err := c.Put(dst, nil, 0, header)
+	return err
+}
+
+// Delete removes the object, it is not an error if there is none
+func (c *Client) Delete(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil, nil, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns the objects with the prefix in the order of their keys
+func (c *Client) List(prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+	}
+	for {
+		resp, err := c.do(http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents              []Object `xml:"Contents"`
+			IsTruncated           bool     `xml:"IsTruncated"`
+			NextContinuationToken string   `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 list %s: %w", prefix, err)
+		}
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// sign adds the headers of AWS Signature Version 4, the payload is not signed
+func (c *Client) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.cfg.SessionToken)
+	}
+	if c.cfg.AccessKeyID == "" {
+		return
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonicalHeaders := &strings.Builder{}
+	for _, k := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, c.cfg.Region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + c.cfg.SecretAccessKey)
+	for _, v := range []string{date, c.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escape escapes like the canonical requests of S3, only the unreserved characters are kept
+func escape(s string, keepSlash bool) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || keepSlash && ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func escapePath(s string) string {
+	return escape(s, true)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, escape(k, false)+"="+escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
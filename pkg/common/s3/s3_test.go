@@ -0,0 +1,76 @@
+package s3_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3"
+	"github.com/actions-oss/act-cli/pkg/common/s3/s3test"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+
+	cfg, err := s3.ParseURL("s3://bucket/some/prefix?endpoint=http://localhost:9000&region=eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, &s3.Config{
+		Endpoint:        "http://localhost:9000",
+		Region:          "eu-west-1",
+		Bucket:          "bucket",
+		Prefix:          "some/prefix/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+	}, cfg)
+
+	cfg, err = s3.ParseURL("s3://bucket")
+	require.NoError(t, err)
+	assert.Equal(t, "https://s3.us-east-1.amazonaws.com", cfg.Endpoint)
+	assert.Empty(t, cfg.Prefix)
+
+	_, err = s3.ParseURL("s3:///prefix")
+	assert.Error(t, err)
+}
+
+func TestClient(t *testing.T) {
+	fake, cfg := s3test.NewServer(t, "bucket")
+	client, err := s3.NewClient(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, client.PutFile("dir/a", strings.NewReader("hello")))
+	require.NoError(t, client.Copy("dir/a", "dir/b"))
+	ok, err := client.Head("dir/b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	resp, err := client.Get("dir/b", nil)
+	require.NoError(t, err)
+	content, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	objects, err := client.List("dir/")
+	require.NoError(t, err)
+	assert.Equal(t, []s3.Object{{Key: "dir/a", Size: 5}, {Key: "dir/b", Size: 5}}, objects)
+
+	header := http.Header{}
+	header.Set("If-None-Match", "*")
+	_, err = client.Put("dir/a", strings.NewReader("again"), 5, header)
+	assert.ErrorIs(t, err, s3.ErrConflict)
+
+	require.NoError(t, client.Delete("dir/a"))
+	require.NoError(t, client.Delete("dir/a"))
+	_, err = client.Get("dir/a", nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, []string{"dir/b"}, fake.Keys())
+}
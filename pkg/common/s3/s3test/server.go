@@ -0,0 +1,143 @@
+// Package s3test provides a fake S3-compatible object store for the tests of the storages which use package s3.
+package s3test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actions-oss/act-cli/pkg/common/s3"
+)
+
+// Server implements the requests of the S3 api which are used by s3.Client for a bucket in memory
+type Server struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	etags   map[string]string
+	version int
+}
+
+// NewServer starts a server until the test ends and returns the config of its bucket
+func NewServer(t testing.TB, bucket string) (*Server, *s3.Config) {
+	s := &Server{bucket: bucket, objects: map[string][]byte{}, etags: map[string]string{}}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, &s3.Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          bucket,
+		Prefix:          "actcache/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.bucket)
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+	// the body is read before locking, since it may be streamed from other objects
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", s.etags[key])
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+	case r.Method == http.MethodPut:
+		etag, exists := s.etags[key]
+		if v := r.Header.Get("If-None-Match"); v == "*" && exists {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		if v := r.Header.Get("If-Match"); v != "" && v != etag {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		var data []byte
+		if src := r.Header.Get("x-amz-copy-source"); src != "" {
+			data, ok = s.objects[strings.TrimPrefix(src, "/"+s.bucket+"/")]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+		} else {
+			if r.ContentLength < 0 {
+				http.Error(w, "MissingContentLength", http.StatusLengthRequired)
+				return
+			}
+			data = body
+		}
+		s.version++
+		s.objects[key] = data
+		s.etags[key] = fmt.Sprintf(`"%d"`, s.version)
+		w.Header().Set("ETag", s.etags[key])
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		delete(s.etags, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, prefix string) {
+	type object struct {
+		Key  string
+		Size int64
+	}
+	result := struct {
+		XMLName  xml.Name `xml:"ListBucketResult"`
+		Contents []object
+	}{}
+	for k, v := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			result.Contents = append(result.Contents, object{Key: k, Size: int64(len(v))})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].Key < result.Contents[j].Key
+	})
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+// Keys returns the keys of the objects in the bucket in their order
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"time"
 
 	"github.com/actions-oss/act-cli/pkg/common"
 	"github.com/docker/go-connections/nat"
@@ -32,6 +33,10 @@ type NewContainerInput struct {
 	NetworkAliases []string
 	ExposedPorts   nat.PortSet
 	PortBindings   nat.PortMap
+	ImagePull      ImagePullConfig
+	// StreamLogs attaches Stdout and Stderr to the container output on Start
+	// without waiting for the container to exit
+	StreamLogs bool
 }
 
 // FileEntry is a file to copy to a container
@@ -57,6 +62,7 @@ type Container interface {
 	Close() common.Executor
 	ReplaceLogWriter(io.Writer, io.Writer) (io.Writer, io.Writer)
 	GetHealth(ctx context.Context) Health
+	GetState(ctx context.Context) (*State, error)
 }
 
 // NewDockerBuildExecutorInput the input for the NewDockerBuildExecutor function
@@ -66,6 +72,14 @@ type NewDockerBuildExecutorInput struct {
 	BuildContext io.Reader
 	ImageTag     string
 	Platform     string
+	// BuildArgs are passed as --build-arg to the build
+	BuildArgs map[string]string
+	// Secrets are exposed to RUN --mount=type=secret,id=<name>, they require BuildKit
+	Secrets map[string]string
+	// CacheDir keeps the built images keyed by the digest of the build context, as a docker save archive per image
+	CacheDir string
+	// CacheSize bounds the archives in CacheDir, the least recently used are removed beyond it, 0 means unlimited
+	CacheSize int64
 }
 
 // NewDockerPullExecutorInput the input for the NewDockerPullExecutor function
@@ -75,6 +89,17 @@ type NewDockerPullExecutorInput struct {
 	Platform  string
 	Username  string
 	Password  string
+	ImagePull ImagePullConfig
+}
+
+// ImagePullConfig configures retries and registry mirrors for image pulls
+type ImagePullConfig struct {
+	// Retries is the number of retries after a transient registry error
+	Retries int
+	// RetryBackoff is the delay before the first retry, it doubles with every retry
+	RetryBackoff time.Duration
+	// Mirrors maps registry hosts to mirror hosts, e.g. docker.io to registry.local:5000
+	Mirrors map[string]string
 }
 
 type Health int
@@ -85,6 +110,28 @@ const (
 	HealthUnHealthy
 )
 
+// HealthCheckResult is a single probe of a container health check
+type HealthCheckResult struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
+}
+
+// State is a snapshot of the runtime state of a container, used for diagnostics
+type State struct {
+	ID            string
+	Status        string
+	Running       bool
+	ExitCode      int
+	OOMKilled     bool
+	Error         string
+	Health        string
+	HealthHistory []HealthCheckResult
+	// Ports maps the exposed container ports to the ports published on the host
+	Ports map[string]string
+}
+
 var containerAllocateTerminal bool
 
 func init() {
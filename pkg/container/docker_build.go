@@ -3,19 +3,27 @@
 package container
 
 import (
+	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/build"
-	"github.com/moby/go-archive"
-
-	"github.com/moby/patternmatcher"
-	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/docker/docker/client"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 
 	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/filecollector"
 )
 
 // NewDockerBuildExecutor function to create a run executor for the container
@@ -39,6 +47,30 @@ func NewDockerBuildExecutor(input NewDockerBuildExecutorInput) common.Executor {
 
 		logger.Debugf("Building image from '%v'", input.ContextDir)
 
+		buildContext, err := prepareBuildContext(ctx, input)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			buildContext.Close()
+			os.Remove(buildContext.Name())
+		}()
+
+		var cacheFile string
+		if input.CacheDir != "" {
+			digest, err := buildCacheKey(buildContext, input)
+			if err != nil {
+				return err
+			}
+			cacheFile = filepath.Join(input.CacheDir, digest+".tar")
+			if loaded, err := loadCachedImage(ctx, cli, cacheFile); err != nil {
+				logger.Warnf("Failed to load cached image %s: %v", cacheFile, err)
+			} else if loaded {
+				logger.Infof("%sUsing cached image %s from %s", logPrefix, input.ImageTag, cacheFile)
+				return nil
+			}
+		}
+
 		tags := []string{input.ImageTag}
 		options := build.ImageBuildOptions{
 			Tags:        tags,
@@ -46,69 +78,317 @@ func NewDockerBuildExecutor(input NewDockerBuildExecutorInput) common.Executor {
 			Platform:    input.Platform,
 			AuthConfigs: LoadDockerAuthConfigs(ctx),
 			Dockerfile:  input.Dockerfile,
+			BuildArgs:   map[string]*string{},
+		}
+		for k, v := range input.BuildArgs {
+			options.BuildArgs[k] = &v
 		}
-		var buildContext io.ReadCloser
-		if input.BuildContext != nil {
-			buildContext = io.NopCloser(input.BuildContext)
+
+		if buildKitEnabled(ctx, cli) {
+			logger.Debugf("Using BuildKit to build image '%s'", input.ImageTag)
+			session, err := startBuildKitSession(ctx, cli, input.Secrets)
+			if err != nil {
+				return err
+			}
+			defer session.Close()
+			options.Version = build.BuilderBuildKit
+			options.SessionID = session.ID()
 		} else {
-			buildContext, err = createBuildContext(ctx, input.ContextDir, input.Dockerfile)
+			if len(input.Secrets) > 0 {
+				return fmt.Errorf("build secrets require BuildKit, set DOCKER_BUILDKIT=1 or enable BuildKit in the docker daemon")
+			}
+			if strings.Contains(input.Platform, ",") {
+				return fmt.Errorf("multi-platform builds require BuildKit, got platform '%s'", input.Platform)
+			}
+			options.Version = build.BuilderV1
 		}
+
+		logger.Debugf("Creating image from context dir '%s' with tag '%s' and platform '%s'", input.ContextDir, input.ImageTag, input.Platform)
+		resp, err := cli.ImageBuild(ctx, buildContext, options)
+
+		err = errors.Join(err, logDockerResponse(logger, resp.Body, err != nil))
 		if err != nil {
 			return err
 		}
 
-		defer buildContext.Close()
+		if cacheFile != "" {
+			if err := saveCachedImage(ctx, cli, input.ImageTag, cacheFile); err != nil {
+				logger.Warnf("Failed to cache image %s in %s: %v", input.ImageTag, cacheFile, err)
+			}
+			if err := evictCachedImages(input.CacheDir, input.CacheSize); err != nil {
+				logger.Warnf("Failed to evict cached images from %s: %v", input.CacheDir, err)
+			}
+		}
+		return nil
+	}
+}
 
-		logger.Debugf("Creating image from context dir '%s' with tag '%s' and platform '%s'", input.ContextDir, input.ImageTag, input.Platform)
-		resp, err := cli.ImageBuild(ctx, buildContext, options)
+// buildKitEnabled follows the docker cli, DOCKER_BUILDKIT overrides the default builder of the daemon
+func buildKitEnabled(ctx context.Context, cli client.APIClient) bool {
+	if v, ok := os.LookupEnv("DOCKER_BUILDKIT"); ok {
+		return v == "1" || strings.EqualFold(v, "true")
+	}
+	ping, err := cli.Ping(ctx)
+	if err != nil {
+		common.Logger(ctx).Debugf("Failed to ping docker daemon: %v", err)
+		return false
+	}
+	return ping.BuilderVersion == build.BuilderBuildKit && ping.OSType != "windows"
+}
 
-		err = errors.Join(err, logDockerResponse(logger, resp.Body, err != nil))
-		return err
+// prepareBuildContext writes the build context into a temporary tar file, the
+// patterns of .dockerignore are applied while collecting the files
+func prepareBuildContext(ctx context.Context, input NewDockerBuildExecutorInput) (*os.File, error) {
+	tarFile, err := os.CreateTemp("", "act-build-context")
+	if err != nil {
+		return nil, err
+	}
+	if input.BuildContext != nil {
+		err = filterBuildContext(ctx, input.BuildContext, tarFile, input.Dockerfile)
+	} else {
+		err = createBuildContext(ctx, input.ContextDir, input.Dockerfile, tarFile)
+	}
+	if err == nil {
+		_, err = tarFile.Seek(0, io.SeekStart)
 	}
+	if err != nil {
+		tarFile.Close()
+		os.Remove(tarFile.Name())
+		return nil, err
+	}
+	return tarFile, nil
 }
-func createBuildContext(ctx context.Context, contextDir string, relDockerfile string) (io.ReadCloser, error) {
+
+func createBuildContext(ctx context.Context, contextDir string, relDockerfile string, w io.Writer) error {
 	common.Logger(ctx).Debugf("Creating archive for build context dir '%s' with relative dockerfile '%s'", contextDir, relDockerfile)
 
-	// And canonicalize dockerfile name to a platform-independent one
-	relDockerfile = filepath.ToSlash(relDockerfile)
+	excludes, err := filecollector.ReadDockerIgnore(contextDir)
+	if err != nil {
+		return err
+	}
 
-	f, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
-	if err != nil && !os.IsNotExist(err) {
-		return nil, err
+	// Dockerfile and .dockerignore are always sent to the daemon, it removes
+	// them after parsing the Dockerfile if they are excluded
+	ignorer, err := filecollector.NewDockerIgnoreMatcher(excludes, ".dockerignore", relDockerfile)
+	if err != nil {
+		return err
 	}
-	defer f.Close()
 
+	tw := tar.NewWriter(w)
+	contextDir = filepath.Clean(contextDir)
+	fc := &filecollector.FileCollector{
+		Fs:        &buildContextFs{},
+		Ignorer:   ignorer,
+		SrcPath:   contextDir,
+		SrcPrefix: contextDir + string(filepath.Separator),
+		Handler: &filecollector.TarCollector{
+			TarWriter: tw,
+		},
+	}
+	// the trailing "." makes the FileCollector skip the root folder
+	if err := filepath.Walk(contextDir+string(filepath.Separator)+".", fc.CollectFiles(ctx, []string{})); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// buildContextFs ignores the git index, files tracked by git are still excluded by .dockerignore
+type buildContextFs struct {
+	filecollector.DefaultFs
+}
+
+func (*buildContextFs) OpenGitIndex(_ string) (*index.Index, error) {
+	return nil, index.ErrEntryNotFound
+}
+
+// filterBuildContext removes the files matching .dockerignore from a tar build context
+func filterBuildContext(ctx context.Context, src io.Reader, dst *os.File, relDockerfile string) error {
+	raw, err := os.CreateTemp("", "act-build-context-raw")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		raw.Close()
+		os.Remove(raw.Name())
+	}()
+
+	// the .dockerignore file may appear anywhere in the stream
 	var excludes []string
-	if err == nil {
-		excludes, err = ignorefile.ReadAll(f)
-		if err != nil {
-			return nil, err
+	tr := tar.NewReader(io.TeeReader(src, raw))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read build context: %w", err)
 		}
+		if path.Clean(hdr.Name) == ".dockerignore" {
+			if excludes, err = filecollector.ParseDockerIgnore(tr); err != nil {
+				return err
+			}
+		}
+	}
+	// drain the remaining padding of the stream
+	if _, err := io.Copy(io.Discard, io.TeeReader(src, raw)); err != nil {
+		return err
+	}
+	if _, err := raw.Seek(0, io.SeekStart); err != nil {
+		return err
 	}
 
-	// If .dockerignore mentions .dockerignore or the Dockerfile
-	// then make sure we send both files over to the daemon
-	// because Dockerfile is, obviously, needed no matter what, and
-	// .dockerignore is needed to know if either one needs to be
-	// removed. The daemon will remove them for us, if needed, after it
-	// parses the Dockerfile. Ignore errors here, as they will have been
-	// caught by validateContextDirectory above.
-	var includes = []string{"."}
-	keepThem1, _ := patternmatcher.Matches(".dockerignore", excludes)
-	keepThem2, _ := patternmatcher.Matches(relDockerfile, excludes)
-	if keepThem1 || keepThem2 {
-		includes = append(includes, ".dockerignore", relDockerfile)
+	ignorer, err := filecollector.NewDockerIgnoreMatcher(excludes, ".dockerignore", relDockerfile)
+	if err != nil {
+		return err
 	}
 
-	compression := archive.Uncompressed
-	buildCtx, err := archive.TarWithOptions(contextDir, &archive.TarOptions{
-		Compression:     compression,
-		ExcludePatterns: excludes,
-		IncludeFiles:    includes,
-	})
+	tr = tar.NewReader(raw)
+	tw := tar.NewWriter(dst)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if ignorer.Match(strings.Split(name, "/"), hdr.Typeflag == tar.TypeDir) {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// buildCacheKey is the digest of everything which affects the built image, the files of the context
+// count with their names, modes and content but without their times, which change with every checkout
+func buildCacheKey(buildContext io.ReadSeeker, input NewDockerBuildExecutorInput) (string, error) {
+	h := sha256.New()
+	tr := tar.NewReader(buildContext)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "\x00file:%s\x00%c\x00%o\x00%s\x00%d\x00", header.Name, header.Typeflag, header.Mode, header.Linkname, header.Size)
+		if _, err := io.Copy(h, tr); err != nil {
+			return "", err
+		}
+	}
+	if _, err := buildContext.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "\x00dockerfile=%s\x00platform=%s\x00tag=%s", input.Dockerfile, input.Platform, input.ImageTag)
+	keys := make([]string, 0, len(input.BuildArgs))
+	for k := range input.BuildArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00arg:%s=%s", k, input.BuildArgs[k])
+	}
+	keys = keys[:0]
+	for k := range input.Secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// an image may contain what it built with a secret, so it is rebuilt if the secret changes
+		fmt.Fprintf(h, "\x00secret:%s=%x", k, sha256.Sum256([]byte(input.Secrets[k])))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadCachedImage(ctx context.Context, cli client.APIClient, cacheFile string) (bool, error) {
+	f, err := os.Open(cacheFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	resp, err := cli.ImageLoad(ctx, f, client.ImageLoadWithQuiet(true))
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	if err := logDockerResponse(common.Logger(ctx), resp.Body, false); err != nil {
+		return false, err
 	}
+	// the modification time orders the archives for evictCachedImages
+	now := time.Now()
+	_ = os.Chtimes(cacheFile, now, now)
+	return true, nil
+}
 
-	return buildCtx, nil
+func saveCachedImage(ctx context.Context, cli client.APIClient, imageTag string, cacheFile string) error {
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err != nil {
+		return err
+	}
+	rc, err := cli.ImageSave(ctx, []string{imageTag})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), ".act-image-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, rc)
+	err = errors.Join(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// evictCachedImages removes the least recently used archives of cacheDir until they fit into size,
+// the most recently used archive is kept even if it alone is larger
+func evictCachedImages(cacheDir string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return err
+	}
+	var archives []fs.FileInfo
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tar" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		archives = append(archives, info)
+		total += info.Size()
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].ModTime().Before(archives[j].ModTime())
+	})
+	var errs []error
+	for _, info := range archives[:max(len(archives)-1, 0)] {
+		if total <= size {
+			break
+		}
+		if err := os.Remove(filepath.Join(cacheDir, info.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		total -= info.Size()
+	}
+	return errors.Join(errs...)
 }
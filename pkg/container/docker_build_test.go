@@ -0,0 +1,168 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func tarNames(t *testing.T, r io.Reader) []string {
+	names := []string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCreateBuildContext(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"Dockerfile":        "FROM alpine\n",
+		".dockerignore":     "*.log\nDockerfile\n.dockerignore\nnode_modules\n",
+		"entrypoint.sh":     "#!/bin/sh\n",
+		"debug.log":         "log\n",
+		"node_modules/a.js": "a\n",
+		"src/main.go":       "package main\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, createBuildContext(context.Background(), dir, "Dockerfile", buf))
+	assert.Equal(t, []string{".dockerignore", "Dockerfile", "entrypoint.sh", "src/main.go"}, tarNames(t, bytes.NewReader(buf.Bytes())))
+}
+
+func TestFilterBuildContext(t *testing.T) {
+	src := &bytes.Buffer{}
+	tw := tar.NewWriter(src)
+	for name, content := range map[string]string{
+		"Dockerfile":    "FROM alpine\n",
+		"action.yml":    "runs: {}\n",
+		"docs/index.md": "docs\n",
+		".dockerignore": "docs\n",
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	dst, err := os.CreateTemp(t.TempDir(), "context")
+	require.NoError(t, err)
+	defer dst.Close()
+	require.NoError(t, filterBuildContext(context.Background(), src, dst, "Dockerfile"))
+
+	_, err = dst.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".dockerignore", "Dockerfile", "action.yml"}, tarNames(t, dst))
+}
+
+func TestBuildCacheKey(t *testing.T) {
+	buildContext := func(modTime time.Time, mode int64, content string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: mode, Size: int64(len(content)), ModTime: modTime}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		return buf.Bytes()
+	}
+	now := time.Now()
+	contextKey := func(context []byte, input NewDockerBuildExecutorInput) string {
+		k, err := buildCacheKey(bytes.NewReader(context), input)
+		require.NoError(t, err)
+		return k
+	}
+	key := func(input NewDockerBuildExecutorInput) string {
+		return contextKey(buildContext(now, 0o644, "FROM alpine"), input)
+	}
+	input := NewDockerBuildExecutorInput{ImageTag: "act-test", BuildArgs: map[string]string{"A": "1", "B": "2"}}
+	assert.Equal(t, key(input), key(NewDockerBuildExecutorInput{ImageTag: "act-test", BuildArgs: map[string]string{"B": "2", "A": "1"}}))
+	assert.NotEqual(t, key(input), key(NewDockerBuildExecutorInput{ImageTag: "act-test", BuildArgs: map[string]string{"A": "2"}}))
+	assert.NotEqual(t, key(input), key(NewDockerBuildExecutorInput{ImageTag: "act-test", BuildArgs: input.BuildArgs, Platform: "linux/arm64"}))
+
+	assert.Equal(t, key(input), contextKey(buildContext(now.Add(time.Hour), 0o644, "FROM alpine"), input), "the times of the files don't count")
+	assert.NotEqual(t, key(input), contextKey(buildContext(now, 0o755, "FROM alpine"), input))
+	assert.NotEqual(t, key(input), contextKey(buildContext(now, 0o644, "FROM debian"), input))
+
+	secret := func(value string) NewDockerBuildExecutorInput {
+		return NewDockerBuildExecutorInput{ImageTag: "act-test", Secrets: map[string]string{"npmrc": value}}
+	}
+	assert.Equal(t, key(secret("token")), key(secret("token")))
+	assert.NotEqual(t, key(secret("token")), key(secret("other token")), "the image is rebuilt with another secret")
+}
+
+func TestEvictCachedImages(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"oldest.tar", "older.tar", "newest.tar"} {
+		file := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(file, make([]byte, 10), 0o644))
+		modTime := now.Add(time.Duration(i-3) * time.Minute)
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), make([]byte, 100), 0o644))
+
+	require.NoError(t, evictCachedImages(dir, 0))
+	assert.FileExists(t, filepath.Join(dir, "oldest.tar"), "0 means unlimited")
+
+	require.NoError(t, evictCachedImages(dir, 20))
+	assert.NoFileExists(t, filepath.Join(dir, "oldest.tar"))
+	assert.FileExists(t, filepath.Join(dir, "older.tar"))
+	assert.FileExists(t, filepath.Join(dir, "other"), "only the archives are evicted")
+
+	require.NoError(t, evictCachedImages(dir, 5))
+	assert.NoFileExists(t, filepath.Join(dir, "older.tar"))
+	assert.FileExists(t, filepath.Join(dir, "newest.tar"), "the most recently used archive is kept")
+}
+
+func TestLogBuildKitProgress(t *testing.T) {
+	status := &controlapi.StatusResponse{
+		Vertexes: []*controlapi.Vertex{
+			{Digest: "sha256:1", Name: "[1/2] FROM alpine", Cached: true},
+			{Digest: "sha256:2", Name: "[2/2] RUN make", Started: timestamppb.Now()},
+		},
+		Logs: []*controlapi.VertexLog{
+			{Vertex: "sha256:2", Msg: []byte("compiling\n")},
+		},
+	}
+	dt, err := proto.Marshal(status)
+	require.NoError(t, err)
+	aux, err := json.Marshal(dt)
+	require.NoError(t, err)
+	line, err := json.Marshal(map[string]any{"id": buildKitTraceID, "aux": json.RawMessage(aux)})
+	require.NoError(t, err)
+
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	require.NoError(t, logDockerResponse(logger, io.NopCloser(bytes.NewReader(line)), false))
+
+	messages := []string{}
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"[1/2] FROM alpine :: CACHED\n", "[2/2] RUN make\n", "compiling\n"}, messages)
+}
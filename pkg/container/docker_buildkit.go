@@ -0,0 +1,108 @@
+//go:build !(WITHOUT_DOCKER || !(linux || darwin || windows || netbsd))
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/docker/client"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+)
+
+const buildKitTraceID = "moby.buildkit.trace"
+
+// startBuildKitSession starts a session which serves registry credentials and
+// build secrets to the BuildKit builder of the docker daemon
+func startBuildKitSession(ctx context.Context, cli client.APIClient, secrets map[string]string) (*session.Session, error) {
+	s, err := session.NewSession(ctx, "act")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create buildkit session: %w", err)
+	}
+
+	if cfg, err := config.Load(config.Dir()); err == nil {
+		s.Allow(authprovider.NewDockerAuthProvider(authprovider.DockerAuthProviderConfig{
+			ConfigFile: cfg,
+		}))
+	} else {
+		common.Logger(ctx).Warnf("Could not load docker config for buildkit session: %v", err)
+	}
+
+	values := make(map[string][]byte, len(secrets))
+	for k, v := range secrets {
+		values[k] = []byte(v)
+	}
+	s.Allow(secretsprovider.FromMap(values))
+
+	go func() {
+		err := s.Run(ctx, func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+			return cli.DialHijack(ctx, "/session", proto, meta)
+		})
+		if err != nil {
+			common.Logger(ctx).Debugf("buildkit session ended: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// buildKitProgress logs the BuildKit status updates of a build, every vertex
+// is reported once when it starts and once when it is done
+type buildKitProgress struct {
+	started   map[string]bool
+	completed map[string]bool
+}
+
+func (p *buildKitProgress) handle(logger logrus.FieldLogger, isError bool, aux json.RawMessage) error {
+	var dt []byte
+	if err := json.Unmarshal(aux, &dt); err != nil {
+		return err
+	}
+	var resp controlapi.StatusResponse
+	if err := proto.Unmarshal(dt, &resp); err != nil {
+		return err
+	}
+	if p.started == nil {
+		p.started = map[string]bool{}
+		p.completed = map[string]bool{}
+	}
+
+	for _, v := range resp.Vertexes {
+		switch {
+		case v.Error != "" && !p.completed[v.Digest]:
+			p.completed[v.Digest] = true
+			writeLog(logger, isError, "%s :: ERROR: %s\n", v.Name, v.Error)
+		case v.Cached && !p.completed[v.Digest]:
+			p.completed[v.Digest] = true
+			writeLog(logger, isError, "%s :: CACHED\n", v.Name)
+		case v.Completed != nil && !p.completed[v.Digest]:
+			p.completed[v.Digest] = true
+			duration := v.Completed.AsTime().Sub(v.Started.AsTime())
+			writeLog(logger, isError, "%s :: DONE %.1fs\n", v.Name, duration.Seconds())
+		case v.Started != nil && !p.started[v.Digest]:
+			p.started[v.Digest] = true
+			writeLog(logger, isError, "%s\n", v.Name)
+		}
+	}
+	for _, l := range resp.Logs {
+		for _, line := range strings.SplitAfter(string(l.Msg), "\n") {
+			if line != "" {
+				writeLog(logger, isError, "%s", line)
+			}
+		}
+	}
+	for _, w := range resp.Warnings {
+		writeLog(logger, isError, "WARNING: %s\n", w.Short)
+	}
+	return nil
+}
@@ -18,8 +18,13 @@ type dockerMessage struct {
 	ErrorDetail struct {
 		Message string
 	}
-	Status   string `json:"status"`
-	Progress string `json:"progress"`
+	Status         string `json:"status"`
+	Progress       string `json:"progress"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Aux *json.RawMessage `json:"aux"`
 }
 
 const logPrefix = "  \U0001F433  "
@@ -32,6 +37,7 @@ func logDockerResponse(logger logrus.FieldLogger, dockerResponse io.ReadCloser,
 
 	scanner := bufio.NewScanner(dockerResponse)
 	msg := dockerMessage{}
+	progress := buildKitProgress{}
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
@@ -42,6 +48,9 @@ func logDockerResponse(logger logrus.FieldLogger, dockerResponse io.ReadCloser,
 		msg.ErrorDetail.Message = ""
 		msg.Status = ""
 		msg.Progress = ""
+		msg.ProgressDetail.Current = 0
+		msg.ProgressDetail.Total = 0
+		msg.Aux = nil
 
 		if err := json.Unmarshal(line, &msg); err != nil {
 			writeLog(logger, false, "Unable to unmarshal line [%s] ==> %v", string(line), err)
@@ -58,7 +67,11 @@ func logDockerResponse(logger logrus.FieldLogger, dockerResponse io.ReadCloser,
 			return errors.New(msg.Error)
 		}
 
-		if msg.Status != "" {
+		if msg.ID == buildKitTraceID && msg.Aux != nil {
+			if err := progress.handle(logger, isError, *msg.Aux); err != nil {
+				writeLog(logger, false, "Unable to decode buildkit status: %v", err)
+			}
+		} else if msg.Status != "" {
 			if msg.Progress != "" {
 				writeLog(logger, isError, "%s :: %s :: %s\n", msg.Status, msg.ID, msg.Progress)
 			} else {
@@ -66,7 +79,7 @@ func logDockerResponse(logger logrus.FieldLogger, dockerResponse io.ReadCloser,
 			}
 		} else if msg.Stream != "" {
 			writeLog(logger, isError, "%s", msg.Stream)
-		} else {
+		} else if msg.Aux == nil {
 			writeLog(logger, false, "Unable to handle line: %s", string(line))
 		}
 	}
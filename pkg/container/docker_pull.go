@@ -7,15 +7,25 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
 
 	"github.com/actions-oss/act-cli/pkg/common"
 )
 
+const (
+	defaultPullRetryBackoff = time.Second
+	maxPullRetryBackoff     = 30 * time.Second
+)
+
 // NewDockerPullExecutor function to create a run executor for the container
 func NewDockerPullExecutor(input NewDockerPullExecutorInput) common.Executor {
 	return func(ctx context.Context) error {
@@ -52,26 +62,122 @@ func NewDockerPullExecutor(input NewDockerPullExecutorInput) common.Executor {
 		}
 		defer cli.Close()
 
-		imagePullOptions, err := getImagePullOptions(ctx, input)
-		if err != nil {
-			return err
+		if mirrorRef, ok := mirrorImage(imageRef, input.ImagePull.Mirrors); ok {
+			logger.Infof("%sdocker pull %s from mirror %s", logPrefix, imageRef, mirrorRef)
+			mirrorInput := input
+			mirrorInput.Image = mirrorRef
+			mirrorInput.Username = ""
+			mirrorInput.Password = ""
+			err := pullImageWithRetry(ctx, cli, mirrorRef, mirrorInput)
+			if err == nil {
+				return cli.ImageTag(ctx, mirrorRef, imageRef)
+			} else if ctx.Err() != nil {
+				return err
+			}
+			logger.Warnf("pulling image '%v' from mirror '%v' failed, falling back to the registry: %v", imageRef, mirrorRef, err)
 		}
 
-		reader, err := cli.ImagePull(ctx, imageRef, imagePullOptions)
-
-		_ = logDockerResponse(logger, reader, err != nil)
-		if err != nil {
-			if imagePullOptions.RegistryAuth != "" && strings.Contains(err.Error(), "unauthorized") {
-				logger.Errorf("pulling image '%v' (%s) failed with credentials %s retrying without them, please check for stale docker config files", imageRef, input.Platform, err.Error())
-				imagePullOptions.RegistryAuth = ""
-				reader, err = cli.ImagePull(ctx, imageRef, imagePullOptions)
+		return pullImageWithRetry(ctx, cli, imageRef, input)
+	}
+}
 
-				_ = logDockerResponse(logger, reader, err != nil)
-			}
+// pullImageWithRetry pulls an image and retries transient registry errors with an exponential backoff
+func pullImageWithRetry(ctx context.Context, cli client.APIClient, imageRef string, input NewDockerPullExecutorInput) error {
+	logger := common.Logger(ctx)
+	backoff := input.ImagePull.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultPullRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := pullImage(ctx, cli, imageRef, input)
+		if err == nil || attempt >= input.ImagePull.Retries || !isRetryablePullError(ctx, err) {
 			return err
 		}
-		return nil
+		logger.Warnf("pulling image '%v' failed (attempt %d of %d), retrying in %s: %v", imageRef, attempt+1, input.ImagePull.Retries+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxPullRetryBackoff)
+	}
+}
+
+func pullImage(ctx context.Context, cli client.APIClient, imageRef string, input NewDockerPullExecutorInput) error {
+	logger := common.Logger(ctx)
+	imagePullOptions, err := getImagePullOptions(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	reader, err := cli.ImagePull(ctx, imageRef, imagePullOptions)
+	if err == nil {
+		err = logPullResponse(logger, imageRef, reader)
+	}
+	if err != nil && imagePullOptions.RegistryAuth != "" && strings.Contains(err.Error(), "unauthorized") {
+		logger.Errorf("pulling image '%v' (%s) failed with credentials %s retrying without them, please check for stale docker config files", imageRef, input.Platform, err.Error())
+		imagePullOptions.RegistryAuth = ""
+		reader, err = cli.ImagePull(ctx, imageRef, imagePullOptions)
+		if err == nil {
+			err = logPullResponse(logger, imageRef, reader)
+		}
+	}
+	return err
+}
+
+var (
+	// pullErrorStatus finds the HTTP status of a registry error in the progress of a pull, which only has its text,
+	// like "received unexpected HTTP status: 503 Service Unavailable" or "...": 502 Bad Gateway
+	pullErrorStatus = regexp.MustCompile(`(?i)(?:HTTP status|status code):?\s+(\d{3})\b|:\s(\d{3})\s(?:Internal Server Error|Bad Gateway|Service Unavailable|Gateway Timeout)\b`)
+	// transientPullError matches the errors of the connection to the registry
+	transientPullError = regexp.MustCompile(`(?i)TLS handshake timeout|i/o timeout|Client\.Timeout exceeded|deadline exceeded|connection reset by peer|unexpected EOF`)
+)
+
+// isRetryablePullError reports server errors of the registry and timeouts
+func isRetryablePullError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if cerrdefs.IsUnavailable(err) || cerrdefs.IsDeadlineExceeded(err) {
+		return true
+	}
+	if status, ok := pullErrorStatusCode(err); ok {
+		return status >= 500 && status < 600
+	}
+	return transientPullError.MatchString(err.Error())
+}
+
+// pullErrorStatusCode returns the HTTP status of the registry which an error reports
+func pullErrorStatusCode(err error) (int, bool) {
+	match := pullErrorStatus.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	status, convErr := strconv.Atoi(match[1] + match[2])
+	return status, convErr == nil
+}
+
+// mirrorImage replaces the registry host of an image by its mirror
+func mirrorImage(imageRef string, mirrors map[string]string) (string, bool) {
+	if len(mirrors) == 0 {
+		return "", false
+	}
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", false
+	}
+	mirror, ok := mirrors[reference.Domain(named)]
+	if !ok {
+		return "", false
+	}
+	mirrored := strings.TrimSuffix(mirror, "/") + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		mirrored += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		mirrored += "@" + digested.Digest().String()
 	}
+	return mirrored, true
 }
 
 func getImagePullOptions(ctx context.Context, input NewDockerPullExecutorInput) (image.PullOptions, error) {
@@ -0,0 +1,130 @@
+//go:build !(WITHOUT_DOCKER || !(linux || darwin || windows || netbsd))
+
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/sirupsen/logrus"
+)
+
+const pullProgressInterval = 2 * time.Second
+
+type layerProgress struct {
+	current int64
+	total   int64
+	done    bool
+}
+
+// pullProgress aggregates the progress messages of all layers of an image pull
+type pullProgress struct {
+	logger  logrus.FieldLogger
+	image   string
+	layers  map[string]*layerProgress
+	order   []string
+	lastLog time.Time
+	now     func() time.Time
+}
+
+func newPullProgress(logger logrus.FieldLogger, image string) *pullProgress {
+	return &pullProgress{
+		logger: logger,
+		image:  image,
+		layers: map[string]*layerProgress{},
+		now:    time.Now,
+	}
+}
+
+func (p *pullProgress) layer(id string) *layerProgress {
+	l, ok := p.layers[id]
+	if !ok {
+		l = &layerProgress{}
+		p.layers[id] = l
+		p.order = append(p.order, id)
+	}
+	return l
+}
+
+func (p *pullProgress) handle(msg *dockerMessage) {
+	switch msg.Status {
+	case "Pulling fs layer", "Waiting":
+		p.layer(msg.ID)
+	case "Downloading":
+		l := p.layer(msg.ID)
+		l.current = msg.ProgressDetail.Current
+		if msg.ProgressDetail.Total > 0 {
+			l.total = msg.ProgressDetail.Total
+		}
+		if p.now().Sub(p.lastLog) >= pullProgressInterval {
+			p.log()
+		}
+	case "Verifying Checksum", "Download complete":
+		l := p.layer(msg.ID)
+		l.current = l.total
+	case "Extracting":
+		p.layer(msg.ID)
+	case "Pull complete", "Already exists":
+		l := p.layer(msg.ID)
+		l.current = l.total
+		l.done = true
+		p.logger.Debugf("%s :: %s\n", msg.Status, msg.ID)
+	default:
+		if strings.HasPrefix(msg.Status, "Status:") {
+			if len(p.layers) > 0 {
+				p.log()
+			}
+			p.logger.Infof("%s%s\n", logPrefix, msg.Status)
+		} else if msg.Status != "" {
+			p.logger.Debugf("%s :: %s\n", msg.Status, msg.ID)
+		}
+	}
+}
+
+// log prints the aggregated progress of all layers
+func (p *pullProgress) log() {
+	p.lastLog = p.now()
+	var done int
+	var current, total int64
+	for _, id := range p.order {
+		l := p.layers[id]
+		if l.done {
+			done++
+		}
+		current += l.current
+		total += l.total
+	}
+	p.logger.Infof("%spulling %s: %d/%d layers complete, %s/%s downloaded\n", logPrefix, p.image, done, len(p.layers), units.HumanSize(float64(current)), units.HumanSize(float64(total)))
+}
+
+// logPullResponse logs the aggregated progress of an image pull and returns
+// the error reported in the stream
+func logPullResponse(logger logrus.FieldLogger, image string, dockerResponse io.ReadCloser) error {
+	if dockerResponse == nil {
+		return nil
+	}
+	defer dockerResponse.Close()
+
+	progress := newPullProgress(logger, image)
+	decoder := json.NewDecoder(dockerResponse)
+	for {
+		msg := dockerMessage{}
+		if err := decoder.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		if msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		progress.handle(&msg)
+	}
+}
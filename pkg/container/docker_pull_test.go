@@ -2,11 +2,16 @@ package container
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/cli/cli/config"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	assert "github.com/stretchr/testify/assert"
 )
 
@@ -59,3 +64,79 @@ func TestGetImagePullOptions(t *testing.T) {
 	assert.Nil(t, err, "Failed to create ImagePullOptions")
 	assert.Equal(t, "eyJ1c2VybmFtZSI6InVzZXJuYW1lIiwicGFzc3dvcmQiOiJwYXNzd29yZFxuIiwic2VydmVyYWRkcmVzcyI6Imh0dHBzOi8vaW5kZXguZG9ja2VyLmlvL3YxLyJ9", options.RegistryAuth, "RegistryAuth should be taken from local docker config")
 }
+
+func TestMirrorImage(t *testing.T) {
+	mirrors := map[string]string{
+		"docker.io": "registry.local:5000",
+		"ghcr.io":   "ghcr-mirror.local/",
+	}
+	tables := []struct {
+		imageIn  string
+		imageOut string
+		mirrored bool
+	}{
+		{"ubuntu", "registry.local:5000/library/ubuntu", true},
+		{"docker.io/library/node:18", "registry.local:5000/library/node:18", true},
+		{"cibuilds/hugo:0.53", "registry.local:5000/cibuilds/hugo:0.53", true},
+		{"ghcr.io/owner/image:v1", "ghcr-mirror.local/owner/image:v1", true},
+		{"quay.io/owner/image:v1", "", false},
+	}
+
+	for _, table := range tables {
+		imageOut, mirrored := mirrorImage(table.imageIn, mirrors)
+		assert.Equal(t, table.mirrored, mirrored, table.imageIn)
+		assert.Equal(t, table.imageOut, imageOut, table.imageIn)
+	}
+
+	_, mirrored := mirrorImage("ubuntu", nil)
+	assert.False(t, mirrored)
+}
+
+func TestIsRetryablePullError(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, isRetryablePullError(ctx, errors.New("received unexpected HTTP status: 503 Service Unavailable")))
+	assert.True(t, isRetryablePullError(ctx, errors.New("Get \"https://registry-1.docker.io/v2/\": net/http: TLS handshake timeout")))
+	assert.True(t, isRetryablePullError(ctx, errors.New("read tcp 10.0.0.1:443: connection reset by peer")))
+	assert.False(t, isRetryablePullError(ctx, errors.New("pull access denied for foo, repository does not exist")))
+	assert.False(t, isRetryablePullError(ctx, errors.New("manifest unknown")))
+	assert.True(t, isRetryablePullError(ctx, errors.New(`Head "https://ghcr.io/v2/owner/image/manifests/latest": 502 Bad Gateway`)))
+	assert.True(t, isRetryablePullError(ctx, cerrdefs.ErrUnavailable))
+	assert.False(t, isRetryablePullError(ctx, errors.New("received unexpected HTTP status: 404 Not Found")))
+	// the numbers of tags and digests are no status of the registry
+	assert.False(t, isRetryablePullError(ctx, errors.New("manifest for node:500 not found: manifest unknown")))
+	assert.False(t, isRetryablePullError(ctx, errors.New("pull access denied for app:504-timeout, repository does not exist")))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, isRetryablePullError(canceled, errors.New("Gateway Timeout")))
+}
+
+func TestLogPullResponse(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"status":"Pulling from library/alpine","id":"latest"}`,
+		`{"status":"Pulling fs layer","id":"a"}`,
+		`{"status":"Pulling fs layer","id":"b"}`,
+		`{"status":"Already exists","id":"a"}`,
+		`{"status":"Downloading","progressDetail":{"current":512,"total":2048},"id":"b"}`,
+		`{"status":"Download complete","id":"b"}`,
+		`{"status":"Pull complete","id":"b"}`,
+		`{"status":"Status: Downloaded newer image for alpine:latest"}`,
+	}, "\n")
+
+	logger, hook := test.NewNullLogger()
+	err := logPullResponse(logger, "alpine", io.NopCloser(strings.NewReader(stream)))
+	assert.NoError(t, err)
+
+	messages := []string{}
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{
+		logPrefix + "pulling alpine: 1/2 layers complete, 512B/2.048kB downloaded\n",
+		logPrefix + "pulling alpine: 2/2 layers complete, 2.048kB/2.048kB downloaded\n",
+		logPrefix + "Status: Downloaded newer image for alpine:latest\n",
+	}, messages)
+
+	err = logPullResponse(logger, "alpine", io.NopCloser(strings.NewReader(`{"errorDetail":{"message":"toomanyrequests"},"error":"toomanyrequests"}`)))
+	assert.EqualError(t, err, "toomanyrequests")
+}
@@ -83,7 +83,7 @@ func (cr *containerReference) Start(attach bool) common.Executor {
 			common.NewPipelineExecutor(
 				cr.connect(),
 				cr.find(),
-				cr.attach().IfBool(attach),
+				cr.attach().IfBool(attach || cr.input.StreamLogs),
 				cr.start(),
 				cr.wait().IfBool(attach),
 				cr.tryReadUID(),
@@ -109,6 +109,7 @@ func (cr *containerReference) Pull(forcePull bool) common.Executor {
 				Platform:  cr.input.Platform,
 				Username:  cr.input.Username,
 				Password:  cr.input.Password,
+				ImagePull: cr.input.ImagePull,
 			}),
 		)
 }
@@ -193,6 +194,50 @@ func (cr *containerReference) GetHealth(ctx context.Context) Health {
 	return HealthUnHealthy
 }
 
+func (cr *containerReference) GetState(ctx context.Context) (*State, error) {
+	if cr.cli == nil || cr.id == "" {
+		return nil, fmt.Errorf("container %s has not been created", cr.input.Name)
+	}
+	resp, err := cr.cli.ContainerInspect(ctx, cr.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	state := &State{ID: resp.ID, Ports: map[string]string{}}
+	if resp.NetworkSettings != nil {
+		for port, bindings := range resp.NetworkSettings.Ports {
+			for _, binding := range bindings {
+				if binding.HostPort != "" {
+					state.Ports[port.Port()] = binding.HostPort
+					break
+				}
+			}
+		}
+	}
+	if resp.State == nil {
+		return state, nil
+	}
+	state.Status = resp.State.Status
+	state.Running = resp.State.Running
+	state.ExitCode = resp.State.ExitCode
+	state.OOMKilled = resp.State.OOMKilled
+	state.Error = resp.State.Error
+	if resp.State.Health != nil {
+		state.Health = resp.State.Health.Status
+		for _, probe := range resp.State.Health.Log {
+			if probe == nil {
+				continue
+			}
+			state.HealthHistory = append(state.HealthHistory, HealthCheckResult{
+				Start:    probe.Start,
+				End:      probe.End,
+				ExitCode: probe.ExitCode,
+				Output:   probe.Output,
+			})
+		}
+	}
+	return state, nil
+}
+
 func (cr *containerReference) ReplaceLogWriter(stdout io.Writer, stderr io.Writer) (io.Writer, io.Writer) {
 	out := cr.input.Stdout
 	err := cr.input.Stderr
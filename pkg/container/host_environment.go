@@ -464,6 +464,10 @@ func (e *HostEnvironment) GetHealth(_ context.Context) Health {
 	return HealthHealthy
 }
 
+func (e *HostEnvironment) GetState(_ context.Context) (*State, error) {
+	return &State{Status: "running", Running: true}, nil
+}
+
 func (e *HostEnvironment) ReplaceLogWriter(stdout io.Writer, _ io.Writer) (io.Writer, io.Writer) {
 	org := e.StdOut
 	e.StdOut = stdout
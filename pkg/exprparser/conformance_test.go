@@ -0,0 +1,34 @@
+package exprparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/internal/eval/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	corpus, err := conformance.Load()
+	require.NoError(t, err)
+	env := &EvaluationEnvironment{
+		CtxData: corpus.Contexts,
+	}
+	interpreter := NewInterpeter(env, Config{})
+	for _, c := range corpus.Cases {
+		got, err := interpreter.Evaluate(c.Expr, DefaultStatusCheckNone)
+		if c.Error != "" {
+			assert.ErrorContains(t, err, c.Error, c.Expr)
+			continue
+		}
+		if !assert.NoError(t, err, c.Expr) {
+			continue
+		}
+		got, err = conformance.Normalize(got)
+		require.NoError(t, err)
+		want, err := conformance.Normalize(c.Result)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, c.Expr)
+	}
+}
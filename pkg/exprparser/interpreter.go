@@ -41,6 +41,8 @@ type Config struct {
 	Run        *model.Run
 	WorkingDir string
 	Context    string
+	// Host selects the functions of the forge which runs the workflow, defaults to GitHub
+	Host string
 }
 
 type DefaultStatusCheck int
@@ -110,6 +112,9 @@ func toRaw(left reflect.Value) any {
 				nv := toRaw(iter.Value())
 				if nv != nil {
 					m[key.String()] = nv
+				} else if left.Type().Elem().Kind() == reflect.Interface && iter.Value().IsNil() {
+					// keep the explicit nulls of typed values like a matrix from fromJSON
+					m[key.String()] = nil
 				}
 			}
 		}
@@ -204,15 +209,7 @@ func (impl *interperterImpl) Evaluate(input string, defaultStatusCheck DefaultSt
 		}
 	}
 
-	functions := impl.GetFunctions()
-
-	vars := impl.GetVariables()
-
-	ctx := eval.EvaluationContext{
-		Functions: functions,
-		Variables: vars,
-	}
-	evaluator := eval.NewEvaluator(&ctx)
+	evaluator := impl.newEvaluator(nil)
 	res, err := evaluator.Evaluate(exprNode)
 	if err != nil {
 		return nil, err
@@ -220,15 +217,38 @@ func (impl *interperterImpl) Evaluate(input string, defaultStatusCheck DefaultSt
 	return evaluator.ToRaw(res)
 }
 
+// NewEvaluator creates the evaluator used by the interpreter for an environment,
+// every evaluated node is written to trace if it is not nil
+func NewEvaluator(env *EvaluationEnvironment, config Config, trace eval.TraceWriter) *eval.Evaluator {
+	impl := &interperterImpl{
+		env:    env,
+		config: config,
+	}
+	return impl.newEvaluator(trace)
+}
+
+func (impl *interperterImpl) newEvaluator(trace eval.TraceWriter) *eval.Evaluator {
+	return eval.NewEvaluator(&eval.EvaluationContext{
+		Functions: impl.GetFunctions(),
+		Variables: impl.GetVariables(),
+		Trace:     trace,
+	})
+}
+
 func (impl *interperterImpl) GetFunctions() eval.CaseInsensitiveObject[eval.Function] {
-	functions := eval.GetFunctions()
+	return impl.GetRegistry().Functions()
+}
+
+// GetRegistry returns the functions of the host with the functions which depend on the job
+func (impl *interperterImpl) GetRegistry() *eval.Registry {
+	registry := eval.HostRegistry(impl.config.Host)
 	if impl.env.HashFiles != nil {
-		functions["hashfiles"] = &externalFunc{impl.env.HashFiles}
+		registry.Register("hashFiles", 1, eval.MaxArgs, &externalFunc{impl.env.HashFiles})
 	}
-	functions["always"] = &externalFunc{func(_ []reflect.Value) (interface{}, error) {
+	registry.Register("always", 0, 0, &externalFunc{func(_ []reflect.Value) (interface{}, error) {
 		return impl.always()
-	}}
-	functions["success"] = &externalFunc{func(_ []reflect.Value) (interface{}, error) {
+	}})
+	registry.Register("success", 0, 0, &externalFunc{func(_ []reflect.Value) (interface{}, error) {
 		if impl.config.Context == "job" {
 			return impl.jobSuccess()
 		}
@@ -236,8 +256,8 @@ func (impl *interperterImpl) GetFunctions() eval.CaseInsensitiveObject[eval.Func
 			return impl.stepSuccess()
 		}
 		return nil, fmt.Errorf("context '%s' must be one of 'job' or 'step'", impl.config.Context)
-	}}
-	functions["failure"] = &externalFunc{func(_ []reflect.Value) (interface{}, error) {
+	}})
+	registry.Register("failure", 0, 0, &externalFunc{func(_ []reflect.Value) (interface{}, error) {
 		if impl.config.Context == "job" {
 			return impl.jobFailure()
 		}
@@ -245,15 +265,19 @@ func (impl *interperterImpl) GetFunctions() eval.CaseInsensitiveObject[eval.Func
 			return impl.stepFailure()
 		}
 		return nil, fmt.Errorf("context '%s' must be one of 'job' or 'step'", impl.config.Context)
-	}}
-	functions["cancelled"] = &externalFunc{func(_ []reflect.Value) (interface{}, error) {
+	}})
+	registry.Register("cancelled", 0, 0, &externalFunc{func(_ []reflect.Value) (interface{}, error) {
 		return impl.cancelled()
-	}}
-	return functions
+	}})
+	return registry
 }
 
 func (impl *interperterImpl) GetVariables() eval.ReadOnlyObject[any] {
 	githubCtx := toRawObj(reflect.ValueOf(impl.env.Github))
+	if githubCtx == nil {
+		// the github context may be provided only by CtxData
+		githubCtx = map[string]any{}
+	}
 	var env any
 	if impl.env.EnvCS {
 		env = eval.CaseSensitiveObject[any](toRawObj(reflect.ValueOf(impl.env.Env)))
@@ -274,6 +298,12 @@ func (impl *interperterImpl) GetVariables() eval.ReadOnlyObject[any] {
 		"jobs":     toRawObj(reflect.ValueOf(impl.env.Jobs)),
 		"inputs":   toRawObj(reflect.ValueOf(impl.env.Inputs)),
 	}
+	// forges like Gitea provide the github context with their own name as well
+	if forge, err := model.GetForge(impl.config.Host); err == nil {
+		for _, alias := range forge.ContextAliases {
+			vars[alias] = githubCtx
+		}
+	}
 	for name, cd := range impl.env.CtxData {
 		lowerName := strings.ToLower(name)
 		if serverPayload, ok := cd.(map[string]interface{}); ok {
@@ -547,7 +547,7 @@ func TestContexts(t *testing.T) {
 		{input: "github.custom-field", expected: "custom-value", name: "github-context", ctxdata: map[string]interface{}{"github": map[string]interface{}{"custom-field": "custom-value"}}},
 		{input: "github.event.commits[0].message", expected: nil, name: "github-context-noexist-prop"},
 		{input: "fromjson('{\"commits\":[]}').commits[0].message", expected: nil, name: "github-context-noexist-prop"},
-		{input: "github.event.pull_request.labels.*.name", expected: nil, name: "github-context-noexist-prop"},
+		{input: "github.event.pull_request.labels.*.name", expected: []interface{}{}, name: "github-context-noexist-prop"},
 		{input: "env.TEST", expected: "value", name: "env-context"},
 		{input: "env.TEST", expected: "value", name: "env-context", caseSensitiveEnv: true},
 		{input: "env.test", expected: nil, name: "env-context", caseSensitiveEnv: true},
@@ -555,6 +555,7 @@ func TestContexts(t *testing.T) {
 		{input: "env['test']", expected: nil, name: "env-context", caseSensitiveEnv: true},
 		{input: "env.test", expected: "value", name: "env-context"},
 		{input: "job.status", expected: "success", name: "job-context"},
+		{input: "toJSON(matrix)", expected: "{\n  \"null\": null,\n  \"os\": \"Linux\"\n}", name: "matrix-context-null"},
 		{input: "steps.step-id.outputs.name", expected: "value", name: "steps-context"},
 		{input: "steps.step-id.conclusion", expected: "success", name: "steps-context-conclusion"},
 		{input: "steps.step-id.conclusion && true", expected: true, name: "steps-context-conclusion"},
@@ -621,7 +622,8 @@ func TestContexts(t *testing.T) {
 			"fail-fast": true,
 		},
 		Matrix: map[string]interface{}{
-			"os": "Linux",
+			"os":   "Linux",
+			"null": nil,
 		},
 		Needs: map[string]Needs{
 			"job-id": {
@@ -654,3 +656,22 @@ func TestContexts(t *testing.T) {
 		})
 	}
 }
+
+func TestForgeContextAliases(t *testing.T) {
+	env := &EvaluationEnvironment{
+		Github: &model.GithubContext{
+			Sha: "abc",
+		},
+	}
+
+	output, err := NewInterpeter(env, Config{Host: model.ForgeGitea}).Evaluate("gitea.sha", DefaultStatusCheckNone)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", output)
+
+	output, err = NewInterpeter(env, Config{Host: model.ForgeForgejo}).Evaluate("forge.sha", DefaultStatusCheckNone)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", output)
+
+	_, err = NewInterpeter(env, Config{}).Evaluate("gitea.sha", DefaultStatusCheckNone)
+	assert.EqualError(t, err, "undefined variable gitea")
+}
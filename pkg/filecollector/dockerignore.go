@@ -0,0 +1,80 @@
+package filecollector
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+type dockerIgnoreMatcher struct {
+	pm   *patternmatcher.PatternMatcher
+	keep map[string]bool
+}
+
+// NewDockerIgnoreMatcher creates a matcher for the FileCollector from .dockerignore patterns,
+// the paths in keep are never excluded
+func NewDockerIgnoreMatcher(patterns []string, keep ...string) (gitignore.Matcher, error) {
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, err
+	}
+	m := &dockerIgnoreMatcher{
+		pm:   pm,
+		keep: map[string]bool{},
+	}
+	for _, k := range keep {
+		m.keep[filepath.ToSlash(filepath.Clean(k))] = true
+	}
+	return m, nil
+}
+
+// ReadDockerIgnore reads the .dockerignore patterns of a build context directory,
+// a missing file results in no patterns
+func ReadDockerIgnore(contextDir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseDockerIgnore(f)
+}
+
+// ParseDockerIgnore parses .dockerignore patterns
+func ParseDockerIgnore(r io.Reader) ([]string, error) {
+	return ignorefile.ReadAll(r)
+}
+
+func (m *dockerIgnoreMatcher) Match(path []string, isDir bool) bool {
+	rel := strings.Join(path, "/")
+	if rel == "" || m.keep[rel] {
+		return false
+	}
+	if isDir {
+		// keep directories containing a kept file
+		for k := range m.keep {
+			if strings.HasPrefix(k, rel+"/") {
+				return false
+			}
+		}
+	}
+	matched, err := m.pm.MatchesOrParentMatches(rel)
+	if err != nil {
+		return false
+	}
+	if matched && isDir && m.pm.Exclusions() {
+		// exclusions like !dir/file may re-include files below an excluded directory
+		for _, p := range m.pm.Patterns() {
+			if p.Exclusion() && strings.HasPrefix(p.String(), rel+"/") {
+				return false
+			}
+		}
+	}
+	return matched
+}
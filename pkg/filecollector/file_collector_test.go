@@ -170,3 +170,30 @@ func TestSymlinks(t *testing.T) {
 	assert.Equal(t, ".env", files["test.env"].Linkname)
 	assert.ErrorIs(t, err, io.EOF, "tar must be read cleanly to EOF")
 }
+
+func TestDockerIgnoreMatcher(t *testing.T) {
+	patterns, err := ParseDockerIgnore(strings.NewReader("# comment\nnode_modules\n*.log\nbuild\n!build/keep.txt\nDockerfile\n"))
+	assert.NoError(t, err)
+
+	matcher, err := NewDockerIgnoreMatcher(patterns, "Dockerfile", ".dockerignore")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"node_modules", true, true},
+		{"node_modules/pkg/index.js", false, true},
+		{"debug.log", false, true},
+		{"src/debug.log", false, false},
+		{"src/main.go", false, false},
+		{"build", true, false},
+		{"build/out.bin", false, true},
+		{"build/keep.txt", false, false},
+		{"Dockerfile", false, false},
+		{".dockerignore", false, false},
+	} {
+		assert.Equal(t, tc.ignored, matcher.Match(strings.Split(tc.path, "/"), tc.isDir), tc.path)
+	}
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+)
+
+// Edit replaces the lines from Start up to End of a file with Lines, the lines are
+// counted from 0 and an empty range inserts Lines before Start. Only the edited lines
+// change, so comments and the formatting of the rest of the file are preserved.
+type Edit struct {
+	Start int
+	End   int
+	Lines []string
+}
+
+// ApplyFixes applies the fixes of the findings to the content of their file and
+// returns the new content and the number of applied fixes, overlapping fixes are skipped
+func ApplyFixes(content []byte, findings []Finding) ([]byte, int) {
+	var edits []*Edit
+	for _, f := range findings {
+		if f.Fix != nil {
+			edits = append(edits, f.Fix)
+		}
+	}
+	if len(edits) == 0 {
+		return content, 0
+	}
+	// apply from the end of the file, so the line numbers of the remaining edits stay valid
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start > edits[j].Start
+	})
+	lines := strings.Split(string(content), "\n")
+	applied := 0
+	// limit is the start of the last applied edit, the following edits must end before it
+	limit := len(lines)
+	for _, e := range edits {
+		if e.Start < 0 || e.End < e.Start || e.End > limit {
+			continue
+		}
+		lines = append(lines[:e.Start], append(append([]string{}, e.Lines...), lines[e.End:]...)...)
+		limit = e.Start
+		applied++
+	}
+	return []byte(strings.Join(lines, "\n")), applied
+}
@@ -0,0 +1,238 @@
+// Package lint checks workflows for problems beyond the schema validation
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func parseSeverity(s string) (Severity, bool, error) {
+	switch strings.ToLower(s) {
+	case "error":
+		return SeverityError, true, nil
+	case "warning":
+		return SeverityWarning, true, nil
+	case "info":
+		return SeverityInfo, true, nil
+	case "off":
+		return SeverityInfo, false, nil
+	}
+	return SeverityInfo, false, fmt.Errorf("unknown severity %s, expected one of error, warning, info or off", s)
+}
+
+// Rule checks a workflow and reports its findings to the context
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	Check       func(ctx *Context)
+}
+
+// Finding is a problem found by a rule
+type Finding struct {
+	Rule     string
+	Severity Severity
+	File     string
+	Job      string
+	Line     int
+	Column   int
+	Message  string
+	// Fix rewrites the file to resolve the finding, it is nil if the finding can't be fixed safely
+	Fix *Edit
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+}
+
+// Context provides a workflow to the rules
+type Context struct {
+	File     string
+	Workflow *model.Workflow
+	// Root is the mapping of the workflow file
+	Root   *yaml.Node
+	Lines  []string
+	Config *Config
+
+	rule     *Rule
+	findings []Finding
+}
+
+// Report adds a finding of the current rule at node
+func (ctx *Context) Report(node *yaml.Node, job string, message string, fix *Edit) {
+	ctx.ReportAt(node.Line, node.Column, job, message, fix)
+}
+
+// ReportAt adds a finding of the current rule at a line and column, which are counted from 1
+func (ctx *Context) ReportAt(line, column int, job string, message string, fix *Edit) {
+	ctx.findings = append(ctx.findings, Finding{
+		Rule:     ctx.rule.ID,
+		Severity: ctx.rule.Severity,
+		File:     ctx.File,
+		Job:      job,
+		Line:     line,
+		Column:   column,
+		Message:  message,
+		Fix:      fix,
+	})
+}
+
+// Config configures the severity of the rules and suppresses findings
+type Config struct {
+	// Rules overrides the severity of rules by id, off disables a rule
+	Rules map[string]string `yaml:"rules"`
+	// Ignore suppresses findings
+	Ignore []Suppression `yaml:"ignore"`
+	// TrustedOwners are the owners of actions which may be used without pinning them to a commit sha
+	TrustedOwners []string `yaml:"trusted-owners"`
+	// TimeoutMinutes is inserted by the fix of missing-timeout, defaults to the timeout of GitHub
+	TimeoutMinutes int `yaml:"timeout-minutes"`
+}
+
+// Suppression ignores the findings of a rule, optionally only in matching files or jobs
+type Suppression struct {
+	Rule string `yaml:"rule"`
+	// Path is a glob matched against the path and the name of the file
+	Path string `yaml:"path"`
+	Job  string `yaml:"job"`
+}
+
+func (s Suppression) matches(f Finding) bool {
+	if s.Rule != "" && s.Rule != "*" && s.Rule != f.Rule {
+		return false
+	}
+	if s.Job != "" && s.Job != f.Job {
+		return false
+	}
+	if s.Path != "" {
+		matchPath, _ := filepath.Match(s.Path, filepath.ToSlash(f.File))
+		matchName, _ := filepath.Match(s.Path, filepath.Base(f.File))
+		return matchPath || matchName
+	}
+	return true
+}
+
+// LoadConfig reads the config of the linter, a missing file results in the default config
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to read lint config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Linter runs the enabled rules against workflow files
+type Linter struct {
+	config *Config
+	rules  []*Rule
+}
+
+func New(config *Config) (*Linter, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	l := &Linter{config: config}
+	known := map[string]bool{}
+	for _, rule := range Rules() {
+		known[rule.ID] = true
+		r := *rule
+		if s, ok := config.Rules[r.ID]; ok {
+			severity, enabled, err := parseSeverity(s)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.ID, err)
+			}
+			if !enabled {
+				continue
+			}
+			r.Severity = severity
+		}
+		l.rules = append(l.rules, &r)
+	}
+	for id := range config.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown rule %s", id)
+		}
+	}
+	return l, nil
+}
+
+// Lint checks the content of a workflow file
+func (l *Linter) Lint(file string, content []byte) ([]Finding, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	workflow, err := model.ReadWorkflow(bytes.NewReader(content), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	ctx := &Context{
+		File:     file,
+		Workflow: workflow,
+		Root:     doc.Content[0],
+		Lines:    strings.Split(string(content), "\n"),
+		Config:   l.config,
+	}
+	for _, rule := range l.rules {
+		ctx.rule = rule
+		rule.Check(ctx)
+	}
+	findings := make([]Finding, 0, len(ctx.findings))
+	for _, f := range ctx.findings {
+		if !l.suppressed(f) {
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, nil
+}
+
+func (l *Linter) suppressed(f Finding) bool {
+	for _, s := range l.config.Ignore {
+		if s.matches(f) {
+			return true
+		}
+	}
+	return false
+}
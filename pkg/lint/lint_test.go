@@ -0,0 +1,280 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintRules(t *testing.T, config *Config, workflow string) []Finding {
+	l, err := New(config)
+	require.NoError(t, err)
+	findings, err := l.Lint("ci.yml", []byte(workflow))
+	require.NoError(t, err)
+	return findings
+}
+
+func ruleFindings(findings []Finding, rule string) []Finding {
+	var res []Finding
+	for _, f := range findings {
+		if f.Rule == rule {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func TestRules(t *testing.T) {
+	table := []struct {
+		rule     string
+		workflow string
+		messages []string
+	}{
+		{
+			rule: "unpinned-action",
+			workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: docker://alpine
+      - uses: ./local
+      - uses: owner/action@v1
+      - uses: owner/action/sub@0123456789abcdef0123456789abcdef01234567
+  call:
+    uses: owner/repo/.github/workflows/build.yml@main
+`,
+			messages: []string{
+				"owner/action@v1 is not pinned to a full length commit sha",
+				"owner/repo/.github/workflows/build.yml@main is not pinned to a full length commit sha",
+			},
+		},
+		{
+			rule: "script-injection",
+			workflow: `on: issues
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "${{ github.event.issue.title }}"
+          echo "${{ github.event.issue.number }}"
+      - uses: actions/github-script@v7
+        with:
+          script: console.log("${{ github.head_ref }}")
+      - env:
+          TITLE: ${{ github.event.issue.title }}
+        run: echo "$TITLE"
+`,
+			messages: []string{
+				"github.event.issue.title is potentially untrusted, pass it to the script through an environment variable",
+				"github.head_ref is potentially untrusted, pass it to the script through an environment variable",
+			},
+		},
+		{
+			rule: "deprecated-command",
+			workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "::set-output name=a::b"
+      - run: |
+          echo "::save-state name=a::b"
+          echo "::add-path::/bin"
+`,
+			messages: []string{
+				`the set-output command is deprecated, append name=value to "$GITHUB_OUTPUT" instead`,
+				`the save-state command is deprecated, append name=value to "$GITHUB_STATE" instead`,
+				"the add-path command is disabled, use the environment files instead",
+			},
+		},
+		{
+			rule: "missing-timeout",
+			workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  test:
+    runs-on: ubuntu-latest
+    timeout-minutes: 10
+    steps:
+      - run: exit 0
+  call:
+    uses: ./.github/workflows/build.yml
+`,
+			messages: []string{"job build has no timeout-minutes, it may run for up to 6 hours"},
+		},
+		{
+			rule: "unused-output",
+			workflow: `on:
+  workflow_call:
+    outputs:
+      exported:
+        value: ${{ jobs.build.outputs.exported }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    outputs:
+      used: ${{ steps.a.outputs.used }}
+      exported: ${{ steps.a.outputs.exported }}
+      unused: ${{ steps.a.outputs.unused }}
+    steps:
+      - id: a
+        run: exit 0
+  dump:
+    runs-on: ubuntu-latest
+    outputs:
+      all: x
+    steps:
+      - run: echo ${{ needs.build.outputs.used }}
+`,
+			messages: []string{
+				"output unused of job build is not used",
+				"output all of job dump is not used",
+			},
+		},
+		{
+			rule: "needs-cycle",
+			workflow: `on: push
+jobs:
+  a:
+    needs: c
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  b:
+    needs: a
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  c:
+    needs: [b]
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+`,
+			messages: []string{"jobs a -> c -> b -> a form a cycle, they never run"},
+		},
+		{
+			rule: "unreachable-job",
+			workflow: `on: push
+jobs:
+  disabled:
+    if: ${{ false }}
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  after:
+    needs: disabled
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  cleanup:
+    needs: disabled
+    if: always()
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  missing:
+    needs: [cleanup, unknown]
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+`,
+			messages: []string{
+				"job disabled never runs, its condition is always false",
+				"job after never runs, it needs the job disabled which never runs",
+				"job missing never runs, it needs the job unknown which does not exist",
+			},
+		},
+	}
+	for _, tt := range table {
+		t.Run(tt.rule, func(t *testing.T) {
+			var messages []string
+			for _, f := range ruleFindings(lintRules(t, nil, tt.workflow), tt.rule) {
+				messages = append(messages, f.Message)
+			}
+			assert.ElementsMatch(t, tt.messages, messages)
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: owner/action@v1
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: owner/action@v1
+`
+	findings := lintRules(t, &Config{
+		Rules:  map[string]string{"missing-timeout": "off", "unpinned-action": "error"},
+		Ignore: []Suppression{{Rule: "unpinned-action", Job: "test"}},
+	}, workflow)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "ci.yml:6:15: error: owner/action@v1 is not pinned to a full length commit sha [unpinned-action]", findings[0].String())
+
+	assert.Empty(t, lintRules(t, &Config{
+		Ignore:        []Suppression{{Path: "*.yml", Rule: "missing-timeout"}},
+		TrustedOwners: []string{"owner"},
+	}, workflow))
+
+	_, err := New(&Config{Rules: map[string]string{"unknown": "off"}})
+	assert.Error(t, err)
+	_, err = New(&Config{Rules: map[string]string{"needs-cycle": "fatal"}})
+	assert.Error(t, err)
+}
+
+func TestFix(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    # the build
+    runs-on: ubuntu-latest # comment
+    steps:
+      - run: echo "::set-output name=version::1.0"
+      - run: |
+          # comment
+          echo '::save-state name=pid::${{ github.run_id }}'
+          echo ::set-output name=list::a b
+          echo "::set-output name=multi::a%0Ab"
+      - shell: pwsh
+        run: echo "::set-output name=a::b"
+`
+	l, err := New(nil)
+	require.NoError(t, err)
+	findings, err := l.Lint("ci.yml", []byte(workflow))
+	require.NoError(t, err)
+	fixed, n := ApplyFixes([]byte(workflow), findings)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, `on: push
+jobs:
+  build:
+    timeout-minutes: 360
+    # the build
+    runs-on: ubuntu-latest # comment
+    steps:
+      - run: echo "version=1.0" >> "$GITHUB_OUTPUT"
+      - run: |
+          # comment
+          echo 'pid=${{ github.run_id }}' >> "$GITHUB_STATE"
+          echo list=a b >> "$GITHUB_OUTPUT"
+          echo "::set-output name=multi::a%0Ab"
+      - shell: pwsh
+        run: echo "::set-output name=a::b"
+`, string(fixed))
+
+	findings, err = l.Lint("ci.yml", fixed)
+	require.NoError(t, err)
+	assert.Len(t, ruleFindings(findings, "deprecated-command"), 2, "the commands which can't be fixed safely are left")
+	assert.Empty(t, ruleFindings(findings, "missing-timeout"))
+}
@@ -0,0 +1,504 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/schema"
+)
+
+// Rules returns all rules with their default severity
+func Rules() []*Rule {
+	return []*Rule{
+		{
+			ID:          "unpinned-action",
+			Severity:    SeverityWarning,
+			Description: "third-party actions and reusable workflows should be pinned to a full length commit sha",
+			Check:       checkUnpinnedActions,
+		},
+		{
+			ID:          "script-injection",
+			Severity:    SeverityError,
+			Description: "untrusted event data should be passed to scripts through environment variables instead of expressions",
+			Check:       checkScriptInjection,
+		},
+		{
+			ID:          "deprecated-command",
+			Severity:    SeverityWarning,
+			Description: "the set-output, save-state, set-env and add-path workflow commands are deprecated",
+			Check:       checkDeprecatedCommands,
+		},
+		{
+			ID:          "missing-timeout",
+			Severity:    SeverityInfo,
+			Description: "jobs should limit their run time with timeout-minutes",
+			Check:       checkMissingTimeout,
+		},
+		{
+			ID:          "unused-output",
+			Severity:    SeverityWarning,
+			Description: "outputs of jobs should be used by other jobs or by the outputs of the workflow",
+			Check:       checkUnusedOutputs,
+		},
+		{
+			ID:          "needs-cycle",
+			Severity:    SeverityError,
+			Description: "jobs must not depend on each other in a cycle",
+			Check:       checkNeedsCycles,
+		},
+		{
+			ID:          "unreachable-job",
+			Severity:    SeverityWarning,
+			Description: "jobs should be able to run",
+			Check:       checkUnreachableJobs,
+		},
+	}
+}
+
+// jobNode is a job of the workflow file
+type jobNode struct {
+	id  string
+	key *yaml.Node
+	job *yaml.Node
+}
+
+func jobNodes(ctx *Context) []jobNode {
+	var jobs []jobNode
+	mapping := lookup(ctx.Root, "jobs")
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		jobs = append(jobs, jobNode{id: mapping.Content[i].Value, key: mapping.Content[i], job: mapping.Content[i+1]})
+	}
+	return jobs
+}
+
+func stepNodes(job *yaml.Node) []*yaml.Node {
+	steps := lookup(job, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return steps.Content
+}
+
+// lookup returns the value of a path of keys in a mapping
+func lookup(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// keyOf returns the key node of a key in a mapping
+func keyOf(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func scalar(node *yaml.Node) (string, bool) {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return node.Value, true
+}
+
+var fullSha = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+func checkUnpinnedActions(ctx *Context) {
+	trusted := ctx.Config.TrustedOwners
+	if trusted == nil {
+		trusted = []string{"actions", "github"}
+	}
+	check := func(node *yaml.Node, job string) {
+		uses, ok := scalar(node)
+		if !ok || strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") || strings.Contains(uses, "${{") {
+			return
+		}
+		name, ref, found := strings.Cut(uses, "@")
+		owner, _, _ := strings.Cut(name, "/")
+		for _, t := range trusted {
+			if strings.EqualFold(owner, t) {
+				return
+			}
+		}
+		if !found || !fullSha.MatchString(ref) {
+			ctx.Report(node, job, fmt.Sprintf("%s is not pinned to a full length commit sha", uses), nil)
+		}
+	}
+	for _, j := range jobNodes(ctx) {
+		check(lookup(j.job, "uses"), j.id)
+		for _, step := range stepNodes(j.job) {
+			check(lookup(step, "uses"), j.id)
+		}
+	}
+}
+
+var (
+	expressionPattern = regexp.MustCompile(`(?s)\$\{\{(.*?)\}\}`)
+	// untrustedInput matches the event data which can be chosen by the author of an issue, pull request, comment or commit
+	untrustedInput = regexp.MustCompile(`(?i)\bgithub\.(head_ref|event\.(issue\.(title|body)|pull_request\.(title|body|head\.(ref|label|repo\.default_branch))|comment\.body|review\.body|review_comment\.body|pages(\[[^\]]*\])?(\.[^.\s]+)?\.page_name|(head_commit|commits(\[[^\]]*\])?(\.[^.\s]+)?)\.(message|author\.(email|name))|discussion\.(title|body)|workflow_run\.(head_branch|display_title|head_commit\.(message|author\.(email|name)))))\b`)
+)
+
+func checkScriptInjection(ctx *Context) {
+	check := func(node *yaml.Node, job string) {
+		script, ok := scalar(node)
+		if !ok {
+			return
+		}
+		reported := map[string]bool{}
+		for _, m := range expressionPattern.FindAllStringSubmatch(script, -1) {
+			for _, input := range untrustedInput.FindAllString(m[1], -1) {
+				if reported[input] {
+					continue
+				}
+				reported[input] = true
+				ctx.Report(node, job, fmt.Sprintf("%s is potentially untrusted, pass it to the script through an environment variable", input), nil)
+			}
+		}
+	}
+	for _, j := range jobNodes(ctx) {
+		for _, step := range stepNodes(j.job) {
+			check(lookup(step, "run"), j.id)
+			if uses, _ := scalar(lookup(step, "uses")); strings.HasPrefix(uses, "actions/github-script@") {
+				check(lookup(step, "with", "script"), j.id)
+			}
+		}
+	}
+}
+
+var (
+	deprecatedCommand = regexp.MustCompile(`::(set-output|save-state|set-env|add-path)[ :]`)
+	// setOutputCommand matches an echo of a set-output or save-state command, which makes up the end of a line
+	setOutputCommand = regexp.MustCompile(`echo\s+(?:"::(set-output|save-state) name=([\w-]+)::([^"]*)"|'::(set-output|save-state) name=([\w-]+)::([^']*)'|::(set-output|save-state) name=([\w-]+)::([^"'\s][^"']*?))\s*$`)
+)
+
+// fixCommand rewrites an echo of a set-output or save-state command to append to the file of the command
+func fixCommand(line string) (string, bool) {
+	m := setOutputCommand.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", false
+	}
+	sub := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return line[m[2*i]:m[2*i+1]]
+	}
+	var quote, command, name, value string
+	switch {
+	case sub(1) != "":
+		quote, command, name, value = `"`, sub(1), sub(2), sub(3)
+	case sub(4) != "":
+		quote, command, name, value = `'`, sub(4), sub(5), sub(6)
+	default:
+		command, name, value = sub(7), sub(8), sub(9)
+	}
+	// escaped values may contain newlines, which require a delimiter in the file
+	if strings.Contains(value, "%") {
+		return "", false
+	}
+	file := "$GITHUB_OUTPUT"
+	if command == "save-state" {
+		file = "$GITHUB_STATE"
+	}
+	return fmt.Sprintf(`%secho %s%s=%s%s >> "%s"`, line[:m[0]], quote, name, value, quote, file), true
+}
+
+// scriptStart returns the index of the line of the file with the first line of a script
+// and whether the lines of the script are the lines of the file
+func scriptStart(node *yaml.Node) (int, bool) {
+	switch {
+	case node.Style == yaml.LiteralStyle:
+		return node.Line, true
+	case node.Style == 0 && !strings.Contains(node.Value, "\n"):
+		return node.Line - 1, true
+	}
+	return node.Line - 1, false
+}
+
+// isShell checks if the shell of a step is sh or bash
+func isShell(ctx *Context, job, step *yaml.Node) bool {
+	shell, ok := scalar(lookup(step, "shell"))
+	if !ok {
+		shell, ok = scalar(lookup(job, "defaults", "run", "shell"))
+	}
+	if !ok {
+		shell, ok = scalar(lookup(ctx.Root, "defaults", "run", "shell"))
+	}
+	if !ok {
+		return true
+	}
+	shell = strings.Fields(shell + " ")[0]
+	return shell == "bash" || shell == "sh"
+}
+
+func checkDeprecatedCommands(ctx *Context) {
+	for _, j := range jobNodes(ctx) {
+		for _, step := range stepNodes(j.job) {
+			node := lookup(step, "run")
+			script, ok := scalar(node)
+			if !ok || !deprecatedCommand.MatchString(script) {
+				continue
+			}
+			start, mapped := scriptStart(node)
+			fixable := mapped && isShell(ctx, j.job, step)
+			for i, line := range strings.Split(script, "\n") {
+				m := deprecatedCommand.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				var msg string
+				switch m[1] {
+				case "set-output":
+					msg = `the set-output command is deprecated, append name=value to "$GITHUB_OUTPUT" instead`
+				case "save-state":
+					msg = `the save-state command is deprecated, append name=value to "$GITHUB_STATE" instead`
+				default:
+					msg = fmt.Sprintf("the %s command is disabled, use the environment files instead", m[1])
+				}
+				if !mapped {
+					ctx.Report(node, j.id, msg, nil)
+					continue
+				}
+				fileLine := start + i
+				if fileLine >= len(ctx.Lines) {
+					ctx.Report(node, j.id, msg, nil)
+					continue
+				}
+				var fix *Edit
+				if fixable && strings.Contains(ctx.Lines[fileLine], line) {
+					if fixed, ok := fixCommand(ctx.Lines[fileLine]); ok {
+						fix = &Edit{Start: fileLine, End: fileLine + 1, Lines: []string{fixed}}
+					}
+				}
+				ctx.ReportAt(fileLine+1, strings.Index(ctx.Lines[fileLine], m[0])+1, j.id, msg, fix)
+			}
+		}
+	}
+}
+
+func checkMissingTimeout(ctx *Context) {
+	minutes := ctx.Config.TimeoutMinutes
+	if minutes <= 0 {
+		minutes = 360
+	}
+	for _, j := range jobNodes(ctx) {
+		// reusable workflows set the timeouts of their jobs
+		job := ctx.Workflow.GetJob(j.id)
+		if job == nil || j.job.Kind != yaml.MappingNode || keyOf(j.job, "timeout-minutes") != nil {
+			continue
+		}
+		if t, err := job.Type(); err != nil || t != model.JobTypeDefault {
+			continue
+		}
+		var fix *Edit
+		// insert the timeout before the first key of the job, if it starts its line
+		if j.job.Style&yaml.FlowStyle == 0 && len(j.job.Content) > 0 {
+			first := j.job.Content[0]
+			line := ctx.Lines[first.Line-1]
+			indent := line[:first.Column-1]
+			if strings.TrimSpace(indent) == "" {
+				// keep the comments above the first key together with it
+				start := first.Line - 1
+				for start > 0 && strings.HasPrefix(ctx.Lines[start-1], indent+"#") {
+					start--
+				}
+				fix = &Edit{Start: start, End: start, Lines: []string{fmt.Sprintf("%stimeout-minutes: %d", indent, minutes)}}
+			}
+		}
+		ctx.Report(j.key, j.id, fmt.Sprintf("job %s has no timeout-minutes, it may run for up to 6 hours", j.id), fix)
+	}
+}
+
+// collectText appends the keys and scalar values of a node to the builder
+func collectText(node *yaml.Node, b *strings.Builder) {
+	if node.Kind == yaml.ScalarNode {
+		b.WriteString(strings.ToLower(node.Value))
+		b.WriteString("\n")
+	}
+	for _, c := range node.Content {
+		collectText(c, b)
+	}
+}
+
+func checkUnusedOutputs(ctx *Context) {
+	var b strings.Builder
+	collectText(ctx.Root, &b)
+	text := b.String()
+	// needs is used as a whole, e.g. by toJSON(needs) or needs[format(...)]
+	if regexp.MustCompile(`\bneeds\s*(\[|\)|\.\*)`).MatchString(text) {
+		return
+	}
+	for _, j := range jobNodes(ctx) {
+		outputs := lookup(j.job, "outputs")
+		if outputs == nil || outputs.Kind != yaml.MappingNode {
+			continue
+		}
+		id := regexp.QuoteMeta(strings.ToLower(j.id))
+		// the outputs are used as a whole, e.g. by toJSON(needs.job.outputs)
+		if regexp.MustCompile(`\b(needs|jobs)\.` + id + `\.outputs\b([^.]|$)`).MatchString(text) {
+			continue
+		}
+		for i := 0; i+1 < len(outputs.Content); i += 2 {
+			key := outputs.Content[i]
+			name := regexp.QuoteMeta(strings.ToLower(key.Value))
+			if !regexp.MustCompile(`\b(needs|jobs)\.` + id + `\.outputs\.` + name + `\b`).MatchString(text) {
+				ctx.Report(key, j.id, fmt.Sprintf("output %s of job %s is not used", key.Value, j.id), nil)
+			}
+		}
+	}
+}
+
+// needsOf returns the needs of a job with the nodes of the needed job ids
+func needsOf(job *yaml.Node) []*yaml.Node {
+	needs := lookup(job, "needs")
+	if needs == nil {
+		return nil
+	}
+	switch needs.Kind {
+	case yaml.ScalarNode:
+		return []*yaml.Node{needs}
+	case yaml.SequenceNode:
+		return needs.Content
+	}
+	return nil
+}
+
+func checkNeedsCycles(ctx *Context) {
+	jobs := jobNodes(ctx)
+	byID := map[string]jobNode{}
+	for _, j := range jobs {
+		byID[j.id] = j
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	reported := map[string]bool{}
+	var path []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, need := range needsOf(byID[id].job) {
+			switch state[need.Value] {
+			case unvisited:
+				if _, ok := byID[need.Value]; ok {
+					visit(need.Value)
+				}
+			case visiting:
+				var cycle []string
+				for i := len(path) - 1; i >= 0; i-- {
+					cycle = append([]string{path[i]}, cycle...)
+					if path[i] == need.Value {
+						break
+					}
+				}
+				members := append([]string{}, cycle...)
+				sort.Strings(members)
+				if key := strings.Join(members, "\n"); !reported[key] {
+					reported[key] = true
+					ctx.Report(need, id, fmt.Sprintf("jobs %s -> %s form a cycle, they never run", strings.Join(cycle, " -> "), need.Value), nil)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+	}
+	for _, j := range jobs {
+		if state[j.id] == unvisited {
+			visit(j.id)
+		}
+	}
+}
+
+// statusFunction matches conditions which run a job although a needed job has been skipped
+var statusFunction = regexp.MustCompile(`(?i)\b(always|failure|cancelled)\s*\(`)
+
+// alwaysFalse checks if the condition of a job is false regardless of the run
+func alwaysFalse(ctx *Context, id string, cond string) bool {
+	cond = strings.TrimSpace(cond)
+	if strings.HasPrefix(cond, "${{") && strings.HasSuffix(cond, "}}") {
+		cond = cond[3 : len(cond)-2]
+	} else if strings.Contains(cond, "${{") {
+		return false
+	}
+	node, err := exprparser.Parse(cond)
+	if err != nil {
+		return false
+	}
+	checker := schema.WorkflowExpressionChecker(ctx.Root, []string{"jobs", id, "if"}, -1)
+	return checker != nil && checker.AlwaysFalse(node)
+}
+
+func checkUnreachableJobs(ctx *Context) {
+	jobs := jobNodes(ctx)
+	byID := map[string]jobNode{}
+	for _, j := range jobs {
+		byID[j.id] = j
+	}
+	// never holds the reachability of the checked jobs, jobs in cycles are reported by needs-cycle
+	never := map[string]bool{}
+	checking := map[string]bool{}
+	var check func(j jobNode) bool
+	check = func(j jobNode) bool {
+		if v, ok := never[j.id]; ok {
+			return v
+		}
+		if checking[j.id] {
+			return false
+		}
+		checking[j.id] = true
+		defer delete(checking, j.id)
+		cond, _ := scalar(lookup(j.job, "if"))
+		if cond != "" && alwaysFalse(ctx, j.id, cond) {
+			never[j.id] = true
+			ctx.Report(lookup(j.job, "if"), j.id, fmt.Sprintf("job %s never runs, its condition is always false", j.id), nil)
+			return true
+		}
+		for _, need := range needsOf(j.job) {
+			needed, ok := byID[need.Value]
+			if !ok {
+				never[j.id] = true
+				ctx.Report(need, j.id, fmt.Sprintf("job %s never runs, it needs the job %s which does not exist", j.id, need.Value), nil)
+				return true
+			}
+			if check(needed) && !statusFunction.MatchString(cond) {
+				never[j.id] = true
+				ctx.Report(need, j.id, fmt.Sprintf("job %s never runs, it needs the job %s which never runs", j.id, need.Value), nil)
+				return true
+			}
+		}
+		never[j.id] = false
+		return false
+	}
+	for _, j := range jobs {
+		check(j)
+	}
+}
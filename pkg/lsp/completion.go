@@ -0,0 +1,329 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/runner"
+	"github.com/actions-oss/act-cli/pkg/schema"
+)
+
+func (s *Server) complete(ctx context.Context, doc *document, pos Position) []CompletionItem {
+	root := doc.parseAt(pos)
+	c := locate(root, pos)
+	if expr, ok := expressionBefore(doc, c, pos); ok {
+		return completeExpression(doc, root, c, expr)
+	}
+	if c.key != nil {
+		if isWith(c.path) {
+			return s.completeInputs(ctx, doc, c)
+		}
+		return completeKeys(doc, c)
+	}
+	if c.value != nil {
+		return completeValues(doc, c)
+	}
+	return nil
+}
+
+// expressionBefore returns the text of an unclosed expression before pos
+func expressionBefore(doc *document, c *cursor, pos Position) (string, bool) {
+	prefix := doc.linePrefix(pos)
+	if i := strings.LastIndex(prefix, "${{"); i != -1 && !strings.Contains(prefix[i:], "}}") {
+		return prefix[i+3:], true
+	}
+	if c.key != nil || c.value == nil || c.value.Line-1 != pos.Line || strings.Contains(prefix, "}}") {
+		return "", false
+	}
+	// conditions are expressions without ${{ }}
+	if !isCondition(doc, c) || pos.Character < c.value.Column-1 {
+		return "", false
+	}
+	return prefix[c.value.Column-1:], true
+}
+
+// checkerAt returns the checker of the expressions at the cursor
+func checkerAt(doc *document, root *yaml.Node, c *cursor) *schema.ExpressionChecker {
+	if !doc.isAction() {
+		return schema.WorkflowExpressionChecker(root, c.path, c.step)
+	}
+	sn := doc.schemaNode().GetNestedNode(c.path...)
+	if sn == nil || len(sn.Context) == 0 {
+		return nil
+	}
+	checker := &schema.ExpressionChecker{Contexts: map[string]*schema.Type{}, Functions: sn.GetFunctions()}
+	for _, name := range sn.GetVariables() {
+		checker.Contexts[strings.ToLower(name)] = schema.AnyType()
+	}
+	return checker
+}
+
+func completeExpression(doc *document, root *yaml.Node, c *cursor, expr string) []CompletionItem {
+	checker := checkerAt(doc, root, c)
+	if checker == nil {
+		return nil
+	}
+	parts := strings.Split(chainBefore(expr), ".")
+	items := []CompletionItem{}
+	if len(parts) == 1 {
+		for name := range checker.Contexts {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionKindVariable, Detail: "context"})
+		}
+		for _, f := range checker.Functions {
+			items = append(items, CompletionItem{Label: f.GetName(), Kind: CompletionKindFunction, Detail: "function", InsertText: f.GetName() + "("})
+		}
+		sortItems(items)
+		return items
+	}
+	t := checker.Resolve(parts[:len(parts)-1]...)
+	if t == nil {
+		return items
+	}
+	for name, p := range t.Properties {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionKindProperty, Detail: p.Kind.String()})
+	}
+	sortItems(items)
+	return items
+}
+
+// chainBefore returns the property chain at the end of an expression, e.g. steps.build.out
+func chainBefore(expr string) string {
+	i := len(expr)
+	for i > 0 && isChainChar(expr[i-1]) {
+		i--
+	}
+	return expr[i:]
+}
+
+// chainAt returns the property chain around col of line
+func chainAt(line string, col int) string {
+	if col > len(line) {
+		return ""
+	}
+	start, end := col, col
+	for start > 0 && isChainChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isChainChar(line[end]) {
+		end++
+	}
+	return line[start:end]
+}
+
+func isChainChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-' || ch == '.'
+}
+
+// mappingProperties returns the properties of the mappings a schema node may have
+func mappingProperties(sn *schema.Node) map[string]schema.MappingProperty {
+	props := map[string]schema.MappingProperty{}
+	def := sn.Schema.GetDefinition(sn.Definition)
+	if def.Mapping != nil {
+		for k, v := range def.Mapping.Properties {
+			props[k] = v
+		}
+	}
+	if def.OneOf != nil {
+		for _, one := range *def.OneOf {
+			for k, v := range mappingProperties(&schema.Node{Definition: one, Schema: sn.Schema}) {
+				if _, ok := props[k]; !ok {
+					props[k] = v
+				}
+			}
+		}
+	}
+	return props
+}
+
+// propertyDescription returns the description of a property or of its type
+func propertyDescription(sch *schema.Schema, p schema.MappingProperty) string {
+	if p.Description != "" {
+		return p.Description
+	}
+	return sch.GetDefinition(p.Type).Description
+}
+
+func completeKeys(doc *document, c *cursor) []CompletionItem {
+	sn := doc.schemaNode().GetNestedNode(c.path...)
+	if sn == nil {
+		return nil
+	}
+	items := []CompletionItem{}
+	for name, p := range mappingProperties(sn) {
+		if k := keyNode(c.mapping, name); k != nil && k != c.key {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          CompletionKindProperty,
+			Detail:        p.Type,
+			Documentation: propertyDescription(sn.Schema, p),
+			InsertText:    name + ": ",
+		})
+	}
+	sortItems(items)
+	return items
+}
+
+func completeValues(doc *document, c *cursor) []CompletionItem {
+	sn := doc.schemaNode().GetNestedNode(c.path...)
+	if sn == nil {
+		return nil
+	}
+	items := []CompletionItem{}
+	for _, v := range allowedValues(sn) {
+		items = append(items, CompletionItem{Label: v, Kind: CompletionKindProperty})
+	}
+	sortItems(items)
+	return items
+}
+
+// allowedValues returns the constants and allowed values of a schema node
+func allowedValues(sn *schema.Node) []string {
+	def := sn.Schema.GetDefinition(sn.Definition)
+	var values []string
+	if def.AllowedValues != nil {
+		values = append(values, *def.AllowedValues...)
+	}
+	if def.String != nil && def.String.Constant != "" {
+		values = append(values, def.String.Constant)
+	}
+	if def.OneOf != nil {
+		for _, one := range *def.OneOf {
+			values = append(values, allowedValues(&schema.Node{Definition: one, Schema: sn.Schema})...)
+		}
+	}
+	return values
+}
+
+func (s *Server) completeInputs(ctx context.Context, doc *document, c *cursor) []CompletionItem {
+	inputs := s.inputsOf(ctx, doc, c.nodes[len(c.path)-1])
+	items := []CompletionItem{}
+	for name, input := range inputs {
+		if k := keyNode(c.mapping, name); k != nil && k != c.key {
+			continue
+		}
+		detail := "optional"
+		if input.Required {
+			detail = "required"
+		}
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          CompletionKindProperty,
+			Detail:        detail,
+			Documentation: input.Description,
+			InsertText:    name + ": ",
+		})
+	}
+	sortItems(items)
+	return items
+}
+
+// isWith checks if path is the with mapping of a step or of a job calling a reusable workflow
+func isWith(path []string) bool {
+	return len(path) >= 2 && path[len(path)-1] == "with"
+}
+
+// inputsOf returns the inputs of the action or reusable workflow used by a step or job
+func (s *Server) inputsOf(ctx context.Context, doc *document, node *yaml.Node) map[string]model.Input {
+	uses := lookup(node, "uses")
+	if uses == nil || uses.Kind != yaml.ScalarNode {
+		return nil
+	}
+	action, inputs, err := s.resolveUses(ctx, doc, uses.Value)
+	if err != nil {
+		common.Logger(ctx).Debugf("lsp: %v", err)
+		return nil
+	}
+	if action != nil {
+		return action.Inputs
+	}
+	return inputs
+}
+
+// resolveUses reads the action or the inputs of the reusable workflow of uses
+func (s *Server) resolveUses(ctx context.Context, doc *document, uses string) (*model.Action, map[string]model.Input, error) {
+	if strings.HasPrefix(uses, "docker://") || strings.Contains(uses, "${{") {
+		return nil, nil, nil
+	}
+	if !strings.HasPrefix(uses, "./") {
+		if s.ActionCache == nil || strings.Contains(uses, ".github/workflows/") {
+			return nil, nil, nil
+		}
+		action, err := runner.ReadCachedAction(ctx, *s.ActionCache, uses)
+		return action, nil, err
+	}
+	p := localUsesPath(doc, uses)
+	if p == "" {
+		return nil, nil, nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if strings.HasSuffix(p, "action.yml") || strings.HasSuffix(p, "action.yaml") {
+		action, err := model.ReadAction(f)
+		return action, nil, err
+	}
+	var workflow yaml.Node
+	if err := yaml.NewDecoder(f).Decode(&workflow); err != nil {
+		return nil, nil, err
+	}
+	inputs := map[string]model.Input{}
+	calls := lookup(workflow.Content[0], "on", "workflow_call", "inputs")
+	if calls != nil && calls.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(calls.Content); i += 2 {
+			var input model.Input
+			_ = calls.Content[i+1].Decode(&input)
+			inputs[calls.Content[i].Value] = input
+		}
+	}
+	return nil, inputs, nil
+}
+
+// localUsesPath returns the file of a local action or reusable workflow, which exists on disk
+func localUsesPath(doc *document, uses string) string {
+	dir := filepath.Join(workspaceDir(doc), filepath.FromSlash(uses))
+	if strings.HasSuffix(uses, ".yml") || strings.HasSuffix(uses, ".yaml") {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		return ""
+	}
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
+	}
+	return ""
+}
+
+// workspaceDir returns the root of the repository of a document, local uses are relative to it
+func workspaceDir(doc *document) string {
+	dir := filepath.Dir(uriToPath(doc.uri))
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Base(d) == ".github" {
+			return filepath.Dir(d)
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+func sortItems(items []CompletionItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// definition finds the declaration of a needed job, a step id or a local action or workflow at pos
+func definition(doc *document, pos Position) []Location {
+	root, _ := doc.parse()
+	if root == nil {
+		return nil
+	}
+	c := locate(root, pos)
+	if c.value == nil || len(c.path) == 0 {
+		return nil
+	}
+	n := len(c.path)
+	last := c.path[n-1]
+	if last == "*" && n > 1 {
+		last = c.path[n-2]
+	}
+	switch {
+	case last == "needs" && c.path[0] == "jobs":
+		return keyLocation(doc, lookup(root, "jobs"), c.value.Value)
+	case last == "uses" && strings.HasPrefix(c.value.Value, "./"):
+		if p := localUsesPath(doc, c.value.Value); p != "" {
+			return []Location{{URI: pathToURI(p)}}
+		}
+		return nil
+	}
+
+	if pos.Line >= len(doc.lines) {
+		return nil
+	}
+	parts := strings.Split(chainAt(doc.lines[pos.Line], pos.Character), ".")
+	if len(parts) < 2 {
+		return nil
+	}
+	switch strings.ToLower(parts[0]) {
+	case "needs":
+		return keyLocation(doc, lookup(root, "jobs"), parts[1])
+	case "steps":
+		var steps *yaml.Node
+		if doc.isAction() {
+			steps = lookup(root, "runs", "steps")
+		} else if c.path[0] == "jobs" && n > 1 {
+			steps = lookup(root, "jobs", c.path[1], "steps")
+		}
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, step := range steps.Content {
+			if id := lookup(step, "id"); id != nil && strings.EqualFold(id.Value, parts[1]) {
+				return []Location{{URI: doc.uri, Range: nodeRange(id)}}
+			}
+		}
+	}
+	return nil
+}
+
+// keyLocation returns the location of a key of a mapping, keys are compared case insensitive
+func keyLocation(doc *document, mapping *yaml.Node, key string) []Location {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return []Location{{URI: doc.uri, Range: nodeRange(mapping.Content[i])}}
+		}
+	}
+	return nil
+}
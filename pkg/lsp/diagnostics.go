@@ -0,0 +1,94 @@
+package lsp
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/actions-oss/act-cli/pkg/schema"
+)
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// diagnostics validates a document against the schema and type checks the expressions of workflows
+func diagnostics(doc *document) []Diagnostic {
+	diags := []Diagnostic{}
+	root, err := doc.parse()
+	if err != nil {
+		return append(diags, parseDiagnostic(err))
+	}
+	if root == nil {
+		return diags
+	}
+	if err := doc.schemaNode().UnmarshalYAML(root); err != nil {
+		for _, e := range flattenErrors(err) {
+			diags = append(diags, toDiagnostic(e))
+		}
+		// the expressions are only checked if the structure of the document is valid
+		return diags
+	}
+	if !doc.isAction() {
+		for _, e := range schema.CheckWorkflowExpressions(root) {
+			diags = append(diags, toDiagnostic(e))
+		}
+	}
+	return diags
+}
+
+func parseDiagnostic(err error) Diagnostic {
+	msg := err.Error()
+	var line int
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		line--
+		msg = msg[len(m[0]):]
+	}
+	return Diagnostic{
+		Range:    Range{Start: Position{Line: line}, End: Position{Line: line + 1}},
+		Severity: SeverityError,
+		Source:   "act",
+		Message:  msg,
+	}
+}
+
+func toDiagnostic(e schema.ValidationError) Diagnostic {
+	severity := SeverityError
+	if e.Kind == schema.ValidationKindWarning {
+		severity = SeverityWarning
+	}
+	start := Position{Line: max(e.Line-1, 0), Character: max(e.Column-1, 0)}
+	return Diagnostic{
+		Range:    Range{Start: start, End: Position{Line: start.Line + 1}},
+		Severity: severity,
+		Source:   "act",
+		Message:  e.Message,
+	}
+}
+
+// flattenErrors returns the individual errors of the nested collections of the schema validation
+func flattenErrors(err error) []schema.ValidationError {
+	if col := schema.AsValidationErrorCollection(err); col != nil {
+		var errs []schema.ValidationError
+		for _, e := range col.Errors {
+			// the summary of a nested collection is replaced by its errors
+			if len(col.Collections) > 0 && strings.HasPrefix(e.Message, "error found in value of key") {
+				continue
+			}
+			errs = append(errs, e)
+		}
+		for _, c := range col.Collections {
+			errs = append(errs, flattenErrors(c)...)
+		}
+		return errs
+	}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		var errs []schema.ValidationError
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, flattenErrors(e)...)
+		}
+		return errs
+	}
+	return []schema.ValidationError{{Message: err.Error()}}
+}
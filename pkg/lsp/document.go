@@ -0,0 +1,224 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/actions-oss/act-cli/pkg/schema"
+)
+
+// placeholderKey is inserted on an empty line, so the mapping of the line can be found
+const placeholderKey = "__act_lsp__"
+
+// document is an open workflow or action file
+type document struct {
+	uri   string
+	text  string
+	lines []string
+}
+
+func newDocument(uri, text string) *document {
+	return &document{uri: uri, text: text, lines: strings.Split(text, "\n")}
+}
+
+func (d *document) isAction() bool {
+	name := filepath.Base(uriToPath(d.uri))
+	return name == "action.yml" || name == "action.yaml"
+}
+
+// schemaNode is the root of the schema of the document
+func (d *document) schemaNode() *schema.Node {
+	if d.isAction() {
+		return &schema.Node{Definition: "action-root", Schema: schema.GetActionSchema()}
+	}
+	return &schema.Node{Definition: "workflow-root", Schema: schema.GetWorkflowSchema()}
+}
+
+func (d *document) parse() (*yaml.Node, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(d.text), &node); err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return nil, nil
+	}
+	return node.Content[0], nil
+}
+
+// parseAt parses the document while it is typed, an empty line or an incomplete key
+// at the line of pos is completed to a key, so the mapping of the line is found
+func (d *document) parseAt(pos Position) *yaml.Node {
+	if pos.Line < len(d.lines) {
+		lines := append([]string{}, d.lines...)
+		line := lines[pos.Line]
+		trimmed := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-"))
+		switch {
+		case trimmed == "":
+			lines[pos.Line] = line + placeholderKey + ":"
+		case !strings.ContainsAny(trimmed, ":{[\"'#"):
+			lines[pos.Line] = line + ":"
+		}
+		if lines[pos.Line] != line {
+			patched := newDocument(d.uri, strings.Join(lines, "\n"))
+			if node, err := patched.parse(); err == nil && node != nil {
+				return node
+			}
+		}
+	}
+	node, _ := d.parse()
+	return node
+}
+
+// linePrefix returns the text of the line of pos before pos
+func (d *document) linePrefix(pos Position) string {
+	if pos.Line >= len(d.lines) {
+		return ""
+	}
+	line := d.lines[pos.Line]
+	if pos.Character < len(line) {
+		return line[:pos.Character]
+	}
+	return line
+}
+
+// cursor describes the yaml node at a position
+type cursor struct {
+	path  []string // schema path of the node, items of sequences are *
+	nodes []*yaml.Node
+	// key is set if the position is at a key, path is the one of the mapping of the key
+	key     *yaml.Node
+	mapping *yaml.Node
+	// value is the scalar at the position
+	value *yaml.Node
+	step  int // index of the enclosing step or -1
+}
+
+func locate(root *yaml.Node, pos Position) *cursor {
+	c := &cursor{step: -1}
+	if root == nil {
+		return c
+	}
+	c.find(root, pos, -1)
+	return c
+}
+
+// find descends into the node which contains pos, endLine is the first line after node or -1
+func (c *cursor) find(node *yaml.Node, pos Position, endLine int) {
+	c.nodes = append(c.nodes, node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			end := endLine
+			if i+2 < len(node.Content) {
+				end = node.Content[i+2].Line - 1
+			}
+			if !inRegion(k, pos, end) {
+				continue
+			}
+			if k.Line-1 == pos.Line && pos.Character <= k.Column-1+len(k.Value) {
+				if pos.Character >= k.Column-1 {
+					c.key = k
+					c.mapping = node
+				}
+				return
+			}
+			c.path = append(c.path, k.Value)
+			if len(c.path) == 3 && c.path[0] == "jobs" && c.path[2] == "steps" {
+				c.step = itemIndex(v, pos, end)
+			}
+			c.find(v, pos, end)
+			return
+		}
+	case yaml.SequenceNode:
+		if i := itemIndex(node, pos, endLine); i != -1 {
+			c.path = append(c.path, "*")
+			end := endLine
+			if i+1 < len(node.Content) {
+				end = node.Content[i+1].Line - 1
+			}
+			c.find(node.Content[i], pos, end)
+		}
+	case yaml.ScalarNode:
+		c.value = node
+	}
+}
+
+// itemIndex returns the index of the item of a sequence which contains pos or -1
+func itemIndex(seq *yaml.Node, pos Position, endLine int) int {
+	if seq.Kind != yaml.SequenceNode {
+		return -1
+	}
+	for i, item := range seq.Content {
+		end := endLine
+		if i+1 < len(seq.Content) {
+			end = seq.Content[i+1].Line - 1
+		}
+		if inRegion(item, pos, end) {
+			return i
+		}
+	}
+	return -1
+}
+
+// inRegion checks if pos is between the start of node and endLine, which is -1 at the end of the document
+func inRegion(node *yaml.Node, pos Position, endLine int) bool {
+	if pos.Line < node.Line-1 {
+		return false
+	}
+	return endLine == -1 || pos.Line < endLine
+}
+
+// lookup returns the value of a path of keys in a mapping
+func lookup(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+func keyNode(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i]
+		}
+	}
+	return nil
+}
+
+func nodeRange(node *yaml.Node) Range {
+	start := Position{Line: node.Line - 1, Character: node.Column - 1}
+	end := start
+	if !strings.Contains(node.Value, "\n") {
+		end.Character += len(node.Value)
+	}
+	return Range{Start: start, End: end}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func (s *Server) hover(ctx context.Context, doc *document, pos Position) *Hover {
+	root, _ := doc.parse()
+	if root == nil {
+		return nil
+	}
+	c := locate(root, pos)
+	var text string
+	var node = c.key
+	switch {
+	case c.key != nil && isWith(c.path):
+		if input, ok := s.inputsOf(ctx, doc, c.nodes[len(c.path)-1])[c.key.Value]; ok {
+			text = fmt.Sprintf("**%s** (input)\n\n%s", c.key.Value, input.Description)
+			if input.Default != "" {
+				text += fmt.Sprintf("\n\nDefault: `%s`", input.Default)
+			}
+		}
+	case c.key != nil:
+		sn := doc.schemaNode().GetNestedNode(c.path...)
+		if sn == nil {
+			return nil
+		}
+		if p, ok := mappingProperties(sn)[c.key.Value]; ok {
+			text = propertyDescription(sn.Schema, p)
+		}
+	case c.value != nil && len(c.path) > 0 && c.path[len(c.path)-1] == "uses":
+		node = c.value
+		if action, _, _ := s.resolveUses(ctx, doc, c.value.Value); action != nil {
+			text = fmt.Sprintf("**%s**\n\n%s", action.Name, action.Description)
+		}
+	case c.value != nil:
+		node = c.value
+		text = expressionHover(doc, root, c, pos)
+	}
+	if text == "" || node == nil {
+		return nil
+	}
+	r := nodeRange(node)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}
+}
+
+// expressionHover describes the type of the property chain of an expression at pos
+func expressionHover(doc *document, root *yaml.Node, c *cursor, pos Position) string {
+	if pos.Line >= len(doc.lines) {
+		return ""
+	}
+	line := doc.lines[pos.Line]
+	if !strings.Contains(line, "${{") && !isCondition(doc, c) {
+		return ""
+	}
+	chain := strings.Trim(chainAt(line, pos.Character), ".")
+	checker := checkerAt(doc, root, c)
+	if chain == "" || checker == nil {
+		return ""
+	}
+	t := checker.Resolve(strings.Split(chain, ".")...)
+	if t == nil {
+		return ""
+	}
+	return fmt.Sprintf("`%s`: %s", chain, t.Kind)
+}
+
+// isCondition checks if the value at the cursor is an expression without ${{ }}
+func isCondition(doc *document, c *cursor) bool {
+	sn := doc.schemaNode().GetNestedNode(c.path...)
+	if sn == nil {
+		return false
+	}
+	def := sn.Schema.GetDefinition(sn.Definition)
+	return def.String != nil && def.String.IsExpression
+}
@@ -0,0 +1,142 @@
+// Package lsp implements a language server for workflow and action files
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/runner"
+)
+
+// Server answers the requests of an editor about the open workflow and action files
+type Server struct {
+	// ActionCache provides the action.yml of remote actions, which are completed and documented
+	// if they have been fetched before
+	ActionCache *runner.GoGitActionCache
+
+	conn     *conn
+	mu       sync.Mutex
+	docs     map[string]*document
+	shutdown bool
+}
+
+func NewServer(actionCache *runner.GoGitActionCache) *Server {
+	return &Server{
+		ActionCache: actionCache,
+		docs:        map[string]*document{},
+	}
+}
+
+// Run serves the requests read from r until the client exits or r is closed
+func (s *Server) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		req, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(ctx, req); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req *request) error {
+	logger := common.Logger(ctx)
+	logger.Debugf("lsp: %s", req.Method)
+
+	var result interface{}
+	var rerr *responseError
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1, // full documents
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{".", " ", "{"},
+				},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "act"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		return s.open(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		return s.open(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		s.mu.Lock()
+		delete(s.docs, params.TextDocument.URI)
+		s.mu.Unlock()
+		return s.conn.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	case "textDocument/completion", "textDocument/hover", "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			rerr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		doc := s.document(params.TextDocument.URI)
+		if doc == nil {
+			break
+		}
+		switch req.Method {
+		case "textDocument/completion":
+			result = &completionList{Items: s.complete(ctx, doc, params.Position)}
+		case "textDocument/hover":
+			if hover := s.hover(ctx, doc, params.Position); hover != nil {
+				result = hover
+			}
+		default:
+			if locations := definition(doc, params.Position); len(locations) > 0 {
+				result = locations
+			}
+		}
+	default:
+		if req.ID == nil {
+			// notifications which are not supported are ignored
+			return nil
+		}
+		rerr = &responseError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	return s.conn.reply(req.ID, result, rerr)
+}
+
+func (s *Server) open(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.mu.Lock()
+	s.docs[uri] = doc
+	s.mu.Unlock()
+	return s.conn.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics(doc)})
+}
+
+func (s *Server) document(uri string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[uri]
+}
@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn *conn
+	in   io.WriteCloser
+	id   int
+	done chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &testClient{t: t, conn: newConn(clientIn, clientOut), in: clientOut, done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(nil).Run(context.Background(), serverIn, serverOut)
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, c.conn.write(&request{JSONRPC: "2.0", Method: "exit"}))
+		require.NoError(t, <-c.done)
+	})
+	return c
+}
+
+// call sends a request and decodes the result into result, notifications are skipped
+func (c *testClient) call(method string, params interface{}, result interface{}) {
+	c.id++
+	id := json.RawMessage(strings.TrimSpace(string(mustJSON(c.t, c.id))))
+	require.NoError(c.t, c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "id": &id, "method": method, "params": params}))
+	for {
+		msg := c.next()
+		if msg["id"] == nil {
+			continue
+		}
+		require.Nil(c.t, msg["error"], method)
+		require.NoError(c.t, json.Unmarshal(mustJSON(c.t, msg["result"]), result))
+		return
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	require.NoError(c.t, c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}))
+}
+
+// diagnostics waits for the diagnostics of the next published document
+func (c *testClient) diagnostics() publishDiagnosticsParams {
+	msg := c.next()
+	require.Equal(c.t, "textDocument/publishDiagnostics", msg["method"])
+	var params publishDiagnosticsParams
+	require.NoError(c.t, json.Unmarshal(mustJSON(c.t, msg["params"]), &params))
+	return params
+}
+
+func (c *testClient) next() map[string]interface{} {
+	body, err := c.conn.readMessage()
+	require.NoError(c.t, err)
+	msg := map[string]interface{}{}
+	require.NoError(c.t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+func position(uri string, line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func labels(items []CompletionItem) []string {
+	var l []string
+	for _, item := range items {
+		l = append(l, item.Label)
+	}
+	return l
+}
+
+const testWorkflow = `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    outputs:
+      version: ${{ steps.version.outputs.value }}
+    steps:
+      - id: version
+        run: echo value=1 >> $GITHUB_OUTPUT
+      - uses: ./action
+        with:
+          name: ${{ steps.version.outputs.value }}
+      - run: echo ${{ steps.
+  test:
+    needs: build
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo ${{ needs.build.outputs.version }}
+    
+`
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github", "workflows"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "action"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "action", "action.yml"), []byte(`name: greet
+description: Greets someone
+inputs:
+  name:
+    description: who to greet
+    required: true
+  greeting:
+    description: the greeting
+    default: hello
+runs:
+  using: node20
+  main: index.js
+`), 0o600))
+	uri := pathToURI(filepath.Join(dir, ".github", "workflows", "ci.yml"))
+
+	c := newTestClient(t)
+	var init map[string]interface{}
+	c.call("initialize", initializeParams{RootURI: pathToURI(dir)}, &init)
+	assert.Contains(t, init["capabilities"], "completionProvider")
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: uri, Text: testWorkflow}})
+	diags := c.diagnostics()
+	assert.Equal(t, uri, diags.URI)
+	assert.NotEmpty(t, diags.Diagnostics, "the unclosed expression is reported")
+
+	t.Run("complete keys", func(t *testing.T) {
+		var list completionList
+		c.call("textDocument/completion", position(uri, 18, 4), &list)
+		assert.Contains(t, labels(list.Items), "timeout-minutes")
+		assert.Contains(t, labels(list.Items), "strategy")
+		assert.NotContains(t, labels(list.Items), "runs-on", "existing keys are not completed")
+	})
+
+	t.Run("complete step ids", func(t *testing.T) {
+		var list completionList
+		c.call("textDocument/completion", position(uri, 12, 28), &list)
+		assert.Equal(t, []string{"version"}, labels(list.Items))
+	})
+
+	t.Run("complete contexts", func(t *testing.T) {
+		var list completionList
+		c.call("textDocument/completion", position(uri, 17, 21), &list)
+		assert.Contains(t, labels(list.Items), "needs")
+		assert.Contains(t, labels(list.Items), "hashFiles")
+	})
+
+	t.Run("complete action inputs", func(t *testing.T) {
+		var list completionList
+		c.call("textDocument/completion", position(uri, 11, 10), &list)
+		assert.Equal(t, []string{"greeting", "name"}, labels(list.Items))
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var hover Hover
+		c.call("textDocument/hover", position(uri, 3, 6), &hover)
+		assert.Contains(t, hover.Contents.Value, "machine")
+
+		c.call("textDocument/hover", position(uri, 9, 15), &hover)
+		assert.Contains(t, hover.Contents.Value, "Greets someone")
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var locations []Location
+		c.call("textDocument/definition", position(uri, 14, 12), &locations)
+		require.Len(t, locations, 1)
+		assert.Equal(t, Position{Line: 2, Character: 2}, locations[0].Range.Start)
+
+		c.call("textDocument/definition", position(uri, 5, 25), &locations)
+		require.Len(t, locations, 1)
+		assert.Equal(t, Position{Line: 7, Character: 12}, locations[0].Range.Start)
+
+		c.call("textDocument/definition", position(uri, 17, 30), &locations)
+		require.Len(t, locations, 1)
+		assert.Equal(t, Position{Line: 2, Character: 2}, locations[0].Range.Start)
+
+		c.call("textDocument/definition", position(uri, 9, 16), &locations)
+		require.Len(t, locations, 1)
+		assert.Equal(t, pathToURI(filepath.Join(dir, "action", "action.yml")), locations[0].URI)
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		text := strings.Replace(testWorkflow, "      - run: echo ${{ steps.\n", "      - run: echo ${{ steps.missing.outputs.value }}\n", 1)
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   textDocumentIdentifier{URI: uri},
+			"contentChanges": []map[string]string{{"text": text}},
+		})
+		diags := c.diagnostics()
+		require.Len(t, diags.Diagnostics, 1)
+		assert.Equal(t, 12, diags.Diagnostics[0].Range.Start.Line)
+		assert.Contains(t, diags.Diagnostics[0].Message, "missing")
+
+		c.notify("textDocument/didClose", didCloseParams{TextDocument: textDocumentIdentifier{URI: uri}})
+		assert.Empty(t, c.diagnostics().Diagnostics)
+	})
+}
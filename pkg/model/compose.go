@@ -0,0 +1,326 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/kballard/go-shellquote"
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeFile is the subset of a docker compose file used to define job services
+type ComposeFile struct {
+	Services map[string]*ComposeService `yaml:"services"`
+	// Dir is the directory of the compose file, relative paths are resolved against it
+	Dir string `yaml:"-"`
+}
+
+// ComposeService is a service of a docker compose file
+type ComposeService struct {
+	Image       string              `yaml:"image"`
+	Build       yaml.Node           `yaml:"build"`
+	Environment composeEnvironment  `yaml:"environment"`
+	EnvFile     composeStringList   `yaml:"env_file"`
+	Ports       []string            `yaml:"ports"`
+	Volumes     []composeVolume     `yaml:"volumes"`
+	Command     composeCommand      `yaml:"command"`
+	Entrypoint  composeCommand      `yaml:"entrypoint"`
+	User        string              `yaml:"user"`
+	ShmSize     string              `yaml:"shm_size"`
+	Healthcheck *ComposeHealthcheck `yaml:"healthcheck"`
+}
+
+// ComposeHealthcheck is the healthcheck of a docker compose service
+type ComposeHealthcheck struct {
+	Test        composeCommand `yaml:"test"`
+	Interval    string         `yaml:"interval"`
+	Timeout     string         `yaml:"timeout"`
+	Retries     int            `yaml:"retries"`
+	StartPeriod string         `yaml:"start_period"`
+	Disable     bool           `yaml:"disable"`
+}
+
+// composeEnvironment accepts both the map and the list (KEY=VALUE) syntax,
+// keys without a value are taken from the environment of act
+type composeEnvironment map[string]string
+
+func (e *composeEnvironment) UnmarshalYAML(node *yaml.Node) error {
+	env := composeEnvironment{}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		for _, entry := range list {
+			if k, v, ok := strings.Cut(entry, "="); ok {
+				env[k] = v
+			} else {
+				env[k] = os.Getenv(k)
+			}
+		}
+	case yaml.MappingNode:
+		var m map[string]*string
+		if err := node.Decode(&m); err != nil {
+			return err
+		}
+		for k, v := range m {
+			if v == nil {
+				env[k] = os.Getenv(k)
+			} else {
+				env[k] = *v
+			}
+		}
+	default:
+		return fmt.Errorf("line %d: environment must be a mapping or a sequence", node.Line)
+	}
+	*e = env
+	return nil
+}
+
+// composeStringList accepts a single string or a list of strings
+type composeStringList []string
+
+func (l *composeStringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = composeStringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// composeCommand accepts the shell form (string) and the exec form (list)
+type composeCommand struct {
+	Shell string
+	Exec  []string
+}
+
+func (c *composeCommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Shell = node.Value
+		return nil
+	}
+	return node.Decode(&c.Exec)
+}
+
+// Args returns the command as a list of arguments, the shell form is split
+// into words like docker compose does
+func (c composeCommand) Args() []string {
+	if len(c.Exec) > 0 {
+		return c.Exec
+	}
+	if args, err := shellquote.Split(c.Shell); err == nil {
+		return args
+	}
+	return strings.Fields(c.Shell)
+}
+
+// composeVolume accepts the short (source:target[:mode]) and the long syntax
+type composeVolume struct {
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+	Mode     string `yaml:"-"`
+}
+
+func (v *composeVolume) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		type volume composeVolume
+		return node.Decode((*volume)(v))
+	}
+	parts := strings.Split(node.Value, ":")
+	switch len(parts) {
+	case 1:
+		v.Target = parts[0]
+	case 2:
+		v.Source, v.Target = parts[0], parts[1]
+	default:
+		v.Source, v.Target, v.Mode = parts[0], parts[1], strings.Join(parts[2:], ":")
+	}
+	return nil
+}
+
+// ReadComposeFile reads a docker compose file
+func ReadComposeFile(path string) (*ComposeFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compose := &ComposeFile{}
+	if err := yaml.Unmarshal([]byte(expandComposeVariables(string(content))), compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file %s: %w", path, err)
+	}
+	if abs, err := filepath.Abs(filepath.Dir(path)); err == nil {
+		compose.Dir = abs
+	} else {
+		compose.Dir = filepath.Dir(path)
+	}
+	return compose, nil
+}
+
+// expandComposeVariables substitutes ${VAR}, ${VAR:-default} and $VAR with the
+// environment of act, $$ is an escaped $
+func expandComposeVariables(content string) string {
+	const escaped = "\x00"
+	content = strings.ReplaceAll(content, "$$", escaped)
+	content = os.Expand(content, func(name string) string {
+		if k, def, ok := strings.Cut(name, ":-"); ok {
+			if v := os.Getenv(k); v != "" {
+				return v
+			}
+			return def
+		}
+		if k, def, ok := strings.Cut(name, "-"); ok {
+			if v, ok := os.LookupEnv(k); ok {
+				return v
+			}
+			return def
+		}
+		return os.Getenv(name)
+	})
+	return strings.ReplaceAll(content, escaped, "$")
+}
+
+// ServiceNames returns the sorted names of all services in the compose file
+func (c *ComposeFile) ServiceNames() []string {
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ContainerSpecs converts the named compose services to job service containers,
+// all services are converted if no names are given
+func (c *ComposeFile) ContainerSpecs(names ...string) (map[string]*ContainerSpec, error) {
+	if len(names) == 0 {
+		names = c.ServiceNames()
+	}
+	specs := make(map[string]*ContainerSpec, len(names))
+	for _, name := range names {
+		svc, ok := c.Services[name]
+		if !ok {
+			return nil, fmt.Errorf("service %s not found in compose file", name)
+		}
+		spec, err := svc.containerSpec(c.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("compose service %s: %w", name, err)
+		}
+		specs[name] = spec
+	}
+	return specs, nil
+}
+
+func (s *ComposeService) containerSpec(dir string) (*ContainerSpec, error) {
+	if s.Image == "" {
+		if !s.Build.IsZero() {
+			return nil, fmt.Errorf("build is not supported, an image is required")
+		}
+		return nil, fmt.Errorf("an image is required")
+	}
+
+	env := map[string]string{}
+	for _, file := range s.EnvFile {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		fileEnv, err := godotenv.Read(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env_file %s: %w", file, err)
+		}
+		for k, v := range fileEnv {
+			env[k] = v
+		}
+	}
+	for k, v := range s.Environment {
+		env[k] = v
+	}
+
+	volumes := make([]string, 0, len(s.Volumes))
+	for _, v := range s.Volumes {
+		source := v.Source
+		if strings.HasPrefix(source, ".") {
+			source = filepath.Join(dir, source)
+		} else if strings.HasPrefix(source, "~") {
+			if home, err := os.UserHomeDir(); err == nil {
+				source = filepath.Join(home, source[1:])
+			}
+		}
+		volume := v.Target
+		if source != "" {
+			volume = source + ":" + v.Target
+		}
+		if v.Mode != "" {
+			volume += ":" + v.Mode
+		} else if v.ReadOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+
+	options := []string{}
+	if s.User != "" {
+		options = append(options, "--user", s.User)
+	}
+	if s.ShmSize != "" {
+		options = append(options, "--shm-size", s.ShmSize)
+	}
+	command := s.Command.Args()
+	if entrypoint := s.Entrypoint.Args(); len(entrypoint) > 0 {
+		// docker only accepts the executable as entrypoint, the remaining
+		// arguments are prepended to the command
+		options = append(options, "--entrypoint", entrypoint[0])
+		command = append(append([]string{}, entrypoint[1:]...), command...)
+	}
+	if hc := s.Healthcheck; hc != nil {
+		options = append(options, hc.options()...)
+	}
+
+	return &ContainerSpec{
+		Image:   s.Image,
+		Env:     env,
+		Ports:   s.Ports,
+		Volumes: volumes,
+		Options: shellquote.Join(options...),
+		Args:    shellquote.Join(command...),
+	}, nil
+}
+
+func (hc *ComposeHealthcheck) options() []string {
+	test := hc.Test.Exec
+	if hc.Disable || len(test) > 0 && strings.EqualFold(test[0], "NONE") {
+		return []string{"--no-healthcheck"}
+	}
+	options := []string{}
+	switch {
+	case hc.Test.Shell != "":
+		options = append(options, "--health-cmd", hc.Test.Shell)
+	case len(test) > 1 && test[0] == "CMD-SHELL":
+		options = append(options, "--health-cmd", strings.Join(test[1:], " "))
+	case len(test) > 1 && test[0] == "CMD":
+		options = append(options, "--health-cmd", shellquote.Join(test[1:]...))
+	}
+	if hc.Interval != "" {
+		options = append(options, "--health-interval", hc.Interval)
+	}
+	if hc.Timeout != "" {
+		options = append(options, "--health-timeout", hc.Timeout)
+	}
+	if hc.StartPeriod != "" {
+		options = append(options, "--health-start-period", hc.StartPeriod)
+	}
+	if hc.Retries > 0 {
+		options = append(options, "--health-retries", fmt.Sprint(hc.Retries))
+	}
+	return options
+}
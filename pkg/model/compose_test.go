@@ -0,0 +1,45 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeContainerSpecs(t *testing.T) {
+	compose, err := ReadComposeFile("testdata/compose/docker-compose.yml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "db", "redis"}, compose.ServiceNames())
+
+	specs, err := compose.ContainerSpecs("db", "redis")
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+
+	db := specs["db"]
+	assert.Equal(t, "postgres:16", db.Image)
+	assert.Equal(t, map[string]string{
+		"POSTGRES_USER":     "postgres",
+		"POSTGRES_PASSWORD": "secret",
+		"POSTGRES_DB":       "app",
+	}, db.Env)
+	assert.Equal(t, []string{"5432", "6543:6543"}, db.Ports)
+	assert.Equal(t, []string{
+		filepath.Join(compose.Dir, "init") + ":/docker-entrypoint-initdb.d:ro",
+		"pgdata:/var/lib/postgresql/data",
+	}, db.Volumes)
+	assert.Equal(t, "--health-cmd 'pg_isready -U postgres' --health-interval 10s --health-timeout 5s --health-retries 5", db.Options)
+	assert.Empty(t, db.Args)
+
+	redis := specs["redis"]
+	assert.Equal(t, map[string]string{"REDIS_ARGS": `--save ""`}, redis.Env)
+	assert.Equal(t, "redis-server --appendonly yes", redis.Args)
+	assert.Equal(t, "--health-cmd 'redis-cli ping'", redis.Options)
+
+	_, err = compose.ContainerSpecs("app")
+	assert.ErrorContains(t, err, "build is not supported")
+
+	_, err = compose.ContainerSpecs("missing")
+	assert.ErrorContains(t, err, "service missing not found")
+}
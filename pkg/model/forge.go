@@ -0,0 +1,174 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/actions-oss/act-cli/pkg/schema"
+)
+
+// Names of the forges which run workflows, they are the hosts of the expression functions as well
+const (
+	ForgeGitHub  = "github"
+	ForgeGitea   = "gitea"
+	ForgeForgejo = "forgejo"
+)
+
+// giteaEvents are the events which trigger workflows on Gitea and Forgejo
+var giteaEvents = []string{
+	"create", "delete", "fork", "gollum", "issue_comment", "issues", "label", "milestone",
+	"pull_request", "pull_request_comment", "pull_request_review", "pull_request_review_comment",
+	"pull_request_target", "push", "registry_package", "release", "schedule",
+	"workflow_call", "workflow_dispatch",
+}
+
+// Forge is the profile of a host which runs workflows, it selects the schema, the default
+// server, the contexts and the events of GitHub, Gitea or Forgejo
+type Forge struct {
+	Name string
+	// Instance is the default host of the server, actions are cloned from it
+	Instance string
+	// ContextAliases are additional names of the github context, like gitea
+	ContextAliases []string
+	// EnvAliases are prefixes which alias the GITHUB_* environment variables, like GITEA
+	EnvAliases []string
+	// Events which trigger workflows, nil allows all events
+	Events []string
+	// CaseSensitiveLabels matches the labels of runs-on exactly instead of ignoring the case
+	CaseSensitiveLabels bool
+	// apiPath is the path of the REST api on a server other than github.com
+	apiPath string
+	// graphQLPath is the path of the GraphQL api on a server other than github.com, empty if there is none
+	graphQLPath string
+}
+
+var forges = map[string]*Forge{
+	ForgeGitHub: {
+		Name:        ForgeGitHub,
+		Instance:    "github.com",
+		apiPath:     "/api/v3",
+		graphQLPath: "/api/graphql",
+	},
+	ForgeGitea: {
+		Name:                ForgeGitea,
+		Instance:            "gitea.com",
+		ContextAliases:      []string{"gitea"},
+		EnvAliases:          []string{"GITEA"},
+		Events:              append(slices.Clone(giteaEvents), "workflow_run"),
+		CaseSensitiveLabels: true,
+		apiPath:             "/api/v1",
+	},
+	ForgeForgejo: {
+		Name:                ForgeForgejo,
+		Instance:            "code.forgejo.org",
+		ContextAliases:      []string{"gitea", "forge"},
+		EnvAliases:          []string{"GITEA", "FORGEJO"},
+		Events:              giteaEvents,
+		CaseSensitiveLabels: true,
+		apiPath:             "/api/v1",
+	},
+}
+
+// ForgeNames returns the names of the supported forges
+func ForgeNames() []string {
+	return []string{ForgeGitHub, ForgeGitea, ForgeForgejo}
+}
+
+// GetForge returns the profile of a forge, an empty name is GitHub
+func GetForge(name string) (*Forge, error) {
+	if name == "" {
+		name = ForgeGitHub
+	}
+	forge, ok := forges[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown forge %q, expected one of %s", name, strings.Join(ForgeNames(), ", "))
+	}
+	return forge, nil
+}
+
+// Schema returns the workflow schema of the forge, it only accepts the supported events
+func (f *Forge) Schema() *schema.Schema {
+	if f == nil || f.Name == ForgeGitHub {
+		return schema.GetWorkflowSchema()
+	}
+	s := schema.GetGiteaWorkflowSchema()
+	for _, alias := range f.ContextAliases {
+		if alias == "gitea" {
+			continue
+		}
+		for k, def := range s.Definitions {
+			if slices.Contains(def.Context, "github") {
+				def.Context = append(def.Context, alias)
+				s.Definitions[k] = def
+			}
+		}
+	}
+	if f.Events != nil {
+		s.RestrictEvents(f.Events)
+	}
+	return s
+}
+
+// SupportsEvent returns true if the event triggers workflows on the forge
+func (f *Forge) SupportsEvent(event string) bool {
+	return f == nil || f.Events == nil || slices.Contains(f.Events, event)
+}
+
+// APIURL returns the url of the REST api of instance
+func (f *Forge) APIURL(instance string) string {
+	if instance == "github.com" {
+		return "https://api.github.com"
+	}
+	return fmt.Sprintf("https://%s%s", instance, f.apiPath)
+}
+
+// GraphQLURL returns the url of the GraphQL api of instance, it is empty if the forge has none
+func (f *Forge) GraphQLURL(instance string) string {
+	if instance == "github.com" {
+		return "https://api.github.com/graphql"
+	}
+	if f.graphQLPath == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s%s", instance, f.graphQLPath)
+}
+
+// PlatformImage returns the image of the first label of runs-on which has a platform.
+// Like the runners of Gitea and Forgejo, their platforms may be written as docker://image
+// or host, which is the same as -self-hosted.
+func (f *Forge) PlatformImage(platforms map[string]string, labels []string) string {
+	for _, label := range labels {
+		var image string
+		if f.CaseSensitiveLabels {
+			image = platforms[label]
+		} else {
+			image = platforms[strings.ToLower(label)]
+		}
+		if image == "" {
+			continue
+		}
+		if f.Name != ForgeGitHub {
+			if image == "host" {
+				return "-self-hosted"
+			}
+			image = strings.TrimPrefix(image, "docker://")
+		}
+		return image
+	}
+	return ""
+}
+
+// EnvAliasesOf returns the aliases of the GITHUB_* variables of env, like GITEA_SHA for GITHUB_SHA
+func (f *Forge) EnvAliasesOf(env map[string]string) map[string]string {
+	aliases := map[string]string{}
+	for _, prefix := range f.EnvAliases {
+		aliases[prefix+"_ACTIONS"] = "true"
+		for k, v := range env {
+			if name, ok := strings.CutPrefix(k, "GITHUB_"); ok {
+				aliases[prefix+"_"+name] = v
+			}
+		}
+	}
+	return aliases
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetForge(t *testing.T) {
+	forge, err := GetForge("")
+	require.NoError(t, err)
+	assert.Equal(t, ForgeGitHub, forge.Name)
+
+	forge, err = GetForge("Gitea")
+	require.NoError(t, err)
+	assert.Equal(t, ForgeGitea, forge.Name)
+	assert.Equal(t, "https://gitea.com/api/v1", forge.APIURL(forge.Instance))
+	assert.Equal(t, "", forge.GraphQLURL(forge.Instance))
+
+	github, err := GetForge(ForgeGitHub)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.github.com", github.APIURL("github.com"))
+	assert.Equal(t, "https://ghe.local/api/v3", github.APIURL("ghe.local"))
+	assert.Equal(t, "https://ghe.local/api/graphql", github.GraphQLURL("ghe.local"))
+
+	_, err = GetForge("gitlab")
+	assert.EqualError(t, err, `unknown forge "gitlab", expected one of github, gitea, forgejo`)
+}
+
+func TestForgeEvents(t *testing.T) {
+	forgejo, err := GetForge(ForgeForgejo)
+	require.NoError(t, err)
+	assert.True(t, forgejo.SupportsEvent("push"))
+	assert.False(t, forgejo.SupportsEvent("merge_group"))
+
+	github, err := GetForge(ForgeGitHub)
+	require.NoError(t, err)
+	assert.True(t, github.SupportsEvent("merge_group"))
+
+	workflow := `
+on: [push, merge_group]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo ${{ gitea.sha }}
+`
+	_, err = ReadForgeWorkflow(strings.NewReader(workflow), true, forgejo)
+	assert.ErrorContains(t, err, "got merge_group")
+
+	w, err := ReadForgeWorkflow(strings.NewReader(strings.Replace(workflow, ", merge_group", "", 1)), true, forgejo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"push"}, w.On())
+
+	_, err = ReadForgeWorkflow(strings.NewReader(strings.Replace(workflow, ", merge_group", "", 1)), false, github)
+	assert.ErrorContains(t, err, "unknown Variable Access gitea")
+}
+
+func TestForgePlatformImage(t *testing.T) {
+	github, err := GetForge(ForgeGitHub)
+	require.NoError(t, err)
+	gitea, err := GetForge(ForgeGitea)
+	require.NoError(t, err)
+
+	platforms := map[string]string{
+		"ubuntu-latest": "node:16-bullseye",
+		"Linux":         "docker://node:20",
+		"self":          "host",
+	}
+	assert.Equal(t, "node:16-bullseye", github.PlatformImage(platforms, []string{"self-hosted", "Ubuntu-Latest"}))
+	assert.Equal(t, "", gitea.PlatformImage(platforms, []string{"Ubuntu-Latest"}))
+	assert.Equal(t, "node:20", gitea.PlatformImage(platforms, []string{"Linux"}))
+	assert.Equal(t, "-self-hosted", gitea.PlatformImage(platforms, []string{"self"}))
+	assert.Equal(t, "docker://node:20", github.PlatformImage(map[string]string{"linux": "docker://node:20"}, []string{"Linux"}))
+}
+
+func TestForgeEnvAliases(t *testing.T) {
+	forgejo, err := GetForge(ForgeForgejo)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GITEA_ACTIONS":   "true",
+		"GITEA_SHA":       "abc",
+		"FORGEJO_ACTIONS": "true",
+		"FORGEJO_SHA":     "abc",
+	}, forgejo.EnvAliasesOf(map[string]string{"GITHUB_SHA": "abc", "CI": "true"}))
+
+	github, err := GetForge(ForgeGitHub)
+	require.NoError(t, err)
+	assert.Empty(t, github.EnvAliasesOf(map[string]string{"GITHUB_SHA": "abc"}))
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -45,6 +46,15 @@ func (r *Run) String() string {
 	return jobName
 }
 
+// PlanName returns the name shown for the run before it is executed, a matrix which uses the
+// outputs of its needs is shown as placeholder because it is expanded once they completed
+func (r *Run) PlanName() string {
+	if needs := r.Job().MatrixNeeds(); len(needs) > 0 {
+		return fmt.Sprintf("%s (matrix from %s)", r.String(), strings.Join(needs, ", "))
+	}
+	return r.String()
+}
+
 // Job returns the job for this Run
 func (r *Run) Job() *Job {
 	return r.Workflow.GetJob(r.JobID)
@@ -57,6 +67,11 @@ type WorkflowFiles struct {
 
 // NewWorkflowPlanner will load a specific workflow, all workflows from a directory or all workflows from a directory and its subdirectories
 func NewWorkflowPlanner(path string, noWorkflowRecurse, strict bool) (WorkflowPlanner, error) {
+	return NewForgeWorkflowPlanner(path, noWorkflowRecurse, strict, nil)
+}
+
+// NewForgeWorkflowPlanner is NewWorkflowPlanner for the workflows of a forge, nil is GitHub
+func NewForgeWorkflowPlanner(path string, noWorkflowRecurse, strict bool, forge *Forge) (WorkflowPlanner, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
@@ -124,7 +139,7 @@ func NewWorkflowPlanner(path string, noWorkflowRecurse, strict bool) (WorkflowPl
 			}
 
 			log.Debugf("Reading workflow '%s'", f.Name())
-			workflow, err := ReadWorkflow(f, strict)
+			workflow, err := ReadForgeWorkflow(f, strict, forge)
 			if err != nil {
 				_ = f.Close()
 				if err == io.EOF {
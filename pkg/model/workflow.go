@@ -4,12 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 
-	"github.com/actions-oss/act-cli/pkg/common"
+	strategy "github.com/actions-oss/act-cli/internal/model"
 	"github.com/actions-oss/act-cli/pkg/schema"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -69,33 +68,50 @@ func (w *Workflow) OnEvent(event string) interface{} {
 }
 
 func (w *Workflow) UnmarshalYAML(node *yaml.Node) error {
-	if err := resolveAliases(node); err != nil {
-		return err
-	}
-	// Validate the schema before deserializing it into our model
-	if err := (&schema.Node{
-		Definition: "workflow-root",
-		Schema:     schema.GetWorkflowSchema(),
-	}).UnmarshalYAML(node); err != nil {
-		return errors.Join(err, fmt.Errorf("actions YAML Schema Validation Error detected:\nFor more information, see: https://actions-oss.github.io/act-docs/usage/schema.html"))
-	}
-	type WorkflowDefault Workflow
-	return node.Decode((*WorkflowDefault)(w))
+	return w.unmarshalWithSchema(node, schema.GetWorkflowSchema(), false)
 }
 
 type WorkflowStrict Workflow
 
 func (w *WorkflowStrict) UnmarshalYAML(node *yaml.Node) error {
+	return (*Workflow)(w).unmarshalWithSchema(node, schema.GetWorkflowSchema(), true)
+}
+
+// unmarshalWithSchema validates the workflow with the schema of a forge before deserializing it
+func (w *Workflow) unmarshalWithSchema(node *yaml.Node, s *schema.Schema, strict bool) error {
 	if err := resolveAliases(node); err != nil {
 		return err
 	}
+	if !strict {
+		// Validate the schema before deserializing it into our model
+		if err := (&schema.Node{
+			Definition: "workflow-root",
+			Schema:     s,
+		}).UnmarshalYAML(node); err != nil {
+			return errors.Join(err, fmt.Errorf("actions YAML Schema Validation Error detected:\nFor more information, see: https://actions-oss.github.io/act-docs/usage/schema.html"))
+		}
+		type WorkflowDefault Workflow
+		return node.Decode((*WorkflowDefault)(w))
+	}
 	// Validate the schema before deserializing it into our model
 	if err := (&schema.Node{
 		Definition: "workflow-root-strict",
-		Schema:     schema.GetWorkflowSchema(),
+		Schema:     s,
 	}).UnmarshalYAML(node); err != nil {
 		return errors.Join(err, fmt.Errorf("actions YAML Strict Schema Validation Error detected:\nFor more information, see: https://nektosact.com/usage/schema.html"))
 	}
+	// Type check the expressions against the contexts declared by the workflow
+	var typeErrors schema.ValidationErrorCollection
+	for _, e := range schema.CheckWorkflowExpressionsWithSchema(node, s) {
+		if e.Kind == schema.ValidationKindWarning {
+			log.Warn(e.Error())
+			continue
+		}
+		typeErrors.AddError(e)
+	}
+	if len(typeErrors.Errors) > 0 {
+		return errors.Join(typeErrors, fmt.Errorf("actions YAML Strict Expression Validation Error detected"))
+	}
 	type WorkflowDefault Workflow
 	return node.Decode((*WorkflowDefault)(w))
 }
@@ -207,7 +223,34 @@ type Job struct {
 	Uses           string                    `yaml:"uses"`
 	With           map[string]interface{}    `yaml:"with"`
 	RawSecrets     yaml.Node                 `yaml:"secrets"`
-	Result         string
+	// RawServices is the node of Services, the services are evaluated with the locations of the workflow
+	RawServices yaml.Node `yaml:"-"`
+	Result      string
+}
+
+// UnmarshalYAML decodes the job and keeps the node of the services
+func (j *Job) UnmarshalYAML(node *yaml.Node) error {
+	type JobDefault Job
+	if err := node.Decode((*JobDefault)(j)); err != nil {
+		return err
+	}
+	if raw := mappingValue(node, "services"); raw != nil {
+		j.RawServices = *raw
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a mapping node or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
 }
 
 // Strategy for the job
@@ -394,105 +437,109 @@ func (j *Job) Matrix() map[string][]interface{} {
 	return nil
 }
 
-// GetMatrixes returns the matrix cross product
-// It skips includes and hard fails excludes for non-existing keys
-func (j *Job) GetMatrixes() ([]map[string]interface{}, error) {
-	matrixes := make([]map[string]interface{}, 0)
+// MatrixNeeds returns the needs of the job whose outputs are used by its matrix, like
+// `${{ fromJSON(needs.plan.outputs.targets) }}`. Such a matrix is expanded once they completed.
+func (j *Job) MatrixNeeds() []string {
+	if j.Strategy == nil {
+		return nil
+	}
+	exprs := []string{}
+	var collect func(node *yaml.Node)
+	collect = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${{") {
+			exprs = append(exprs, node.Value)
+		}
+		for _, c := range node.Content {
+			collect(c)
+		}
+	}
+	collect(&j.Strategy.RawMatrix)
+	if len(exprs) == 0 {
+		return nil
+	}
+	text := strings.Join(exprs, "\n")
+	var needs []string
+	for _, need := range j.Needs() {
+		ref := regexp.MustCompile(`(?i)needs\s*(\.\s*` + regexp.QuoteMeta(need) + `([^\w-]|$)|\[\s*'` + regexp.QuoteMeta(need) + `'\s*\])`)
+		if ref.MatchString(text) {
+			needs = append(needs, need)
+		}
+	}
+	return needs
+}
+
+// MatrixStrategy is the expanded strategy of a job
+type MatrixStrategy struct {
+	Combinations []MatrixCombination
+	FailFast     bool
+	// MaxParallel limits the combinations which run at the same time, 0 means no limit
+	MaxParallel int
+}
+
+// MatrixCombination is a combination of the matrix which runs as its own job
+type MatrixCombination struct {
+	Matrix map[string]interface{}
+	// DisplaySuffix is appended to the name of the job, like "(ubuntu-latest, 18)"
+	DisplaySuffix string
+}
+
+// ExpandStrategy expands the matrix with the include and exclude semantics of GitHub,
+// trace explains how every include and exclude entry changed the combinations.
+// A matrix which isn't evaluated yet results in a single empty combination.
+func (j *Job) ExpandStrategy(trace strategy.TraceWriter) (*MatrixStrategy, error) {
+	var s *strategy.Strategy
 	if j.Strategy != nil {
 		j.Strategy.FailFast = j.Strategy.GetFailFast()
 		j.Strategy.MaxParallel = j.Strategy.GetMaxParallel()
-
-		if m := j.Matrix(); m != nil {
-			includes := make([]map[string]interface{}, 0)
-			extraIncludes := make([]map[string]interface{}, 0)
-			for _, v := range m["include"] {
-				switch t := v.(type) {
-				case []interface{}:
-					for _, i := range t {
-						i := i.(map[string]interface{})
-						includes = append(includes, i)
-					}
-				case interface{}:
-					v := v.(map[string]interface{})
-					includes = append(includes, v)
-				}
-			}
-			delete(m, "include")
-
-			excludes := make([]map[string]interface{}, 0)
-			for _, e := range m["exclude"] {
-				e := e.(map[string]interface{})
-				for k := range e {
-					if _, ok := m[k]; ok {
-						excludes = append(excludes, e)
-					} else {
-						// We fail completely here because that's what GitHub does for non-existing matrix keys, fail on exclude, silent skip on include
-						return nil, fmt.Errorf("the workflow is not valid. Matrix exclude key %q does not match any key within the matrix", k)
-					}
-				}
-			}
-			delete(m, "exclude")
-
-			matrixProduct := common.CartesianProduct(m)
-		MATRIX:
-			for _, matrix := range matrixProduct {
-				for _, exclude := range excludes {
-					if commonKeysMatch(matrix, exclude) {
-						log.Debugf("Skipping matrix '%v' due to exclude '%v'", matrix, exclude)
-						continue MATRIX
-					}
-				}
-				matrixes = append(matrixes, matrix)
-			}
-			for _, include := range includes {
-				matched := false
-				for _, matrix := range matrixes {
-					if commonKeysMatch2(matrix, include, m) {
-						matched = true
-						log.Debugf("Adding include values '%v' to existing entry", include)
-						for k, v := range include {
-							matrix[k] = v
-						}
-					}
-				}
-				if !matched {
-					extraIncludes = append(extraIncludes, include)
-				}
-			}
-			for _, include := range extraIncludes {
-				log.Debugf("Adding include '%v'", include)
-				matrixes = append(matrixes, include)
-			}
-			if len(matrixes) == 0 {
-				matrixes = append(matrixes, make(map[string]interface{}))
+		s = &strategy.Strategy{
+			FailFast:    j.Strategy.FailFast,
+			MaxParallel: float64(j.Strategy.MaxParallel),
+		}
+		if j.Strategy.RawMatrix.Kind == yaml.MappingNode {
+			if err := s.SetMatrix(&j.Strategy.RawMatrix); err != nil {
+				log.Errorf("failed to decode the matrix: %v", err)
 			}
-		} else {
-			matrixes = append(matrixes, make(map[string]interface{}))
 		}
 	} else {
-		matrixes = append(matrixes, make(map[string]interface{}))
-		log.Debugf("Empty Strategy, matrixes=%v", matrixes)
-	}
-	return matrixes, nil
-}
-
-func commonKeysMatch(a map[string]interface{}, b map[string]interface{}) bool {
-	for aKey, aVal := range a {
-		if bVal, ok := b[aKey]; ok && !reflect.DeepEqual(aVal, bVal) {
-			return false
+		log.Debugf("Empty Strategy")
+	}
+	res, err := strategy.ExpandStrategy(s, trace)
+	if err != nil {
+		return nil, err
+	}
+	expanded := &MatrixStrategy{FailFast: res.FailFast}
+	if res.MaxParallel != nil {
+		expanded.MaxParallel = int(*res.MaxParallel)
+	}
+	for _, entry := range res.Entries() {
+		matrix := make(map[string]interface{}, len(entry.Matrix))
+		for k, v := range entry.Matrix {
+			var val interface{}
+			if err := v.Decode(&val); err != nil {
+				return nil, err
+			}
+			matrix[k] = val
 		}
+		expanded.Combinations = append(expanded.Combinations, MatrixCombination{
+			Matrix:        matrix,
+			DisplaySuffix: entry.DisplaySuffix,
+		})
 	}
-	return true
+	return expanded, nil
 }
 
-func commonKeysMatch2(a map[string]interface{}, b map[string]interface{}, m map[string][]interface{}) bool {
-	for aKey, aVal := range a {
-		_, useKey := m[aKey]
-		if bVal, ok := b[aKey]; useKey && ok && !reflect.DeepEqual(aVal, bVal) {
-			return false
-		}
+// GetMatrixes returns the matrix cross product
+// It skips includes and hard fails excludes for non-existing keys
+func (j *Job) GetMatrixes() ([]map[string]interface{}, error) {
+	expanded, err := j.ExpandStrategy(nil)
+	if err != nil {
+		return nil, err
 	}
-	return true
+	matrixes := make([]map[string]interface{}, 0, len(expanded.Combinations))
+	for _, c := range expanded.Combinations {
+		matrixes = append(matrixes, c.Matrix)
+	}
+	return matrixes, nil
 }
 
 // JobType describes what type of job we are about to run
@@ -578,6 +625,20 @@ type Step struct {
 	With               map[string]string `yaml:"with"`
 	RawContinueOnError string            `yaml:"continue-on-error"`
 	TimeoutMinutes     string            `yaml:"timeout-minutes"`
+	// RawWith is the node of With, the inputs are evaluated with the locations of the workflow
+	RawWith yaml.Node `yaml:"-"`
+}
+
+// UnmarshalYAML decodes the step and keeps the node of the inputs
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	type StepDefault Step
+	if err := node.Decode((*StepDefault)(s)); err != nil {
+		return err
+	}
+	if raw := mappingValue(node, "with"); raw != nil {
+		s.RawWith = *raw
+	}
+	return nil
 }
 
 // String gets the name of step
@@ -710,6 +771,22 @@ func (s *Step) Type() StepType {
 
 // ReadWorkflow returns a list of jobs for a given workflow file reader
 func ReadWorkflow(in io.Reader, strict bool) (*Workflow, error) {
+	return ReadForgeWorkflow(in, strict, nil)
+}
+
+// ReadForgeWorkflow reads a workflow and validates it with the schema of forge, nil is GitHub
+func ReadForgeWorkflow(in io.Reader, strict bool, forge *Forge) (*Workflow, error) {
+	if forge != nil && forge.Name != ForgeGitHub {
+		var node yaml.Node
+		if err := yaml.NewDecoder(in).Decode(&node); err != nil {
+			return new(Workflow), err
+		}
+		w := new(Workflow)
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			return w, w.unmarshalWithSchema(node.Content[0], forge.Schema(), strict)
+		}
+		return w, nil
+	}
 	if strict {
 		w := new(WorkflowStrict)
 		err := yaml.NewDecoder(in).Decode(w)
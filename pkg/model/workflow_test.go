@@ -635,3 +635,53 @@ jobs:
 		assert.Equal(t, "actions/checkout@v5", job.Steps[0].Uses)
 	}
 }
+
+func TestJobMatrixNeeds(t *testing.T) {
+	yaml := `
+on: push
+jobs:
+  plan:
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo
+  plan-extra:
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo
+  build:
+    needs: [plan, plan-extra]
+    runs-on: ubuntu-latest
+    strategy:
+      matrix: ${{ fromJSON(needs.plan.outputs.targets) }}
+    steps:
+    - run: echo
+  test:
+    needs: [plan, plan-extra]
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: ${{ fromJSON(needs['plan-extra'].outputs.os) }}
+        node: [18, 20]
+    steps:
+    - run: echo
+  static:
+    needs: plan
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [linux]
+    steps:
+    - run: echo
+`
+
+	w, err := ReadWorkflow(strings.NewReader(yaml), false)
+	require.NoError(t, err, "read workflow should succeed")
+
+	assert.Equal(t, []string{"plan"}, w.GetJob("build").MatrixNeeds())
+	assert.Equal(t, []string{"plan-extra"}, w.GetJob("test").MatrixNeeds())
+	assert.Nil(t, w.GetJob("static").MatrixNeeds())
+	assert.Nil(t, w.GetJob("plan").MatrixNeeds())
+
+	assert.Equal(t, "build (matrix from plan)", (&Run{Workflow: w, JobID: "build"}).PlanName())
+	assert.Equal(t, "static", (&Run{Workflow: w, JobID: "static"}).PlanName())
+}
@@ -0,0 +1,272 @@
+package results
+
+// Receiver API of the results service, the runner uploads the step summaries and the logs with it
+//
+// 1. Get the signed url of a blob
+// Post: /twirp/results.services.receiver.Receiver/GetStepSummarySignedBlobURL
+// Post: /twirp/results.services.receiver.Receiver/GetStepLogsSignedBlobURL
+// Post: /twirp/results.services.receiver.Receiver/GetJobLogsSignedBlobURL
+// Request:
+// {
+//     "workflow_run_backend_id": "1",
+//     "workflow_job_run_backend_id": "1",
+//     "step_backend_id": "5c0b8a4e-0c1d-4d6a-9d3f-7a9b6c0e2f11"
+// }
+// step_backend_id is not sent for the logs of a job
+// Response:
+// {
+//     "summary_url": "http://localhost:3000/results/blobs/1/1/steps/5c0b8a4e-0c1d-4d6a-9d3f-7a9b6c0e2f11/summary.md?sig=...&expires=1713822517",
+//     "soft_size_limit": "1048576",
+//     "blob_storage_type": "BLOB_STORAGE_TYPE_AZURE"
+// }
+// the logs are at logs_url instead of summary_url
+// 2. Upload the content to the signed url (unauthenticated request)
+// PUT: <url>, a BlockBlob is written at once and an AppendBlob is created empty
+// PUT: <url>&comp=appendblock, repeat until the logs are uploaded
+// PUT: <url>&comp=seal
+// The content is downloaded again with GET: <url>
+// 3. Record the upload
+// Post: /twirp/results.services.receiver.Receiver/CreateStepSummaryMetadata
+// Post: /twirp/results.services.receiver.Receiver/CreateStepLogsMetadata
+// Post: /twirp/results.services.receiver.Receiver/CreateJobLogsMetadata
+// Request:
+// {
+//     "workflow_run_backend_id": "1",
+//     "workflow_job_run_backend_id": "1",
+//     "step_backend_id": "5c0b8a4e-0c1d-4d6a-9d3f-7a9b6c0e2f11",
+//     "uploaded_at": "2024-04-22T21:48:37Z",
+//     "line_count": 42
+// }
+// Response:
+// {
+//     "ok": true
+// }
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/actions-oss/act-cli/pkg/artifacts"
+)
+
+const (
+	ReceiverRouteBase = "/twirp/results.services.receiver.Receiver"
+	blobURLBase       = "/results/blobs"
+	blobURLExpiry     = time.Hour
+	// summarySizeLimit is the size of a step summary which GitHub accepts
+	summarySizeLimit = 1024 * 1024
+)
+
+type blobRequest struct {
+	WorkflowRunBackendID    string `json:"workflow_run_backend_id"`
+	WorkflowJobRunBackendID string `json:"workflow_job_run_backend_id"`
+	StepBackendID           string `json:"step_backend_id"`
+}
+
+type signedBlobResponse struct {
+	SummaryURL      string `json:"summary_url,omitempty"`
+	LogsURL         string `json:"logs_url,omitempty"`
+	SoftSizeLimit   int64  `json:"soft_size_limit,string,omitempty"`
+	BlobStorageType string `json:"blob_storage_type"`
+}
+
+// receiver stores the summaries and the logs at <dir>/results/<run id>/<job id>
+type receiver struct {
+	fs     artifacts.ReadWriteFS
+	dir    string
+	secret []byte
+	router *httprouter.Router
+}
+
+func newReceiver(fsys artifacts.ReadWriteFS, baseDir string) *receiver {
+	r := &receiver{fs: fsys, dir: filepath.Join(baseDir, "results"), secret: make([]byte, 32)}
+	if _, err := rand.Read(r.secret); err != nil {
+		panic(fmt.Errorf("generate secret: %w", err))
+	}
+
+	router := httprouter.New()
+	router.POST(ReceiverRouteBase+"/GetStepSummarySignedBlobURL", r.signedBlobURL(func(req *blobRequest) string {
+		return stepBlob(req, "summary.md")
+	}, func(res *signedBlobResponse, url string) {
+		res.SummaryURL, res.SoftSizeLimit = url, summarySizeLimit
+	}))
+	router.POST(ReceiverRouteBase+"/GetStepLogsSignedBlobURL", r.signedBlobURL(func(req *blobRequest) string {
+		return stepBlob(req, "logs.txt")
+	}, func(res *signedBlobResponse, url string) {
+		res.LogsURL = url
+	}))
+	router.POST(ReceiverRouteBase+"/GetJobLogsSignedBlobURL", r.signedBlobURL(func(*blobRequest) string {
+		return "logs.txt"
+	}, func(res *signedBlobResponse, url string) {
+		res.LogsURL = url
+	}))
+	for _, method := range []string{"CreateStepSummaryMetadata", "CreateStepLogsMetadata", "CreateJobLogsMetadata"} {
+		router.POST(ReceiverRouteBase+"/"+method, r.createMetadata)
+	}
+	router.PUT(blobURLBase+"/:runId/:jobId/*name", r.uploadBlob)
+	router.GET(blobURLBase+"/:runId/:jobId/*name", r.downloadBlob)
+	r.router = router
+	return r
+}
+
+func (r *receiver) routes() http.Handler {
+	return r.router
+}
+
+// signedBlobURL answers the request of a signed url for the blob at the name which blobName returns
+func (r *receiver) signedBlobURL(blobName func(*blobRequest) string, respond func(*signedBlobResponse, string)) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		body := &blobRequest{}
+		if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+			responseTwirpError(w, req, http.StatusBadRequest, "malformed", err)
+			return
+		}
+		name := blobName(body)
+		if !validID(body.WorkflowRunBackendID) || !validID(body.WorkflowJobRunBackendID) || !filepath.IsLocal(name) {
+			responseTwirpError(w, req, http.StatusBadRequest, "invalid_argument", errors.New("invalid run, job or step id"))
+			return
+		}
+		blob := path.Join(body.WorkflowRunBackendID, body.WorkflowJobRunBackendID, name)
+		expires := strconv.FormatInt(time.Now().Add(blobURLExpiry).Unix(), 10)
+		url := fmt.Sprintf("http://%s%s/%s?sig=%s&expires=%s", req.Host, blobURLBase, blob, r.blobSignature(blob, expires), expires)
+
+		res := &signedBlobResponse{BlobStorageType: "BLOB_STORAGE_TYPE_AZURE"}
+		respond(res, url)
+		responseJSON(w, res)
+	}
+}
+
+// POST /twirp/results.services.receiver.Receiver/Create*Metadata
+func (r *receiver) createMetadata(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	body := &blobRequest{}
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		responseTwirpError(w, req, http.StatusBadRequest, "malformed", err)
+		return
+	}
+	responseJSON(w, map[string]any{"ok": true})
+}
+
+// PUT /results/blobs/:runId/:jobId/*name
+func (r *receiver) uploadBlob(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	name, ok := r.verifyBlobURL(w, req, params)
+	if !ok {
+		return
+	}
+	var file artifacts.WritableFile
+	var err error
+	switch req.URL.Query().Get("comp") {
+	case "":
+		file, err = r.fs.OpenWritable(name)
+	case "appendblock":
+		file, err = r.fs.OpenAppendable(name)
+	case "seal":
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.Error(w, "unsupported comp "+req.URL.Query().Get("comp"), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := io.Copy(file, req.Body); err != nil {
+		_ = file.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := file.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GET /results/blobs/:runId/:jobId/*name
+func (r *receiver) downloadBlob(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	name, ok := r.verifyBlobURL(w, req, params)
+	if !ok {
+		return
+	}
+	file, err := r.fs.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, file); err != nil {
+		log.Errorf("download %s: %v", name, err)
+	}
+}
+
+func (r *receiver) blobSignature(blob, expires string) string {
+	mac := hmac.New(sha256.New, r.secret)
+	fmt.Fprintf(mac, "%s\n%s", blob, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyBlobURL returns the name of the blob in the storage if the url is signed and has not expired
+func (r *receiver) verifyBlobURL(w http.ResponseWriter, req *http.Request, params httprouter.Params) (string, bool) {
+	blob := path.Join(params.ByName("runId"), params.ByName("jobId"), strings.TrimPrefix(params.ByName("name"), "/"))
+	expires := req.URL.Query().Get("expires")
+	if !hmac.Equal([]byte(req.URL.Query().Get("sig")), []byte(r.blobSignature(blob, expires))) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return "", false
+	}
+	if t, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > t {
+		http.Error(w, "url expired", http.StatusUnauthorized)
+		return "", false
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(blob)), true
+}
+
+// stepBlob returns the name of a blob of the step of a request, it is empty if the request has no valid step id
+func stepBlob(req *blobRequest, name string) string {
+	if !validID(req.StepBackendID) {
+		return ""
+	}
+	return path.Join("steps", req.StepBackendID, name)
+}
+
+// validID reports whether an id of the requests may be used as a directory name
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && filepath.IsLocal(id)
+}
+
+func responseJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("encode response: %v", err)
+	}
+}
+
+// responseTwirpError writes an error like Twirp, its code is one of the Twirp error codes
+func responseTwirpError(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	log.Errorf("%v %v: %v", r.Method, r.RequestURI, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"code": code,
+		"msg":  err.Error(),
+	})
+}
@@ -0,0 +1,179 @@
+// Package results emulates the results service of GitHub at ACTIONS_RESULTS_URL, it serves the artifacts v4,
+// the cache v2, the step summaries and the logs of the jobs of a run on one port
+package results
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+	"github.com/actions-oss/act-cli/pkg/artifacts"
+	"github.com/actions-oss/act-cli/pkg/common"
+)
+
+// Config configures the results service
+type Config struct {
+	// Addr and Port are where the service listens, on a free port if Port is empty or 0
+	Addr string
+	Port string
+	// ArtifactPath is where the artifacts, the summaries and the logs are stored, see artifacts.OpenStorage.
+	// The artifacts are not served if it is empty and the summaries and the logs are kept in memory.
+	ArtifactPath string
+	// CachePath is where the caches are stored, see artifactcache.OpenStorage, the cache is not served if it is empty
+	CachePath string
+	// RequireAuth rejects the requests without a valid runtime token, except those of the signed urls of the blobs
+	RequireAuth bool
+}
+
+// Service is a results service which was started by Start
+type Service struct {
+	server   *http.Server
+	cache    *artifactcache.Handler
+	browser  *artifacts.Browser
+	receiver *receiver
+	cancel   context.CancelFunc
+	done     chan struct{}
+	addr     string
+	port     string
+}
+
+// Start starts a results service until ctx is done or it is closed
+func Start(ctx context.Context, cfg Config) (*Service, error) {
+	port := cfg.Port
+	if port == "" {
+		port = "0"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(cfg.Addr, port))
+	if err != nil {
+		return nil, err
+	}
+	serviceContext, cancel := context.WithCancel(ctx)
+	s := &Service{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		addr:   cfg.Addr,
+		port:   strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+	}
+	handler, err := s.routes(serviceContext, cfg)
+	if err != nil {
+		cancel()
+		_ = listener.Close()
+		return nil, err
+	}
+	s.server = &http.Server{
+		ReadHeaderTimeout: 2 * time.Second,
+		Handler:           handler,
+	}
+
+	logger := common.Logger(serviceContext)
+	go func() {
+		logger.Infof("Start results service on %s", s.URL())
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("results service: %v", err)
+		}
+	}()
+	go func() {
+		defer close(s.done)
+		<-serviceContext.Done()
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			logger.Errorf("failed shutdown gracefully - force shutdown: %v", err)
+			s.server.Close()
+		}
+		_ = s.cache.Close()
+	}()
+	return s, nil
+}
+
+func (s *Service) routes(ctx context.Context, cfg Config) (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	var store artifacts.ReadWriteFS = artifacts.NewMemoryFS()
+	resultsDir := "/"
+	if cfg.ArtifactPath != "" {
+		fsys, baseDir, err := artifacts.OpenStorage(cfg.ArtifactPath)
+		if err != nil {
+			return nil, fmt.Errorf("artifact storage %s: %w", cfg.ArtifactPath, err)
+		}
+		router := httprouter.New()
+		artifacts.Routes(ctx, router, baseDir, fsys)
+		mux.Handle("/", router)
+		s.browser = artifacts.NewBrowser(fsys, baseDir)
+		// the directory is no run id, so it is not listed with the artifacts
+		store, resultsDir = fsys, baseDir
+	}
+	s.receiver = newReceiver(store, resultsDir)
+	mux.Handle(ReceiverRouteBase+"/", s.receiver.routes())
+	mux.Handle(blobURLBase+"/", s.receiver.routes())
+
+	if cfg.CachePath != "" {
+		var opts []artifactcache.Option
+		if cfg.RequireAuth {
+			opts = append(opts, artifactcache.WithAuth())
+		}
+		cache, router, err := artifactcache.CreateHandler(cfg.CachePath, strings.TrimSuffix(s.URL(), "/"), common.Logger(ctx), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("cache storage %s: %w", cfg.CachePath, err)
+		}
+		s.cache = cache
+		mux.Handle("/_apis/artifactcache/", router)
+		mux.Handle(artifactcache.CacheV2RouteBase+"/", router)
+		mux.Handle("/metrics", router)
+	}
+
+	if !cfg.RequireAuth {
+		return mux, nil
+	}
+	return authenticate(mux), nil
+}
+
+// authenticate rejects the requests without a valid runtime token, the signed urls of the blobs are verified by their handlers
+// because the clients don't send the token to them
+func authenticate(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("sig") || r.URL.Path == "/metrics" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		if claims, err := common.ParseAuthorizationClaims(r); err != nil || claims == nil {
+			http.Error(w, fmt.Sprintf("invalid token: %v", err), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Port returns the port where the service listens
+func (s *Service) Port() string {
+	return s.port
+}
+
+// URL returns the url of the service like ACTIONS_RESULTS_URL, with a trailing slash
+func (s *Service) URL() string {
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(s.addr, s.port))
+}
+
+// Artifacts returns the browser of the artifacts, nil if the service doesn't serve them
+func (s *Service) Artifacts() *artifacts.Browser {
+	return s.browser
+}
+
+// Close stops the service and waits until it is stopped
+func (s *Service) Close() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
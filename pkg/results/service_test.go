@@ -0,0 +1,143 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/artifactcache"
+	"github.com/actions-oss/act-cli/pkg/artifacts"
+	"github.com/actions-oss/act-cli/pkg/common"
+)
+
+func TestService(t *testing.T) {
+	service, err := Start(context.Background(), Config{
+		Addr:         "127.0.0.1",
+		ArtifactPath: "memory://",
+		CachePath:    filepath.Join(t.TempDir(), "cache"),
+		RequireAuth:  true,
+	})
+	require.NoError(t, err)
+	defer service.Close()
+
+	token, err := common.CreateJobAuthorizationToken(1, 1, 1, "build", "owner/repo", "refs/heads/main")
+	require.NoError(t, err)
+	do := func(t *testing.T, method, url, token string, body []byte) (int, []byte) {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, data
+	}
+	call := func(t *testing.T, route, token string, req, res any) int {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		status, data := do(t, http.MethodPost, strings.TrimSuffix(service.URL(), "/")+route, token, body)
+		if res != nil && status == http.StatusOK {
+			require.NoError(t, json.Unmarshal(data, res))
+		}
+		return status
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		// a token with the claims of act which is signed with another key
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"scp": "Actions.Results:1:1", "TaskID": 1, "RunID": 1, "JobID": 1, "repository": "owner/repo",
+		}).SignedString([]byte{})
+		require.NoError(t, err)
+		for _, route := range []string{
+			artifactcache.CacheV2RouteBase + "/CreateCacheEntry",
+			artifacts.ArtifactV4RouteBase + "/CreateArtifact",
+			ReceiverRouteBase + "/GetStepSummarySignedBlobURL",
+		} {
+			assert.Equal(t, http.StatusUnauthorized, call(t, route, "", map[string]any{}, nil), route)
+			assert.Equal(t, http.StatusUnauthorized, call(t, route, "invalid", map[string]any{}, nil), route)
+			assert.Equal(t, http.StatusUnauthorized, call(t, route, forged, map[string]any{}, nil), route)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		version := "c19da02a2bd7e77277f1ac29ab45c09b7d46a4ee758284e26bb3045ad11d9d20"
+		created := &artifactcache.CreateCacheEntryResponse{}
+		require.Equal(t, http.StatusOK, call(t, artifactcache.CacheV2RouteBase+"/CreateCacheEntry", token,
+			&artifactcache.CreateCacheEntryRequest{Key: "key", Version: version}, created))
+		assert.True(t, strings.HasPrefix(created.SignedUploadURL, service.URL()), "the cache is served at the results url")
+		status, _ := do(t, http.MethodPut, created.SignedUploadURL, "", []byte("content"))
+		require.Equal(t, http.StatusCreated, status)
+		require.Equal(t, http.StatusOK, call(t, artifactcache.CacheV2RouteBase+"/FinalizeCacheEntryUpload", token,
+			map[string]any{"key": "key", "version": version, "sizeBytes": "7"}, nil))
+
+		found := &artifactcache.GetCacheEntryDownloadURLResponse{}
+		require.Equal(t, http.StatusOK, call(t, artifactcache.CacheV2RouteBase+"/GetCacheEntryDownloadURL", token,
+			&artifactcache.GetCacheEntryDownloadURLRequest{Key: "key", Version: version}, found))
+		require.True(t, found.Ok)
+		status, content := do(t, http.MethodGet, found.SignedDownloadURL, "", nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "content", string(content))
+	})
+
+	t.Run("artifacts", func(t *testing.T) {
+		created := map[string]any{}
+		require.Equal(t, http.StatusOK, call(t, artifacts.ArtifactV4RouteBase+"/CreateArtifact", token,
+			map[string]any{"workflow_run_backend_id": "1", "name": "result", "version": 4}, &created))
+		assert.True(t, strings.HasPrefix(created["signedUploadUrl"].(string), service.URL()), "the artifacts are served at the results url")
+		status, _ := do(t, http.MethodPut, created["signedUploadUrl"].(string)+"&comp=appendBlock", "", []byte("zip"))
+		require.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		req := map[string]any{"workflow_run_backend_id": "1", "workflow_job_run_backend_id": "2", "step_backend_id": "step"}
+		signed := &signedBlobResponse{}
+		require.Equal(t, http.StatusOK, call(t, ReceiverRouteBase+"/GetStepSummarySignedBlobURL", token, req, signed))
+		assert.Equal(t, int64(summarySizeLimit), signed.SoftSizeLimit)
+		status, _ := do(t, http.MethodPut, signed.SummaryURL, "", []byte("# Summary"))
+		require.Equal(t, http.StatusCreated, status)
+		require.Equal(t, http.StatusOK, call(t, ReceiverRouteBase+"/CreateStepSummaryMetadata", token, req, nil))
+
+		status, content := do(t, http.MethodGet, signed.SummaryURL, "", nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "# Summary", string(content))
+
+		status, _ = do(t, http.MethodGet, strings.Replace(signed.SummaryURL, "/2/", "/3/", 1), "", nil)
+		assert.Equal(t, http.StatusUnauthorized, status, "the signature covers the job")
+		delete(req, "step_backend_id")
+		assert.Equal(t, http.StatusBadRequest, call(t, ReceiverRouteBase+"/GetStepSummarySignedBlobURL", token, req, nil))
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		signed := &signedBlobResponse{}
+		require.Equal(t, http.StatusOK, call(t, ReceiverRouteBase+"/GetJobLogsSignedBlobURL", token,
+			map[string]any{"workflow_run_backend_id": "1", "workflow_job_run_backend_id": "2"}, signed))
+		for _, part := range []struct{ comp, content string }{{"", ""}, {"appendblock", "line 1\n"}, {"appendblock", "line 2\n"}, {"seal", ""}} {
+			url := signed.LogsURL
+			if part.comp != "" {
+				url += "&comp=" + part.comp
+			}
+			status, _ := do(t, http.MethodPut, url, "", []byte(part.content))
+			require.Less(t, status, 300, part.comp)
+		}
+		status, content := do(t, http.MethodGet, signed.LogsURL, "", nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "line 1\nline 2\n", string(content))
+	})
+
+	list, err := service.Artifacts().List(1)
+	require.NoError(t, err)
+	assert.Empty(t, list, "the results are not listed as artifacts and the artifact is not finalized")
+}
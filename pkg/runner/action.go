@@ -192,56 +192,23 @@ func execAsDocker(ctx context.Context, step actionStep, actionName, subpath stri
 	rc := step.getRunContext()
 	action := step.getActionModel()
 
-	var prepImage common.Executor
-	var image string
-	forcePull := false
-	if strings.HasPrefix(action.Runs.Image, "docker://") {
-		image = strings.TrimPrefix(action.Runs.Image, "docker://")
-		// Apply forcePull only for prebuild docker images
-		forcePull = rc.Config.ForcePull
-	} else {
-		// "-dockeraction" ensures that "./", "./test " won't get converted to "act-:latest", "act-test-:latest" which are invalid docker image names
-		image = fmt.Sprintf("%s-dockeraction:%s", regexp.MustCompile("[^a-zA-Z0-9]").ReplaceAllString(actionName, "-"), "latest")
-		image = fmt.Sprintf("act-%s", strings.TrimLeft(image, "-"))
-		image = strings.ToLower(image)
-		contextDir, fileName := path.Split(path.Join(subpath, action.Runs.Image))
-
-		anyArchExists, err := container.ImageExistsLocally(ctx, image, "any")
-		if err != nil {
-			return err
-		}
+	image, buildInput, err := dockerActionImage(ctx, rc, action, actionName, subpath)
+	if err != nil {
+		return err
+	}
+	// Apply forcePull only for prebuild docker images
+	forcePull := buildInput == nil && strings.HasPrefix(action.Runs.Image, "docker://") && rc.Config.ForcePull
 
-		correctArchExists, err := container.ImageExistsLocally(ctx, image, rc.Config.ContainerArchitecture)
+	var prepImage common.Executor
+	if buildInput != nil {
+		logger.Debugf("image '%s' for architecture '%s' will be built from context '%s", image, rc.Config.ContainerArchitecture, buildInput.ContextDir)
+		buildContext, err := step.getTarArchive(ctx, buildInput.ContextDir+".")
 		if err != nil {
 			return err
 		}
-
-		if anyArchExists && !correctArchExists {
-			wasRemoved, err := container.RemoveImage(ctx, image, true, true)
-			if err != nil {
-				return err
-			}
-			if !wasRemoved {
-				return fmt.Errorf("failed to remove image '%s'", image)
-			}
-		}
-
-		if !correctArchExists || rc.Config.ForceRebuild {
-			logger.Debugf("image '%s' for architecture '%s' will be built from context '%s", image, rc.Config.ContainerArchitecture, contextDir)
-			buildContext, err := step.getTarArchive(ctx, contextDir+".")
-			if err != nil {
-				return err
-			}
-			defer buildContext.Close()
-			prepImage = container.NewDockerBuildExecutor(container.NewDockerBuildExecutorInput{
-				Dockerfile:   fileName,
-				ImageTag:     image,
-				BuildContext: buildContext,
-				Platform:     rc.Config.ContainerArchitecture,
-			})
-		} else {
-			logger.Debugf("image '%s' for architecture '%s' already exists", image, rc.Config.ContainerArchitecture)
-		}
+		defer buildContext.Close()
+		buildInput.BuildContext = buildContext
+		prepImage = container.NewDockerBuildExecutor(*buildInput)
 	}
 	eval := rc.NewStepExpressionEvaluator(ctx, step)
 	cmd, err := shellquote.Split(eval.Interpolate(ctx, step.getStepModel().With["args"]))
@@ -287,6 +254,56 @@ func execAsDocker(ctx context.Context, step actionStep, actionName, subpath stri
 	).Finally(stepContainer.Close())(ctx)
 }
 
+// dockerActionImage returns the image of a docker action, the build input is
+// set if the image has to be built from the Dockerfile of the action
+func dockerActionImage(ctx context.Context, rc *RunContext, action *model.Action, actionName, subpath string) (string, *container.NewDockerBuildExecutorInput, error) {
+	logger := common.Logger(ctx)
+	if strings.HasPrefix(action.Runs.Image, "docker://") {
+		return strings.TrimPrefix(action.Runs.Image, "docker://"), nil, nil
+	}
+
+	// "-dockeraction" ensures that "./", "./test " won't get converted to "act-:latest", "act-test-:latest" which are invalid docker image names
+	image := fmt.Sprintf("%s-dockeraction:%s", regexp.MustCompile("[^a-zA-Z0-9]").ReplaceAllString(actionName, "-"), "latest")
+	image = fmt.Sprintf("act-%s", strings.TrimLeft(image, "-"))
+	image = strings.ToLower(image)
+	contextDir, fileName := path.Split(path.Join(subpath, action.Runs.Image))
+
+	anyArchExists, err := container.ImageExistsLocally(ctx, image, "any")
+	if err != nil {
+		return "", nil, err
+	}
+
+	correctArchExists, err := container.ImageExistsLocally(ctx, image, rc.Config.ContainerArchitecture)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if anyArchExists && !correctArchExists {
+		wasRemoved, err := container.RemoveImage(ctx, image, true, true)
+		if err != nil {
+			return "", nil, err
+		}
+		if !wasRemoved {
+			return "", nil, fmt.Errorf("failed to remove image '%s'", image)
+		}
+	}
+
+	if correctArchExists && !rc.Config.ForceRebuild {
+		logger.Debugf("image '%s' for architecture '%s' already exists", image, rc.Config.ContainerArchitecture)
+		return image, nil, nil
+	}
+	return image, &container.NewDockerBuildExecutorInput{
+		ContextDir: contextDir,
+		Dockerfile: fileName,
+		ImageTag:   image,
+		Platform:   rc.Config.ContainerArchitecture,
+		BuildArgs:  rc.Config.ActionBuildArgs,
+		Secrets:    rc.Config.ActionBuildSecrets,
+		CacheDir:   actionBuildCacheDir(rc),
+		CacheSize:  rc.Config.ActionBuildCacheSize,
+	}, nil
+}
+
 func evalDockerArgs(ctx context.Context, step step, action *model.Action, cmd *[]string) {
 	rc := step.getRunContext()
 	stepModel := step.getStepModel()
@@ -366,6 +383,7 @@ func newStepContainer(ctx context.Context, step step, image string, cmd []string
 		UsernsMode:  rc.Config.UsernsMode,
 		Platform:    rc.Config.ContainerArchitecture,
 		Options:     rc.Config.ContainerOptions,
+		ImagePull:   rc.Config.ImagePull,
 	})
 	return stepContainer
 }
@@ -560,3 +578,11 @@ func runPostStep(step actionStep) common.Executor {
 		}
 	}
 }
+
+// actionBuildCacheDir returns the image cache of Dockerfile actions, --rebuild bypasses the cache
+func actionBuildCacheDir(rc *RunContext) string {
+	if rc.Config.ForceRebuild {
+		return ""
+	}
+	return rc.Config.ActionBuildCacheDir
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
 	git "github.com/go-git/go-git/v5"
 	config "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -86,9 +87,60 @@ func (c GoGitActionCache) Fetch(ctx context.Context, cacheDir, url, ref, token s
 		return "", fmt.Errorf("goGitActionCache failed to resolve sha %s with ref %s at %s: %w", url, ref, gitPath, err)
 	}
 	logger.Infof("GoGitActionCache fetch %s with ref %s at %s resolved to %s", url, ref, gitPath, hash.String())
+	// remember the ref, so the cached content can be found without fetching again
+	_ = gogitrepo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName("refs/action-cache/"+ref), *hash))
 	return hash.String(), nil
 }
 
+// Resolve returns the sha of a ref which has been fetched before without accessing the remote
+func (c GoGitActionCache) Resolve(cacheDir, ref string) (string, error) {
+	gitPath := path.Join(c.Path, safeFilename(cacheDir)+".git")
+	gogitrepo, err := git.PlainOpen(gitPath)
+	if err != nil {
+		return "", fmt.Errorf("goGitActionCache failed to open bare git %s at %s: %w", cacheDir, gitPath, err)
+	}
+	for _, name := range []string{"refs/action-cache/" + ref, "refs/action-cache-offline/" + ref} {
+		if r, err := gogitrepo.Reference(plumbing.ReferenceName(name), true); err == nil {
+			return r.Hash().String(), nil
+		}
+	}
+	if plumbing.IsHash(ref) {
+		if _, err := gogitrepo.CommitObject(plumbing.NewHash(ref)); err == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("goGitActionCache %s has no cached ref %s", cacheDir, ref)
+}
+
+// ReadCachedAction reads the metadata of a remote action, e.g. owner/repo/path@ref,
+// from the action cache without fetching it
+func ReadCachedAction(ctx context.Context, cache GoGitActionCache, uses string) (*model.Action, error) {
+	ra := newRemoteAction(uses)
+	if ra == nil {
+		return nil, fmt.Errorf("expected format {org}/{repo}[/path]@ref. Actual '%s' Input string was not in a correct format", uses)
+	}
+	cacheDir := fmt.Sprintf("%s/%s", ra.Org, ra.Repo)
+	sha, err := cache.Resolve(cacheDir, ra.Ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		archive, err := cache.GetTarArchive(ctx, cacheDir, sha, path.Join(ra.Path, name))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(archive)
+		_, err = tr.Next()
+		if err == nil {
+			action, err := model.ReadAction(tr)
+			archive.Close()
+			return action, err
+		}
+		archive.Close()
+	}
+	return nil, fmt.Errorf("no action.yml found in %s", uses)
+}
+
 type GitFileInfo struct {
 	name    string
 	size    int64
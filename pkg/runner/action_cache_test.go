@@ -6,9 +6,15 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //nolint:gosec
@@ -144,3 +150,47 @@ func TestActionCacheFailures(t *testing.T) {
 		})
 	}
 }
+
+func TestReadCachedAction(t *testing.T) {
+	src := t.TempDir()
+	repo, err := git.PlainInit(src, false)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "action.yml"), []byte(`
+name: sub
+inputs:
+  name:
+    description: the name
+runs:
+  using: node20
+  main: index.js
+`), 0o644))
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("sub/action.yml")
+	require.NoError(t, err)
+	_, err = wt.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "act", Email: "act@example.com", When: time.Now()}})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("v1", mustHead(t, repo), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cache := GoGitActionCache{Path: t.TempDir()}
+	_, err = ReadCachedAction(ctx, cache, "owner/repo/sub@v1")
+	assert.Error(t, err, "not fetched yet")
+
+	_, err = cache.Fetch(ctx, "owner/repo", src, "v1", "")
+	require.NoError(t, err)
+	action, err := ReadCachedAction(ctx, cache, "owner/repo/sub@v1")
+	require.NoError(t, err)
+	assert.Equal(t, "the name", action.Inputs["name"].Description)
+
+	_, err = ReadCachedAction(ctx, cache, "owner/repo@v1")
+	assert.Error(t, err, "no action.yml in the root")
+}
+
+func mustHead(t *testing.T, repo *git.Repository) plumbing.Hash {
+	head, err := repo.Head()
+	require.NoError(t, err)
+	return head.Hash()
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	eval "github.com/actions-oss/act-cli/internal/eval/v2"
+	expr "github.com/actions-oss/act-cli/internal/expr"
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/exprparser"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+// JobEvaluator evaluates expressions in the context of a job, the environment
+// is constructed like the runner does it before the job starts
+type JobEvaluator struct {
+	Matrix map[string]interface{} // the matrix combination of the job
+	env    *exprparser.EvaluationEnvironment
+	config exprparser.Config
+}
+
+// NewJobEvaluator creates a JobEvaluator for a job of the plan. The first matrix
+// combination which matches Config.Matrix is used, needs replaces the outputs and
+// results of the needed jobs, e.g. with the ones of a previous run. Secrets are masked.
+func NewJobEvaluator(ctx context.Context, config *Config, plan *model.Plan, jobID string, needs map[string]exprparser.Needs) (*JobEvaluator, error) {
+	runner := &runnerImpl{
+		config: config,
+	}
+	if _, err := runner.configure(); err != nil {
+		return nil, err
+	}
+
+	var run *model.Run
+	for _, stage := range plan.Stages {
+		for _, r := range stage.Runs {
+			if r.JobID == jobID {
+				run = r
+			}
+		}
+	}
+	if run == nil {
+		return nil, fmt.Errorf("job '%s' not found", jobID)
+	}
+	for id, n := range needs {
+		job := run.Workflow.GetJob(id)
+		if job == nil {
+			return nil, fmt.Errorf("needed job '%s' not found", id)
+		}
+		job.Outputs = n.Outputs
+		job.Result = n.Result
+	}
+
+	matrixes := runner.expandMatrix(ctx, run)
+	if len(matrixes) == 0 {
+		return nil, fmt.Errorf("no matrix combination of job '%s' matches", jobID)
+	}
+	if len(matrixes) > 1 {
+		common.Logger(ctx).Infof("Job '%s' has %d matrix combinations, using %v", jobID, len(matrixes), matrixes[0])
+	}
+
+	rc := runner.newRunContext(ctx, run, matrixes[0])
+	env := rc.newEvaluationEnvironment(ctx, rc.GetEnv())
+	secrets := make(map[string]string, len(env.Secrets))
+	for k := range env.Secrets {
+		secrets[k] = "***"
+	}
+	env.Secrets = secrets
+
+	return &JobEvaluator{
+		Matrix: matrixes[0],
+		env:    env,
+		config: exprparser.Config{
+			Run:        run,
+			WorkingDir: config.Workdir,
+			Context:    "job",
+			Host:       config.Forge,
+		},
+	}, nil
+}
+
+// Evaluate evaluates an expression, optionally enclosed in ${{ }}, and returns the
+// raw value and kind of the result, every evaluated node is written to trace if set
+func (je *JobEvaluator) Evaluate(expression string, trace eval.TraceWriter) (interface{}, eval.ValueKind, error) {
+	expression = strings.TrimSpace(expression)
+	expression = strings.TrimPrefix(expression, "${{")
+	expression = strings.TrimSuffix(expression, "}}")
+	node, err := expr.Parse(expression)
+	if err != nil {
+		return nil, eval.ValueKindNull, fmt.Errorf("failed to parse: %w", err)
+	}
+	evaluator := exprparser.NewEvaluator(je.env, je.config, trace)
+	result, err := evaluator.Evaluate(node)
+	if err != nil {
+		return nil, eval.ValueKindNull, err
+	}
+	raw, err := evaluator.ToRaw(result)
+	return raw, result.Kind(), err
+}
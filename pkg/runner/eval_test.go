@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eval "github.com/actions-oss/act-cli/internal/eval/v2"
+	"github.com/actions-oss/act-cli/pkg/exprparser"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+func TestJobEvaluator(t *testing.T) {
+	workflow := `
+name: eval
+on: push
+jobs:
+  setup:
+    runs-on: ubuntu-latest
+    outputs:
+      matrix: ${{ steps.m.outputs.matrix }}
+    steps:
+      - id: m
+        run: echo
+  build:
+    needs: setup
+    runs-on: ubuntu-latest
+    env:
+      FOO: bar
+    strategy:
+      matrix:
+        os: [linux, windows]
+    steps:
+      - run: echo
+`
+	planner, err := model.NewSingleWorkflowPlanner("eval.yml", strings.NewReader(workflow))
+	require.NoError(t, err)
+	plan, err := planner.PlanJob("build")
+	require.NoError(t, err)
+
+	config := &Config{
+		Workdir:   t.TempDir(),
+		EventName: "push",
+		Secrets:   map[string]string{"TOKEN": "secret"},
+		Vars:      map[string]string{"NAME": "value"},
+		Matrix:    map[string]map[string]bool{"os": {"windows": true}},
+	}
+	needs := map[string]exprparser.Needs{
+		"setup": {Outputs: map[string]string{"matrix": `[{"os":"linux"}]`}, Result: "success"},
+	}
+	evaluator, err := NewJobEvaluator(context.Background(), config, plan, "build", needs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"os": "windows"}, evaluator.Matrix)
+
+	for expression, want := range map[string]struct {
+		value interface{}
+		kind  eval.ValueKind
+	}{
+		"fromJSON(needs.setup.outputs.matrix)[0]": {map[string]interface{}{"os": "linux"}, eval.ValueKindObject},
+		"${{ needs.setup.result }}":               {"success", eval.ValueKindString},
+		"matrix.os":                               {"windows", eval.ValueKindString},
+		"secrets.TOKEN":                           {"***", eval.ValueKindString},
+		"vars.NAME":                               {"value", eval.ValueKindString},
+		"env.FOO == 'bar'":                        {true, eval.ValueKindBoolean},
+		"github.event_name":                       {"push", eval.ValueKindString},
+		"needs.setup.outputs.missing":             {nil, eval.ValueKindNull},
+	} {
+		value, kind, err := evaluator.Evaluate(expression, nil)
+		require.NoError(t, err, expression)
+		assert.Equal(t, want.value, value, expression)
+		assert.Equal(t, want.kind, kind, expression)
+	}
+
+	_, err = NewJobEvaluator(context.Background(), config, plan, "missing", nil)
+	assert.Error(t, err)
+}
@@ -8,14 +8,18 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	_ "embed"
 
+	eval "github.com/actions-oss/act-cli/internal/eval/v2"
+	"github.com/actions-oss/act-cli/internal/templateeval"
 	"github.com/actions-oss/act-cli/pkg/common"
 	"github.com/actions-oss/act-cli/pkg/container"
 	"github.com/actions-oss/act-cli/pkg/exprparser"
 	"github.com/actions-oss/act-cli/pkg/model"
+	"github.com/actions-oss/act-cli/pkg/schema"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,6 +27,8 @@ import (
 type ExpressionEvaluator interface {
 	evaluate(context.Context, string, exprparser.DefaultStatusCheck) (interface{}, error)
 	EvaluateYamlNode(context.Context, *yaml.Node) error
+	// EvaluateSchemaYamlNode evaluates node as the value at path below a job of the workflow schema
+	EvaluateSchemaYamlNode(ctx context.Context, node *yaml.Node, path ...string) error
 	Interpolate(context.Context, string) string
 }
 
@@ -32,6 +38,24 @@ func (rc *RunContext) NewExpressionEvaluator(ctx context.Context) ExpressionEval
 }
 
 func (rc *RunContext) NewExpressionEvaluatorWithEnv(ctx context.Context, env map[string]string) ExpressionEvaluator {
+	return newExpressionEvaluator(rc.newEvaluationEnvironment(ctx, env), exprparser.Config{
+		Run:        rc.Run,
+		WorkingDir: rc.Config.Workdir,
+		Context:    "job",
+		Host:       rc.Config.Forge,
+	})
+}
+
+func newExpressionEvaluator(env *exprparser.EvaluationEnvironment, config exprparser.Config) expressionEvaluator {
+	return expressionEvaluator{
+		interpreter: exprparser.NewInterpeter(env, config),
+		env:         env,
+		config:      config,
+	}
+}
+
+// newEvaluationEnvironment creates the environment of the expressions of a job
+func (rc *RunContext) newEvaluationEnvironment(ctx context.Context, env map[string]string) *exprparser.EvaluationEnvironment {
 	var workflowCallResult map[string]*model.WorkflowCallResult
 
 	// todo: cleanup EvaluationEnvironment creation
@@ -62,10 +86,7 @@ func (rc *RunContext) NewExpressionEvaluatorWithEnv(ctx context.Context, env map
 
 			for jobName, job := range jobs {
 				result := model.WorkflowCallResult{
-					Outputs: map[string]string{},
-				}
-				for k, v := range job.Outputs {
-					result.Outputs[k] = v
+					Outputs: jobOutputs(job),
 				}
 				workflowCallResult[jobName] = &result
 			}
@@ -96,13 +117,7 @@ func (rc *RunContext) NewExpressionEvaluatorWithEnv(ctx context.Context, env map
 		ee.Runner = rc.JobContainer.GetRunnerContext(ctx)
 		ee.EnvCS = !rc.JobContainer.IsEnvironmentCaseInsensitive()
 	}
-	return expressionEvaluator{
-		interpreter: exprparser.NewInterpeter(ee, exprparser.Config{
-			Run:        rc.Run,
-			WorkingDir: rc.Config.Workdir,
-			Context:    "job",
-		}),
-	}
+	return ee
 }
 
 //go:embed hashfiles/index.js
@@ -162,13 +177,12 @@ func (rc *RunContext) newStepExpressionEvaluator(ctx context.Context, step step,
 		ee.Runner = rc.JobContainer.GetRunnerContext(ctx)
 		ee.EnvCS = !rc.JobContainer.IsEnvironmentCaseInsensitive()
 	}
-	return expressionEvaluator{
-		interpreter: exprparser.NewInterpeter(ee, exprparser.Config{
-			Run:        rc.Run,
-			WorkingDir: rc.Config.Workdir,
-			Context:    "step",
-		}),
-	}
+	return newExpressionEvaluator(ee, exprparser.Config{
+		Run:        rc.Run,
+		WorkingDir: rc.Config.Workdir,
+		Context:    "step",
+		Host:       rc.Config.Forge,
+	})
 }
 
 func getHashFilesFunction(ctx context.Context, rc *RunContext) func(v []reflect.Value) (interface{}, error) {
@@ -226,14 +240,22 @@ func getHashFilesFunction(ctx context.Context, rc *RunContext) func(v []reflect.
 					return output[outstart : outstart+outend], nil
 				}
 			}
+			return "", nil
 		}
-		return "", nil
+		// without a job container, e.g. for act eval, the files of the workdir are hashed by act
+		args := make([]string, 0, len(v))
+		for _, p := range v {
+			args = append(args, p.String())
+		}
+		return eval.HashFilesIn(rc.Config.Workdir, args...)
 	}
 	return hashFiles
 }
 
 type expressionEvaluator struct {
 	interpreter exprparser.Interpreter
+	env         *exprparser.EvaluationEnvironment
+	config      exprparser.Config
 }
 
 func (ee expressionEvaluator) evaluate(ctx context.Context, in string, defaultStatusCheck exprparser.DefaultStatusCheck) (interface{}, error) {
@@ -260,11 +282,7 @@ func (ee expressionEvaluator) evaluateScalarYamlNode(ctx context.Context, node *
 	if err != nil {
 		return nil, err
 	}
-	ret := &yaml.Node{}
-	if err := ret.Encode(res); err != nil {
-		return nil, err
-	}
-	return ret, err
+	return templateeval.EncodeResult(res)
 }
 
 func (ee expressionEvaluator) evaluateMappingYamlNode(ctx context.Context, node *yaml.Node) (*yaml.Node, error) {
@@ -381,6 +399,43 @@ func (ee expressionEvaluator) EvaluateYamlNode(ctx context.Context, node *yaml.N
 	return nil
 }
 
+// forgeSchemas holds the workflow schema of each forge, it is parsed once for the schema aware evaluation of all jobs
+var forgeSchemas sync.Map
+
+func workflowSchema(forge string) *schema.Schema {
+	if s, ok := forgeSchemas.Load(forge); ok {
+		return s.(*schema.Schema)
+	}
+	f, err := model.GetForge(forge)
+	if err != nil {
+		f, _ = model.GetForge(model.ForgeGitHub)
+	}
+	s, _ := forgeSchemas.LoadOrStore(forge, f.Schema())
+	return s.(*schema.Schema)
+}
+
+// jobSchemaNode returns the schema of the value at path below a job, it includes the contexts
+// and functions which the forge allows for the value
+func jobSchemaNode(forge string, path ...string) *schema.Node {
+	return (&schema.Node{Definition: "job-factory", Schema: workflowSchema(forge)}).GetNestedNode(path...)
+}
+
+func (ee expressionEvaluator) EvaluateSchemaYamlNode(ctx context.Context, node *yaml.Node, path ...string) error {
+	snode := jobSchemaNode(ee.config.Host, path...)
+	if snode == nil {
+		return fmt.Errorf("%s is not a key of a job", strings.Join(path, "."))
+	}
+	// like GitHub only the contexts of the key are available to its expressions
+	if err := snode.UnmarshalYAML(node); err != nil {
+		return err
+	}
+	common.Logger(ctx).Debugf("evaluating %s at line %d", strings.Join(path, "."), node.Line)
+	evaluator := templateeval.ExpressionEvaluator{
+		EvaluationContext: *exprparser.NewEvaluator(ee.env, ee.config, nil).Context(),
+	}
+	return evaluator.EvaluateYamlNode(ctx, node, snode)
+}
+
 func (ee expressionEvaluator) Interpolate(ctx context.Context, in string) string {
 	if !strings.Contains(in, "${{") || !strings.Contains(in, "}}") {
 		return in
@@ -47,10 +47,14 @@ func newJobExecutor(info jobInfo, sf stepFactory, rc *RunContext) common.Executo
 		rc.ExprEval = rc.NewExpressionEvaluator(ctx)
 		// evaluate environment variables since they can contain
 		// GitHub's special environment variables.
+		// The env of the job is evaluated once through the workflow schema by evaluateJobEnv.
+		jobEnv := rc.jobEnvKeys()
 		for k, v := range rc.GetEnv() {
-			rc.Env[k] = rc.ExprEval.Interpolate(ctx, v)
+			if !jobEnv[k] {
+				rc.Env[k] = rc.ExprEval.Interpolate(ctx, v)
+			}
 		}
-		return nil
+		return rc.evaluateJobEnv(ctx)
 	})
 
 	var setJobError = func(ctx context.Context, err error) error {
@@ -160,8 +164,8 @@ func setJobResult(ctx context.Context, info jobInfo, rc *RunContext, success boo
 	jobResult := "success"
 	// we have only one result for a whole matrix build, so we need
 	// to keep an existing result state if we run a matrix
-	if len(info.matrix()) > 0 && rc.Run.Job().Result != "" {
-		jobResult = rc.Run.Job().Result
+	if result := sharedJobResult(rc.Run.Job()); len(info.matrix()) > 0 && result != "" {
+		jobResult = result
 	}
 
 	if !success {
@@ -193,7 +197,9 @@ func setJobOutputs(ctx context.Context, rc *RunContext) {
 			callerOutputs[k] = ee.Interpolate(ctx, ee.Interpolate(ctx, v.Value))
 		}
 
+		jobMu.Lock()
 		rc.caller.runContext.Run.Job().Outputs = callerOutputs
+		jobMu.Unlock()
 	}
 }
 
@@ -0,0 +1,336 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/container"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+// maxPrepareDepth limits nested composite actions and reusable workflows
+const maxPrepareDepth = 10
+
+// PrepareSummary lists everything cached by the prepare executor
+type PrepareSummary struct {
+	Images  []string // pulled images
+	Built   []string // images built from the Dockerfile of an action
+	Actions []string // remote actions and reusable workflows fetched into the action cache
+	Failed  []string // items which could not be prepared, with the error
+	seen    map[string]bool
+}
+
+func (s *PrepareSummary) once(kind, name string) bool {
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	key := kind + "\x00" + name
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+func (s *PrepareSummary) fail(ctx context.Context, name string, err error) {
+	common.Logger(ctx).Errorf("Failed to prepare %s: %v", name, err)
+	s.Failed = append(s.Failed, fmt.Sprintf("%s: %v", name, err))
+}
+
+// NewPrepareExecutor pulls the images and fetches the actions used by the
+// jobs of a plan without running them, the matrix of every job is expanded
+func NewPrepareExecutor(runnerConfig *Config, plan *model.Plan, summary *PrepareSummary) (common.Executor, error) {
+	runner := &runnerImpl{
+		config: runnerConfig,
+	}
+	if _, err := runner.configure(); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		runner.preparePlan(ctx, plan, summary, 0)
+		if len(summary.Failed) > 0 {
+			return fmt.Errorf("failed to prepare %d item(s)", len(summary.Failed))
+		}
+		return nil
+	}, nil
+}
+
+func (runner *runnerImpl) preparePlan(ctx context.Context, plan *model.Plan, summary *PrepareSummary, depth int) {
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			for _, matrix := range runner.expandMatrix(ctx, run) {
+				rc := runner.newRunContext(ctx, run, matrix)
+				p := &preparer{rc: rc, summary: summary, depth: depth}
+				if err := p.prepareJob(ctx); err != nil {
+					summary.fail(ctx, rc.String(), err)
+				}
+			}
+		}
+	}
+}
+
+type preparer struct {
+	rc      *RunContext
+	summary *PrepareSummary
+	depth   int
+}
+
+func (p *preparer) prepareJob(ctx context.Context) error {
+	rc := p.rc
+	job := rc.Run.Job()
+	jobType, err := job.Type()
+	if err != nil {
+		return err
+	}
+
+	switch jobType {
+	case model.JobTypeReusableWorkflowLocal:
+		return p.prepareReusableWorkflow(ctx, func(_ context.Context) (*model.Plan, error) {
+			planner, err := model.NewForgeWorkflowPlanner(filepath.Join(rc.Config.Workdir, job.Uses), true, false, rc.Config.GetForge())
+			if err != nil {
+				return nil, err
+			}
+			return planner.PlanEvent("workflow_call")
+		})
+	case model.JobTypeReusableWorkflowRemote:
+		return p.prepareReusableWorkflow(ctx, p.fetchReusableWorkflow)
+	}
+
+	if image := rc.containerImage(ctx); image != "" {
+		username, password, err := rc.handleCredentials(ctx)
+		if err != nil {
+			return err
+		}
+		p.pull(ctx, image, username, password)
+	} else if image := p.runsOnImage(ctx); image != "" && !strings.HasPrefix(image, "-") && !strings.HasPrefix(image, "tart://") {
+		p.pull(ctx, image, "", "")
+	}
+
+	services, err := rc.serviceSpecs(ctx)
+	if err != nil {
+		return err
+	}
+	for id, spec := range services {
+		username, password, err := rc.handleServiceCredentials(ctx, spec.Credentials)
+		if err != nil {
+			p.summary.fail(ctx, "service "+id, err)
+			continue
+		}
+		p.pull(ctx, spec.Image, username, password)
+	}
+
+	return p.prepareSteps(ctx, job.Steps, p.depth)
+}
+
+// runsOnImage resolves the platform image of the job, runs-on is evaluated on
+// a copy because the node of the job is shared by all matrix combinations
+func (p *preparer) runsOnImage(ctx context.Context) string {
+	job := *p.rc.Run.Job()
+	job.RawRunsOn = *copyYamlNode(&job.RawRunsOn)
+	if err := p.rc.ExprEval.EvaluateSchemaYamlNode(ctx, &job.RawRunsOn, "runs-on"); err != nil {
+		common.Logger(ctx).Errorf("error while evaluating runs-on: %v", err)
+		return ""
+	}
+	return p.rc.Config.GetForge().PlatformImage(p.rc.Config.Platforms, job.RunsOn())
+}
+
+func (p *preparer) prepareSteps(ctx context.Context, steps []*model.Step, depth int) error {
+	if depth > maxPrepareDepth {
+		return fmt.Errorf("composite actions are nested deeper than %d levels", maxPrepareDepth)
+	}
+	for _, s := range steps {
+		if s == nil {
+			continue
+		}
+		step := *s
+		step.Uses = p.rc.ExprEval.Interpolate(ctx, s.Uses)
+		var err error
+		switch step.Type() {
+		case model.StepTypeUsesDockerURL:
+			p.pull(ctx, strings.TrimPrefix(step.Uses, "docker://"), p.rc.Config.Secrets["DOCKER_USERNAME"], p.rc.Config.Secrets["DOCKER_PASSWORD"])
+		case model.StepTypeUsesActionRemote:
+			err = p.prepareRemoteAction(ctx, &step, depth)
+		case model.StepTypeUsesActionLocal:
+			err = p.prepareLocalAction(ctx, &step, depth)
+		}
+		if err != nil {
+			p.summary.fail(ctx, step.Uses, err)
+		}
+	}
+	return nil
+}
+
+func (p *preparer) prepareRemoteAction(ctx context.Context, step *model.Step, depth int) error {
+	sar := &stepActionRemote{
+		Step:       step,
+		RunContext: p.rc,
+		readAction: readActionImpl,
+	}
+	if !p.summary.once("action", step.Uses) {
+		return nil
+	}
+	if err := sar.prepareActionExecutor()(ctx); err != nil {
+		return err
+	}
+	if sar.action == nil {
+		// the local checkout is skipped
+		return nil
+	}
+	p.summary.Actions = append(p.summary.Actions, step.Uses)
+
+	return p.prepareAction(ctx, sar.action, depth, sar.getActionName(sar.remoteAction.Path), sar.remoteAction.Path, sar.getTarArchive)
+}
+
+func (p *preparer) prepareLocalAction(ctx context.Context, step *model.Step, depth int) error {
+	if !p.summary.once("local", step.Uses) {
+		return nil
+	}
+	actionDir := filepath.Join(p.rc.Config.Workdir, step.Uses)
+	readFile := func(filename string) (io.Reader, io.Closer, error) {
+		f, err := os.Open(filepath.Join(actionDir, filename))
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	}
+	// the synthetic trampoline action is written when the step runs
+	noWrite := func(string, []byte, fs.FileMode) error { return nil }
+	action, err := readActionImpl(ctx, step, actionDir, "", readFile, noWrite)
+	if err != nil {
+		return err
+	}
+	return p.prepareAction(ctx, action, depth, normalizePath(step.Uses), filepath.ToSlash(actionDir), nil)
+}
+
+// prepareAction pulls or builds the image of a docker action and prepares the steps of a composite action,
+// the build context is read from the action cache if getTarArchive is set, from the local filesystem otherwise
+func (p *preparer) prepareAction(ctx context.Context, action *model.Action, depth int, actionName, subpath string, getTarArchive func(context.Context, string) (io.ReadCloser, error)) error {
+	switch {
+	case action.Runs.Using.IsDocker():
+		image, buildInput, err := dockerActionImage(ctx, p.rc, action, actionName, subpath)
+		if err != nil {
+			return err
+		}
+		if buildInput == nil {
+			p.pull(ctx, image, p.rc.Config.Secrets["DOCKER_USERNAME"], p.rc.Config.Secrets["DOCKER_PASSWORD"])
+			return nil
+		}
+		if !p.summary.once("build", image) {
+			return nil
+		}
+		if getTarArchive != nil {
+			buildContext, err := getTarArchive(ctx, buildInput.ContextDir+".")
+			if err != nil {
+				return err
+			}
+			defer buildContext.Close()
+			buildInput.BuildContext = buildContext
+		}
+		if err := container.NewDockerBuildExecutor(*buildInput)(ctx); err != nil {
+			return err
+		}
+		p.summary.Built = append(p.summary.Built, image)
+	case action.Runs.Using.IsComposite():
+		steps := make([]*model.Step, len(action.Runs.Steps))
+		for i := range action.Runs.Steps {
+			steps[i] = &action.Runs.Steps[i]
+		}
+		return p.prepareSteps(ctx, steps, depth+1)
+	}
+	return nil
+}
+
+func (p *preparer) pull(ctx context.Context, image, username, password string) {
+	if image == "" || !p.summary.once("image", image) {
+		return
+	}
+	err := container.NewDockerPullExecutor(container.NewDockerPullExecutorInput{
+		Image:     image,
+		ForcePull: p.rc.Config.ForcePull,
+		Platform:  p.rc.Config.ContainerArchitecture,
+		Username:  username,
+		Password:  password,
+		ImagePull: p.rc.Config.ImagePull,
+	})(ctx)
+	if err != nil {
+		p.summary.fail(ctx, image, err)
+		return
+	}
+	p.summary.Images = append(p.summary.Images, image)
+}
+
+func (p *preparer) prepareReusableWorkflow(ctx context.Context, load func(context.Context) (*model.Plan, error)) error {
+	if p.depth >= maxPrepareDepth {
+		return fmt.Errorf("reusable workflows are nested deeper than %d levels", maxPrepareDepth)
+	}
+	plan, err := load(ctx)
+	if err != nil {
+		return err
+	}
+	runner := &runnerImpl{
+		config:    p.rc.Config,
+		eventJSON: p.rc.EventJSON,
+		caller: &caller{
+			runContext: p.rc,
+		},
+	}
+	if _, err := runner.configure(); err != nil {
+		return err
+	}
+	runner.preparePlan(ctx, plan, p.summary, p.depth+1)
+	return nil
+}
+
+func (p *preparer) fetchReusableWorkflow(ctx context.Context) (*model.Plan, error) {
+	uses := p.rc.Run.Job().Uses
+	remoteReusableWorkflow := newRemoteReusableWorkflow(uses)
+	if remoteReusableWorkflow == nil {
+		return nil, fmt.Errorf("expected format {owner}/{repo}/.github/workflows/{filename}@{ref}. Actual '%s' Input string was not in a correct format", uses)
+	}
+	filename := fmt.Sprintf("%s/%s@%s", remoteReusableWorkflow.Org, remoteReusableWorkflow.Repo, remoteReusableWorkflow.Ref)
+
+	ghctx := p.rc.getGithubContext(ctx)
+	remoteReusableWorkflow.URL = ghctx.ServerURL
+	cache := p.rc.getActionCache()
+	sha, err := cache.Fetch(ctx, filename, remoteReusableWorkflow.CloneURL(), remoteReusableWorkflow.Ref, ghctx.Token)
+	if err != nil {
+		return nil, err
+	}
+	if p.summary.once("action", uses) {
+		p.summary.Actions = append(p.summary.Actions, uses)
+	}
+	archive, err := cache.GetTarArchive(ctx, filename, sha, fmt.Sprintf(".github/workflows/%s", remoteReusableWorkflow.Filename))
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	treader := tar.NewReader(archive)
+	if _, err = treader.Next(); err != nil {
+		return nil, err
+	}
+	planner, err := model.NewSingleWorkflowPlanner(remoteReusableWorkflow.Filename, treader)
+	if err != nil {
+		return nil, err
+	}
+	return planner.PlanEvent("workflow_call")
+}
+
+func copyYamlNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	if node.Content != nil {
+		c.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			c.Content[i] = copyYamlNode(child)
+		}
+	}
+	return &c
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+type prepareActionCache struct {
+	fetched []string
+	files   map[string]string
+}
+
+func (c *prepareActionCache) Fetch(_ context.Context, cacheDir, _, ref, _ string) (string, error) {
+	c.fetched = append(c.fetched, cacheDir+"@"+ref)
+	return "sha", nil
+}
+
+func (c *prepareActionCache) GetTarArchive(_ context.Context, cacheDir, _, includePrefix string) (io.ReadCloser, error) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	if content, ok := c.files[cacheDir+"/"+includePrefix]; ok {
+		if err := tw.WriteHeader(&tar.Header{Name: includePrefix, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(buf), nil
+}
+
+func TestPrepareExecutor(t *testing.T) {
+	workflow := `
+name: prepare
+on: push
+jobs:
+  build:
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+        os: [ubuntu-latest, ubuntu-22.04]
+        node: [18, 20]
+    services:
+      redis:
+        image: redis:${{ matrix.node }}
+    steps:
+      - uses: docker://alpine:3
+      - uses: owner/composite@v1
+      - uses: owner/node-20@v2
+  test:
+    runs-on: self-hosted
+    container: node:${{ github.event_name }}
+    steps:
+      - run: echo
+`
+	planner, err := model.NewSingleWorkflowPlanner("prepare.yml", strings.NewReader(workflow))
+	require.NoError(t, err)
+	plan, err := planner.PlanEvent("push")
+	require.NoError(t, err)
+
+	cache := &prepareActionCache{
+		files: map[string]string{
+			"owner/composite/action.yml": `
+runs:
+  using: composite
+  steps:
+    - uses: docker://busybox
+    - uses: owner/node-18@v2
+`,
+			"owner/node-18/action.yml": "runs:\n  using: node20\n  main: index.js\n",
+			"owner/node-20/action.yml": "runs:\n  using: node20\n  main: index.js\n",
+		},
+	}
+	config := &Config{
+		Workdir:     t.TempDir(),
+		EventName:   "push",
+		ActionCache: cache,
+		Platforms: map[string]string{
+			"ubuntu-latest": "node:16-bullseye",
+			"ubuntu-22.04":  "catthehacker/ubuntu:act-22.04",
+			"self-hosted":   "-self-hosted",
+		},
+	}
+
+	summary := &PrepareSummary{}
+	executor, err := NewPrepareExecutor(config, plan, summary)
+	require.NoError(t, err)
+	require.NoError(t, executor(common.WithDryrun(context.Background(), true)))
+
+	assert.ElementsMatch(t, []string{
+		"node:16-bullseye",
+		"catthehacker/ubuntu:act-22.04",
+		"redis:18",
+		"redis:20",
+		"alpine:3",
+		"busybox",
+		"node:push",
+	}, summary.Images)
+	assert.ElementsMatch(t, []string{"owner/composite@v1", "owner/node-18@v2", "owner/node-20@v2"}, summary.Actions)
+	assert.ElementsMatch(t, []string{"owner/composite@v1", "owner/node-18@v2", "owner/node-20@v2"}, cache.fetched)
+	assert.Empty(t, summary.Built)
+	assert.Empty(t, summary.Failed)
+}
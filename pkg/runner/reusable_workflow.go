@@ -69,7 +69,7 @@ func newActionCacheReusableWorkflowExecutor(rc *RunContext, filename string, rem
 
 func newReusableWorkflowExecutor(rc *RunContext, directory string, workflow string) common.Executor {
 	return func(ctx context.Context) error {
-		planner, err := model.NewWorkflowPlanner(path.Join(directory, workflow), true, false)
+		planner, err := model.NewForgeWorkflowPlanner(path.Join(directory, workflow), true, false, rc.Config.GetForge())
 		if err != nil {
 			return err
 		}
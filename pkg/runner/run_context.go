@@ -16,8 +16,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/actions-oss/act-cli/pkg/common"
@@ -25,9 +27,11 @@ import (
 	"github.com/actions-oss/act-cli/pkg/exprparser"
 	"github.com/actions-oss/act-cli/pkg/model"
 	"github.com/docker/go-connections/nat"
+	"github.com/kballard/go-shellquote"
 	"github.com/opencontainers/selinux/go-selinux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/semaphore"
+	"gopkg.in/yaml.v3"
 )
 
 // RunContext contains info about current job
@@ -46,13 +50,16 @@ type RunContext struct {
 	ExprEval            ExpressionEvaluator
 	JobContainer        container.ExecutionsEnvironment
 	ServiceContainers   []container.ExecutionsEnvironment
+	serviceLogs         []*serviceLogs
+	serviceContexts     map[string]*model.JobServiceContext
 	OutputMappings      map[MappableOutput]MappableOutput
 	JobName             string
 	ActionPath          string
 	Parent              *RunContext
 	Masks               []string
 	cleanUpJobContainer common.Executor
-	caller              *caller // job calling this RunContext (reusable workflows)
+	containerSpec       *model.ContainerSpec // container of the job evaluated through the workflow schema
+	caller              *caller              // job calling this RunContext (reusable workflows)
 	Cancelled           bool
 	ContextData         map[string]interface{}
 	nodeToolFullPath    string
@@ -92,6 +99,45 @@ func (rc *RunContext) GetEnv() map[string]string {
 	return rc.Env
 }
 
+// jobEnvKeys returns the variables which evaluateJobEnv evaluates, the env of the config takes precedence
+func (rc *RunContext) jobEnvKeys() map[string]bool {
+	keys := map[string]bool{}
+	job := rc.Run.Job()
+	if job == nil || job.Env.Kind != yaml.MappingNode {
+		return keys
+	}
+	for k := range job.Environment() {
+		if _, ok := rc.Config.Env[k]; !ok {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+// evaluateJobEnv evaluates the env of the job through the workflow schema, which coerces the
+// values to strings and reports errors at their location in the workflow
+func (rc *RunContext) evaluateJobEnv(ctx context.Context) error {
+	job := rc.Run.Job()
+	if job == nil || job.Env.Kind != yaml.MappingNode {
+		return nil
+	}
+	node := copyYamlNode(&job.Env)
+	if err := rc.ExprEval.EvaluateSchemaYamlNode(ctx, node, "env"); err != nil {
+		return fmt.Errorf("failed to evaluate env: %w", err)
+	}
+	env := map[string]string{}
+	if err := node.Decode(&env); err != nil {
+		return err
+	}
+	for k, v := range env {
+		// the env of the config takes precedence like in GetEnv
+		if _, ok := rc.Config.Env[k]; !ok {
+			rc.Env[k] = v
+		}
+	}
+	return nil
+}
+
 func (rc *RunContext) jobContainerName() string {
 	return createContainerName("act", rc.String())
 }
@@ -99,7 +145,7 @@ func (rc *RunContext) jobContainerName() string {
 // networkName return the name of the network which will be created by `act` automatically for job,
 // only create network if using a service container
 func (rc *RunContext) networkName() (string, bool) {
-	if len(rc.Run.Job().Services) > 0 {
+	if len(rc.services()) > 0 {
 		return fmt.Sprintf("%s-%s-network", rc.jobContainerName(), rc.Run.JobID), true
 	}
 	if rc.Config.ContainerNetworkMode == "" {
@@ -108,6 +154,21 @@ func (rc *RunContext) networkName() (string, bool) {
 	return string(rc.Config.ContainerNetworkMode), false
 }
 
+// services returns the service containers of the job, services defined in the
+// workflow take precedence over services read from a docker compose file
+func (rc *RunContext) services() map[string]*model.ContainerSpec {
+	services := map[string]*model.ContainerSpec{}
+	if rc.Config != nil {
+		for id, spec := range rc.Config.ComposeServices[rc.Run.JobID] {
+			services[id] = spec
+		}
+	}
+	for id, spec := range rc.Run.Job().Services {
+		services[id] = spec
+	}
+	return services
+}
+
 func getDockerDaemonSocketMountPath(daemonPath string) string {
 	if protoIndex := strings.Index(daemonPath, "://"); protoIndex != -1 {
 		scheme := daemonPath[:protoIndex]
@@ -156,7 +217,7 @@ func (rc *RunContext) GetBindsAndMounts() ([]string, map[string]string) {
 	}
 
 	if job := rc.Run.Job(); job != nil {
-		if container := job.Container(); container != nil {
+		if container := rc.evaluatedContainer(); container != nil {
 			for _, v := range container.Volumes {
 				if !strings.Contains(v, ":") || filepath.IsAbs(v) {
 					// Bind anonymous volume or host file.
@@ -235,7 +296,7 @@ func (rc *RunContext) startHostEnvironment() common.Executor {
 			},
 			StdOut: logWriter,
 		}
-		networkName, createAndDeleteNetwork, err := rc.prepareServiceContainers(ctx, logger, container.LinuxContainerEnvironmentExtensions{}, logWriter)
+		networkName, createAndDeleteNetwork, err := rc.prepareServiceContainers(ctx, logger, container.LinuxContainerEnvironmentExtensions{})
 		if err != nil {
 			return err
 		}
@@ -279,6 +340,9 @@ func (rc *RunContext) startHostEnvironment() common.Executor {
 func (rc *RunContext) startJobContainer() common.Executor {
 	return func(ctx context.Context) error {
 		logger := common.Logger(ctx)
+		if _, err := rc.jobContainerSpec(ctx); err != nil {
+			return err
+		}
 		image := rc.platformImage(ctx)
 		rawLogger := logger.WithField("raw_output", true)
 		logWriter := common.NewLineWriter(rc.commandHandler(ctx), func(s string) bool {
@@ -309,7 +373,7 @@ func (rc *RunContext) startJobContainer() common.Executor {
 		ext := container.LinuxContainerEnvironmentExtensions{}
 		binds, mounts := rc.GetBindsAndMounts()
 
-		networkName, createAndDeleteNetwork, err := rc.prepareServiceContainers(ctx, logger, ext, logWriter)
+		networkName, createAndDeleteNetwork, err := rc.prepareServiceContainers(ctx, logger, ext)
 		if err != nil {
 			return err
 		}
@@ -355,6 +419,7 @@ func (rc *RunContext) startJobContainer() common.Executor {
 			Privileged:     rc.Config.Privileged,
 			UsernsMode:     rc.Config.UsernsMode,
 			Platform:       rc.Config.ContainerArchitecture,
+			ImagePull:      rc.Config.ImagePull,
 			Options:        rc.options(ctx),
 		})
 		if rc.JobContainer == nil {
@@ -399,21 +464,31 @@ func (rc *RunContext) cleanupServiceContainer(ctx context.Context, logger logrus
 	return nil
 }
 
-func (rc *RunContext) prepareServiceContainers(ctx context.Context, logger logrus.FieldLogger, ext container.LinuxContainerEnvironmentExtensions, logWriter io.Writer) (string, bool, error) {
+func (rc *RunContext) prepareServiceContainers(ctx context.Context, logger logrus.FieldLogger, ext container.LinuxContainerEnvironmentExtensions) (_ string, _ bool, err error) {
+	// the job container and with it the cleanup of the services isn't created if a service fails here,
+	// so the log files of the services before it are closed right away
+	prepared := len(rc.serviceLogs)
+	defer func() {
+		if err != nil {
+			for _, sl := range rc.serviceLogs[prepared:] {
+				_ = sl.Close()
+			}
+		}
+	}()
+
 	// specify the network to which the container will connect when `docker create` stage. (like execute command line: docker create --network <networkName> <image>)
 	// if using service containers, will create a new network for the containers.
 	// and it will be removed after at last
 	networkName, createAndDeleteNetwork := rc.networkName()
 
+	services, err := rc.serviceSpecs(ctx)
+	if err != nil {
+		return "", false, err
+	}
 	// add service containers
-	for serviceID, spec := range rc.Run.Job().Services {
-		// interpolate env
-		interpolatedEnvs := make(map[string]string, len(spec.Env))
+	for serviceID, spec := range services {
+		envs := make([]string, 0, len(spec.Env))
 		for k, v := range spec.Env {
-			interpolatedEnvs[k] = rc.ExprEval.Interpolate(ctx, v)
-		}
-		envs := make([]string, 0, len(interpolatedEnvs))
-		for k, v := range interpolatedEnvs {
 			envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 		}
 		username, password, err := rc.handleServiceCredentials(ctx, spec.Credentials)
@@ -421,30 +496,35 @@ func (rc *RunContext) prepareServiceContainers(ctx context.Context, logger logru
 			return "", false, fmt.Errorf("failed to handle service %s credentials: %w", serviceID, err)
 		}
 
-		interpolatedVolumes := make([]string, 0, len(spec.Volumes))
-		for _, volume := range spec.Volumes {
-			interpolatedVolumes = append(interpolatedVolumes, rc.ExprEval.Interpolate(ctx, volume))
-		}
-		serviceBinds, serviceMounts := rc.GetServiceBindsAndMounts(interpolatedVolumes)
+		serviceBinds, serviceMounts := rc.GetServiceBindsAndMounts(spec.Volumes)
 
-		interpolatedPorts := make([]string, 0, len(spec.Ports))
-		for _, port := range spec.Ports {
-			interpolatedPorts = append(interpolatedPorts, rc.ExprEval.Interpolate(ctx, port))
-		}
-		exposedPorts, portBindings, err := nat.ParsePortSpecs(interpolatedPorts)
+		exposedPorts, portBindings, err := nat.ParsePortSpecs(spec.Ports)
 		if err != nil {
 			return "", false, fmt.Errorf("failed to parse service %s ports: %w", serviceID, err)
 		}
 
-		imageName := rc.ExprEval.Interpolate(ctx, spec.Image)
+		imageName := spec.Image
 		if imageName == "" {
 			logger.Infof("The service '%s' will not be started because the container definition has an empty image.", serviceID)
 			continue
 		}
 
+		var cmd []string
+		if args := spec.Args; args != "" {
+			if cmd, err = shellquote.Split(args); err != nil {
+				return "", false, fmt.Errorf("failed to parse service %s command: %w", serviceID, err)
+			}
+		}
+
+		serviceLogs, serviceLogWriter, err := newServiceLogs(rc, logger, serviceID)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to capture service %s logs: %w", serviceID, err)
+		}
+
 		serviceContainerName := createContainerName(rc.jobContainerName(), serviceID)
 		c := container.NewContainer(&container.NewContainerInput{
 			Name:           serviceContainerName,
+			Cmd:            cmd,
 			WorkingDir:     ext.ToContainerPath(rc.Config.Workdir),
 			Image:          imageName,
 			Username:       username,
@@ -452,18 +532,21 @@ func (rc *RunContext) prepareServiceContainers(ctx context.Context, logger logru
 			Env:            envs,
 			Mounts:         serviceMounts,
 			Binds:          serviceBinds,
-			Stdout:         logWriter,
-			Stderr:         logWriter,
+			Stdout:         serviceLogWriter,
+			Stderr:         serviceLogWriter,
+			StreamLogs:     true,
 			Privileged:     rc.Config.Privileged,
 			UsernsMode:     rc.Config.UsernsMode,
 			Platform:       rc.Config.ContainerArchitecture,
-			Options:        rc.ExprEval.Interpolate(ctx, spec.Options),
+			ImagePull:      rc.Config.ImagePull,
+			Options:        spec.Options,
 			NetworkMode:    networkName,
 			NetworkAliases: []string{serviceID},
 			ExposedPorts:   exposedPorts,
 			PortBindings:   portBindings,
 		})
 		rc.ServiceContainers = append(rc.ServiceContainers, c)
+		rc.serviceLogs = append(rc.serviceLogs, serviceLogs)
 	}
 	return networkName, createAndDeleteNetwork, nil
 }
@@ -601,7 +684,7 @@ func (rc *RunContext) pullServicesImages(forcePull bool) common.Executor {
 	}
 }
 
-func (rc *RunContext) startServiceContainers(_ string) common.Executor {
+func (rc *RunContext) startServiceContainers(networkName string) common.Executor {
 	return func(ctx context.Context) error {
 		execs := []common.Executor{}
 		for _, c := range rc.ServiceContainers {
@@ -611,11 +694,33 @@ func (rc *RunContext) startServiceContainers(_ string) common.Executor {
 				c.Start(false),
 			))
 		}
-		return common.NewParallelExecutor(len(execs), execs...)(ctx)
+		if err := common.NewParallelExecutor(len(execs), execs...)(ctx); err != nil {
+			return err
+		}
+		rc.updateServiceContexts(ctx, networkName)
+		return nil
+	}
+}
+
+// updateServiceContexts fills the job.services context from the started service containers
+func (rc *RunContext) updateServiceContexts(ctx context.Context, networkName string) {
+	rc.serviceContexts = map[string]*model.JobServiceContext{}
+	for i, c := range rc.ServiceContainers {
+		serviceCtx := &model.JobServiceContext{
+			Network: networkName,
+			Ports:   map[string]string{},
+		}
+		if state, err := c.GetState(ctx); err == nil {
+			serviceCtx.ID = state.ID
+			serviceCtx.Ports = state.Ports
+		} else {
+			common.Logger(ctx).Debugf("Unable to inspect service %s: %v", rc.serviceLogs[i].ID, err)
+		}
+		rc.serviceContexts[rc.serviceLogs[i].ID] = serviceCtx
 	}
 }
 
-func (rc *RunContext) waitForServiceContainer(c container.ExecutionsEnvironment) common.Executor {
+func (rc *RunContext) waitForServiceContainer(c container.ExecutionsEnvironment, sl *serviceLogs) common.Executor {
 	return func(ctx context.Context) error {
 		sctx, cancel := context.WithTimeout(ctx, time.Minute*5)
 		defer cancel()
@@ -635,15 +740,16 @@ func (rc *RunContext) waitForServiceContainer(c container.ExecutionsEnvironment)
 		if health == container.HealthHealthy {
 			return nil
 		}
-		return fmt.Errorf("service container failed to start")
+		reportServiceLogs(ctx, c, sl)
+		return fmt.Errorf("service container %s failed to start", sl.ID)
 	}
 }
 
 func (rc *RunContext) waitForServiceContainers() common.Executor {
 	return func(ctx context.Context) error {
 		execs := []common.Executor{}
-		for _, c := range rc.ServiceContainers {
-			execs = append(execs, rc.waitForServiceContainer(c))
+		for i, c := range rc.ServiceContainers {
+			execs = append(execs, rc.waitForServiceContainer(c, rc.serviceLogs[i]))
 		}
 		return common.NewParallelExecutor(len(execs), execs...)(ctx)
 	}
@@ -655,7 +761,11 @@ func (rc *RunContext) stopServiceContainers() common.Executor {
 		for _, c := range rc.ServiceContainers {
 			execs = append(execs, c.Remove().Finally(c.Close()))
 		}
-		return common.NewParallelExecutor(len(execs), execs...)(ctx)
+		err := common.NewParallelExecutor(len(execs), execs...)(ctx)
+		for _, sl := range rc.serviceLogs {
+			_ = sl.Close()
+		}
+		return err
 	}
 }
 
@@ -689,13 +799,40 @@ func (rc *RunContext) getActionCache() ActionCache {
 }
 
 // Interpolate outputs after a job is done
+// jobMu guards the outputs and the result of the jobs, the combinations of a matrix share them while they run in parallel
+var jobMu sync.Mutex
+
+// jobOutputs returns a copy of the outputs of job
+func jobOutputs(job *model.Job) map[string]string {
+	jobMu.Lock()
+	defer jobMu.Unlock()
+	outputs := make(map[string]string, len(job.Outputs))
+	for k, v := range job.Outputs {
+		outputs[k] = v
+	}
+	return outputs
+}
+
+// sharedJobResult returns the result of job which its combinations share
+func sharedJobResult(job *model.Job) string {
+	jobMu.Lock()
+	defer jobMu.Unlock()
+	return job.Result
+}
+
+func setJobOutput(job *model.Job, name, value string) {
+	jobMu.Lock()
+	defer jobMu.Unlock()
+	job.Outputs[name] = value
+}
+
 func (rc *RunContext) interpolateOutputs() common.Executor {
 	return func(ctx context.Context) error {
 		ee := rc.NewExpressionEvaluator(ctx)
-		for k, v := range rc.Run.Job().Outputs {
+		for k, v := range jobOutputs(rc.Run.Job()) {
 			interpolated := ee.Interpolate(ctx, v)
 			if v != interpolated {
-				rc.Run.Job().Outputs[k] = interpolated
+				setJobOutput(rc.Run.Job(), k, interpolated)
 			}
 		}
 		return nil
@@ -746,6 +883,8 @@ func (rc *RunContext) matrix() map[string]interface{} {
 }
 
 func (rc *RunContext) result(result string) {
+	jobMu.Lock()
+	defer jobMu.Unlock()
 	rc.Run.Job().Result = result
 }
 
@@ -796,29 +935,105 @@ func (rc *RunContext) Executor() (common.Executor, error) {
 }
 
 func (rc *RunContext) containerImage(ctx context.Context) string {
-	job := rc.Run.Job()
-
-	c := job.Container()
+	c, err := rc.jobContainerSpec(ctx)
+	if err != nil {
+		common.Logger(ctx).Errorf("%v", err)
+		return ""
+	}
 	if c != nil {
-		return rc.ExprEval.Interpolate(ctx, c.Image)
+		return c.Image
 	}
 
 	return ""
 }
 
-func (rc *RunContext) runsOnImage(ctx context.Context) string {
-	if rc.Run.Job().RunsOn() == nil {
-		common.Logger(ctx).Errorf("'runs-on' key not defined in %s", rc.String())
+// jobContainerSpec evaluates the container of the job through the workflow schema, the result
+// is kept because the contexts available to the container don't change while the job runs
+func (rc *RunContext) jobContainerSpec(ctx context.Context) (*model.ContainerSpec, error) {
+	if rc.containerSpec != nil {
+		return rc.containerSpec, nil
 	}
+	job := rc.Run.Job()
+	if job == nil || job.RawContainer.Kind == 0 {
+		return nil, nil
+	}
+	evaluated := model.Job{RawContainer: *copyYamlNode(&job.RawContainer)}
+	if err := rc.ExprEval.EvaluateSchemaYamlNode(ctx, &evaluated.RawContainer, "container"); err != nil {
+		return nil, fmt.Errorf("failed to evaluate container: %w", err)
+	}
+	rc.containerSpec = evaluated.Container()
+	return rc.containerSpec, nil
+}
 
-	for _, platformName := range rc.runsOnPlatformNames(ctx) {
-		image := rc.Config.Platforms[strings.ToLower(platformName)]
-		if image != "" {
-			return image
+// evaluatedContainer returns the evaluated container of the job if it was already evaluated
+func (rc *RunContext) evaluatedContainer() *model.ContainerSpec {
+	if rc.containerSpec != nil {
+		return rc.containerSpec
+	}
+	return rc.Run.Job().Container()
+}
+
+// serviceSpecs evaluates the service containers of the job, services of the workflow are evaluated
+// through the workflow schema and services read from a docker compose file are interpolated
+func (rc *RunContext) serviceSpecs(ctx context.Context) (map[string]*model.ContainerSpec, error) {
+	services := rc.services()
+	job := rc.Run.Job()
+	for id, spec := range services {
+		var raw *yaml.Node
+		if job.RawServices.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(job.RawServices.Content); i += 2 {
+				if job.RawServices.Content[i].Value == id {
+					raw = job.RawServices.Content[i+1]
+				}
+			}
+		}
+		if raw == nil {
+			services[id] = rc.interpolateContainerSpec(ctx, spec)
+			continue
+		}
+		evaluated := model.Job{RawContainer: *copyYamlNode(raw)}
+		if err := rc.ExprEval.EvaluateSchemaYamlNode(ctx, &evaluated.RawContainer, "services", id); err != nil {
+			return nil, fmt.Errorf("failed to evaluate service %s: %w", id, err)
+		}
+		if services[id] = evaluated.Container(); services[id] == nil {
+			services[id] = &model.ContainerSpec{}
 		}
 	}
+	return services, nil
+}
 
-	return ""
+func (rc *RunContext) interpolateContainerSpec(ctx context.Context, spec *model.ContainerSpec) *model.ContainerSpec {
+	c := *spec
+	c.Image = rc.ExprEval.Interpolate(ctx, spec.Image)
+	c.Options = rc.ExprEval.Interpolate(ctx, spec.Options)
+	c.Args = rc.ExprEval.Interpolate(ctx, spec.Args)
+	c.Env = make(map[string]string, len(spec.Env))
+	for k, v := range spec.Env {
+		c.Env[k] = rc.ExprEval.Interpolate(ctx, v)
+	}
+	c.Volumes = make([]string, 0, len(spec.Volumes))
+	for _, v := range spec.Volumes {
+		c.Volumes = append(c.Volumes, rc.ExprEval.Interpolate(ctx, v))
+	}
+	c.Ports = make([]string, 0, len(spec.Ports))
+	for _, v := range spec.Ports {
+		c.Ports = append(c.Ports, rc.ExprEval.Interpolate(ctx, v))
+	}
+	if spec.Credentials != nil {
+		c.Credentials = make(map[string]string, len(spec.Credentials))
+		for k, v := range spec.Credentials {
+			c.Credentials[k] = rc.ExprEval.Interpolate(ctx, v)
+		}
+	}
+	return &c
+}
+
+func (rc *RunContext) runsOnImage(ctx context.Context) string {
+	if rc.Run.Job().RunsOn() == nil {
+		common.Logger(ctx).Errorf("'runs-on' key not defined in %s", rc.String())
+	}
+
+	return rc.Config.GetForge().PlatformImage(rc.Config.Platforms, rc.runsOnPlatformNames(ctx))
 }
 
 func (rc *RunContext) runsOnPlatformNames(ctx context.Context) []string {
@@ -828,12 +1043,14 @@ func (rc *RunContext) runsOnPlatformNames(ctx context.Context) []string {
 		return []string{}
 	}
 
-	if err := rc.ExprEval.EvaluateYamlNode(ctx, &job.RawRunsOn); err != nil {
+	// evaluate a copy, the node of the job is shared by all matrix combinations
+	evaluated := model.Job{RawRunsOn: *copyYamlNode(&job.RawRunsOn)}
+	if err := rc.ExprEval.EvaluateSchemaYamlNode(ctx, &evaluated.RawRunsOn, "runs-on"); err != nil {
 		common.Logger(ctx).Errorf("error while evaluating runs-on: %v", err)
 		return []string{}
 	}
 
-	return job.RunsOn()
+	return evaluated.RunsOn()
 }
 
 func (rc *RunContext) platformImage(ctx context.Context) string {
@@ -845,10 +1062,12 @@ func (rc *RunContext) platformImage(ctx context.Context) string {
 }
 
 func (rc *RunContext) options(ctx context.Context) string {
-	job := rc.Run.Job()
-	c := job.Container()
+	c, err := rc.jobContainerSpec(ctx)
+	if err != nil {
+		common.Logger(ctx).Errorf("%v", err)
+	}
 	if c != nil {
-		return rc.ExprEval.Interpolate(ctx, c.Options)
+		return c.Options
 	}
 
 	return rc.Config.ContainerOptions
@@ -934,7 +1153,8 @@ func (rc *RunContext) getJobContext() *model.JobContext {
 		}
 	}
 	return &model.JobContext{
-		Status: jobStatus,
+		Status:   jobStatus,
+		Services: rc.serviceContexts,
 	}
 }
 
@@ -1139,9 +1359,16 @@ func (rc *RunContext) withGithubEnv(ctx context.Context, github *model.GithubCon
 	env["GITHUB_SERVER_URL"] = github.ServerURL
 	env["GITHUB_API_URL"] = github.APIURL
 	env["GITHUB_GRAPHQL_URL"] = github.GraphQLURL
+	// forges like Gitea provide the same variables with their own prefix
+	for k, v := range rc.Config.GetForge().EnvAliasesOf(env) {
+		env[k] = v
+	}
 
-	if rc.Config.ArtifactServerPath != "" {
-		setActionRuntimeVars(rc, env)
+	if rc.Config.ArtifactServerPath != "" || rc.Config.ResultsURL != "" {
+		setActionRuntimeVars(rc, github, env)
+	} else if rc.Config.Env["ACTIONS_CACHE_URL"] != "" && env["ACTIONS_RUNTIME_TOKEN"] == "" {
+		// the cache actions require a token, its scopes isolate the caches of the repository and the ref
+		env["ACTIONS_RUNTIME_TOKEN"] = rc.runtimeToken(github)
 	}
 
 	for _, platformName := range rc.runsOnPlatformNames(ctx) {
@@ -1159,9 +1386,11 @@ func (rc *RunContext) withGithubEnv(ctx context.Context, github *model.GithubCon
 	return env
 }
 
-func setActionRuntimeVars(rc *RunContext, env map[string]string) {
+func setActionRuntimeVars(rc *RunContext, github *model.GithubContext, env map[string]string) {
 	actionsRuntimeURL := os.Getenv("ACTIONS_RUNTIME_URL")
-	if actionsRuntimeURL == "" {
+	if actionsRuntimeURL == "" && rc.Config.ResultsURL != "" {
+		actionsRuntimeURL = rc.Config.ResultsURL
+	} else if actionsRuntimeURL == "" {
 		actionsRuntimeURL = fmt.Sprintf("http://%s:%s/", rc.Config.ArtifactServerAddr, rc.Config.ArtifactServerPort)
 	}
 	env["ACTIONS_RUNTIME_URL"] = actionsRuntimeURL
@@ -1169,21 +1398,51 @@ func setActionRuntimeVars(rc *RunContext, env map[string]string) {
 
 	actionsRuntimeToken := os.Getenv("ACTIONS_RUNTIME_TOKEN")
 	if actionsRuntimeToken == "" {
-		runID := int64(1)
-		if rid, ok := rc.Config.Env["GITHUB_RUN_ID"]; ok {
-			runID, _ = strconv.ParseInt(rid, 10, 64)
-		}
-		actionsRuntimeToken, _ = common.CreateAuthorizationToken(runID, runID, runID)
+		actionsRuntimeToken = rc.runtimeToken(github)
 	}
 	env["ACTIONS_RUNTIME_TOKEN"] = actionsRuntimeToken
 }
 
+// runtimeToken creates the token of the job, like GitHub it may write the caches of the ref
+// and read the caches of the base ref and the default branch of the repository
+func (rc *RunContext) runtimeToken(github *model.GithubContext) string {
+	runID := int64(1)
+	if rid, ok := rc.Config.Env["GITHUB_RUN_ID"]; ok {
+		runID, _ = strconv.ParseInt(rid, 10, 64)
+	}
+	refs := []string{github.Ref}
+	if github.BaseRef != "" {
+		refs = append(refs, "refs/heads/"+github.BaseRef)
+	}
+	if defaultBranch, ok := nestedMapLookup(github.Event, "repository", "default_branch").(string); ok && defaultBranch != "" {
+		refs = append(refs, "refs/heads/"+defaultBranch)
+	} else if rc.Config.DefaultBranch != "" {
+		refs = append(refs, "refs/heads/"+rc.Config.DefaultBranch)
+	}
+	token, _ := common.CreateJobAuthorizationToken(runID, runID, runID, github.Job, github.Repository, uniqueRefs(refs)...)
+	return token
+}
+
+// uniqueRefs removes the duplicates of refs and keeps the order of their first occurrence
+func uniqueRefs(refs []string) []string {
+	ret := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !slices.Contains(ret, ref) {
+			ret = append(ret, ref)
+		}
+	}
+	return ret
+}
+
 func (rc *RunContext) handleCredentials(ctx context.Context) (string, string, error) {
 	// TODO: remove below 2 lines when we can release act with breaking changes
 	username := rc.Config.Secrets["DOCKER_USERNAME"]
 	password := rc.Config.Secrets["DOCKER_PASSWORD"]
 
-	container := rc.Run.Job().Container()
+	container, err := rc.jobContainerSpec(ctx)
+	if err != nil {
+		return "", "", err
+	}
 	if container == nil || container.Credentials == nil {
 		return username, password, nil
 	}
@@ -1193,21 +1452,16 @@ func (rc *RunContext) handleCredentials(ctx context.Context) (string, string, er
 		return "", "", err
 	}
 
-	ee := rc.NewExpressionEvaluator(ctx)
-	if username = ee.Interpolate(ctx, container.Credentials["username"]); username == "" {
+	// the credentials were evaluated with the container
+	if username = container.Credentials["username"]; username == "" {
 		err := fmt.Errorf("failed to interpolate container.credentials.username")
 		return "", "", err
 	}
-	if password = ee.Interpolate(ctx, container.Credentials["password"]); password == "" {
+	if password = container.Credentials["password"]; password == "" {
 		err := fmt.Errorf("failed to interpolate container.credentials.password")
 		return "", "", err
 	}
 
-	if container.Credentials["username"] == "" || container.Credentials["password"] == "" {
-		err := fmt.Errorf("container.credentials cannot be empty")
-		return "", "", err
-	}
-
 	return username, password, nil
 }
 
@@ -1220,13 +1474,13 @@ func (rc *RunContext) handleServiceCredentials(ctx context.Context, creds map[st
 		return
 	}
 
-	ee := rc.NewExpressionEvaluator(ctx)
-	if username = ee.Interpolate(ctx, creds["username"]); username == "" {
+	// the credentials were evaluated with the service
+	if username = creds["username"]; username == "" {
 		err = fmt.Errorf("failed to interpolate credentials.username")
 		return
 	}
 
-	if password = ee.Interpolate(ctx, creds["password"]); password == "" {
+	if password = creds["password"]; password == "" {
 		err = fmt.Errorf("failed to interpolate credentials.password")
 		return
 	}
@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"runtime"
@@ -10,12 +11,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/actions-oss/act-cli/pkg/common"
 	"github.com/actions-oss/act-cli/pkg/exprparser"
 	"github.com/actions-oss/act-cli/pkg/model"
 	"github.com/golang-jwt/jwt/v5"
 
 	log "github.com/sirupsen/logrus"
 	assert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -735,7 +738,7 @@ func TestSetRuntimeVariables(t *testing.T) {
 	}
 	v := "http://myhost:8000/"
 	env := map[string]string{}
-	setActionRuntimeVars(rc, env)
+	setActionRuntimeVars(rc, &model.GithubContext{}, env)
 
 	assert.Equal(t, v, env["ACTIONS_RESULTS_URL"])
 	assert.Equal(t, v, env["ACTIONS_RUNTIME_URL"])
@@ -747,6 +750,22 @@ func TestSetRuntimeVariables(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestSetRuntimeVariablesWithResultsURL(t *testing.T) {
+	rc := &RunContext{
+		Config: &Config{
+			ArtifactServerAddr: "myhost",
+			ArtifactServerPort: "8000",
+			ResultsURL:         "http://results:9000/",
+		},
+	}
+	env := map[string]string{}
+	setActionRuntimeVars(rc, &model.GithubContext{}, env)
+
+	assert.Equal(t, "http://results:9000/", env["ACTIONS_RESULTS_URL"])
+	assert.Equal(t, "http://results:9000/", env["ACTIONS_RUNTIME_URL"])
+	assert.NotEmpty(t, env["ACTIONS_RUNTIME_TOKEN"])
+}
+
 func TestSetRuntimeVariablesWithRunID(t *testing.T) {
 	rc := &RunContext{
 		Config: &Config{
@@ -759,7 +778,7 @@ func TestSetRuntimeVariablesWithRunID(t *testing.T) {
 	}
 	v := "http://myhost:8000/"
 	env := map[string]string{}
-	setActionRuntimeVars(rc, env)
+	setActionRuntimeVars(rc, &model.GithubContext{}, env)
 
 	assert.Equal(t, v, env["ACTIONS_RESULTS_URL"])
 	assert.Equal(t, v, env["ACTIONS_RUNTIME_URL"])
@@ -774,3 +793,34 @@ func TestSetRuntimeVariablesWithRunID(t *testing.T) {
 	assert.True(t, ok, "scp claim exists")
 	assert.Equal(t, "Actions.Results:45:45", scp, "contains expected scp claim")
 }
+
+func TestRuntimeTokenUniqueRefs(t *testing.T) {
+	rc := &RunContext{Config: &Config{DefaultBranch: "main"}}
+	token := rc.runtimeToken(&model.GithubContext{Ref: "refs/heads/main", BaseRef: "feature"})
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	claims, err := common.ParseAuthorizationClaims(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/main", "refs/heads/feature"}, claims.CacheScopes())
+}
+
+func TestJobEnvKeys(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`on: push
+jobs:
+  a:
+    runs-on: ubuntu-latest
+    env:
+      JOB_ENV: ${{ github.sha }}
+      OVERRIDDEN: ${{ github.sha }}
+    steps:
+    - run: echo
+`), false)
+	require.NoError(t, err)
+	rc := &RunContext{
+		Config: &Config{Env: map[string]string{"OVERRIDDEN": "config"}},
+		Run:    &model.Run{Workflow: workflow, JobID: "a"},
+	}
+	assert.Equal(t, map[string]bool{"JOB_ENV": true}, rc.jobEnvKeys(), "the env of the config is interpolated")
+}
@@ -6,13 +6,15 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"runtime"
+	"strings"
 
 	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/container"
 	"github.com/actions-oss/act-cli/pkg/model"
 	docker_container "github.com/docker/docker/api/types/container"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/semaphore"
+	"gopkg.in/yaml.v3"
 )
 
 // Runner provides capabilities to run GitHub actions
@@ -49,7 +51,8 @@ type Config struct {
 	ContainerDaemonSocket              string                       // Path to Docker daemon socket
 	ContainerOptions                   string                       // Options for the job container
 	UseGitIgnore                       bool                         // controls if paths in .gitignore should not be copied into container, default true
-	GitHubInstance                     string                       // GitHub instance to use, default "github.com"
+	Forge                              string                       // forge which runs the workflows: github, gitea or forgejo, default github
+	GitHubInstance                     string                       // GitHub instance to use, defaults to the instance of the forge like "github.com"
 	GitHubServerURL                    string                       // GitHub server url to use
 	GitHubAPIServerURL                 string                       // GitHub api server url to use
 	GitHubGraphQlAPIServerURL          string                       // GitHub graphql server url to use
@@ -59,50 +62,71 @@ type Config struct {
 	ArtifactServerPath                 string                       // the path where the artifact server stores uploads
 	ArtifactServerAddr                 string                       // the address the artifact server binds to
 	ArtifactServerPort                 string                       // the port the artifact server binds to
+	ResultsURL                         string                       // the url of the results service which serves the artifacts, the cache, the summaries and the logs
 	NoSkipCheckout                     bool                         // do not skip actions/checkout
 	RemoteName                         string                       // remote name in local git repo config
 	ReplaceGheActionWithGithubCom      []string                     // Use actions from GitHub Enterprise instance to GitHub
 	ReplaceGheActionTokenWithGithubCom string                       // Token of private action repo on GitHub.
 	Matrix                             map[string]map[string]bool   // Matrix config to run
+	TraceMatrix                        bool                         // explain how include and exclude changed the combinations of the matrix
+	StatePath                          string                       // path where the state of the workflow runs is persisted, empty disables it
 	ContainerNetworkMode               docker_container.NetworkMode // the network mode of job containers (the value of --network)
 	ActionCache                        ActionCache                  // Use a custom ActionCache Implementation
 	HostEnvironmentDir                 string                       // Custom folder for host environment, parallel jobs must be 1
+	ServiceLogDir                      string                       // path where the output of service containers is written, one file per service
+	ServiceLogLines                    int                          // number of service log lines reported when a service or step fails
+	ActionBuildCacheDir                string                       // path where the images of Dockerfile actions are cached, empty disables the cache
+	ActionBuildCacheSize               int64                        // size of the cached images beyond which the least recently used are removed, 0 means unlimited
+	ActionBuildArgs                    map[string]string            // build args for Dockerfile actions
+	ActionBuildSecrets                 map[string]string            // build secrets for Dockerfile actions, keyed by secret id
+	ImagePull                          container.ImagePullConfig    // retries and registry mirrors for pulling job, service and docker action images
 
-	CustomExecutor map[model.JobType]func(*RunContext) common.Executor // Custom executor to run jobs
-	semaphore      *semaphore.Weighted
-	Parallel       int // Number of parallel jobs to run
+	CustomExecutor  map[model.JobType]func(*RunContext) common.Executor // Custom executor to run jobs
+	ComposeServices map[string]map[string]*model.ContainerSpec          // services read from a docker compose file, keyed by job id and service id
+	semaphore       *semaphore.Weighted
+	Parallel        int // Number of parallel jobs to run
+}
+
+// GetForge returns the profile of the forge, an unknown forge is GitHub
+func (runnerConfig *Config) GetForge() *model.Forge {
+	forge, err := model.GetForge(runnerConfig.Forge)
+	if err != nil {
+		forge, _ = model.GetForge(model.ForgeGitHub)
+	}
+	return forge
+}
+
+func (runnerConfig *Config) instance() string {
+	if len(runnerConfig.GitHubInstance) > 0 {
+		return runnerConfig.GitHubInstance
+	}
+	return runnerConfig.GetForge().Instance
 }
 
 func (runnerConfig *Config) GetGitHubServerURL() string {
 	if len(runnerConfig.GitHubServerURL) > 0 {
 		return runnerConfig.GitHubServerURL
 	}
-	return fmt.Sprintf("https://%s", runnerConfig.GitHubInstance)
+	return fmt.Sprintf("https://%s", runnerConfig.instance())
 }
 func (runnerConfig *Config) GetGitHubAPIServerURL() string {
 	if len(runnerConfig.GitHubAPIServerURL) > 0 {
 		return runnerConfig.GitHubAPIServerURL
 	}
-	if runnerConfig.GitHubInstance == "github.com" {
-		return "https://api.github.com"
-	}
-	return fmt.Sprintf("https://%s/api/v3", runnerConfig.GitHubInstance)
+	return runnerConfig.GetForge().APIURL(runnerConfig.instance())
 }
 func (runnerConfig *Config) GetGitHubGraphQlAPIServerURL() string {
 	if len(runnerConfig.GitHubGraphQlAPIServerURL) > 0 {
 		return runnerConfig.GitHubGraphQlAPIServerURL
 	}
-	if runnerConfig.GitHubInstance == "github.com" {
-		return "https://api.github.com/graphql"
-	}
-	return fmt.Sprintf("https://%s/api/graphql", runnerConfig.GitHubInstance)
+	return runnerConfig.GetForge().GraphQLURL(runnerConfig.instance())
 }
 func (runnerConfig *Config) GetGitHubInstance() string {
 	if len(runnerConfig.GitHubServerURL) > 0 {
 		regex := regexp.MustCompile("^https?://(.*)$")
 		return regex.ReplaceAllString(runnerConfig.GitHubServerURL, "$1")
 	}
-	return runnerConfig.GitHubInstance
+	return runnerConfig.instance()
 }
 
 type caller struct {
@@ -126,6 +150,10 @@ func New(runnerConfig *Config) (Runner, error) {
 
 func (runner *runnerImpl) configure() (Runner, error) {
 	runner.eventJSON = "{}"
+	if runner.config.Parallel > 0 && runner.config.semaphore == nil {
+		// created before the jobs start, which run concurrently
+		runner.config.semaphore = semaphore.NewWeighted(int64(runner.config.Parallel))
+	}
 	if runner.config.EventPath != "" {
 		log.Debugf("Reading event.json from %s", runner.config.EventPath)
 		eventJSONBytes, err := os.ReadFile(runner.config.EventPath)
@@ -148,108 +176,96 @@ func (runner *runnerImpl) configure() (Runner, error) {
 
 // NewPlanExecutor ...
 func (runner *runnerImpl) NewPlanExecutor(plan *model.Plan) common.Executor {
-	maxJobNameLen := 0
-
-	stagePipeline := make([]common.Executor, 0)
 	log.Debugf("Plan Stages: %v", plan.Stages)
+	return common.Executor(func(ctx context.Context) error {
+		s, err := newScheduler(runner, plan)
+		if err != nil {
+			return err
+		}
+		return s.run(ctx)
+	}).Then(handleFailure(plan))
+}
 
-	for i := range plan.Stages {
-		stage := plan.Stages[i]
-		stagePipeline = append(stagePipeline, func(ctx context.Context) error {
-			pipeline := make([]common.Executor, 0)
-			for _, run := range stage.Runs {
-				log.Debugf("Stages Runs: %v", stage.Runs)
-				stageExecutor := make([]common.Executor, 0)
-				job := run.Job()
-				log.Debugf("Job.Name: %v", job.Name)
-				log.Debugf("Job.RawNeeds: %v", job.RawNeeds)
-				log.Debugf("Job.RawRunsOn: %v", job.RawRunsOn)
-				log.Debugf("Job.Env: %v", job.Env)
-				log.Debugf("Job.If: %v", job.If)
-				for step := range job.Steps {
-					if nil != job.Steps[step] {
-						log.Debugf("Job.Steps: %v", job.Steps[step].String())
-					}
-				}
-				log.Debugf("Job.TimeoutMinutes: %v", job.TimeoutMinutes)
-				log.Debugf("Job.Services: %v", job.Services)
-				log.Debugf("Job.Strategy: %v", job.Strategy)
-				log.Debugf("Job.RawContainer: %v", job.RawContainer)
-				log.Debugf("Job.Defaults.Run.Shell: %v", job.Defaults.Run.Shell)
-				log.Debugf("Job.Defaults.Run.WorkingDirectory: %v", job.Defaults.Run.WorkingDirectory)
-				log.Debugf("Job.Outputs: %v", job.Outputs)
-				log.Debugf("Job.Uses: %v", job.Uses)
-				log.Debugf("Job.With: %v", job.With)
-				// log.Debugf("Job.RawSecrets: %v", job.RawSecrets)
-				log.Debugf("Job.Result: %v", job.Result)
-
-				if job.Strategy != nil {
-					log.Debugf("Job.Strategy.FailFast: %v", job.Strategy.FailFast)
-					log.Debugf("Job.Strategy.MaxParallel: %v", job.Strategy.MaxParallel)
-					log.Debugf("Job.Strategy.FailFastString: %v", job.Strategy.FailFastString)
-					log.Debugf("Job.Strategy.MaxParallelString: %v", job.Strategy.MaxParallelString)
-					log.Debugf("Job.Strategy.RawMatrix: %v", job.Strategy.RawMatrix)
-
-					strategyRc := runner.newRunContext(ctx, run, nil)
-					if err := strategyRc.NewExpressionEvaluator(ctx).EvaluateYamlNode(ctx, &job.Strategy.RawMatrix); err != nil {
-						log.Errorf("error while evaluating matrix: %v", err)
-					}
-				}
+// expandMatrix evaluates the strategy of a job and returns the matrix combinations selected by --matrix
+func (runner *runnerImpl) expandMatrix(ctx context.Context, run *model.Run) []map[string]interface{} {
+	var matrixes []map[string]interface{}
+	for _, c := range runner.expandStrategy(ctx, run).Combinations {
+		matrixes = append(matrixes, c.Matrix)
+	}
+	return matrixes
+}
 
-				var matrixes []map[string]interface{}
-				if m, err := job.GetMatrixes(); err != nil {
-					log.Errorf("error while get job's matrix: %v", err)
-				} else {
-					log.Debugf("Job Matrices: %v", m)
-					log.Debugf("Runner Matrices: %v", runner.config.Matrix)
-					matrixes = selectMatrixes(m, runner.config.Matrix)
-				}
-				log.Debugf("Final matrix after applying user inclusions '%v'", matrixes)
+// expandStrategy evaluates the strategy of a job and returns the combinations selected by --matrix
+func (runner *runnerImpl) expandStrategy(ctx context.Context, run *model.Run) *model.MatrixStrategy {
+	job := run.Job()
+	if job.Strategy != nil {
+		log.Debugf("Job.Strategy.FailFastString: %v", job.Strategy.FailFastString)
+		log.Debugf("Job.Strategy.MaxParallelString: %v", job.Strategy.MaxParallelString)
+		log.Debugf("Job.Strategy.RawMatrix: %v", job.Strategy.RawMatrix)
 
-				maxParallel := 4
-				if job.Strategy != nil {
-					maxParallel = job.Strategy.MaxParallel
-				}
+		strategyRc := runner.newRunContext(ctx, run, nil)
+		ee := strategyRc.NewExpressionEvaluator(ctx)
+		if err := ee.EvaluateSchemaYamlNode(ctx, &job.Strategy.RawMatrix, "strategy", "matrix"); err != nil {
+			log.Errorf("error while evaluating matrix: %v", err)
+		}
+		job.Strategy.FailFastString = evaluateStrategyValue(ctx, ee, "fail-fast", job.Strategy.FailFastString)
+		job.Strategy.MaxParallelString = evaluateStrategyValue(ctx, ee, "max-parallel", job.Strategy.MaxParallelString)
+	}
 
-				if len(matrixes) < maxParallel {
-					maxParallel = len(matrixes)
-				}
+	var expanded *model.MatrixStrategy
+	var err error
+	if runner.config.TraceMatrix {
+		expanded, err = job.ExpandStrategy(&matrixTraceWriter{jobID: run.JobID})
+	} else {
+		expanded, err = job.ExpandStrategy(nil)
+	}
+	if err != nil {
+		log.Errorf("error while get job's matrix: %v", err)
+		return &model.MatrixStrategy{}
+	}
+	log.Debugf("Job.Strategy.FailFast: %v", expanded.FailFast)
+	log.Debugf("Job.Strategy.MaxParallel: %v", expanded.MaxParallel)
+	log.Debugf("Runner Matrices: %v", runner.config.Matrix)
+	combinations := make([]model.MatrixCombination, 0, len(expanded.Combinations))
+	for _, c := range expanded.Combinations {
+		if isMatrixSelected(c.Matrix, runner.config.Matrix) {
+			combinations = append(combinations, c)
+		}
+	}
+	expanded.Combinations = combinations
+	log.Debugf("Final matrix after applying user inclusions '%v'", combinations)
+	return expanded
+}
 
-				for i, matrix := range matrixes {
-					rc := runner.newRunContext(ctx, run, matrix)
-					rc.JobName = rc.Name
-					if len(matrixes) > 1 {
-						rc.Name = fmt.Sprintf("%s-%d", rc.Name, i+1)
-					}
-					if len(rc.String()) > maxJobNameLen {
-						maxJobNameLen = len(rc.String())
-					}
-					stageExecutor = append(stageExecutor, func(ctx context.Context) error {
-						jobName := fmt.Sprintf("%-*s", maxJobNameLen, rc.String())
-						executor, err := rc.Executor()
+// matrixTraceWriter prints the explanation of --trace-matrix
+type matrixTraceWriter struct {
+	jobID string
+}
 
-						if err != nil {
-							return err
-						}
+func (w *matrixTraceWriter) Info(format string, args ...interface{}) {
+	log.Infof("[%s] matrix: %s", w.jobID, fmt.Sprintf(format, args...))
+}
 
-						return executor(common.WithJobErrorContainer(WithJobLogger(ctx, rc.Run.JobID, jobName, rc.Config, &rc.Masks, matrix)))
-					})
-				}
-				pipeline = append(pipeline, common.NewParallelExecutor(maxParallel, stageExecutor...))
-			}
-			if runner.config.Parallel != 0 {
-				return common.NewParallelExecutor(len(pipeline), pipeline...)(ctx)
-			}
-			ncpu := runtime.NumCPU()
-			if 1 > ncpu {
-				ncpu = 1
-			}
-			log.Debugf("Detected CPUs: %d", ncpu)
-			return common.NewParallelExecutor(ncpu, pipeline...)(ctx)
-		})
+// matrixJobName appends the values of the combination to the name of the job like GitHub,
+// unless the name is an expression which already includes the values it needs
+func matrixJobName(job *model.Job, name string, suffix string) string {
+	if suffix == "" || strings.Contains(job.Name, "${{") {
+		return name
 	}
+	return name + " " + suffix
+}
 
-	return common.NewPipelineExecutor(stagePipeline...).Then(handleFailure(plan))
+// evaluateStrategyValue evaluates fail-fast or max-parallel, the schema coerces the result to a boolean or a number
+func evaluateStrategyValue(ctx context.Context, ee ExpressionEvaluator, key string, value string) string {
+	if !strings.Contains(value, "${{") {
+		return value
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if err := ee.EvaluateSchemaYamlNode(ctx, node, "strategy", key); err != nil {
+		log.Errorf("error while evaluating %s: %v", key, err)
+		return value
+	}
+	return node.Value
 }
 
 func handleFailure(plan *model.Plan) common.Executor {
@@ -265,23 +281,16 @@ func handleFailure(plan *model.Plan) common.Executor {
 	}
 }
 
-func selectMatrixes(originalMatrixes []map[string]interface{}, targetMatrixValues map[string]map[string]bool) []map[string]interface{} {
-	matrixes := make([]map[string]interface{}, 0)
-	for _, original := range originalMatrixes {
-		flag := true
-		for key, val := range original {
-			if allowedVals, ok := targetMatrixValues[key]; ok {
-				valToString := fmt.Sprintf("%v", val)
-				if _, ok := allowedVals[valToString]; !ok {
-					flag = false
-				}
+func isMatrixSelected(matrix map[string]interface{}, targetMatrixValues map[string]map[string]bool) bool {
+	for key, val := range matrix {
+		if allowedVals, ok := targetMatrixValues[key]; ok {
+			valToString := fmt.Sprintf("%v", val)
+			if _, ok := allowedVals[valToString]; !ok {
+				return false
 			}
 		}
-		if flag {
-			matrixes = append(matrixes, original)
-		}
 	}
-	return matrixes
+	return true
 }
 
 func (runner *runnerImpl) newRunContext(ctx context.Context, run *model.Run, matrix map[string]interface{}) *RunContext {
@@ -18,6 +18,7 @@ import (
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 	assert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
 	"github.com/actions-oss/act-cli/pkg/common"
@@ -851,3 +852,67 @@ func TestRunMatrixWithUserDefinedInclusions(t *testing.T) {
 
 	tjfi.runTest(context.Background(), t, &Config{Matrix: matrix})
 }
+
+func TestMatrixJobName(t *testing.T) {
+	job := &model.Job{}
+	assert.Equal(t, "build (ubuntu-latest, 18)", matrixJobName(job, "build", "(ubuntu-latest, 18)"))
+	assert.Equal(t, "build", matrixJobName(job, "build", ""))
+
+	job.Name = "test ${{ matrix.os }}"
+	assert.Equal(t, "test ubuntu-latest", matrixJobName(job, "test ubuntu-latest", "(ubuntu-latest, 18)"))
+}
+
+func TestExpandStrategyInsert(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`on: push
+jobs:
+  a:
+    strategy:
+      matrix:
+        a:
+        - env:
+            key1: val1
+            ${{ insert }}:
+              key2: val2
+            ${{ insert }}: ${{ fromJSON('{"key3":"val3"}') }}
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo
+`), false)
+	require.NoError(t, err)
+	r, err := New(&Config{Workdir: workdir})
+	require.NoError(t, err)
+
+	expanded := r.(*runnerImpl).expandStrategy(context.Background(), &model.Run{Workflow: workflow, JobID: "a"})
+	require.Len(t, expanded.Combinations, 1)
+	assert.Equal(t, map[string]interface{}{
+		"env": map[string]interface{}{"key1": "val1", "key2": "val2", "key3": "val3"},
+	}, expanded.Combinations[0].Matrix["a"])
+}
+
+func TestExpandStrategyExpressionKeys(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`on: push
+jobs:
+  a:
+    strategy:
+      matrix:
+        ${{ format('{0}', 'os') }}: ${{ fromJSON('["linux", "windows"]') }}
+        ${{ format('{0}', 'arch') }}: |-
+          ${{ toJSON(fromJSON('["amd64"]')) }}
+    runs-on: ubuntu-latest
+    steps:
+    - run: echo
+`), false)
+	require.NoError(t, err)
+	r, err := New(&Config{Workdir: workdir})
+	require.NoError(t, err)
+
+	expanded := r.(*runnerImpl).expandStrategy(context.Background(), &model.Run{Workflow: workflow, JobID: "a"})
+	var matrixes []map[string]interface{}
+	for _, c := range expanded.Combinations {
+		matrixes = append(matrixes, c.Matrix)
+	}
+	assert.ElementsMatch(t, []map[string]interface{}{
+		{"os": "linux", "arch": "amd64"},
+		{"os": "windows", "arch": "amd64"},
+	}, matrixes)
+}
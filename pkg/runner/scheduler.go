@@ -0,0 +1,401 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	state "github.com/actions-oss/act-cli/internal/model"
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// scheduler runs the jobs of a plan. A job starts as soon as the jobs it needs are
+// completed, its matrix is expanded at that moment, so it may depend on their outputs.
+// The combinations of all jobs share the --parallel limit, the combinations of a job
+// are additionally limited by its max-parallel.
+type scheduler struct {
+	runner *runnerImpl
+	// limit is the number of combinations which run at the same time
+	limit     int
+	jobs      []*scheduledJob
+	workflows map[*model.Workflow]*state.WorkflowState
+
+	running       int
+	maxJobNameLen int
+	// err is the first error of a job executor, no further jobs are started after it
+	err          error
+	persistError bool
+}
+
+// scheduledJob is a job of the plan with its matrix combinations
+type scheduledJob struct {
+	run   *model.Run
+	needs []*scheduledJob
+	state *state.JobState
+
+	legs []*scheduledLeg
+	// next is the index of the first combination which isn't started yet
+	next        int
+	running     int
+	maxParallel int
+	// matrixCancelCtx is cancelled by a failed combination if fail-fast is enabled
+	matrixCancelCtx context.Context
+	cancelMatrix    context.CancelFunc
+}
+
+// scheduledLeg is a combination of the matrix of a job
+type scheduledLeg struct {
+	job    *scheduledJob
+	index  int
+	rc     *RunContext
+	matrix map[string]interface{}
+}
+
+type legResult struct {
+	leg    *scheduledLeg
+	err    error
+	failed bool
+	// outputs of the job when the combination completed, the other combinations keep writing them
+	outputs map[string]string
+}
+
+func newScheduler(runner *runnerImpl, plan *model.Plan) (*scheduler, error) {
+	s := &scheduler{
+		runner:    runner,
+		limit:     runner.config.Parallel,
+		workflows: map[*model.Workflow]*state.WorkflowState{},
+	}
+	if s.limit <= 0 {
+		s.limit = runtime.NumCPU()
+		log.Debugf("Detected CPUs: %d", s.limit)
+	}
+	if s.limit < 1 {
+		s.limit = 1
+	}
+
+	byKey := map[*model.Workflow]map[string]*scheduledJob{}
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			jobs, ok := byKey[run.Workflow]
+			if !ok {
+				jobs = map[string]*scheduledJob{}
+				byKey[run.Workflow] = jobs
+				s.workflows[run.Workflow] = &state.WorkflowState{
+					Name: run.Workflow.Name,
+					File: run.Workflow.File,
+					Jobs: map[string]*state.JobState{},
+				}
+			}
+			if _, ok := jobs[run.JobID]; ok {
+				continue
+			}
+			job := &scheduledJob{
+				run: run,
+				state: &state.JobState{
+					JobID: s.jobStateID(run.JobID),
+					State: state.JobStatusPending,
+					Needs: run.Job().Needs(),
+				},
+				cancelMatrix: func() {},
+			}
+			jobs[run.JobID] = job
+			s.jobs = append(s.jobs, job)
+			s.workflows[run.Workflow].Jobs[job.state.JobID] = job.state
+		}
+	}
+	for _, job := range s.jobs {
+		for _, need := range job.state.Needs {
+			n, ok := byKey[job.run.Workflow][need]
+			if !ok {
+				return nil, fmt.Errorf("job '%s' needs the job '%s' which is not part of the plan", job.run.JobID, need)
+			}
+			job.needs = append(job.needs, n)
+		}
+	}
+	return s, nil
+}
+
+// jobStateID prefixes the id of a job with the job calling its reusable workflow
+func (s *scheduler) jobStateID(jobID string) string {
+	if s.runner.caller != nil {
+		return s.runner.caller.runContext.Run.JobID + "/" + jobID
+	}
+	return jobID
+}
+
+func (s *scheduler) run(ctx context.Context) error {
+	results := make(chan legResult)
+	s.setWorkflowStatus(state.WorkflowStatusRunning)
+	for {
+		if ctx.Err() == nil && s.err == nil {
+			s.schedule(ctx, results)
+		}
+		s.persist()
+		if s.running == 0 {
+			break
+		}
+		s.finish(<-results)
+	}
+	for _, job := range s.jobs {
+		job.cancelMatrix()
+	}
+	if err := ctx.Err(); err != nil {
+		s.persist()
+		return err
+	}
+	if s.err != nil {
+		s.persist()
+		return s.err
+	}
+	for _, job := range s.jobs {
+		if job.state.State != state.JobStatusCompleted {
+			s.persist()
+			return fmt.Errorf("unable to schedule the job '%s', the jobs it needs can't complete", job.run.JobID)
+		}
+	}
+	s.setWorkflowStatus(state.WorkflowStatusCompleted)
+	s.persist()
+	return nil
+}
+
+func (s *scheduler) setWorkflowStatus(status state.WorkflowStatus) {
+	for _, w := range s.workflows {
+		w.StateWorkflowStatus = status
+	}
+}
+
+// schedule starts what can start, until no further job completes without running
+func (s *scheduler) schedule(ctx context.Context, results chan<- legResult) {
+	for {
+		completed := s.completedJobs()
+		s.promote(ctx)
+		s.start(ctx, results)
+		if s.completedJobs() == completed {
+			return
+		}
+	}
+}
+
+func (s *scheduler) completedJobs() int {
+	n := 0
+	for _, job := range s.jobs {
+		if job.state.State == state.JobStatusCompleted {
+			n++
+		}
+	}
+	return n
+}
+
+// promote expands the matrix of every pending job whose needs are completed
+func (s *scheduler) promote(ctx context.Context) {
+	for _, job := range s.jobs {
+		if job.state.State != state.JobStatusPending || !job.needsCompleted() {
+			continue
+		}
+		logJob(job.run.Job())
+
+		expanded := s.runner.expandStrategy(ctx, job.run)
+		job.maxParallel = expanded.MaxParallel
+		if job.maxParallel <= 0 {
+			job.maxParallel = len(expanded.Combinations)
+		}
+		// with fail-fast a failed combination cancels the others of the matrix, like a cancelled workflow
+		if expanded.FailFast && len(expanded.Combinations) > 1 {
+			parent := common.JobCancelContext(ctx)
+			if parent == nil {
+				parent = ctx
+			}
+			job.matrixCancelCtx, job.cancelMatrix = context.WithCancel(parent)
+		}
+
+		names := map[string]bool{}
+		for i, combination := range expanded.Combinations {
+			rc := s.runner.newRunContext(ctx, job.run, combination.Matrix)
+			rc.JobName = rc.Name
+			rc.Name = matrixJobName(job.run.Job(), rc.Name, combination.DisplaySuffix)
+			if names[rc.Name] {
+				// container names are derived from the name, so every combination needs its own
+				rc.Name = fmt.Sprintf("%s-%d", rc.Name, i+1)
+			}
+			names[rc.Name] = true
+			if len(rc.String()) > s.maxJobNameLen {
+				s.maxJobNameLen = len(rc.String())
+			}
+			job.legs = append(job.legs, &scheduledLeg{job: job, index: i, rc: rc, matrix: combination.Matrix})
+			job.state.Strategy = append(job.state.Strategy, state.MatrixJobState{
+				Matrix: combination.Matrix,
+				Name:   rc.Name,
+				State:  state.JobStatusDependenciesReady,
+			})
+		}
+		job.state.State = state.JobStatusDependenciesReady
+		if len(job.legs) == 0 {
+			// no combination is selected by --matrix
+			job.complete()
+		}
+	}
+}
+
+func (job *scheduledJob) needsCompleted() bool {
+	for _, n := range job.needs {
+		if n.state.State != state.JobStatusCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// start starts the combinations which fit into the limits, the others are blocked
+func (s *scheduler) start(ctx context.Context, results chan<- legResult) {
+	for _, job := range s.jobs {
+		for job.next < len(job.legs) {
+			leg := job.legs[job.next]
+			if job.matrixCancelCtx != nil && job.matrixCancelCtx.Err() != nil {
+				log.Infof("Skipping %s, another combination of the matrix failed and fail-fast is enabled", leg.rc.String())
+				job.next++
+				job.setLeg(leg.index, state.JobStatusCompleted, "cancelled")
+				continue
+			}
+			if job.running >= job.maxParallel || s.running >= s.limit {
+				break
+			}
+			job.next++
+			job.running++
+			s.running++
+			job.setLeg(leg.index, state.JobStatusRunning, "")
+			jobName := fmt.Sprintf("%-*s", s.maxJobNameLen, leg.rc.String())
+			go func() {
+				failed, err := s.runLeg(ctx, leg, jobName)
+				results <- legResult{leg: leg, err: err, failed: failed, outputs: jobOutputs(leg.rc.Run.Job())}
+			}()
+		}
+		for i := job.next; i < len(job.legs); i++ {
+			job.setLeg(i, state.JobStatusBlocked, "")
+		}
+		switch {
+		case job.state.State == state.JobStatusCompleted || job.state.State == state.JobStatusPending:
+		case job.running > 0:
+			job.state.State = state.JobStatusRunning
+		case job.next < len(job.legs):
+			job.state.State = state.JobStatusBlocked
+		default:
+			job.complete()
+		}
+	}
+}
+
+// runLeg runs a combination and reports whether it failed
+func (s *scheduler) runLeg(ctx context.Context, leg *scheduledLeg, jobName string) (bool, error) {
+	job := leg.job
+	if job.matrixCancelCtx != nil {
+		ctx = common.WithJobCancelContext(ctx, job.matrixCancelCtx)
+	}
+	executor, err := leg.rc.Executor()
+	if err != nil {
+		return true, err
+	}
+	ctx = common.WithJobErrorContainer(WithJobLogger(ctx, leg.rc.Run.JobID, jobName, leg.rc.Config, &leg.rc.Masks, leg.matrix))
+	err = executor(ctx)
+	return err != nil || common.JobError(ctx) != nil, err
+}
+
+// finish records the result of a combination and completes its job after the last one
+func (s *scheduler) finish(res legResult) {
+	job := res.leg.job
+	s.running--
+	job.running--
+	if res.err != nil && s.err == nil {
+		s.err = res.err
+	}
+	result := "success"
+	switch {
+	case res.failed:
+		result = "failure"
+		job.cancelMatrix()
+	case sharedJobResult(job.run.Job()) == "skipped":
+		result = "skipped"
+	}
+	job.setLeg(res.leg.index, state.JobStatusCompleted, result)
+	job.state.Strategy[res.leg.index].Outputs = res.outputs
+	if job.running == 0 && job.next == len(job.legs) {
+		job.complete()
+	}
+}
+
+func (job *scheduledJob) setLeg(index int, status state.JobStatus, result string) {
+	leg := &job.state.Strategy[index]
+	if leg.State == state.JobStatusCompleted {
+		return
+	}
+	leg.State = status
+	leg.Result = result
+}
+
+// complete sets the result of the job, which is shared by all of its combinations
+func (job *scheduledJob) complete() {
+	job.cancelMatrix()
+	job.state.State = state.JobStatusCompleted
+	job.state.Result = sharedJobResult(job.run.Job())
+	if job.state.Result == "" {
+		job.state.Result = "skipped"
+		for _, leg := range job.state.Strategy {
+			if leg.Result == "failure" || leg.Result == "success" && job.state.Result != "failure" {
+				job.state.Result = leg.Result
+			}
+		}
+	}
+	job.state.Outputs = jobOutputs(job.run.Job())
+}
+
+// persist writes the state of the workflows to --state-path, the state of reusable
+// workflows is part of the job calling them
+func (s *scheduler) persist() {
+	if s.runner.config.StatePath == "" || s.runner.caller != nil {
+		return
+	}
+	for w, ws := range s.workflows {
+		if err := ws.Save(s.statePath(w)); err != nil && !s.persistError {
+			// report it once, the state is only informational
+			s.persistError = true
+			log.Warnf("failed to persist the state of workflow %s: %v", w.File, err)
+		}
+	}
+}
+
+// statePath returns the file of the state of a workflow, grouped by the working directory
+func (s *scheduler) statePath(w *model.Workflow) string {
+	workdir := fmt.Sprintf("%x", sha256.Sum256([]byte(s.runner.config.Workdir)))[:16]
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(w.File)
+	if name == "" {
+		name = "workflow"
+	}
+	return filepath.Join(s.runner.config.StatePath, workdir, name+".json")
+}
+
+func logJob(job *model.Job) {
+	log.Debugf("Job.Name: %v", job.Name)
+	log.Debugf("Job.RawNeeds: %v", job.RawNeeds)
+	log.Debugf("Job.RawRunsOn: %v", job.RawRunsOn)
+	log.Debugf("Job.Env: %v", job.Env)
+	log.Debugf("Job.If: %v", job.If)
+	for step := range job.Steps {
+		if nil != job.Steps[step] {
+			log.Debugf("Job.Steps: %v", job.Steps[step].String())
+		}
+	}
+	log.Debugf("Job.TimeoutMinutes: %v", job.TimeoutMinutes)
+	log.Debugf("Job.Services: %v", job.Services)
+	log.Debugf("Job.Strategy: %v", job.Strategy)
+	log.Debugf("Job.RawContainer: %v", job.RawContainer)
+	log.Debugf("Job.Defaults.Run.Shell: %v", job.Defaults.Run.Shell)
+	log.Debugf("Job.Defaults.Run.WorkingDirectory: %v", job.Defaults.Run.WorkingDirectory)
+	log.Debugf("Job.Outputs: %v", job.Outputs)
+	log.Debugf("Job.Uses: %v", job.Uses)
+	log.Debugf("Job.With: %v", job.With)
+	log.Debugf("Job.Result: %v", job.Result)
+}
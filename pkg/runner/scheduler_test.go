@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	state "github.com/actions-oss/act-cli/internal/model"
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+// runScheduled plans the workflow and runs it, every job runs executor instead of its steps
+func runScheduled(t *testing.T, workflow string, config *Config, executor func(rc *RunContext) common.Executor) error {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ci.yml"), []byte(workflow), 0o600))
+	planner, err := model.NewWorkflowPlanner(filepath.Join(dir, "ci.yml"), true, false)
+	require.NoError(t, err)
+	plan, err := planner.PlanEvent("push")
+	require.NoError(t, err)
+
+	config.Workdir = dir
+	config.EventName = "push"
+	config.Platforms = map[string]string{"ubuntu-latest": "-self-hosted"}
+	config.CustomExecutor = map[model.JobType]func(*RunContext) common.Executor{
+		model.JobTypeDefault: executor,
+	}
+	r, err := New(config)
+	require.NoError(t, err)
+	return r.NewPlanExecutor(plan)(context.Background())
+}
+
+func TestSchedulerStartsJobsWhenTheirNeedsComplete(t *testing.T) {
+	workflow := `on: push
+jobs:
+  slow:
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  after-slow:
+    needs: slow
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  fast:
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+  after-fast:
+    needs: fast
+    runs-on: ubuntu-latest
+    steps:
+      - run: exit 0
+`
+	// slow only completes after after-fast started, a stage based plan would wait forever
+	afterFastStarted := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	statePath := t.TempDir()
+	err := runScheduled(t, workflow, &Config{Parallel: 4, StatePath: statePath}, func(rc *RunContext) common.Executor {
+		return func(_ context.Context) error {
+			mu.Lock()
+			order = append(order, rc.Run.JobID)
+			mu.Unlock()
+			switch rc.Run.JobID {
+			case "slow":
+				select {
+				case <-afterFastStarted:
+				case <-time.After(10 * time.Second):
+					return errors.New("after-fast didn't start while slow was running")
+				}
+			case "after-fast":
+				close(afterFastStarted)
+			}
+			rc.result("success")
+			return nil
+		}
+	})
+	require.NoError(t, err)
+	assert.Less(t, indexOf(order, "after-fast"), indexOf(order, "after-slow"))
+
+	files, err := filepath.Glob(filepath.Join(statePath, "*", "ci.yml.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	ws, err := state.LoadWorkflowState(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, state.WorkflowStatusCompleted, ws.StateWorkflowStatus)
+	require.Len(t, ws.Jobs, 4)
+	for id, job := range ws.Jobs {
+		assert.Equal(t, state.JobStatusCompleted, job.State, id)
+		assert.Equal(t, "success", job.Result, id)
+	}
+	assert.Equal(t, []string{"slow"}, ws.Jobs["after-slow"].Needs)
+}
+
+func TestSchedulerMaxParallel(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      max-parallel: 2
+      matrix:
+        n: [1, 2, 3, 4, 5]
+    steps:
+      - run: exit 0
+`
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var names []string
+	err := runScheduled(t, workflow, &Config{Parallel: 8}, func(rc *RunContext) common.Executor {
+		return func(_ context.Context) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			names = append(names, rc.Name)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, maxRunning)
+	assert.ElementsMatch(t, []string{"build (1)", "build (2)", "build (3)", "build (4)", "build (5)"}, names)
+}
+
+func TestSchedulerFailFast(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      max-parallel: 1
+      matrix:
+        n: [1, 2, 3]
+    steps:
+      - run: exit 0
+`
+	var started []string
+	err := runScheduled(t, workflow, &Config{Parallel: 1}, func(rc *RunContext) common.Executor {
+		return func(ctx context.Context) error {
+			started = append(started, rc.Name)
+			common.SetJobError(ctx, errors.New("failed"))
+			rc.result("failure")
+			return nil
+		}
+	})
+	assert.EqualError(t, err, "job 'build' failed")
+	assert.Equal(t, []string{"build (1)"}, started, "the other combinations are cancelled")
+}
+
+func TestSchedulerOutputsOfParallelCombinations(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        n: [1, 2, 3, 4, 5, 6, 7, 8]
+    outputs:
+      out: value
+    steps:
+      - run: exit 0
+`
+	statePath := t.TempDir()
+	// the combinations write the outputs while the scheduler records those of the completed ones, go test -race
+	// reports an access which isn't guarded
+	err := runScheduled(t, workflow, &Config{Parallel: 8, StatePath: statePath}, func(rc *RunContext) common.Executor {
+		return func(_ context.Context) error {
+			// the later combinations keep writing after the first ones completed
+			until := time.Now().Add(time.Duration(rc.Matrix["n"].(int)) * 5 * time.Millisecond)
+			for i := 0; time.Now().Before(until); i++ {
+				setJobOutput(rc.Run.Job(), fmt.Sprintf("out%d", i%4096), rc.Name)
+			}
+			rc.result("success")
+			return nil
+		}
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(statePath, "*", "ci.yml.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	ws, err := state.LoadWorkflowState(files[0])
+	require.NoError(t, err)
+	for _, leg := range ws.Jobs["build"].Strategy {
+		assert.Equal(t, "value", leg.Outputs["out"])
+		assert.Len(t, leg.Outputs, 4097)
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
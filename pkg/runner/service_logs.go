@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/container"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultServiceLogLines = 50
+
+// serviceLogs captures the output of a service container. Every line is
+// forwarded to the job logger prefixed with `service:<id>`, optionally appended
+// to a per service log file and the last lines are kept for failure reports.
+type serviceLogs struct {
+	ID    string
+	Path  string
+	mu    sync.Mutex
+	lines []string
+	max   int
+	file  *os.File
+}
+
+func newServiceLogs(rc *RunContext, logger logrus.FieldLogger, serviceID string) (*serviceLogs, io.Writer, error) {
+	sl := &serviceLogs{
+		ID:  serviceID,
+		max: rc.Config.ServiceLogLines,
+	}
+	if sl.max <= 0 {
+		sl.max = defaultServiceLogLines
+	}
+	if rc.Config.ServiceLogDir != "" {
+		dir := filepath.Join(rc.Config.ServiceLogDir, rc.jobContainerName())
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create service log directory: %w", err)
+		}
+		sl.Path = filepath.Join(dir, serviceID+".log")
+		f, err := os.OpenFile(sl.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create service log file: %w", err)
+		}
+		sl.file = f
+	}
+
+	rawLogger := logger.WithField("raw_output", true).WithField("service", serviceID)
+	writer := common.NewLineWriter(sl.record, func(s string) bool {
+		if rc.Config.LogOutput {
+			rawLogger.Infof("service:%s %s", serviceID, s)
+		} else {
+			rawLogger.Debugf("service:%s %s", serviceID, s)
+		}
+		return true
+	})
+	return sl, writer, nil
+}
+
+func (sl *serviceLogs) record(line string) bool {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.file != nil {
+		_, _ = sl.file.WriteString(line)
+	}
+	sl.lines = append(sl.lines, strings.TrimRight(line, "\r\n"))
+	if len(sl.lines) > sl.max {
+		sl.lines = sl.lines[len(sl.lines)-sl.max:]
+	}
+	return true
+}
+
+// Tail returns the last captured lines of the service output
+func (sl *serviceLogs) Tail() []string {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return append([]string{}, sl.lines...)
+}
+
+func (sl *serviceLogs) Close() error {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.file == nil {
+		return nil
+	}
+	err := sl.file.Close()
+	sl.file = nil
+	return err
+}
+
+// reportServiceLogs prints the tail of the service output together with the
+// container state and health check history
+func reportServiceLogs(ctx context.Context, c container.ExecutionsEnvironment, sl *serviceLogs) {
+	logger := common.Logger(ctx)
+	if state, err := c.GetState(ctx); err != nil {
+		logger.Warnf("Unable to inspect service %s: %v", sl.ID, err)
+	} else {
+		logger.Errorf("Service %s: status=%s running=%t exitcode=%d oomkilled=%t health=%s", sl.ID, state.Status, state.Running, state.ExitCode, state.OOMKilled, state.Health)
+		if state.Error != "" {
+			logger.Errorf("Service %s: error: %s", sl.ID, state.Error)
+		}
+		for _, probe := range state.HealthHistory {
+			logger.Errorf("Service %s: health check at %s exitcode=%d: %s", sl.ID, probe.Start.Format("15:04:05"), probe.ExitCode, strings.TrimSpace(probe.Output))
+		}
+	}
+	lines := sl.Tail()
+	logger.Errorf("Service %s: last %d log line(s)", sl.ID, len(lines))
+	for _, line := range lines {
+		logger.Errorf("service:%s %s", sl.ID, line)
+	}
+	if sl.Path != "" {
+		logger.Errorf("Service %s: full log written to %s", sl.ID, sl.Path)
+	}
+}
+
+// reportAllServiceLogs prints the diagnostics of every service of the job
+func (rc *RunContext) reportAllServiceLogs(ctx context.Context) {
+	for i, c := range rc.ServiceContainers {
+		if i < len(rc.serviceLogs) {
+			reportServiceLogs(ctx, c, rc.serviceLogs[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/actions-oss/act-cli/pkg/container"
+	"github.com/actions-oss/act-cli/pkg/model"
+)
+
+func TestServiceLogs(t *testing.T) {
+	logDir := t.TempDir()
+	rc := &RunContext{
+		Name: "job",
+		Config: &Config{
+			LogOutput:       true,
+			ServiceLogDir:   logDir,
+			ServiceLogLines: 3,
+		},
+		Run: &model.Run{
+			JobID:    "job",
+			Workflow: &model.Workflow{Name: "wf"},
+		},
+	}
+	logger, hook := test.NewNullLogger()
+
+	sl, writer, err := newServiceLogs(rc, logger, "postgres")
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := fmt.Fprintf(writer, "line %d\n", i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, sl.Close())
+
+	assert.Equal(t, []string{"line 2", "line 3", "line 4"}, sl.Tail())
+
+	content, err := os.ReadFile(filepath.Join(logDir, rc.jobContainerName(), "postgres.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 0\nline 1\nline 2\nline 3\nline 4\n", string(content))
+
+	require.Len(t, hook.AllEntries(), 5)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "service:postgres line 4\n", entry.Message)
+	assert.Equal(t, "postgres", entry.Data["service"])
+}
+
+func TestPrepareServiceContainersClosesLogsOnError(t *testing.T) {
+	services := map[string]*model.ContainerSpec{"broken": {Image: "redis", Ports: []string{"not a port"}}}
+	for i := 0; i < 5; i++ {
+		services[fmt.Sprintf("service%d", i)] = &model.ContainerSpec{Image: "postgres"}
+	}
+	rc := &RunContext{
+		Name:   "job",
+		Config: &Config{ServiceLogDir: t.TempDir()},
+		Run: &model.Run{
+			JobID: "job",
+			Workflow: &model.Workflow{
+				Name: "wf",
+				Jobs: map[string]*model.Job{"job": {Services: services}},
+			},
+		},
+	}
+	rc.ExprEval = rc.NewExpressionEvaluator(context.Background())
+	logger, _ := test.NewNullLogger()
+
+	_, _, err := rc.prepareServiceContainers(context.Background(), logger, container.LinuxContainerEnvironmentExtensions{})
+	require.ErrorContains(t, err, "failed to parse service broken ports")
+	for _, sl := range rc.serviceLogs {
+		assert.Nil(t, sl.file, "the log of %s is closed", sl.ID)
+	}
+}
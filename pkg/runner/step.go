@@ -16,6 +16,7 @@ import (
 	"github.com/actions-oss/act-cli/pkg/exprparser"
 	"github.com/actions-oss/act-cli/pkg/model"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 type step interface {
@@ -200,6 +201,7 @@ func runStepExecutor(step step, stage stepStage, executor common.Executor) commo
 			}
 
 			logger.WithField("stepResult", stepResult.Outcome).Errorf("  \u274C  Failure - %s %s", stage, stepString)
+			rc.reportAllServiceLogs(ctx)
 		}
 		// Process Runner File Commands
 		ferrors := []error{err}
@@ -247,32 +249,77 @@ func setupEnv(ctx context.Context, step step) error {
 	// merge step env last, since it should not be overwritten
 	mergeIntoMap(step, step.getEnv(), step.getStepModel().GetEnv())
 
+	// the env and the inputs of the step are evaluated once through the workflow schema
+	evaluated := schemaEvaluatedKeys(step.getStepModel())
 	exprEval := rc.NewExpressionEvaluator(ctx)
 	for k, v := range *step.getEnv() {
-		if !strings.HasPrefix(k, "INPUT_") {
+		if !strings.HasPrefix(k, "INPUT_") && !evaluated[k] {
 			(*step.getEnv())[k] = exprEval.Interpolate(ctx, v)
 		}
 	}
+	stepEnv, err := evaluateStepMapping(ctx, exprEval, &step.getStepModel().Env, "env")
+	if err != nil {
+		return err
+	}
+	mergeIntoMap(step, step.getEnv(), stepEnv)
 	// after we have an evaluated step context, update the expressions evaluator with a new env context
 	// you can use step level env in the with property of a uses construct
 	exprEval = rc.NewExpressionEvaluatorWithEnv(ctx, *step.getEnv())
 	for k, v := range *step.getEnv() {
-		if strings.HasPrefix(k, "INPUT_") {
+		if strings.HasPrefix(k, "INPUT_") && !evaluated[k] {
 			(*step.getEnv())[k] = exprEval.Interpolate(ctx, v)
 		}
 	}
+	inputs, err := evaluateStepMapping(ctx, exprEval, &step.getStepModel().RawWith, "with")
+	if err != nil {
+		return err
+	}
+	mergeIntoMap(step, step.getEnv(), (&model.Step{With: inputs}).GetEnv())
 
 	common.Logger(ctx).Debugf("setupEnv => %v", *step.getEnv())
 
 	return nil
 }
 
+// schemaEvaluatedKeys returns the variables of the env of a step which evaluateStepMapping evaluates,
+// the inputs are among them as INPUT_ variables
+func schemaEvaluatedKeys(stepModel *model.Step) map[string]bool {
+	keys := map[string]bool{}
+	if stepModel.Env.Kind == yaml.MappingNode {
+		for k := range stepModel.Environment() {
+			keys[k] = true
+		}
+	}
+	if stepModel.RawWith.Kind == yaml.MappingNode {
+		for k := range (&model.Step{With: stepModel.With}).GetEnv() {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+// evaluateStepMapping evaluates the env or with of a step through the workflow schema, which
+// coerces the values to strings and reports errors at their location in the workflow
+func evaluateStepMapping(ctx context.Context, ee ExpressionEvaluator, node *yaml.Node, key string) (map[string]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	evaluated := copyYamlNode(node)
+	if err := ee.EvaluateSchemaYamlNode(ctx, evaluated, "steps", "*", key); err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", key, err)
+	}
+	values := map[string]string{}
+	if err := evaluated.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
 func mergeEnv(ctx context.Context, step step) {
 	env := step.getEnv()
 	rc := step.getRunContext()
-	job := rc.Run.Job()
 
-	c := job.Container()
+	c := rc.evaluatedContainer()
 	if c != nil {
 		mergeIntoMap(step, env, rc.GetEnv(), c.Env)
 	} else {
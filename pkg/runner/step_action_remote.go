@@ -181,13 +181,17 @@ func (sar *stepActionRemote) getContainerActionPaths() (string, string) {
 }
 
 func (sar *stepActionRemote) getContainerActionPathsExt(subPath string) (string, string) {
-	cacheDir := sar.RunContext.ActionCacheDir()
-	actionDir := filepath.Join(cacheDir, safeFilename(sar.Step.Uses), subPath)
-	actionName := getOsSafeRelativePath(actionDir, cacheDir)
+	actionName := sar.getActionName(subPath)
 	containerActionDir := sar.RunContext.JobContainer.GetActPath() + "/actions/" + actionName
 	return actionName, containerActionDir
 }
 
+func (sar *stepActionRemote) getActionName(subPath string) string {
+	cacheDir := sar.RunContext.ActionCacheDir()
+	actionDir := filepath.Join(cacheDir, safeFilename(sar.Step.Uses), subPath)
+	return getOsSafeRelativePath(actionDir, cacheDir)
+}
+
 func (sar *stepActionRemote) getTarArchive(ctx context.Context, src string) (io.ReadCloser, error) {
 	return sar.RunContext.getActionCache().GetTarArchive(ctx, sar.cacheDir, sar.resolvedSha, src)
 }
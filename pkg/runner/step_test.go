@@ -2,9 +2,11 @@ package runner
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/actions-oss/act-cli/pkg/common"
+	"github.com/actions-oss/act-cli/pkg/exprparser"
 	"github.com/actions-oss/act-cli/pkg/model"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -172,18 +174,18 @@ func TestSetupEnv(t *testing.T) {
 		"GITHUB_ACTION_PATH":       "",
 		"GITHUB_ACTION_REF":        "",
 		"GITHUB_ACTION_REPOSITORY": "",
-		"GITHUB_API_URL":           "https:///api/v3",
+		"GITHUB_API_URL":           "https://api.github.com",
 		"GITHUB_BASE_REF":          "",
 		"GITHUB_EVENT_NAME":        "",
 		"GITHUB_EVENT_PATH":        "/var/run/act/workflow/event.json",
-		"GITHUB_GRAPHQL_URL":       "https:///api/graphql",
+		"GITHUB_GRAPHQL_URL":       "https://api.github.com/graphql",
 		"GITHUB_HEAD_REF":          "",
 		"GITHUB_JOB":               "1",
 		"GITHUB_RETENTION_DAYS":    "0",
 		"GITHUB_RUN_ID":            "runId",
 		"GITHUB_RUN_NUMBER":        "1",
 		"GITHUB_RUN_ATTEMPT":       "1",
-		"GITHUB_SERVER_URL":        "https://",
+		"GITHUB_SERVER_URL":        "https://github.com",
 		"GITHUB_WORKFLOW":          "",
 		"INPUT_STEP_WITH":          "with-value",
 		"RC_KEY":                   "rcvalue",
@@ -372,3 +374,58 @@ func TestSymlinkJoin(t *testing.T) {
 		assert.Equal(t, entry.result, result)
 	}
 }
+
+func TestEvaluateStepMapping(t *testing.T) {
+	var step model.Step
+	err := yaml.Unmarshal([]byte(`
+uses: ./action
+with:
+  flag: ${{ matrix.n == 1 }}
+  list: ${{ toJSON(fromJSON('[1,2]')) }}
+  text: v${{ matrix.n }}
+`), &step)
+	assert.NoError(t, err)
+
+	ee := newExpressionEvaluator(&exprparser.EvaluationEnvironment{
+		Github:   &model.GithubContext{},
+		Env:      map[string]string{},
+		Job:      &model.JobContext{},
+		Steps:    map[string]*model.StepResult{},
+		Runner:   map[string]interface{}{},
+		Secrets:  map[string]string{},
+		Vars:     map[string]string{},
+		Strategy: map[string]interface{}{},
+		Matrix:   map[string]interface{}{"n": 1},
+		Needs:    map[string]exprparser.Needs{},
+		Inputs:   map[string]interface{}{},
+		HashFiles: func([]reflect.Value) (interface{}, error) {
+			return "", nil
+		},
+	}, exprparser.Config{Context: "job"})
+	with, err := evaluateStepMapping(context.Background(), ee, &step.RawWith, "with")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"flag": "true",
+		"list": "[\n  1,\n  2\n]",
+		"text": "v1",
+	}, with)
+	assert.Equal(t, "${{ matrix.n == 1 }}", step.With["flag"], "the workflow is not modified")
+}
+
+func TestSchemaEvaluatedKeys(t *testing.T) {
+	var step model.Step
+	err := yaml.Unmarshal([]byte(`
+uses: ./action
+env:
+  STEP_ENV: ${{ hashFiles('**') }}
+with:
+  some-input: ${{ hashFiles('**') }}
+`), &step)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"STEP_ENV": true, "INPUT_SOME-INPUT": true}, schemaEvaluatedKeys(&step),
+		"the env and the inputs are not interpolated before they are evaluated through the schema")
+
+	step.With["programmatic"] = "value"
+	step.RawWith = yaml.Node{}
+	assert.Equal(t, map[string]bool{"STEP_ENV": true}, schemaEvaluatedKeys(&step), "inputs without node are interpolated")
+}
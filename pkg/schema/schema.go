@@ -131,6 +131,7 @@ func (s *Schema) GetDefinition(name string) Definition {
 }
 
 type Definition struct {
+	Description   string              `json:"description,omitempty"`
 	Context       []string            `json:"context,omitempty"`
 	Mapping       *MappingDefinition  `json:"mapping,omitempty"`
 	Sequence      *SequenceDefinition `json:"sequence,omitempty"`
@@ -149,8 +150,9 @@ type MappingDefinition struct {
 }
 
 type MappingProperty struct {
-	Type     string `json:"type,omitempty"`
-	Required bool   `json:"required,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 func (s *MappingProperty) UnmarshalJSON(data []byte) error {
@@ -343,6 +345,12 @@ func (s *Node) checkExpression(node *yaml.Node) (bool, error) {
 		hadExpr = true
 
 		j := exprEnd(val)
+		if j == -1 {
+			return hadExpr, errors.Join(err, ValidationError{
+				Location: toLocation(node),
+				Message:  "unclosed expression, expected }} after ${{",
+			})
+		}
 
 		exprNode, parseErr := exprparser.Parse(val[:j])
 		if parseErr != nil {
@@ -585,6 +593,15 @@ func (s *Node) GetNestedNode(path ...string) *Node {
 	return nil
 }
 
+// GetKeyNode returns the node of the keys of a mapping with loose keys, it is nil if the keys are properties
+func (s *Node) GetKeyNode() *Node {
+	def := s.Schema.GetDefinition(s.Definition)
+	if def.Mapping == nil || def.Mapping.LooseKeyType == "" {
+		return nil
+	}
+	return s.childNode(def.Mapping.LooseKeyType)
+}
+
 func (s *Node) checkMapping(node *yaml.Node, def Definition) error {
 	if err := assertKind(node, yaml.MappingNode); err != nil {
 		return err
@@ -737,3 +754,35 @@ func (s *Node) ValidateExpression(node *yaml.Node, allowedVars map[string]struct
 	}
 	return true
 }
+
+// RestrictEvents limits the events accepted by the strict validation of on to events,
+// forges like Gitea only support a subset of the events of GitHub
+func (s *Schema) RestrictEvents(events []string) {
+	supported := map[string]bool{}
+	for _, e := range events {
+		supported[e] = true
+	}
+	if def, ok := s.Definitions["on-mapping-strict"]; ok && def.Mapping != nil {
+		properties := map[string]MappingProperty{}
+		for k, v := range def.Mapping.Properties {
+			if supported[k] {
+				properties[k] = v
+			}
+		}
+		mapping := *def.Mapping
+		mapping.Properties = properties
+		def.Mapping = &mapping
+		s.Definitions["on-mapping-strict"] = def
+	}
+	if def, ok := s.Definitions["on-string-strict"]; ok && def.OneOf != nil {
+		oneOf := []string{}
+		for _, name := range *def.OneOf {
+			event := strings.ReplaceAll(strings.TrimSuffix(name, "-string"), "-", "_")
+			if supported[event] {
+				oneOf = append(oneOf, name)
+			}
+		}
+		def.OneOf = &oneOf
+		s.Definitions["on-string-strict"] = def
+	}
+}
@@ -129,6 +129,26 @@ jobs:
 	assert.Error(t, err)
 }
 
+func TestUnclosedExpression(t *testing.T) {
+	var node yaml.Node
+	err := yaml.Unmarshal([]byte(`
+on: push
+jobs:
+  job:
+    runs-on: self-hosted
+    steps:
+    - run: echo ${{ github.event
+`), &node)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = (&Node{
+		Definition: "workflow-root-strict",
+		Schema:     GetWorkflowSchema(),
+	}).UnmarshalYAML(&node)
+	assert.ErrorContains(t, err, "unclosed expression")
+}
+
 func TestEscape(t *testing.T) {
 	var node yaml.Node
 	err := yaml.Unmarshal([]byte(`
@@ -0,0 +1,441 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v2 "github.com/actions-oss/act-cli/internal/eval/v2"
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+)
+
+type TypeKind int
+
+const (
+	TypeKindAny TypeKind = iota
+	TypeKindNull
+	TypeKindBoolean
+	TypeKindNumber
+	TypeKindString
+	TypeKindObject
+	TypeKindArray
+)
+
+func (k TypeKind) String() string {
+	switch k {
+	case TypeKindNull:
+		return "null"
+	case TypeKindBoolean:
+		return "boolean"
+	case TypeKindNumber:
+		return "number"
+	case TypeKindString:
+		return "string"
+	case TypeKindObject:
+		return "object"
+	case TypeKindArray:
+		return "array"
+	}
+	return "any"
+}
+
+// Type describes the shape of a context or of the result of an expression
+type Type struct {
+	Kind TypeKind
+	// Properties are the known properties of an object, the names are lower case
+	Properties map[string]*Type
+	// Loose is the type of the properties which are not known, accessing them is reported if nil
+	Loose *Type
+	// Items is the type of the items of an array
+	Items *Type
+}
+
+func AnyType() *Type {
+	return &Type{Kind: TypeKindAny}
+}
+
+func ScalarType(kind TypeKind) *Type {
+	return &Type{Kind: kind}
+}
+
+// ObjectType creates an object type, the property names are case insensitive
+func ObjectType(properties map[string]*Type, loose *Type) *Type {
+	props := make(map[string]*Type, len(properties))
+	for k, v := range properties {
+		props[strings.ToLower(k)] = v
+	}
+	return &Type{Kind: TypeKindObject, Properties: props, Loose: loose}
+}
+
+func ArrayType(items *Type) *Type {
+	return &Type{Kind: TypeKindArray, Items: items}
+}
+
+func (t *Type) property(name string) (*Type, bool) {
+	if p, ok := t.Properties[strings.ToLower(name)]; ok {
+		return p, true
+	}
+	if t.Loose != nil {
+		return t.Loose, true
+	}
+	return nil, false
+}
+
+// anyProperty is the type of a property with a name unknown before the evaluation
+func (t *Type) anyProperty() *Type {
+	if len(t.Properties) == 0 && t.Loose != nil {
+		return t.Loose
+	}
+	return AnyType()
+}
+
+type truthiness int
+
+const (
+	truthUnknown truthiness = iota
+	truthAlways
+	truthNever
+)
+
+// checkedNode is the inferred type of an evaluated node
+type checkedNode struct {
+	typ      *Type
+	path     string
+	filtered bool // result of a * filter, property access applies to every item
+	constant bool
+	value    interface{}
+	truth    truthiness
+}
+
+// ExpressionChecker checks expressions against the types of the contexts and the known functions
+type ExpressionChecker struct {
+	// Contexts are the types of the available contexts, the names are lower case
+	Contexts  map[string]*Type
+	Functions []FunctionInfo
+}
+
+// functionTypes are the result types of the builtin functions
+var functionTypes = map[string]TypeKind{
+	"contains":   TypeKindBoolean,
+	"startswith": TypeKindBoolean,
+	"endswith":   TypeKindBoolean,
+	"format":     TypeKindString,
+	"join":       TypeKindString,
+	"tojson":     TypeKindString,
+	"fromjson":   TypeKindAny,
+	"hashfiles":  TypeKindString,
+	"success":    TypeKindBoolean,
+	"failure":    TypeKindBoolean,
+	"always":     TypeKindBoolean,
+	"cancelled":  TypeKindBoolean,
+	"case":       TypeKindAny,
+}
+
+// pureFunctions can be evaluated while checking if all arguments are constant
+var pureFunctions = map[string]bool{
+	"contains":   true,
+	"startswith": true,
+	"endswith":   true,
+	"format":     true,
+	"join":       true,
+	"tojson":     true,
+	"fromjson":   true,
+	"case":       true,
+}
+
+// Check infers the type of an expression and returns the problems found
+func (c *ExpressionChecker) Check(node exprparser.Node) (*Type, []ValidationError) {
+	var errs []ValidationError
+	res := c.check(node, &errs)
+	return res.typ, errs
+}
+
+// CheckCondition checks an expression like Check and also reports if the
+// condition is always true or always false
+func (c *ExpressionChecker) CheckCondition(node exprparser.Node) []ValidationError {
+	var errs []ValidationError
+	res := c.check(node, &errs)
+	switch res.truth {
+	case truthAlways:
+		errs = append(errs, ValidationError{Kind: ValidationKindWarning, Message: "condition is always true"})
+	case truthNever:
+		errs = append(errs, ValidationError{Kind: ValidationKindWarning, Message: "condition is always false"})
+	}
+	return errs
+}
+
+// AlwaysFalse checks if a condition is false regardless of the values of the contexts
+func (c *ExpressionChecker) AlwaysFalse(node exprparser.Node) bool {
+	var errs []ValidationError
+	return c.check(node, &errs).truth == truthNever
+}
+
+func (c *ExpressionChecker) check(n exprparser.Node, errs *[]ValidationError) checkedNode {
+	var res checkedNode
+	switch node := n.(type) {
+	case *exprparser.ValueNode:
+		res = c.checkValue(node, errs)
+	case *exprparser.FunctionNode:
+		res = c.checkFunction(node, errs)
+	case *exprparser.BinaryNode:
+		res = c.checkBinary(node, errs)
+	case *exprparser.UnaryNode:
+		operand := c.check(node.Operand, errs)
+		res = checkedNode{typ: ScalarType(TypeKindBoolean)}
+		switch operand.truth {
+		case truthAlways:
+			res.truth = truthNever
+		case truthNever:
+			res.truth = truthAlways
+		}
+		if operand.constant {
+			res = constantNode(n)
+		}
+	default:
+		res = checkedNode{typ: AnyType()}
+	}
+	if res.truth == truthUnknown && (res.typ.Kind == TypeKindObject || res.typ.Kind == TypeKindArray) {
+		res.truth = truthAlways
+	}
+	return res
+}
+
+func (c *ExpressionChecker) checkValue(node *exprparser.ValueNode, errs *[]ValidationError) checkedNode {
+	if node.Kind != exprparser.TokenKindNamedValue {
+		return constantNode(node)
+	}
+	name, _ := node.Value.(string)
+	typ, ok := c.Contexts[strings.ToLower(name)]
+	if !ok {
+		*errs = append(*errs, ValidationError{Kind: ValidationKindInvalidProperty, Message: fmt.Sprintf("unknown Variable Access %s", name)})
+		return checkedNode{typ: AnyType(), path: name}
+	}
+	return checkedNode{typ: typ, path: name}
+}
+
+func (c *ExpressionChecker) checkFunction(node *exprparser.FunctionNode, errs *[]ValidationError) checkedNode {
+	var info FunctionInfo
+	for _, f := range c.Functions {
+		if strings.EqualFold(f.GetName(), node.Name) {
+			info = f
+			break
+		}
+	}
+	// only valid calls are evaluated
+	constant := true
+	if info == nil {
+		*errs = append(*errs, ValidationError{Kind: ValidationKindInvalidProperty, Message: fmt.Sprintf("unknown Function Call %s", node.Name)})
+		constant = false
+	} else if err := info.Check(node.Args); err != nil {
+		*errs = append(*errs, ValidationError{Kind: ValidationKindFatal, Message: err.Error()})
+		constant = false
+	}
+
+	for _, arg := range node.Args {
+		if !c.check(arg, errs).constant {
+			constant = false
+		}
+	}
+	name := strings.ToLower(node.Name)
+	if constant && pureFunctions[name] {
+		return constantNode(node)
+	}
+	return checkedNode{typ: ScalarType(functionTypes[name])}
+}
+
+func (c *ExpressionChecker) checkBinary(node *exprparser.BinaryNode, errs *[]ValidationError) checkedNode {
+	left := c.check(node.Left, errs)
+	switch node.Op {
+	case ".":
+		v, ok := node.Right.(*exprparser.ValueNode)
+		if !ok {
+			return checkedNode{typ: AnyType()}
+		}
+		if v.Kind == exprparser.TokenKindWildcard {
+			return c.filter(left)
+		}
+		return c.access(left, fmt.Sprint(v.Value), true, errs)
+	case "[":
+		index := c.check(node.Right, errs)
+		if index.constant {
+			if name, ok := index.value.(string); ok {
+				return c.access(left, name, true, errs)
+			}
+			return c.index(left)
+		}
+		if left.typ.Kind == TypeKindObject {
+			return c.access(left, "", false, errs)
+		}
+		return c.index(left)
+	}
+
+	right := c.check(node.Right, errs)
+	if left.constant && right.constant {
+		return constantNode(node)
+	}
+	switch node.Op {
+	case "&&":
+		res := checkedNode{typ: mergeTypes(left.typ, right.typ)}
+		switch {
+		case left.truth == truthNever || right.truth == truthNever:
+			res.truth = truthNever
+		case left.truth == truthAlways && right.truth == truthAlways:
+			res.truth = truthAlways
+		}
+		return res
+	case "||":
+		res := checkedNode{typ: mergeTypes(left.typ, right.typ)}
+		switch {
+		case left.truth == truthAlways || right.truth == truthAlways:
+			res.truth = truthAlways
+		case left.truth == truthNever && right.truth == truthNever:
+			res.truth = truthNever
+		}
+		return res
+	}
+	return checkedNode{typ: ScalarType(TypeKindBoolean)}
+}
+
+// access checks the access of a property, an empty name is a property not known before the evaluation
+func (c *ExpressionChecker) access(left checkedNode, name string, known bool, errs *[]ValidationError) checkedNode {
+	path := left.path
+	if path != "" {
+		if known {
+			path += "." + name
+		} else {
+			path += "[*]"
+		}
+	}
+	if left.filtered {
+		item := c.access(checkedNode{typ: left.typ.Items, path: left.path}, name, known, errs)
+		return checkedNode{typ: ArrayType(item.typ), path: path, filtered: true}
+	}
+	switch left.typ.Kind {
+	case TypeKindObject:
+		if !known {
+			return checkedNode{typ: left.typ.anyProperty(), path: path}
+		}
+		typ, ok := left.typ.property(name)
+		if !ok {
+			*errs = append(*errs, ValidationError{
+				Kind:    ValidationKindInvalidProperty,
+				Message: fmt.Sprintf("unknown property %s of %s%s", name, left.path, expectedProperties(left.typ)),
+			})
+			return checkedNode{typ: AnyType(), path: path}
+		}
+		return checkedNode{typ: typ, path: path}
+	case TypeKindAny, TypeKindNull:
+		return checkedNode{typ: AnyType(), path: path}
+	case TypeKindArray:
+		if !known {
+			return checkedNode{typ: left.typ.Items, path: path}
+		}
+	}
+	*errs = append(*errs, ValidationError{
+		Kind:    ValidationKindMismatched,
+		Message: fmt.Sprintf("property %s of %s does not exist, it is a %s", name, left.path, left.typ.Kind),
+	})
+	return checkedNode{typ: ScalarType(TypeKindNull), path: path}
+}
+
+func (c *ExpressionChecker) index(left checkedNode) checkedNode {
+	if left.filtered {
+		item := c.index(checkedNode{typ: left.typ.Items})
+		return checkedNode{typ: ArrayType(item.typ), path: left.path, filtered: true}
+	}
+	switch left.typ.Kind {
+	case TypeKindArray:
+		return checkedNode{typ: left.typ.Items, path: left.path + "[*]"}
+	case TypeKindObject:
+		return checkedNode{typ: left.typ.anyProperty(), path: left.path + "[*]"}
+	}
+	return checkedNode{typ: AnyType(), path: left.path + "[*]"}
+}
+
+func (c *ExpressionChecker) filter(left checkedNode) checkedNode {
+	var item *Type
+	switch left.typ.Kind {
+	case TypeKindArray:
+		item = left.typ.Items
+	case TypeKindObject:
+		item = left.typ.anyProperty()
+	default:
+		item = AnyType()
+	}
+	if left.filtered {
+		item = c.filter(checkedNode{typ: left.typ.Items}).typ.Items
+	}
+	return checkedNode{typ: ArrayType(item), path: left.path + ".*", filtered: true}
+}
+
+func expectedProperties(t *Type) string {
+	if len(t.Properties) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(t.Properties))
+	for k := range t.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return ", expected one of " + strings.Join(names, ", ")
+}
+
+func mergeTypes(a, b *Type) *Type {
+	if a.Kind == b.Kind && a.Kind != TypeKindObject && a.Kind != TypeKindArray {
+		return a
+	}
+	return AnyType()
+}
+
+// constantNode evaluates a node without any variables
+func constantNode(node exprparser.Node) checkedNode {
+	eval := v2.NewEvaluator(&v2.EvaluationContext{
+		Variables: v2.CaseInsensitiveObject[any]{},
+		Functions: v2.GetFunctions(),
+	})
+	res, err := eval.Evaluate(node)
+	if err != nil {
+		return checkedNode{typ: AnyType()}
+	}
+	value, _ := eval.ToRaw(res)
+	ret := checkedNode{constant: true, value: value, truth: truthNever}
+	if res.IsTruthy() {
+		ret.truth = truthAlways
+	}
+	switch res.Kind() {
+	case v2.ValueKindNull:
+		ret.typ = ScalarType(TypeKindNull)
+	case v2.ValueKindBoolean:
+		ret.typ = ScalarType(TypeKindBoolean)
+	case v2.ValueKindNumber:
+		ret.typ = ScalarType(TypeKindNumber)
+	case v2.ValueKindString:
+		ret.typ = ScalarType(TypeKindString)
+	case v2.ValueKindArray:
+		ret.typ = ArrayType(AnyType())
+	default:
+		ret.typ = ObjectType(nil, AnyType())
+	}
+	return ret
+}
+
+// Resolve returns the type of a context or of one of its nested properties, e.g.
+// steps, build, outputs for steps.build.outputs, or nil if it is unknown
+func (c *ExpressionChecker) Resolve(path ...string) *Type {
+	if len(path) == 0 {
+		return nil
+	}
+	t, ok := c.Contexts[strings.ToLower(path[0])]
+	if !ok {
+		return nil
+	}
+	for _, name := range path[1:] {
+		if t.Kind == TypeKindAny {
+			return t
+		}
+		if t, ok = t.property(name); !ok {
+			return nil
+		}
+	}
+	return t
+}
@@ -0,0 +1,455 @@
+package schema
+
+import (
+	"strings"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+	"gopkg.in/yaml.v3"
+)
+
+// jobShape holds the declarations of a job which define the shape of its contexts
+type jobShape struct {
+	needs    []string
+	stepIDs  []string // ids of the steps in order, empty for steps without id
+	services []string
+	matrix   *Type
+	outputs  *Type
+}
+
+type workflowChecker struct {
+	root   *Node
+	inputs *Type
+	jobs   map[string]*jobShape
+	errors []ValidationError
+}
+
+// CheckWorkflowExpressions type checks the expressions of a workflow against the
+// contexts available at their location. The shape of the contexts is derived from
+// the workflow, e.g. steps from the declared step ids, needs from the declared needs,
+// inputs from the workflow_dispatch and workflow_call inputs and matrix from the strategy.
+func CheckWorkflowExpressions(node *yaml.Node) []ValidationError {
+	return CheckWorkflowExpressionsWithSchema(node, GetWorkflowSchema())
+}
+
+// CheckWorkflowExpressionsWithSchema is CheckWorkflowExpressions with the schema of a forge like Gitea
+func CheckWorkflowExpressionsWithSchema(node *yaml.Node, s *Schema) []ValidationError {
+	wc, node := newWorkflowChecker(node, s)
+	if wc == nil {
+		return nil
+	}
+	wc.walk(node, nil, "", -1)
+	return wc.errors
+}
+
+// WorkflowExpressionChecker returns the checker of the expressions at path in the workflow,
+// the items of sequences are addressed by * in path and step is the index of the
+// enclosing step or -1. It returns nil if expressions are not allowed at path.
+func WorkflowExpressionChecker(node *yaml.Node, path []string, step int) *ExpressionChecker {
+	wc, _ := newWorkflowChecker(node, GetWorkflowSchema())
+	if wc == nil {
+		return nil
+	}
+	sn := wc.root.GetNestedNode(path...)
+	if sn == nil || len(sn.Context) == 0 {
+		return nil
+	}
+	var job string
+	if len(path) > 1 && path[0] == "jobs" {
+		job = path[1]
+	}
+	return wc.checkerAt(sn, job, step)
+}
+
+func newWorkflowChecker(node *yaml.Node, s *Schema) (*workflowChecker, *yaml.Node) {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	wc := &workflowChecker{
+		root: &Node{
+			Definition: "workflow-root",
+			Schema:     s,
+		},
+		inputs: ObjectType(nil, nil),
+		jobs:   map[string]*jobShape{},
+	}
+	if on := mappingValue(node, "on"); on != nil {
+		wc.inputs = workflowInputs(on)
+	}
+	if jobs := mappingValue(node, "jobs"); jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobs.Content); i += 2 {
+			wc.jobs[jobs.Content[i].Value] = newJobShape(jobs.Content[i+1])
+		}
+	}
+	return wc, node
+}
+
+// walk checks the expressions of all scalars, job and step locate the node inside of a job
+func (wc *workflowChecker) walk(node *yaml.Node, path []string, job string, step int) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			childJob, childStep := job, step
+			if len(path) == 1 && path[0] == "jobs" {
+				childJob = key
+			}
+			wc.walk(node.Content[i+1], append(path[:len(path):len(path)], key), childJob, childStep)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			childStep := step
+			if len(path) == 3 && path[0] == "jobs" && path[2] == "steps" {
+				childStep = i
+			}
+			wc.walk(item, append(path[:len(path):len(path)], "*"), job, childStep)
+		}
+	case yaml.ScalarNode:
+		wc.checkScalar(node, path, job, step)
+	}
+}
+
+func (wc *workflowChecker) checkScalar(node *yaml.Node, path []string, job string, step int) {
+	sn := wc.root.GetNestedNode(path...)
+	if sn == nil || len(sn.Context) == 0 {
+		return
+	}
+	def := sn.Schema.GetDefinition(sn.Definition)
+	isCondition := def.String != nil && def.String.IsExpression
+	if !isCondition && !strings.Contains(node.Value, "${{") {
+		return
+	}
+
+	checker := wc.checkerAt(sn, job, step)
+	val := strings.TrimSpace(node.Value)
+	if isCondition {
+		if !strings.Contains(val, "${{") {
+			wc.checkExpression(node, checker, val, true)
+			return
+		}
+		if strings.HasPrefix(val, "${{") && strings.HasSuffix(val, "}}") && exprEnd(val[3:]) == len(val)-5 {
+			wc.checkExpression(node, checker, val[3:len(val)-2], true)
+			return
+		}
+		wc.errors = append(wc.errors, ValidationError{
+			Kind:     ValidationKindWarning,
+			Location: toLocation(node),
+			Message:  "condition is always true, the text outside of ${{ }} makes it a non-empty string",
+		})
+	}
+	for {
+		i := strings.Index(val, "${{")
+		if i == -1 {
+			return
+		}
+		val = val[i+3:]
+		j := exprEnd(val)
+		if j == -1 {
+			return
+		}
+		wc.checkExpression(node, checker, val[:j], false)
+		val = val[j+2:]
+	}
+}
+
+func (wc *workflowChecker) checkerAt(sn *Node, job string, step int) *ExpressionChecker {
+	checker := &ExpressionChecker{
+		Contexts:  map[string]*Type{},
+		Functions: sn.GetFunctions(),
+	}
+	for _, name := range sn.GetVariables() {
+		checker.Contexts[strings.ToLower(name)] = wc.contextType(strings.ToLower(name), job, step)
+	}
+	return checker
+}
+
+func (wc *workflowChecker) checkExpression(node *yaml.Node, checker *ExpressionChecker, expr string, isCondition bool) {
+	exprNode, err := exprparser.Parse(expr)
+	if err != nil {
+		// reported by the schema validation
+		return
+	}
+	var errs []ValidationError
+	if isCondition {
+		errs = checker.CheckCondition(exprNode)
+	} else {
+		_, errs = checker.Check(exprNode)
+	}
+	for _, e := range errs {
+		e.Location = toLocation(node)
+		wc.errors = append(wc.errors, e)
+	}
+}
+
+func (wc *workflowChecker) contextType(name string, job string, step int) *Type {
+	shape := wc.jobs[job]
+	switch name {
+	case "env", "vars", "secrets":
+		return ObjectType(nil, ScalarType(TypeKindString))
+	case "inputs":
+		return wc.inputs
+	case "runner":
+		return ObjectType(map[string]*Type{
+			"name":        ScalarType(TypeKindString),
+			"os":          ScalarType(TypeKindString),
+			"arch":        ScalarType(TypeKindString),
+			"temp":        ScalarType(TypeKindString),
+			"tool_cache":  ScalarType(TypeKindString),
+			"debug":       ScalarType(TypeKindString),
+			"environment": ScalarType(TypeKindString),
+		}, nil)
+	case "strategy":
+		return ObjectType(map[string]*Type{
+			"fail-fast":    ScalarType(TypeKindBoolean),
+			"job-index":    ScalarType(TypeKindNumber),
+			"job-total":    ScalarType(TypeKindNumber),
+			"max-parallel": ScalarType(TypeKindNumber),
+		}, nil)
+	case "jobs":
+		jobs := map[string]*Type{}
+		for id, s := range wc.jobs {
+			jobs[id] = jobResultType(s)
+		}
+		return ObjectType(jobs, nil)
+	}
+	if shape == nil {
+		return AnyType()
+	}
+	switch name {
+	case "matrix":
+		return shape.matrix
+	case "needs":
+		needs := map[string]*Type{}
+		for _, id := range shape.needs {
+			if s, ok := wc.jobs[id]; ok {
+				needs[id] = jobResultType(s)
+			} else {
+				needs[id] = ObjectType(nil, AnyType())
+			}
+		}
+		return ObjectType(needs, nil)
+	case "steps":
+		steps := map[string]*Type{}
+		for i, id := range shape.stepIDs {
+			if step != -1 && i >= step {
+				break
+			}
+			if id != "" {
+				steps[id] = ObjectType(map[string]*Type{
+					"outputs":    ObjectType(nil, ScalarType(TypeKindString)),
+					"outcome":    ScalarType(TypeKindString),
+					"conclusion": ScalarType(TypeKindString),
+				}, nil)
+			}
+		}
+		return ObjectType(steps, nil)
+	case "job":
+		services := map[string]*Type{}
+		for _, id := range shape.services {
+			services[id] = ObjectType(map[string]*Type{
+				"id":      ScalarType(TypeKindString),
+				"network": ScalarType(TypeKindString),
+				"ports":   ObjectType(nil, ScalarType(TypeKindString)),
+			}, nil)
+		}
+		return ObjectType(map[string]*Type{
+			"status":              ScalarType(TypeKindString),
+			"check_run_id":        ScalarType(TypeKindNumber),
+			"workflow_ref":        ScalarType(TypeKindString),
+			"workflow_sha":        ScalarType(TypeKindString),
+			"workflow_repository": ScalarType(TypeKindString),
+			"workflow_file_path":  ScalarType(TypeKindString),
+			"container": ObjectType(map[string]*Type{
+				"id":      ScalarType(TypeKindString),
+				"network": ScalarType(TypeKindString),
+			}, nil),
+			"services": ObjectType(services, nil),
+		}, nil)
+	}
+	return AnyType()
+}
+
+func jobResultType(s *jobShape) *Type {
+	return ObjectType(map[string]*Type{
+		"result":  ScalarType(TypeKindString),
+		"outputs": s.outputs,
+	}, nil)
+}
+
+func newJobShape(node *yaml.Node) *jobShape {
+	shape := &jobShape{
+		outputs: ObjectType(nil, nil),
+		matrix:  ObjectType(nil, nil),
+	}
+	if node.Kind != yaml.MappingNode {
+		return shape
+	}
+	if needs := mappingValue(node, "needs"); needs != nil {
+		switch needs.Kind {
+		case yaml.ScalarNode:
+			shape.needs = []string{needs.Value}
+		case yaml.SequenceNode:
+			for _, n := range needs.Content {
+				shape.needs = append(shape.needs, n.Value)
+			}
+		}
+	}
+	if steps := mappingValue(node, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+		for _, s := range steps.Content {
+			var id string
+			if n := mappingValue(s, "id"); n != nil {
+				id = n.Value
+			}
+			shape.stepIDs = append(shape.stepIDs, id)
+		}
+	}
+	if services := mappingValue(node, "services"); services != nil && services.Kind == yaml.MappingNode {
+		for i := 0; i < len(services.Content); i += 2 {
+			shape.services = append(shape.services, services.Content[i].Value)
+		}
+	}
+	if mappingValue(node, "uses") != nil {
+		// the outputs of a reusable workflow are declared in the called workflow
+		shape.outputs = ObjectType(nil, ScalarType(TypeKindString))
+	} else if outputs := mappingValue(node, "outputs"); outputs != nil {
+		shape.outputs = ObjectType(nil, ScalarType(TypeKindString))
+		if outputs.Kind == yaml.MappingNode {
+			props := map[string]*Type{}
+			for i := 0; i < len(outputs.Content); i += 2 {
+				props[outputs.Content[i].Value] = ScalarType(TypeKindString)
+			}
+			shape.outputs = ObjectType(props, nil)
+		}
+	}
+	if strategy := mappingValue(node, "strategy"); strategy != nil {
+		if strategy.Kind != yaml.MappingNode {
+			shape.matrix = ObjectType(nil, AnyType())
+		} else if matrix := mappingValue(strategy, "matrix"); matrix != nil {
+			shape.matrix = matrixType(matrix)
+		}
+	}
+	return shape
+}
+
+// matrixType derives the keys of the matrix from the strategy
+func matrixType(node *yaml.Node) *Type {
+	if node.Kind != yaml.MappingNode {
+		return ObjectType(nil, AnyType())
+	}
+	props := map[string]*Type{}
+	var loose *Type
+	add := func(key string, t *Type) {
+		key = strings.ToLower(key)
+		if strings.Contains(key, "${{") {
+			loose = AnyType()
+			return
+		}
+		if old, ok := props[key]; ok {
+			t = mergeTypes(old, t)
+		}
+		props[key] = t
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "exclude":
+		case "include":
+			if value.Kind != yaml.SequenceNode {
+				loose = AnyType()
+				continue
+			}
+			for _, entry := range value.Content {
+				if entry.Kind != yaml.MappingNode {
+					loose = AnyType()
+					continue
+				}
+				for j := 0; j+1 < len(entry.Content); j += 2 {
+					add(entry.Content[j].Value, yamlType(entry.Content[j+1]))
+				}
+			}
+		default:
+			if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+				add(key, AnyType())
+				continue
+			}
+			t := yamlType(value.Content[0])
+			for _, item := range value.Content[1:] {
+				t = mergeTypes(t, yamlType(item))
+			}
+			add(key, t)
+		}
+	}
+	return ObjectType(props, loose)
+}
+
+func yamlType(node *yaml.Node) *Type {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return ObjectType(nil, AnyType())
+	case yaml.SequenceNode:
+		return ArrayType(AnyType())
+	case yaml.ScalarNode:
+		if strings.Contains(node.Value, "${{") {
+			return AnyType()
+		}
+		switch node.ShortTag() {
+		case "!!int", "!!float":
+			return ScalarType(TypeKindNumber)
+		case "!!bool":
+			return ScalarType(TypeKindBoolean)
+		case "!!null":
+			return ScalarType(TypeKindNull)
+		}
+		return ScalarType(TypeKindString)
+	}
+	return AnyType()
+}
+
+// workflowInputs derives the inputs context from the workflow_dispatch and workflow_call inputs
+func workflowInputs(on *yaml.Node) *Type {
+	props := map[string]*Type{}
+	if on.Kind != yaml.MappingNode {
+		return ObjectType(props, nil)
+	}
+	for _, event := range []string{"workflow_dispatch", "workflow_call"} {
+		trigger := mappingValue(on, event)
+		if trigger == nil {
+			continue
+		}
+		inputs := mappingValue(trigger, "inputs")
+		if inputs == nil || inputs.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(inputs.Content); i += 2 {
+			t := ScalarType(TypeKindString)
+			if typ := mappingValue(inputs.Content[i+1], "type"); typ != nil {
+				switch typ.Value {
+				case "boolean":
+					t = ScalarType(TypeKindBoolean)
+				case "number":
+					t = ScalarType(TypeKindNumber)
+				}
+			}
+			name := strings.ToLower(inputs.Content[i].Value)
+			if old, ok := props[name]; ok {
+				t = mergeTypes(old, t)
+			}
+			props[name] = t
+		}
+	}
+	return ObjectType(props, nil)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	exprparser "github.com/actions-oss/act-cli/internal/expr"
+)
+
+func TestCheckWorkflowExpressions(t *testing.T) {
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(`
+on:
+  workflow_dispatch:
+    inputs:
+      name:
+        type: string
+      debug:
+        type: boolean
+jobs:
+  setup:
+    runs-on: ubuntu-latest
+    outputs:
+      out: ${{ steps.a.outputs.x }}
+    services:
+      redis:
+        image: redis
+    steps:
+      - id: a
+        run: echo ${{ steps.b.outputs.x }} ${{ inputs.nam }} ${{ inputs.debug }}
+      - id: b
+        if: steps.a.outcome == 'success' && steps.c.outcome
+        run: echo ${{ contains('a') }} ${{ job.services.redis.id }} ${{ job.services.db.id }}
+  build:
+    needs: setup
+    if: ${{ needs.setup.result }} == 'success'
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+        os: [ubuntu-latest]
+        include:
+          - node: 20
+    steps:
+      - run: echo ${{ needs.setup.outputs.out }} ${{ needs.setup.outputs.foo }} ${{ needs.other.result }}
+      - run: echo ${{ matrix.node }} ${{ matrix.arch }} ${{ inputs.name.length }} ${{ steps.*.outputs.x }}
+        if: github.event_name == 'push' || 'pull_request'
+      - run: echo
+        if: false
+`), &node))
+
+	type result struct {
+		Line    int
+		Kind    ValidationKind
+		Message string
+	}
+	var results []result
+	for _, e := range CheckWorkflowExpressions(&node) {
+		results = append(results, result{e.Line, e.Kind, e.Message})
+	}
+	assert.ElementsMatch(t, []result{
+		{19, ValidationKindInvalidProperty, "unknown property b of steps"},
+		{19, ValidationKindInvalidProperty, "unknown property nam of inputs, expected one of debug, name"},
+		{21, ValidationKindInvalidProperty, "unknown property c of steps, expected one of a"},
+		{22, ValidationKindFatal, "missing parameters for contains expected >= 2 got 1"},
+		{22, ValidationKindInvalidProperty, "unknown property db of job.services, expected one of redis"},
+		{25, ValidationKindWarning, "condition is always true, the text outside of ${{ }} makes it a non-empty string"},
+		{33, ValidationKindInvalidProperty, "unknown property foo of needs.setup.outputs, expected one of out"},
+		{33, ValidationKindInvalidProperty, "unknown property other of needs, expected one of setup"},
+		{34, ValidationKindInvalidProperty, "unknown property arch of matrix, expected one of node, os"},
+		{34, ValidationKindMismatched, "property length of inputs.name does not exist, it is a string"},
+		{35, ValidationKindWarning, "condition is always true"},
+		{37, ValidationKindWarning, "condition is always false"},
+	}, results)
+}
+
+func TestExpressionCheckerTypes(t *testing.T) {
+	checker := &ExpressionChecker{
+		Contexts: map[string]*Type{
+			"matrix": ObjectType(map[string]*Type{"os": ScalarType(TypeKindString)}, nil),
+			"steps": ObjectType(map[string]*Type{
+				"a": ObjectType(map[string]*Type{"outputs": ObjectType(nil, ScalarType(TypeKindString))}, nil),
+			}, nil),
+		},
+		Functions: (&Node{}).GetFunctions(),
+	}
+	for expr, kind := range map[string]TypeKind{
+		"matrix.os":                       TypeKindString,
+		"matrix['OS']":                    TypeKindString,
+		"steps.a.outputs":                 TypeKindObject,
+		"steps.*.outputs.x":               TypeKindArray,
+		"fromJSON(matrix.os)":             TypeKindAny,
+		"format('{0}', matrix.os)":        TypeKindString,
+		"matrix.os == 'linux'":            TypeKindBoolean,
+		"matrix.os || 'linux'":            TypeKindString,
+		"steps.a.outputs.x && matrix.os":  TypeKindString,
+		"contains(matrix.os, 'ubuntu')":   TypeKindBoolean,
+		"steps.a.outputs[matrix.os]":      TypeKindString,
+		"toJSON(steps.a.outputs) || null": TypeKindAny,
+	} {
+		node, err := exprparser.Parse(expr)
+		require.NoError(t, err)
+		typ, errs := checker.Check(node)
+		assert.Empty(t, errs, expr)
+		assert.Equal(t, kind, typ.Kind, expr)
+	}
+}